@@ -0,0 +1,80 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "sync/atomic"
+
+// Activity is a snapshot of the package wide activity counters, for
+// watching crypto activity from a debug endpoint, see the debugvars
+// package. Counters only increase for the life of the process.
+type Activity struct {
+	// Created counts generated keys by type, e.g. "user" or "x25519".
+	Created map[string]uint64 `json:"created"`
+	// Signed counts signatures made by seed based KeyPairs.
+	Signed uint64 `json:"signed"`
+	// Verified and VerifyFailures count Verify calls by outcome.
+	Verified       uint64 `json:"verified"`
+	VerifyFailures uint64 `json:"verify_failures"`
+	// DecodeFailures counts encoded keys that failed to decode, including
+	// those probed by the IsValid* functions.
+	DecodeFailures uint64 `json:"decode_failures"`
+}
+
+var activity struct {
+	// created is indexed by the prefix byte shifted right by three.
+	created        [32]uint64
+	signed         uint64
+	verified       uint64
+	verifyFailures uint64
+	decodeFailures uint64
+}
+
+// ActivitySnapshot returns the current activity counters.
+func ActivitySnapshot() Activity {
+	a := Activity{
+		Created:        make(map[string]uint64),
+		Signed:         atomic.LoadUint64(&activity.signed),
+		Verified:       atomic.LoadUint64(&activity.verified),
+		VerifyFailures: atomic.LoadUint64(&activity.verifyFailures),
+		DecodeFailures: atomic.LoadUint64(&activity.decodeFailures),
+	}
+	for i := range activity.created {
+		if n := atomic.LoadUint64(&activity.created[i]); n > 0 {
+			a.Created[PrefixByte(i<<3).String()] = n
+		}
+	}
+	return a
+}
+
+func countCreated(prefix PrefixByte) {
+	atomic.AddUint64(&activity.created[prefix>>3], 1)
+}
+
+func countSigned() {
+	atomic.AddUint64(&activity.signed, 1)
+}
+
+// countVerify counts a verification with result err and returns err.
+func countVerify(err error) error {
+	if err != nil {
+		atomic.AddUint64(&activity.verifyFailures, 1)
+	} else {
+		atomic.AddUint64(&activity.verified, 1)
+	}
+	return err
+}
+
+func countDecodeFailure() {
+	atomic.AddUint64(&activity.decodeFailures, 1)
+}
@@ -0,0 +1,343 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package agent implements an ssh-agent like signing agent. The agent holds
+// key pairs in memory and signs on behalf of clients connecting over a unix
+// domain socket, so that many processes can share one signing identity
+// without each of them holding the seed.
+//
+// The protocol is a sequence of newline delimited JSON requests, each
+// answered by exactly one JSON response.
+//
+// Peer credentials are only available on Linux. On other platforms every
+// connection to the socket is accepted, so the permissions of the socket
+// and its directory are the only access control, AllowedUIDs has no effect
+// and keys constrained to Executables can not sign.
+package agent
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/nats-io/nkeys"
+)
+
+// Errors
+const (
+	ErrLocked        = agentError("agent: locked")
+	ErrNotLocked     = agentError("agent: not locked")
+	ErrBadPassphrase = agentError("agent: incorrect passphrase")
+	ErrUnknownKey    = agentError("agent: unknown key")
+	ErrUnknownOp     = agentError("agent: unknown operation")
+	ErrCannotExport  = agentError("agent: keys held by the agent can not be exported")
+	ErrPeerDenied    = agentError("agent: peer not allowed")
+	ErrNotConfirmed  = agentError("agent: signature not confirmed")
+)
+
+type agentError string
+
+func (e agentError) Error() string {
+	return string(e)
+}
+
+var knownErrors = []error{ErrLocked, ErrNotLocked, ErrBadPassphrase, ErrUnknownKey, ErrUnknownOp, ErrCannotExport, ErrPeerDenied, ErrNotConfirmed}
+
+// Operations
+const (
+	OpList      = "list"
+	OpSign      = "sign"
+	OpAdd       = "add"
+	OpRemove    = "remove"
+	OpRemoveAll = "remove_all"
+	OpLock      = "lock"
+	OpUnlock    = "unlock"
+)
+
+type request struct {
+	Op         string `json:"op"`
+	PublicKey  string `json:"public_key,omitempty"`
+	Data       []byte `json:"data,omitempty"`
+	Seed       []byte `json:"seed,omitempty"`
+	Passphrase []byte `json:"passphrase,omitempty"`
+	// Constraints apply to keys added with OpAdd.
+	Constraints *KeyConstraints `json:"constraints,omitempty"`
+}
+
+type response struct {
+	Error     string   `json:"error,omitempty"`
+	Keys      []string `json:"keys,omitempty"`
+	Signature []byte   `json:"sig,omitempty"`
+}
+
+// Agent holds key pairs and serves signing requests.
+//
+// Connections from processes of another user are refused when the platform
+// reports peer credentials, and keys added with constraints can be bound to
+// specific executables or require confirmation of every signature.
+type Agent struct {
+	// AllowedUIDs lists further users, beside the one running the agent,
+	// that may connect.
+	AllowedUIDs []int
+	// Confirm is asked to approve signatures with keys added with
+	// KeyConstraints.Confirm, e.g. by showing a dialog. If nil such
+	// signatures are refused.
+	Confirm func(ConfirmRequest) bool
+
+	// mu is held for reading while signing, so that Remove and RemoveAll
+	// never wipe a key that is in use.
+	mu     sync.RWMutex
+	keys   map[string]*heldKey
+	locked bool
+	lock   [sha256.Size]byte
+}
+
+type heldKey struct {
+	kp          nkeys.KeyPair
+	constraints KeyConstraints
+}
+
+// New returns an empty, unlocked Agent.
+func New() *Agent {
+	return &Agent{keys: make(map[string]*heldKey)}
+}
+
+// Add adds a key pair. The agent takes ownership and wipes it on removal.
+func (a *Agent) Add(kp nkeys.KeyPair) error {
+	return a.AddConstrained(kp, KeyConstraints{})
+}
+
+// AddConstrained adds a key pair whose use is restricted by kc. Requests
+// made with Sign directly, rather than through a connection, only honor
+// Confirm.
+func (a *Agent) AddConstrained(kp nkeys.KeyPair, kc KeyConstraints) error {
+	pk, err := kp.PublicKey()
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return ErrLocked
+	}
+	if old, ok := a.keys[pk]; ok && old.kp != kp {
+		old.kp.Wipe()
+	}
+	kc.Executables = append([]string{}, kc.Executables...)
+	a.keys[pk] = &heldKey{kp, kc}
+	return nil
+}
+
+// Remove removes and wipes the key pair for public.
+func (a *Agent) Remove(public string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return ErrLocked
+	}
+	hk, ok := a.keys[public]
+	if !ok {
+		return ErrUnknownKey
+	}
+	hk.kp.Wipe()
+	delete(a.keys, public)
+	return nil
+}
+
+// RemoveAll removes and wipes all key pairs.
+func (a *Agent) RemoveAll() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return ErrLocked
+	}
+	for pk, hk := range a.keys {
+		hk.kp.Wipe()
+		delete(a.keys, pk)
+	}
+	return nil
+}
+
+// List returns the public keys held by the agent in lexical order. A locked
+// agent lists no keys.
+func (a *Agent) List() ([]string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(a.keys))
+	for pk := range a.keys {
+		keys = append(keys, pk)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Sign signs data with the key pair for public on behalf of the local process.
+func (a *Agent) Sign(public string, data []byte) ([]byte, error) {
+	return a.signFor(Peer{}, public, data)
+}
+
+func (a *Agent) signFor(peer Peer, public string, data []byte) ([]byte, error) {
+	a.mu.Lock()
+	if a.locked {
+		a.mu.Unlock()
+		return nil, ErrLocked
+	}
+	hk, ok := a.keys[public]
+	a.mu.Unlock()
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if !hk.constraints.allowsPeer(peer) {
+		return nil, ErrPeerDenied
+	}
+	if hk.constraints.Confirm {
+		if a.Confirm == nil || !a.Confirm(ConfirmRequest{PublicKey: public, Peer: peer, Size: len(data)}) {
+			return nil, ErrNotConfirmed
+		}
+	}
+	// The key may have been removed or the agent locked while confirming.
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.locked {
+		return nil, ErrLocked
+	}
+	if a.keys[public] != hk {
+		return nil, ErrUnknownKey
+	}
+	return hk.kp.Sign(data)
+}
+
+// allowsPeer reports whether a connection from peer is accepted. Peers
+// are only known on some platforms; elsewhere the permissions of the socket
+// restrict who can connect, and keys with Executables deny every signature.
+func (a *Agent) allowsPeer(peer Peer) bool {
+	if !peer.Known || peer.UID == os.Getuid() {
+		return true
+	}
+	for _, uid := range a.AllowedUIDs {
+		if uid == peer.UID {
+			return true
+		}
+	}
+	return false
+}
+
+// Lock refuses all operations but Unlock until unlocked with passphrase.
+func (a *Agent) Lock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return ErrLocked
+	}
+	a.locked = true
+	a.lock = sha256.Sum256(passphrase)
+	return nil
+}
+
+// Unlock unlocks the agent if passphrase matches the one given to Lock.
+func (a *Agent) Unlock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.locked {
+		return ErrNotLocked
+	}
+	sum := sha256.Sum256(passphrase)
+	if subtle.ConstantTimeCompare(sum[:], a.lock[:]) != 1 {
+		return ErrBadPassphrase
+	}
+	a.locked = false
+	return nil
+}
+
+// Serve accepts connections on l until it is closed.
+func (a *Agent) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go a.ServeConn(conn)
+	}
+}
+
+// ServeConn serves requests on conn until it is closed.
+func (a *Agent) ServeConn(conn net.Conn) {
+	defer conn.Close()
+	peer := PeerOf(conn)
+	if !a.allowsPeer(peer) {
+		json.NewEncoder(conn).Encode(&response{Error: ErrPeerDenied.Error()})
+		return
+	}
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	enc := json.NewEncoder(conn)
+	for {
+		var req request
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		resp := a.handle(peer, &req)
+		wipe(req.Seed)
+		wipe(req.Passphrase)
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+func (a *Agent) handle(peer Peer, req *request) *response {
+	var resp response
+	var err error
+	switch req.Op {
+	case OpList:
+		resp.Keys, err = a.List()
+	case OpSign:
+		resp.Signature, err = a.signFor(peer, req.PublicKey, req.Data)
+	case OpAdd:
+		var kp nkeys.KeyPair
+		var kc KeyConstraints
+		if req.Constraints != nil {
+			kc = *req.Constraints
+		}
+		if kp, err = nkeys.FromSeed(req.Seed); err == nil {
+			err = a.AddConstrained(kp, kc)
+		}
+	case OpRemove:
+		err = a.Remove(req.PublicKey)
+	case OpRemoveAll:
+		err = a.RemoveAll()
+	case OpLock:
+		err = a.Lock(req.Passphrase)
+	case OpUnlock:
+		err = a.Unlock(req.Passphrase)
+	default:
+		err = ErrUnknownOp
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return &resp
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 'x'
+	}
+}
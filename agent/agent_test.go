@@ -0,0 +1,244 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/nats-io/nkeys"
+	"github.com/nats-io/nkeys/conformance"
+)
+
+func startAgent(t *testing.T) (*Agent, *Client) {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	a := New()
+	go a.Serve(l)
+	c, err := Dial(sock)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return a, c
+}
+
+func TestAgentSign(t *testing.T) {
+	_, c := startAgent(t)
+	user, _ := nkeys.CreateUser()
+	upk, _ := user.PublicKey()
+
+	if err := c.Add(user); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	keys, err := c.List()
+	if err != nil || len(keys) != 1 || keys[0] != upk {
+		t.Fatalf("Expected [%s], got %v %v", upk, keys, err)
+	}
+	kp, err := c.KeyPair(upk)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data := []byte("hello")
+	sig, err := kp.Sign(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := user.Verify(data, sig); err != nil {
+		t.Fatalf("Expected agent signature to verify, got %v", err)
+	}
+	if _, err := kp.Seed(); err != ErrCannotExport {
+		t.Fatalf("Expected %v, got %v", ErrCannotExport, err)
+	}
+
+	if err := c.Remove(upk); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := kp.Sign(data); err != ErrUnknownKey {
+		t.Fatalf("Expected %v, got %v", ErrUnknownKey, err)
+	}
+	if err := c.Remove(upk); err != ErrUnknownKey {
+		t.Fatalf("Expected %v, got %v", ErrUnknownKey, err)
+	}
+}
+
+func TestAgentSignWhileRemoving(t *testing.T) {
+	a := New()
+	user, _ := nkeys.CreateUser()
+	upk, _ := user.PublicKey()
+	pub, _ := user.PublicOnly()
+	a.Add(user)
+
+	data := []byte("hello")
+	var wg, signing sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		signing.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; ; n++ {
+				if n == 10 {
+					signing.Done()
+				}
+				sig, err := a.Sign(upk, data)
+				if err == ErrUnknownKey {
+					return
+				}
+				if err != nil || pub.Verify(data, sig) != nil {
+					t.Errorf("Expected a valid signature or %v, got %v", ErrUnknownKey, err)
+					return
+				}
+			}
+		}()
+	}
+	signing.Wait()
+	a.Remove(upk)
+	wg.Wait()
+}
+
+func TestAgentCheckKey(t *testing.T) {
+	_, c := startAgent(t)
+	user, _ := nkeys.CreateUser()
+	upk, _ := user.PublicKey()
+	c.Add(user)
+	kp, _ := c.KeyPair(upk)
+	if h := nkeys.CheckKey(kp); !h.Healthy() || h.StoreLatency == 0 {
+		t.Fatalf("Expected a healthy key with a store latency, got %+v", h)
+	}
+	c.Remove(upk)
+	if h := nkeys.CheckKey(kp); h.Err != ErrUnknownKey {
+		t.Fatalf("Expected %v, got %v", ErrUnknownKey, h.Err)
+	}
+}
+
+func TestAgentLock(t *testing.T) {
+	a, c := startAgent(t)
+	user, _ := nkeys.CreateUser()
+	upk, _ := user.PublicKey()
+	if err := a.Add(user); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := c.Lock([]byte("secret")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := c.Sign(upk, []byte("hello")); err != ErrLocked {
+		t.Fatalf("Expected %v, got %v", ErrLocked, err)
+	}
+	if keys, _ := c.List(); len(keys) != 0 {
+		t.Fatalf("Expected a locked agent to list no keys, got %v", keys)
+	}
+	if err := c.Unlock([]byte("wrong")); err != ErrBadPassphrase {
+		t.Fatalf("Expected %v, got %v", ErrBadPassphrase, err)
+	}
+	if err := c.Unlock([]byte("secret")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := c.Sign(upk, []byte("hello")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := c.RemoveAll(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if keys, _ := c.List(); len(keys) != 0 {
+		t.Fatalf("Expected no keys, got %v", keys)
+	}
+}
+
+func TestAgentConstraints(t *testing.T) {
+	a, c := startAgent(t)
+	user, _ := nkeys.CreateUser()
+	upk, _ := user.PublicKey()
+	data := []byte("hello")
+
+	if err := c.AddConstrained(user, KeyConstraints{Executables: []string{"/nonexistent/nats"}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Denied on every platform: elsewhere the peer is unknown.
+	if _, err := c.Sign(upk, data); err != ErrPeerDenied {
+		t.Fatalf("Expected %v, got %v", ErrPeerDenied, err)
+	}
+	if _, err := a.signFor(Peer{}, upk, data); err != ErrPeerDenied {
+		t.Fatalf("Expected unknown peers to be denied, got %v", err)
+	}
+	if runtime.GOOS == "linux" {
+		exe, _ := os.Executable()
+		exe, _ = filepath.EvalSymlinks(exe)
+		a.AddConstrained(user, KeyConstraints{Executables: []string{exe}})
+		if _, err := c.Sign(upk, data); err != nil {
+			t.Fatalf("Expected the test binary to be allowed, got %v", err)
+		}
+	}
+
+	a.AddConstrained(user, KeyConstraints{Confirm: true})
+	if _, err := c.Sign(upk, data); err != ErrNotConfirmed {
+		t.Fatalf("Expected %v, got %v", ErrNotConfirmed, err)
+	}
+	var asked []ConfirmRequest
+	a.Confirm = func(r ConfirmRequest) bool {
+		asked = append(asked, r)
+		return true
+	}
+	if _, err := c.Sign(upk, data); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(asked) != 1 || asked[0].PublicKey != upk || asked[0].Size != len(data) {
+		t.Fatalf("Unexpected confirmation requests %+v", asked)
+	}
+	if runtime.GOOS == "linux" && asked[0].Peer.PID != os.Getpid() {
+		t.Fatalf("Expected peer pid %d, got %d", os.Getpid(), asked[0].Peer.PID)
+	}
+}
+
+func TestAgentAllowsPeer(t *testing.T) {
+	a := New()
+	if !a.allowsPeer(Peer{Known: true, UID: os.Getuid()}) {
+		t.Fatalf("Expected the agent's own user to be allowed")
+	}
+	other := os.Getuid() + 1
+	if a.allowsPeer(Peer{Known: true, UID: other}) {
+		t.Fatalf("Expected other users to be denied")
+	}
+	a.AllowedUIDs = []int{other}
+	if !a.allowsPeer(Peer{Known: true, UID: other}) {
+		t.Fatalf("Expected allowed users to be accepted")
+	}
+}
+
+func TestAgentConformance(t *testing.T) {
+	_, c := startAgent(t)
+	conformance.RunConformance(t, func(prefix nkeys.PrefixByte) (nkeys.KeyPair, error) {
+		if prefix == nkeys.PrefixByteCurve {
+			return nil, nkeys.ErrInvalidPrefixByte
+		}
+		kp, err := nkeys.CreatePair(prefix)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Add(kp); err != nil {
+			return nil, err
+		}
+		pk, _ := kp.PublicKey()
+		return c.KeyPair(pk)
+	})
+}
@@ -0,0 +1,218 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/nats-io/nkeys"
+)
+
+// SocketEnv names the environment variable holding the agent socket path.
+const SocketEnv = "NKEY_AGENT_SOCK"
+
+// Client talks to an agent. It is safe for concurrent use.
+type Client struct {
+	mu   sync.Mutex
+	conn net.Conn
+	dec  *json.Decoder
+	enc  *json.Encoder
+}
+
+// Dial connects to the agent listening on the unix socket at path. An empty
+// path uses the SocketEnv environment variable.
+func Dial(path string) (*Client, error) {
+	if path == "" {
+		path = os.Getenv(SocketEnv)
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient returns a Client using conn.
+func NewClient(conn net.Conn) *Client {
+	return &Client{conn: conn, dec: json.NewDecoder(bufio.NewReader(conn)), enc: json.NewEncoder(conn)}
+}
+
+// Close closes the connection to the agent.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) call(req *request) (*response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.enc.Encode(req); err != nil {
+		return nil, err
+	}
+	var resp response
+	if err := c.dec.Decode(&resp); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, remoteError(resp.Error)
+	}
+	return &resp, nil
+}
+
+// remoteError maps error strings back to the package's errors so that
+// callers can compare against them.
+func remoteError(s string) error {
+	for _, err := range knownErrors {
+		if err.Error() == s {
+			return err
+		}
+	}
+	return errors.New(s)
+}
+
+// List returns the public keys held by the agent.
+func (c *Client) List() ([]string, error) {
+	resp, err := c.call(&request{Op: OpList})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Keys, nil
+}
+
+// Sign asks the agent to sign data with the key for public.
+func (c *Client) Sign(public string, data []byte) ([]byte, error) {
+	resp, err := c.call(&request{Op: OpSign, PublicKey: public, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Signature, nil
+}
+
+// Add sends the seed of kp to the agent.
+func (c *Client) Add(kp nkeys.KeyPair) error {
+	return c.AddConstrained(kp, KeyConstraints{})
+}
+
+// AddConstrained sends the seed of kp to the agent, restricting its use by kc.
+func (c *Client) AddConstrained(kp nkeys.KeyPair, kc KeyConstraints) error {
+	seed, err := kp.Seed()
+	if err != nil {
+		return err
+	}
+	_, err = c.call(&request{Op: OpAdd, Seed: seed, Constraints: &kc})
+	return err
+}
+
+// Remove asks the agent to forget the key for public.
+func (c *Client) Remove(public string) error {
+	_, err := c.call(&request{Op: OpRemove, PublicKey: public})
+	return err
+}
+
+// RemoveAll asks the agent to forget all keys.
+func (c *Client) RemoveAll() error {
+	_, err := c.call(&request{Op: OpRemoveAll})
+	return err
+}
+
+// Lock locks the agent with passphrase.
+func (c *Client) Lock(passphrase []byte) error {
+	_, err := c.call(&request{Op: OpLock, Passphrase: passphrase})
+	return err
+}
+
+// Unlock unlocks the agent with passphrase.
+func (c *Client) Unlock(passphrase []byte) error {
+	_, err := c.call(&request{Op: OpUnlock, Passphrase: passphrase})
+	return err
+}
+
+// KeyPair returns a KeyPair that signs through the agent with the key for
+// public. Verification is done locally.
+func (c *Client) KeyPair(public string) (nkeys.KeyPair, error) {
+	pub, err := nkeys.FromPublicKey(public)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteKeyPair{c: c, public: public, pub: pub}, nil
+}
+
+// remoteKeyPair is a KeyPair whose private key lives in the agent.
+type remoteKeyPair struct {
+	c      *Client
+	public string
+	pub    nkeys.KeyPair
+}
+
+func (r *remoteKeyPair) Seed() ([]byte, error) {
+	return nil, ErrCannotExport
+}
+
+func (r *remoteKeyPair) PublicKey() (string, error) {
+	return r.public, nil
+}
+
+func (r *remoteKeyPair) PrivateKey() ([]byte, error) {
+	return nil, ErrCannotExport
+}
+
+func (r *remoteKeyPair) Sign(input []byte) ([]byte, error) {
+	return r.c.Sign(r.public, input)
+}
+
+func (r *remoteKeyPair) Verify(input []byte, sig []byte) error {
+	return r.pub.Verify(input, sig)
+}
+
+func (r *remoteKeyPair) PublicOnly() (nkeys.KeyPair, error) {
+	return r.pub.PublicOnly()
+}
+
+// Ping checks that the agent is reachable and holds the key, for
+// nkeys.CheckKey.
+func (r *remoteKeyPair) Ping() error {
+	keys, err := r.c.List()
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if k == r.public {
+			return nil
+		}
+	}
+	return ErrUnknownKey
+}
+
+// Wipe does nothing, the key pair holds no secrets.
+func (r *remoteKeyPair) Wipe() {}
+
+func (r *remoteKeyPair) Seal(input []byte, recipient string) ([]byte, error) {
+	return nil, nkeys.ErrInvalidNKeyOperation
+}
+
+func (r *remoteKeyPair) SealWithRand(input []byte, recipient string, rr io.Reader) ([]byte, error) {
+	return nil, nkeys.ErrInvalidNKeyOperation
+}
+
+func (r *remoteKeyPair) Open(input []byte, sender string) ([]byte, error) {
+	return nil, nkeys.ErrInvalidNKeyOperation
+}
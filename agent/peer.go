@@ -0,0 +1,78 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"net"
+	"path/filepath"
+)
+
+// Peer identifies the process on the other end of a connection, as
+// reported by the kernel.
+type Peer struct {
+	// Known is false if the platform can not report peer credentials.
+	Known bool
+	UID   int
+	GID   int
+	PID   int
+	// Exe is the path of the peer's executable, if it could be determined.
+	Exe string
+}
+
+// PeerOf returns the credentials of the process connected to conn.
+func PeerOf(conn net.Conn) Peer {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return Peer{}
+	}
+	p, err := peerCredentials(uc)
+	if err != nil {
+		return Peer{}
+	}
+	return p
+}
+
+// KeyConstraints restrict how a key held by the agent may be used.
+type KeyConstraints struct {
+	// Executables, when not empty, lists the executables allowed to sign
+	// with the key. Peers whose executable can not be determined are denied.
+	Executables []string `json:"executables,omitempty"`
+	// Confirm requires the agent's Confirm callback to approve every
+	// signature.
+	Confirm bool `json:"confirm,omitempty"`
+}
+
+// allowsPeer reports whether peer may sign with the key. Unknown peers and
+// peers without an executable are denied if Executables is set.
+func (kc *KeyConstraints) allowsPeer(peer Peer) bool {
+	if len(kc.Executables) == 0 {
+		return true
+	}
+	if !peer.Known || peer.Exe == "" {
+		return false
+	}
+	for _, e := range kc.Executables {
+		if filepath.Clean(e) == peer.Exe {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfirmRequest describes a signature awaiting confirmation.
+type ConfirmRequest struct {
+	PublicKey string
+	Peer      Peer
+	Size      int
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+// AlgorithmID identifies the public key algorithm behind an encoded key.
+// Decoding dispatches on it so that further key classes, e.g. post-quantum
+// ones, can be added next to ed25519 without changing existing encodings.
+type AlgorithmID uint8
+
+const (
+	// AlgorithmUnknown is returned for keys that can not be classified.
+	AlgorithmUnknown AlgorithmID = iota
+	// AlgorithmEd25519 is used by operator, account, server, cluster and
+	// user keys.
+	AlgorithmEd25519
+	// AlgorithmX25519 is used by curve keys.
+	AlgorithmX25519
+	// AlgorithmHybridMLDSA65 is used by the experimental ed25519 and
+	// ML-DSA-65 hybrid keys of package pqhybrid.
+	AlgorithmHybridMLDSA65
+)
+
+func (a AlgorithmID) String() string {
+	switch a {
+	case AlgorithmEd25519:
+		return "ed25519"
+	case AlgorithmX25519:
+		return "x25519"
+	case AlgorithmHybridMLDSA65:
+		return "ed25519+ml-dsa-65"
+	}
+	return "unknown"
+}
+
+// AlgorithmOf returns the algorithm used by keys of the given type.
+func AlgorithmOf(prefix PrefixByte) AlgorithmID {
+	switch prefix {
+	case PrefixByteOperator, PrefixByteServer, PrefixByteCluster, PrefixByteAccount, PrefixByteUser, PrefixBytePrivate:
+		return AlgorithmEd25519
+	case PrefixByteCurve:
+		return AlgorithmX25519
+	}
+	return AlgorithmUnknown
+}
+
+// Algorithm returns the algorithm of an encoded public key, private key or seed.
+func Algorithm(key string) (AlgorithmID, error) {
+	raw, err := decode([]byte(key))
+	if err != nil {
+		return AlgorithmUnknown, err
+	}
+	prefix := PrefixByte(raw[0])
+	if PrefixByte(raw[0]&248) == PrefixByteSeed {
+		if prefix, _, err = DecodeSeed([]byte(key)); err != nil {
+			return AlgorithmUnknown, err
+		}
+	}
+	if a := AlgorithmOf(prefix); a != AlgorithmUnknown {
+		return a, nil
+	}
+	return AlgorithmUnknown, ErrUnsupportedAlgorithm
+}
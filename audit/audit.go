@@ -0,0 +1,199 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit keeps a tamper-evident log of key operations. Every record
+// holds the hash of the record before it and is signed by the audit key, so
+// removing, reordering or editing records breaks the chain. Records removed
+// from the end leave a valid, shorter chain; to detect that, keep the Head
+// of the log somewhere the backend's writers can not reach and pass it to
+// Verify. Logs are fed by the events of nkeys.EventKeyPair.
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nkeys"
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// Errors
+const (
+	ErrBrokenChain   = auditError("audit: record does not follow the previous record")
+	ErrInvalidRecord = auditError("audit: invalid record signature")
+	ErrWrongSigner   = auditError("audit: record is not signed by the audit key")
+	ErrTruncated     = auditError("audit: chain does not reach the expected head")
+)
+
+type auditError string
+
+func (e auditError) Error() string {
+	return string(e)
+}
+
+// Record is a signed entry of the log.
+type Record struct {
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	Operation string    `json:"op"`
+	// Subject is the public key of the KeyPair the operation was done with.
+	Subject string `json:"sub"`
+	// Size is the length of the signed input for signing operations.
+	Size int `json:"size,omitempty"`
+	// Reason is the justification of a forced export.
+	Reason string `json:"reason,omitempty"`
+	// Prev is the Hash of the previous record, empty for the first.
+	Prev      []byte `json:"prev,omitempty"`
+	Signer    string `json:"signer"`
+	Signature []byte `json:"sig"`
+}
+
+func (r *Record) signedBytes() []byte {
+	e := canonical.NewEncoder("nkeys.audit.Record")
+	e.Uint64(r.Seq)
+	e.Time(r.Time)
+	e.Text(r.Operation)
+	e.Text(r.Subject)
+	e.Int64(int64(r.Size))
+	e.Text(r.Reason)
+	e.Blob(r.Prev)
+	e.Text(r.Signer)
+	return e.Bytes()
+}
+
+// Hash returns the hash the next record refers to. It covers the signature.
+func (r *Record) Hash() []byte {
+	sum := sha256.Sum256(append(r.signedBytes(), r.Signature...))
+	return sum[:]
+}
+
+// Head identifies the last record of a chain.
+type Head struct {
+	Seq  uint64 `json:"seq"`
+	Hash []byte `json:"hash,omitempty"`
+}
+
+// Log appends signed records to a Backend. It is safe for concurrent use.
+type Log struct {
+	backend Backend
+	signer  nkeys.KeyPair
+	public  string
+
+	// OnError, when set, receives errors of records appended by Handler.
+	OnError func(error)
+
+	mu   sync.Mutex
+	seq  uint64
+	prev []byte
+}
+
+// NewLog continues the chain stored in backend, signing new records with
+// signer. The stored chain is verified first and must have been signed by
+// signer throughout.
+func NewLog(backend Backend, signer nkeys.KeyPair) (*Log, error) {
+	public, err := signer.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	records, err := backend.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := Verify(records, public, nil, nil); err != nil {
+		return nil, err
+	}
+	l := &Log{backend: backend, signer: signer, public: public}
+	if n := len(records); n > 0 {
+		l.seq = records[n-1].Seq
+		l.prev = records[n-1].Hash()
+	}
+	return l, nil
+}
+
+// Append signs a record of the event and appends it to the backend.
+func (l *Log) Append(ev nkeys.Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r := Record{
+		Seq:       l.seq + 1,
+		Time:      ev.Time.UTC(),
+		Operation: ev.Type.String(),
+		Subject:   ev.PublicKey,
+		Size:      ev.Size,
+		Reason:    ev.Reason,
+		Prev:      l.prev,
+		Signer:    l.public,
+	}
+	sig, err := l.signer.Sign(r.signedBytes())
+	if err != nil {
+		return err
+	}
+	r.Signature = sig
+	if err := l.backend.Append(r); err != nil {
+		return err
+	}
+	l.seq, l.prev = r.Seq, r.Hash()
+	return nil
+}
+
+// Head returns the head of the chain written so far.
+func (l *Log) Head() Head {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return Head{Seq: l.seq, Hash: l.prev}
+}
+
+// Handler returns an EventHandler for nkeys.WithEvents and similar that
+// appends every event to the log.
+func (l *Log) Handler() nkeys.EventHandler {
+	return func(ev nkeys.Event) {
+		if err := l.Append(ev); err != nil && l.OnError != nil {
+			l.OnError(err)
+		}
+	}
+}
+
+// Verify checks that records form an unbroken chain starting at the first
+// record ever written and that every record is correctly signed by the
+// audit key public. Without the expected key anyone able to write to the
+// backend could re-sign a rewritten chain. If head is not nil the chain must
+// reach it, so that records removed from the end are detected; later
+// records are allowed. The policy, which may be nil, is applied to the
+// audit key as well, e.g. to check it was not revoked.
+func Verify(records []Record, public string, head *Head, vp *nkeys.VerifyPolicy) error {
+	var prev []byte
+	for i := range records {
+		r := &records[i]
+		if r.Seq != uint64(i)+1 || !bytes.Equal(r.Prev, prev) {
+			return ErrBrokenChain
+		}
+		if r.Signer != public {
+			return ErrWrongSigner
+		}
+		if err := nkeys.VerifyWithPolicy(vp, r.Signer, r.signedBytes(), r.Signature); err != nil {
+			if err == nkeys.ErrInvalidSignature {
+				return ErrInvalidRecord
+			}
+			return err
+		}
+		prev = r.Hash()
+	}
+	if head != nil && head.Seq > 0 {
+		if head.Seq > uint64(len(records)) || !bytes.Equal(records[head.Seq-1].Hash(), head.Hash) {
+			return ErrTruncated
+		}
+	}
+	return nil
+}
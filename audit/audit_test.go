@@ -0,0 +1,106 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nats-io/nkeys"
+)
+
+func TestLog(t *testing.T) {
+	auditor, _ := nkeys.CreateOperator()
+	backend := NewFileBackend(filepath.Join(t.TempDir(), "audit.log"))
+	log, err := NewLog(backend, auditor)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	log.OnError = func(err error) { t.Fatalf("Expected no error, got %v", err) }
+
+	kp, err := nkeys.CreatePairWithEvents(nkeys.PrefixByteUser, log.Handler())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	kp.Sign([]byte("hello"))
+	kp.Seed()
+
+	// A restarted log continues the chain.
+	if log, err = NewLog(backend, auditor); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := log.Append(nkeys.Event{Type: nkeys.EventWiped}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	records, _ := backend.Load()
+	if len(records) != 4 {
+		t.Fatalf("Expected 4 records, got %d", len(records))
+	}
+	if records[1].Operation != "signed" || records[1].Size != 5 {
+		t.Fatalf("Expected a signed record of 5 bytes, got %+v", records[1])
+	}
+	apk, _ := auditor.PublicKey()
+	vp := &nkeys.VerifyPolicy{Trusted: staticTrust(apk)}
+	head := log.Head()
+	if err := Verify(records, apk, &head, vp); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// A truncated chain is still valid on its own but does not reach the
+	// head, while an earlier head is reached by the full chain.
+	if err := Verify(records[:3], apk, nil, vp); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := Verify(records[:3], apk, &head, vp); err != ErrTruncated {
+		t.Fatalf("Expected %v, got %v", ErrTruncated, err)
+	}
+	earlier := Head{Seq: 2, Hash: records[1].Hash()}
+	if err := Verify(records, apk, &earlier, vp); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	edited := append([]Record{}, records...)
+	edited[1].Size = 500
+	if err := Verify(edited, apk, nil, vp); err != ErrInvalidRecord {
+		t.Fatalf("Expected %v, got %v", ErrInvalidRecord, err)
+	}
+	removed := append(append([]Record{}, records[:1]...), records[2:]...)
+	if err := Verify(removed, apk, nil, vp); err != ErrBrokenChain {
+		t.Fatalf("Expected %v, got %v", ErrBrokenChain, err)
+	}
+
+	// A chain re-signed by another key is rejected, with or without a
+	// policy, and can not be continued.
+	other, _ := nkeys.CreateOperator()
+	forged := &MemoryBackend{}
+	flog, _ := NewLog(forged, other)
+	flog.Append(nkeys.Event{Type: nkeys.EventCreated})
+	frecords, _ := forged.Load()
+	if err := Verify(frecords, apk, nil, nil); err != ErrWrongSigner {
+		t.Fatalf("Expected %v, got %v", ErrWrongSigner, err)
+	}
+	if _, err := NewLog(forged, auditor); err != ErrWrongSigner {
+		t.Fatalf("Expected %v, got %v", ErrWrongSigner, err)
+	}
+	opk, _ := other.PublicKey()
+	if err := Verify(frecords, opk, nil, vp); err != nkeys.ErrKeyNotTrusted {
+		t.Fatalf("Expected %v, got %v", nkeys.ErrKeyNotTrusted, err)
+	}
+}
+
+type staticTrust string
+
+func (s staticTrust) IsTrusted(public string) bool {
+	return public == string(s)
+}
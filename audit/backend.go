@@ -0,0 +1,112 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// Backend stores records. Implementations only need to append; the chain
+// makes tampering with stored records detectable.
+type Backend interface {
+	Append(r Record) error
+	// Load returns all records in the order they were appended.
+	Load() ([]Record, error)
+}
+
+// MemoryBackend keeps records in memory.
+type MemoryBackend struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// Append adds the record.
+func (m *MemoryBackend) Append(r Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, r)
+	return nil
+}
+
+// Load returns a copy of the records.
+func (m *MemoryBackend) Load() ([]Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Record{}, m.records...), nil
+}
+
+// FileBackend appends records to a file as JSON lines.
+type FileBackend struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileBackend stores records in the file at path, which is created when
+// the first record is appended.
+func NewFileBackend(path string) *FileBackend {
+	return &FileBackend{path: path}
+}
+
+// Append writes the record and syncs the file.
+func (f *FileBackend) Append(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Load reads all records. A missing file holds no records.
+func (f *FileBackend) Load() ([]Record, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.Open(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var records []Record
+	s := bufio.NewScanner(file)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		var r Record
+		if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, s.Err()
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/ed25519"
+	"sync"
+	"sync/atomic"
+)
+
+// Backend provides the ed25519 primitives used by all signing KeyPairs.
+// Implementations could be a FIPS validated module, a hardware accelerator
+// or the default software implementation.
+type Backend interface {
+	// NewKeyFromSeed returns the 32 byte public key and the 64 byte private
+	// key for a 32 byte seed.
+	NewKeyFromSeed(seed []byte) (public []byte, private []byte, err error)
+	// Sign signs the message with the 64 byte private key.
+	Sign(private []byte, message []byte) ([]byte, error)
+	// Verify reports whether sig is a valid signature of message by public.
+	Verify(public []byte, message []byte, sig []byte) bool
+}
+
+// stdBackend is the default Backend.
+type stdBackend struct{}
+
+func (stdBackend) NewKeyFromSeed(seed []byte) ([]byte, []byte, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, nil, ErrInvalidSeedLen
+	}
+	priv := ed25519.NewKeyFromSeed(seed)
+	return []byte(priv.Public().(ed25519.PublicKey)), []byte(priv), nil
+}
+
+func (stdBackend) Sign(private []byte, message []byte) ([]byte, error) {
+	if len(private) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidPrivateKey
+	}
+	return ed25519.Sign(private, message), nil
+}
+
+func (stdBackend) Verify(public []byte, message []byte, sig []byte) bool {
+	if len(public) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(public, message, sig)
+}
+
+// backendHolder gives atomic.Value a single concrete type to store.
+type backendHolder struct {
+	Backend
+}
+
+var (
+	backendMu     sync.Mutex
+	backendLocked int32
+	backendValue  atomic.Value
+)
+
+func init() {
+	backendValue.Store(backendHolder{stdBackend{}})
+}
+
+// SetBackend selects the Backend used by the package. It must be called once
+// at program start, before any key is used. Subsequent calls, or calls made
+// after the current backend has been used, return ErrBackendLocked. When
+// SelfTestEnv is set, b must pass SelfTestBackend first.
+func SetBackend(b Backend) error {
+	if b == nil {
+		return ErrInvalidBackend
+	}
+	if selfTestOnSet {
+		if err := SelfTestBackend(b); err != nil {
+			return err
+		}
+	}
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	if atomic.LoadInt32(&backendLocked) == 1 {
+		return ErrBackendLocked
+	}
+	backendValue.Store(backendHolder{b})
+	atomic.StoreInt32(&backendLocked, 1)
+	return nil
+}
+
+// currentBackend returns the selected Backend and prevents it from being
+// changed afterwards.
+func currentBackend() Backend {
+	if atomic.LoadInt32(&backendLocked) == 0 {
+		atomic.StoreInt32(&backendLocked, 1)
+	}
+	return loadBackend()
+}
+
+// loadBackend returns the selected Backend without locking the selection.
+func loadBackend() Backend {
+	return backendValue.Load().(backendHolder).Backend
+}
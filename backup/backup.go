@@ -0,0 +1,338 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package backup splits a seed into a backup kit of Shamir shares that can
+// be kept on different media: printed on paper, saved to files or scanned
+// as QR codes. Any threshold of shares restores the seed, in any mix of
+// media, and fewer reveal nothing about it.
+//
+// Every share names the public key of the seed, so that a restore can
+// check it recovered the right seed, and a random kit id, so that shares of
+// different kits are not mixed up.
+package backup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nats-io/nkeys"
+	"github.com/nats-io/nkeys/internal/canonical"
+	"github.com/nats-io/nkeys/internal/shamir"
+)
+
+// Errors
+const (
+	ErrInvalidOptions   = backupError("backup: threshold must be between 2 and the number of shares, at most 255")
+	ErrInvalidShare     = backupError("backup: invalid or damaged share")
+	ErrNotEnoughShares  = backupError("backup: not enough shares to restore")
+	ErrMixedKits        = backupError("backup: shares belong to different backup kits")
+	ErrConflictingShare = backupError("backup: two different shares have the same number")
+	ErrRestoreMismatch  = backupError("backup: restored seed does not match the kit public key")
+)
+
+type backupError string
+
+func (e backupError) Error() string {
+	return string(e)
+}
+
+const (
+	// QRPrefix starts the text of QR shares. It and the base32 alphabet are
+	// in the QR alphanumeric character set, which gives the densest codes.
+	QRPrefix = "NKB1:"
+
+	armorBegin = "-----BEGIN NKEYS BACKUP SHARE-----"
+	armorEnd   = "-----END NKEYS BACKUP SHARE-----"
+	kitIDLen   = 8
+	sumLen     = 4
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// QRRenderer renders text as a QR code image, e.g. a PNG. The package does
+// not depend on a QR library; wrap the one of your choice.
+type QRRenderer func(text string) ([]byte, error)
+
+// Options configure NewKit.
+type Options struct {
+	// Shares is the number of shares to make, 3 when zero.
+	Shares int
+	// Threshold is the number of shares needed to restore, 2 when zero.
+	Threshold int
+	// Rand defaults to crypto/rand.
+	Rand io.Reader
+}
+
+// Kit is a set of shares of one seed.
+type Kit struct {
+	PublicKey string
+	ID        string
+	Shares    []*Share
+}
+
+// Share is one share of a Kit.
+type Share struct {
+	PublicKey string
+	KitID     string
+	// Index numbers the share from 1 to Total.
+	Index     int
+	Total     int
+	Threshold int
+	data      []byte
+}
+
+// NewKit splits seed into a backup kit.
+func NewKit(seed []byte, opts Options) (*Kit, error) {
+	kp, err := nkeys.FromSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	defer kp.Wipe()
+	public, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	n, k, rr := opts.Shares, opts.Threshold, opts.Rand
+	if n == 0 {
+		n = 3
+	}
+	if k == 0 {
+		k = 2
+	}
+	if rr == nil {
+		rr = rand.Reader
+	}
+	if k < 2 || k > n || n > 255 {
+		return nil, ErrInvalidOptions
+	}
+	id := make([]byte, kitIDLen)
+	if _, err := io.ReadFull(rr, id); err != nil {
+		return nil, err
+	}
+	parts, err := shamir.Split(seed, n, k, rr)
+	if err != nil {
+		return nil, err
+	}
+	kit := &Kit{PublicKey: public, ID: b32.EncodeToString(id)}
+	for i, p := range parts {
+		kit.Shares = append(kit.Shares, &Share{
+			PublicKey: public,
+			KitID:     kit.ID,
+			Index:     i + 1,
+			Total:     n,
+			Threshold: k,
+			data:      p,
+		})
+	}
+	return kit, nil
+}
+
+// Verify checks that the kit restores its seed from its shares, taking
+// consecutive groups of Threshold shares so that every share is used.
+func (k *Kit) Verify() error {
+	if len(k.Shares) == 0 {
+		return ErrNotEnoughShares
+	}
+	t := k.Shares[0].Threshold
+	if t < 2 {
+		return ErrInvalidShare
+	}
+	if len(k.Shares) < t {
+		return ErrNotEnoughShares
+	}
+	for start := 0; start < len(k.Shares); start += t {
+		if start+t > len(k.Shares) {
+			start = len(k.Shares) - t
+		}
+		seed, err := restore(k.Shares[start : start+t])
+		if err != nil {
+			return err
+		}
+		shamir.Wipe(seed)
+	}
+	return nil
+}
+
+// Wipe erases the share data of the kit.
+func (k *Kit) Wipe() {
+	for _, s := range k.Shares {
+		shamir.Wipe(s.data)
+	}
+}
+
+func (s *Share) payload() []byte {
+	e := canonical.NewEncoder("nkeys.BackupShare")
+	e.Text(s.PublicKey)
+	e.Text(s.KitID)
+	e.Int64(int64(s.Index))
+	e.Int64(int64(s.Total))
+	e.Int64(int64(s.Threshold))
+	e.Blob(s.data)
+	data := e.Bytes()
+	sum := sha256.Sum256(data)
+	return append(data, sum[:sumLen]...)
+}
+
+// QRText returns the share as text for a QR code.
+func (s *Share) QRText() string {
+	return QRPrefix + b32.EncodeToString(s.payload())
+}
+
+// QR renders the share as a QR code with r.
+func (s *Share) QR(r QRRenderer) ([]byte, error) {
+	return r(s.QRText())
+}
+
+// Armor returns the share as text for a file or for printing on paper. The
+// data is grouped in blocks of four characters for transcription by hand;
+// spacing and case are ignored when parsing.
+func (s *Share) Armor() string {
+	var b strings.Builder
+	b.WriteString(armorBegin + "\n")
+	fmt.Fprintf(&b, "Kit: %s\n", s.KitID)
+	fmt.Fprintf(&b, "Share: %d of %d, %d needed\n", s.Index, s.Total, s.Threshold)
+	fmt.Fprintf(&b, "Key: %s\n\n", s.PublicKey)
+	text := b32.EncodeToString(s.payload())
+	for i := 0; i < len(text); i += 4 {
+		end := i + 4
+		if end > len(text) {
+			end = len(text)
+		}
+		b.WriteString(text[i:end])
+		if i+4 >= len(text) || (i/4)%8 == 7 {
+			b.WriteByte('\n')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	b.WriteString(armorEnd + "\n")
+	return b.String()
+}
+
+// ParseShare parses a share in the form returned by Armor or QRText.
+func ParseShare(text string) (*Share, error) {
+	text = strings.TrimSpace(text)
+	var body string
+	switch {
+	case strings.HasPrefix(strings.ToUpper(text), QRPrefix):
+		body = text[len(QRPrefix):]
+	case strings.HasPrefix(text, armorBegin):
+		end := strings.Index(text, armorEnd)
+		if end < 0 {
+			return nil, ErrInvalidShare
+		}
+		var lines []string
+		for _, line := range strings.Split(text[len(armorBegin):end], "\n") {
+			// Header lines are informational only.
+			if !strings.Contains(line, ":") {
+				lines = append(lines, line)
+			}
+		}
+		body = strings.Join(lines, "")
+	default:
+		return nil, ErrInvalidShare
+	}
+	body = strings.ToUpper(strings.Join(strings.Fields(body), ""))
+	data, err := b32.DecodeString(body)
+	if err != nil || len(data) < sumLen {
+		return nil, ErrInvalidShare
+	}
+	payload, sum := data[:len(data)-sumLen], data[len(data)-sumLen:]
+	want := sha256.Sum256(payload)
+	if !bytes.Equal(sum, want[:sumLen]) {
+		return nil, ErrInvalidShare
+	}
+	d := canonical.NewDecoder("nkeys.BackupShare", payload)
+	s := &Share{
+		PublicKey: d.Text(),
+		KitID:     d.Text(),
+		Index:     int(d.Int64()),
+		Total:     int(d.Int64()),
+		Threshold: int(d.Int64()),
+		data:      d.Blob(),
+	}
+	if d.Finish() != nil || !nkeys.IsValidPublicKey(s.PublicKey) ||
+		s.Threshold < 2 || s.Threshold > s.Total || s.Total > 255 ||
+		s.Index < 1 || s.Index > s.Total {
+		return nil, ErrInvalidShare
+	}
+	return s, nil
+}
+
+// Restore recovers the seed from shares given as text in any mix of the
+// forms returned by Armor and QRText. Duplicates are ignored.
+func Restore(texts ...string) ([]byte, error) {
+	var shares []*Share
+	for _, t := range texts {
+		s, err := ParseShare(t)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, s)
+	}
+	return restore(shares)
+}
+
+func restore(shares []*Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrNotEnoughShares
+	}
+	first := shares[0]
+	byIndex := make(map[int]*Share)
+	for _, s := range shares {
+		if s.KitID != first.KitID || s.PublicKey != first.PublicKey ||
+			s.Threshold != first.Threshold || s.Total != first.Total {
+			return nil, ErrMixedKits
+		}
+		if prev, ok := byIndex[s.Index]; ok {
+			if !bytes.Equal(prev.data, s.data) {
+				return nil, ErrConflictingShare
+			}
+			continue
+		}
+		byIndex[s.Index] = s
+	}
+	if len(byIndex) < first.Threshold {
+		return nil, ErrNotEnoughShares
+	}
+	var xs []byte
+	var ys [][]byte
+	for _, s := range shares {
+		if byIndex[s.Index] == s && len(xs) < first.Threshold {
+			xs = append(xs, byte(s.Index))
+			ys = append(ys, s.data)
+		}
+	}
+	for _, y := range ys {
+		if len(y) != len(ys[0]) {
+			return nil, ErrInvalidShare
+		}
+	}
+	seed := shamir.Combine(xs, ys)
+	kp, err := nkeys.FromSeed(seed)
+	if err != nil {
+		shamir.Wipe(seed)
+		return nil, ErrRestoreMismatch
+	}
+	defer kp.Wipe()
+	if public, err := kp.PublicKey(); err != nil || public != first.PublicKey {
+		shamir.Wipe(seed)
+		return nil, ErrRestoreMismatch
+	}
+	return seed, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nats-io/nkeys"
+)
+
+func TestKit(t *testing.T) {
+	user, _ := nkeys.CreateUser()
+	seed, _ := user.Seed()
+	kit, err := NewKit(seed, Options{Shares: 5, Threshold: 3})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := kit.Verify(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// A truncated kit fails instead of crashing the verifier.
+	truncated := &Kit{PublicKey: kit.PublicKey, ID: kit.ID, Shares: kit.Shares[:2]}
+	if err := truncated.Verify(); err != ErrNotEnoughShares {
+		t.Fatalf("Expected %v, got %v", ErrNotEnoughShares, err)
+	}
+	corrupted := *kit.Shares[0]
+	corrupted.Threshold = 0
+	truncated.Shares = []*Share{&corrupted}
+	if err := truncated.Verify(); err != ErrInvalidShare {
+		t.Fatalf("Expected %v, got %v", ErrInvalidShare, err)
+	}
+
+	// Paper with hand typed lower case, a file and a scanned QR code.
+	paper := strings.ToLower(kit.Shares[0].Armor())
+	paper = strings.Replace(paper, strings.ToLower(armorBegin), armorBegin, 1)
+	paper = strings.Replace(paper, strings.ToLower(armorEnd), armorEnd, 1)
+	got, err := Restore(paper, kit.Shares[3].Armor(), kit.Shares[4].QRText())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, seed) {
+		t.Fatalf("Expected %s, got %s", seed, got)
+	}
+
+	if _, err := Restore(kit.Shares[0].QRText(), kit.Shares[0].Armor()); err != ErrNotEnoughShares {
+		t.Fatalf("Expected %v, got %v", ErrNotEnoughShares, err)
+	}
+	other, _ := NewKit(seed, Options{Shares: 5, Threshold: 3})
+	if _, err := Restore(kit.Shares[0].QRText(), kit.Shares[1].QRText(), other.Shares[2].QRText()); err != ErrMixedKits {
+		t.Fatalf("Expected %v, got %v", ErrMixedKits, err)
+	}
+	damaged := []byte(kit.Shares[1].QRText())
+	damaged[len(damaged)-5] ^= 1
+	if _, err := ParseShare(string(damaged)); err != ErrInvalidShare {
+		t.Fatalf("Expected %v, got %v", ErrInvalidShare, err)
+	}
+
+	var rendered string
+	if _, err := kit.Shares[2].QR(func(text string) ([]byte, error) {
+		rendered = text
+		return nil, nil
+	}); err != nil || !strings.HasPrefix(rendered, QRPrefix) {
+		t.Fatalf("Expected QR text to be rendered, got %q", rendered)
+	}
+
+	if _, err := NewKit(seed, Options{Shares: 2, Threshold: 3}); err != ErrInvalidOptions {
+		t.Fatalf("Expected %v, got %v", ErrInvalidOptions, err)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"sort"
+	"time"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// VerificationBundle carries everything an air-gapped verifier needs to
+// validate credentials without network access: the trusted root keys and
+// the revoked keys, valid for a window of time and signed by a publisher.
+type VerificationBundle struct {
+	Publisher string
+	Roots     []string
+	Revoked   []string
+	NotBefore time.Time
+	Expires   time.Time
+	Signature []byte
+
+	roots   map[string]struct{}
+	revoked map[string]struct{}
+}
+
+// ExportVerificationBundle returns a bundle of roots and revocations valid
+// from notBefore until expires, signed by publisher. Write it to a file and
+// load it on the verifier with LoadVerificationBundle.
+func ExportVerificationBundle(publisher KeyPair, roots, revocations []string, notBefore, expires time.Time) ([]byte, error) {
+	if !expires.After(notBefore) {
+		return nil, ErrInvalidLifetime
+	}
+	pk, err := publisher.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	b := &VerificationBundle{
+		Publisher: pk,
+		NotBefore: notBefore.UTC(),
+		Expires:   expires.UTC(),
+	}
+	if b.Roots, err = sortedKeys(roots); err != nil {
+		return nil, err
+	}
+	if b.Revoked, err = sortedKeys(revocations); err != nil {
+		return nil, err
+	}
+	if b.Signature, err = publisher.Sign(b.signedBytes()); err != nil {
+		return nil, err
+	}
+	e := canonical.NewEncoder("nkeys.SignedVerificationBundle")
+	b.encode(e)
+	e.Blob(b.Signature)
+	return e.Bytes(), nil
+}
+
+// sortedKeys validates and sorts the keys, dropping duplicates.
+func sortedKeys(keys []string) ([]string, error) {
+	out := make([]string, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if !IsValidPublicKey(k) {
+			return nil, ErrInvalidPublicKey
+		}
+		if !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (b *VerificationBundle) signedBytes() []byte {
+	e := canonical.NewEncoder("nkeys.VerificationBundle")
+	b.encode(e)
+	return e.Bytes()
+}
+
+func (b *VerificationBundle) encode(e *canonical.Encoder) {
+	e.Text(b.Publisher)
+	e.Strings(b.Roots)
+	e.Strings(b.Revoked)
+	e.Time(b.NotBefore)
+	e.Time(b.Expires)
+}
+
+// LoadVerificationBundle decodes a bundle made by ExportVerificationBundle,
+// checks it was signed by publisher and that clock's current time is
+// within its window. A nil clock uses SystemClock.
+func LoadVerificationBundle(publisher string, data []byte, clock Clock) (*VerificationBundle, error) {
+	d := canonical.NewDecoder("nkeys.SignedVerificationBundle", data)
+	b := &VerificationBundle{
+		Publisher: d.Text(),
+		Roots:     d.Strings(),
+		Revoked:   d.Strings(),
+		NotBefore: d.Time(),
+		Expires:   d.Time(),
+		Signature: d.Blob(),
+	}
+	if err := d.Finish(); err != nil {
+		return nil, ErrInvalidBundle
+	}
+	if b.Publisher != publisher {
+		return nil, ErrInvalidSignature
+	}
+	if err := VerifyWithPolicy(nil, publisher, b.signedBytes(), b.Signature); err != nil {
+		return nil, err
+	}
+	if err := (&VerifyPolicy{Clock: clock}).CheckValidity(b.NotBefore, b.Expires); err != nil {
+		return nil, err
+	}
+	b.roots = keySet(b.Roots)
+	b.revoked = keySet(b.Revoked)
+	return b, nil
+}
+
+func keySet(keys []string) map[string]struct{} {
+	m := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		m[k] = struct{}{}
+	}
+	return m
+}
+
+// IsTrusted reports whether public is one of the bundle's roots. Only
+// bundles returned by LoadVerificationBundle trust anything; the roots of
+// a bundle built by hand or decoded from JSON were never checked against
+// the publisher's signature.
+func (b *VerificationBundle) IsTrusted(public string) bool {
+	_, ok := b.roots[public]
+	return ok
+}
+
+// IsRevoked reports whether public is revoked by the bundle. Bundles that
+// were not loaded with LoadVerificationBundle are searched, since honouring
+// an unverified revocation can only reject more keys.
+func (b *VerificationBundle) IsRevoked(public string) (bool, error) {
+	if b.revoked == nil {
+		for _, k := range b.Revoked {
+			if k == public {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	_, ok := b.revoked[public]
+	return ok, nil
+}
+
+// Policy returns a VerifyPolicy that trusts the bundle's roots and rejects
+// its revoked keys.
+func (b *VerificationBundle) Policy() *VerifyPolicy {
+	return &VerifyPolicy{Trusted: b, Revocation: b}
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"time"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// The exported records implement encoding.BinaryMarshaler with a canonical
+// encoding: equal values always produce identical bytes, which makes them
+// suitable for replicated logs and for hashing.
+
+// MarshalBinary returns the canonical encoding of the KeyInfo.
+func (ki KeyInfo) MarshalBinary() ([]byte, error) {
+	e := canonical.NewEncoder("nkeys.KeyInfo")
+	e.Text(ki.PublicKey)
+	e.Text(ki.Name)
+	e.Text(ki.Parent)
+	return e.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the canonical encoding of a KeyInfo.
+func (ki *KeyInfo) UnmarshalBinary(data []byte) error {
+	d := canonical.NewDecoder("nkeys.KeyInfo", data)
+	v := KeyInfo{
+		PublicKey: d.Text(),
+		Name:      d.Text(),
+		Parent:    d.Text(),
+	}
+	if err := d.Finish(); err != nil {
+		return err
+	}
+	*ki = v
+	return nil
+}
+
+// MarshalBinary returns the canonical encoding of the TenantKey.
+func (tk TenantKey) MarshalBinary() ([]byte, error) {
+	e := canonical.NewEncoder("nkeys.TenantKey")
+	e.Text(tk.Tenant)
+	e.Uint64(uint64(tk.Generation))
+	e.Text(tk.PublicKey)
+	return e.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the canonical encoding of a TenantKey.
+func (tk *TenantKey) UnmarshalBinary(data []byte) error {
+	d := canonical.NewDecoder("nkeys.TenantKey", data)
+	tenant := d.Text()
+	g := d.Uint64()
+	pk := d.Text()
+	if err := d.Finish(); err != nil {
+		return err
+	}
+	if g > 1<<32-1 {
+		return canonical.ErrInvalid
+	}
+	*tk = TenantKey{tenant, uint32(g), pk}
+	return nil
+}
+
+// MarshalBinary returns the canonical encoding of the GenerationReport.
+func (r GenerationReport) MarshalBinary() ([]byte, error) {
+	e := canonical.NewEncoder("nkeys.GenerationReport")
+	e.Text(r.PublicKey)
+	e.Text(r.Type)
+	e.Text(r.Algorithm)
+	e.Text(r.LibraryVersion)
+	e.Text(r.GoVersion)
+	e.Text(r.Backend)
+	e.Text(r.EntropySource)
+	e.Time(r.Started)
+	e.Int64(int64(r.Duration))
+	return e.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the canonical encoding of a GenerationReport.
+func (r *GenerationReport) UnmarshalBinary(data []byte) error {
+	d := canonical.NewDecoder("nkeys.GenerationReport", data)
+	v := GenerationReport{
+		PublicKey:      d.Text(),
+		Type:           d.Text(),
+		Algorithm:      d.Text(),
+		LibraryVersion: d.Text(),
+		GoVersion:      d.Text(),
+		Backend:        d.Text(),
+		EntropySource:  d.Text(),
+		Started:        d.Time(),
+		Duration:       time.Duration(d.Int64()),
+	}
+	if err := d.Finish(); err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// MarshalBinary returns the canonical encoding of the Policy. Contexts are
+// encoded in the order given, as they are a list and not a set.
+func (p Policy) MarshalBinary() ([]byte, error) {
+	e := canonical.NewEncoder("nkeys.Policy")
+	e.Uint64(uint64(p.Allowed))
+	e.Int64(int64(p.MaxPayload))
+	e.Strings(p.Contexts)
+	return e.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the canonical encoding of a Policy.
+func (p *Policy) UnmarshalBinary(data []byte) error {
+	d := canonical.NewDecoder("nkeys.Policy", data)
+	allowed := d.Uint64()
+	max := d.Int64()
+	contexts := d.Strings()
+	if err := d.Finish(); err != nil {
+		return err
+	}
+	if allowed > 0xff {
+		return canonical.ErrInvalid
+	}
+	*p = Policy{Operation(allowed), int(max), contexts}
+	return nil
+}
+
+// MarshalGenerations returns the canonical encoding of the tenant
+// generations, ordered by tenant.
+func (ns *Namespace) MarshalGenerations() []byte {
+	e := canonical.NewEncoder("nkeys.NamespaceGenerations")
+	e.Uint32Map(ns.Generations())
+	return e.Bytes()
+}
+
+// UnmarshalGenerations decodes generations encoded by MarshalGenerations,
+// suitable for NewNamespace.
+func UnmarshalGenerations(data []byte) (map[string]uint32, error) {
+	d := canonical.NewDecoder("nkeys.NamespaceGenerations", data)
+	m := d.Uint32Map()
+	if err := d.Finish(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
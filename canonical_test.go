@@ -0,0 +1,149 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type binaryRecord interface {
+	encoding.BinaryMarshaler
+}
+
+func testCanonical(t *testing.T, v binaryRecord, out encoding.BinaryUnmarshaler) {
+	t.Helper()
+	first, err := v.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error marshaling %T: %v", v, err)
+	}
+	for i := 0; i < 20; i++ {
+		again, _ := v.MarshalBinary()
+		if !bytes.Equal(first, again) {
+			t.Fatalf("Expected %T encoding to be deterministic", v)
+		}
+	}
+	if err := out.UnmarshalBinary(first); err != nil {
+		t.Fatalf("Unexpected error unmarshaling %T: %v", v, err)
+	}
+	if again, _ := out.(binaryRecord).MarshalBinary(); !bytes.Equal(first, again) {
+		t.Fatalf("Expected %T to round trip", v)
+	}
+	if err := out.UnmarshalBinary(first[:len(first)-1]); err == nil {
+		t.Fatalf("Expected an error unmarshaling truncated %T", v)
+	}
+}
+
+func TestCanonicalRecords(t *testing.T) {
+	acc, _ := CreateAccount()
+	apk, _ := acc.PublicKey()
+	_, report, _ := CreatePairWithReport(PrefixByteUser, nil)
+
+	testCanonical(t, KeyInfo{PublicKey: apk, Name: "Billing", Parent: apk}, &KeyInfo{})
+	testCanonical(t, TenantKey{"acme", 3, apk}, &TenantKey{})
+	testCanonical(t, *report, &GenerationReport{})
+	testCanonical(t, Policy{Allowed: SignOnly, MaxPayload: 10, Contexts: []string{"b", "a"}}, &Policy{})
+
+	var r GenerationReport
+	data, _ := report.MarshalBinary()
+	r.UnmarshalBinary(data)
+	if !r.Started.Equal(report.Started) || r.Duration != report.Duration {
+		t.Fatal("Expected times to survive the round trip")
+	}
+
+	// Records of one type must not decode as another.
+	data, _ = KeyInfo{}.MarshalBinary()
+	if err := (&TenantKey{}).UnmarshalBinary(data); err == nil {
+		t.Fatal("Expected an error decoding a KeyInfo as a TenantKey")
+	}
+}
+
+func TestCanonicalGenerations(t *testing.T) {
+	op, _ := CreateOperator()
+	seed, _ := op.Seed()
+	ns1, _ := NewNamespace(seed, nil)
+	ns2, _ := NewNamespace(seed, nil)
+	for i := 0; i < 50; i++ {
+		ns1.Add(fmt.Sprintf("t%02d", i))
+		ns2.Add(fmt.Sprintf("t%02d", 49-i))
+	}
+	ns1.Rotate("t07")
+	ns2.Rotate("t07")
+	expected := ns1.MarshalGenerations()
+	for i := 0; i < 20; i++ {
+		if !bytes.Equal(ns1.MarshalGenerations(), expected) || !bytes.Equal(ns2.MarshalGenerations(), expected) {
+			t.Fatal("Expected generations encoding to be independent of map ordering")
+		}
+	}
+	m, err := UnmarshalGenerations(expected)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(m, ns1.Generations()) {
+		t.Fatal("Expected generations to round trip")
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	// Examples from RFC 8785, sections 3.2.2 and 3.2.3.
+	in := `{"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+		"literals": [null, true, false]}`
+	want := `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`
+	got, err := CanonicalJSON([]byte(in))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("Expected %v, got %v", want, string(got))
+	}
+
+	in = `{"\u20ac":5,"\r":1,"\ufb33":7,"1":2,"\ud83d\ude00":6,"\u0080":3,"\u00f6":4}`
+	want = "{\"\\r\":1,\"1\":2,\"\u0080\":3,\"ö\":4,\"€\":5,\"😀\":6,\"\ufb33\":7}"
+	if got, _ := CanonicalJSON([]byte(in)); string(got) != want {
+		t.Fatalf("Expected %v, got %v", want, string(got))
+	}
+
+	for _, bad := range []string{`{"a":1,"a":2}`, `{"a":1} {}`, `[1e400]`, `{"a":`} {
+		if _, err := CanonicalJSON([]byte(bad)); err != ErrInvalidJSON {
+			t.Fatalf("Expected %v for %s, got %v", ErrInvalidJSON, bad, err)
+		}
+	}
+}
+
+func TestSignJSON(t *testing.T) {
+	type claims struct {
+		Name  string   `json:"name"`
+		Count float64  `json:"count"`
+		Tags  []string `json:"tags"`
+	}
+	user, _ := CreateUser()
+	public, _ := user.PublicKey()
+	sig, err := SignJSON(user, claims{"derek", 10, []string{"a<b"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// The same data serialized differently by another party.
+	other := json.RawMessage(`{ "tags": ["a\u003cb"], "count": 1.0E1, "name": "derek" }`)
+	if err := VerifyJSON(public, other, sig); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := VerifyJSON(public, claims{"derek", 11, []string{"a<b"}}, sig); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+}
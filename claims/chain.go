@@ -0,0 +1,113 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package claims
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nats-io/nkeys"
+)
+
+// ErrBrokenChain is returned when a JWT is not issued by the next link of
+// the chain.
+const ErrBrokenChain = claimsError("claims: issuer is not part of the chain")
+
+// Link is the result of validating one JWT of a chain.
+type Link struct {
+	// Type is TypeAccount or TypeUser.
+	Type    string
+	Subject string
+	Issuer  string
+	Expires time.Time
+	// Err is nil if the JWT is valid and issued by the previous link.
+	Err error
+}
+
+// Chain is the result of ValidateChain. Links are ordered from the
+// operator down and are present for every JWT that could be decoded,
+// valid or not, so that callers can report every problem at once.
+type Chain struct {
+	Operator string
+	Account  *AccountClaims
+	User     *UserClaims
+	Links    []Link
+}
+
+// Err returns the first error of the chain, or nil.
+func (c *Chain) Err() error {
+	for _, l := range c.Links {
+		if l.Err != nil {
+			return l.Err
+		}
+	}
+	return nil
+}
+
+// ValidateChain checks that userJWT was issued by the account of
+// accountJWT, either directly or by one of its signing keys, and that
+// accountJWT was issued by operator. Every signature, claim type and
+// validity window is checked. vp, which may be nil, is applied to both
+// issuers and supplies the clock. The returned chain details each link and
+// is returned even on error when the JWTs could be parsed.
+func ValidateChain(userJWT, accountJWT, operator string, vp *nkeys.VerifyPolicy) (*Chain, error) {
+	if !hasType(operator, []nkeys.PrefixByte{nkeys.PrefixByteOperator}) {
+		return nil, ErrInvalidIssuer
+	}
+	if vp == nil {
+		// Decode skips the validity windows without a policy.
+		vp = &nkeys.VerifyPolicy{}
+	}
+	c := &Chain{Operator: operator}
+
+	var ac AccountClaims
+	err := Decode(accountJWT, &ac, vp)
+	if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrInvalidType) {
+		return nil, err
+	}
+	if err == nil && ac.Issuer != operator {
+		err = ErrBrokenChain
+	}
+	c.Account = &ac
+	c.Links = append(c.Links, Link{TypeAccount, ac.Subject, ac.Issuer, unix(ac.Expires), err})
+
+	var uc UserClaims
+	err = Decode(userJWT, &uc, vp)
+	if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrInvalidType) {
+		return c, err
+	}
+	if err == nil && !issuedByAccount(&uc, &ac) {
+		err = ErrBrokenChain
+	}
+	c.User = &uc
+	c.Links = append(c.Links, Link{TypeUser, uc.Subject, uc.Issuer, unix(uc.Expires), err})
+	return c, c.Err()
+}
+
+// issuedByAccount reports whether uc was issued by the account or one of
+// its signing keys.
+func issuedByAccount(uc *UserClaims, ac *AccountClaims) bool {
+	if uc.Issuer == ac.Subject {
+		return uc.Nats.IssuerAccount == "" || uc.Nats.IssuerAccount == ac.Subject
+	}
+	if uc.Nats.IssuerAccount != ac.Subject {
+		return false
+	}
+	for _, sk := range ac.Nats.SigningKeys {
+		if sk == uc.Issuer {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,317 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package claims signs and verifies typed operator, account and user JWTs
+// in the NATS JWT format. Claims are validated before signing so that
+// mistakes such as issuing a user JWT with a server key are caught early.
+package claims
+
+import (
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nkeys"
+)
+
+// Errors
+const (
+	ErrInvalidSubject    = claimsError("claims: subject has the wrong key type")
+	ErrInvalidIssuer     = claimsError("claims: issuer has the wrong key type")
+	ErrInvalidType       = claimsError("claims: unexpected claim type")
+	ErrInvalidExpiry     = claimsError("claims: expires before issued")
+	ErrInvalidToken      = claimsError("claims: invalid token")
+	ErrConflictingAuth   = claimsError("claims: nkey users can not be combined with operators")
+	ErrInvalidSigningKey = claimsError("claims: invalid signing key entry")
+)
+
+type claimsError string
+
+func (e claimsError) Error() string {
+	return string(e)
+}
+
+// Claim types as found in the nats.type field.
+const (
+	TypeOperator = "operator"
+	TypeAccount  = "account"
+	TypeUser     = "user"
+)
+
+const (
+	version = 2
+	header  = `{"typ":"JWT","alg":"ed25519-nkey"}`
+)
+
+// Common holds the registered claims shared by all claim types. Times are
+// in seconds since the epoch.
+type Common struct {
+	ID        string `json:"jti,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Expires   int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+func (c *Common) common() *Common {
+	return c
+}
+
+// validate checks the expiry and the key types of subject and issuer.
+func (c *Common) validate(subject, issuer []nkeys.PrefixByte) error {
+	if !hasType(c.Subject, subject) {
+		return ErrInvalidSubject
+	}
+	if !hasType(c.Issuer, issuer) {
+		return ErrInvalidIssuer
+	}
+	if c.Expires != 0 && c.Expires < c.IssuedAt {
+		return ErrInvalidExpiry
+	}
+	return nil
+}
+
+func hasType(public string, types []nkeys.PrefixByte) bool {
+	if !nkeys.IsValidPublicKey(public) {
+		return false
+	}
+	pre := nkeys.Prefix(public)
+	for _, t := range types {
+		if pre == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Nats holds the fields under the nats claim that identify the type.
+type Nats struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+}
+
+// Claims is implemented by OperatorClaims, AccountClaims and UserClaims.
+type Claims interface {
+	common() *Common
+	nats() *Nats
+	claimType() string
+	// Validate checks that the claims are consistent.
+	Validate() error
+}
+
+// OperatorClaims describe an operator. They are self signed or signed by
+// one of the operator's signing keys.
+type OperatorClaims struct {
+	Common
+	Nats OperatorFields `json:"nats"`
+}
+
+// OperatorFields are the operator specific fields.
+type OperatorFields struct {
+	Nats
+	SigningKeys SigningKeys `json:"signing_keys,omitempty"`
+}
+
+func (c *OperatorClaims) nats() *Nats       { return &c.Nats.Nats }
+func (c *OperatorClaims) claimType() string { return TypeOperator }
+
+// Validate checks that subject and issuer are operator keys.
+func (c *OperatorClaims) Validate() error {
+	if err := c.validate([]nkeys.PrefixByte{nkeys.PrefixByteOperator}, []nkeys.PrefixByte{nkeys.PrefixByteOperator}); err != nil {
+		return err
+	}
+	if !allOfType(c.Nats.SigningKeys, nkeys.PrefixByteOperator) {
+		return ErrInvalidSubject
+	}
+	return nil
+}
+
+// AccountClaims describe an account and are signed by an operator key.
+type AccountClaims struct {
+	Common
+	Nats AccountFields `json:"nats"`
+}
+
+// AccountFields are the account specific fields.
+type AccountFields struct {
+	Nats
+	SigningKeys SigningKeys `json:"signing_keys,omitempty"`
+}
+
+func (c *AccountClaims) nats() *Nats       { return &c.Nats.Nats }
+func (c *AccountClaims) claimType() string { return TypeAccount }
+
+// Validate checks that the subject is an account key and the issuer an
+// operator key.
+func (c *AccountClaims) Validate() error {
+	if err := c.validate([]nkeys.PrefixByte{nkeys.PrefixByteAccount}, []nkeys.PrefixByte{nkeys.PrefixByteOperator}); err != nil {
+		return err
+	}
+	if !allOfType(c.Nats.SigningKeys, nkeys.PrefixByteAccount) {
+		return ErrInvalidSubject
+	}
+	return nil
+}
+
+// UserClaims describe a user and are signed by an account key.
+type UserClaims struct {
+	Common
+	Nats UserFields `json:"nats"`
+}
+
+// UserFields are the user specific fields.
+type UserFields struct {
+	Nats
+	// IssuerAccount is the account when the issuer is one of its signing keys.
+	IssuerAccount string `json:"issuer_account,omitempty"`
+}
+
+func (c *UserClaims) nats() *Nats       { return &c.Nats.Nats }
+func (c *UserClaims) claimType() string { return TypeUser }
+
+// Validate checks that the subject is a user key and the issuer an account
+// key.
+func (c *UserClaims) Validate() error {
+	if err := c.validate([]nkeys.PrefixByte{nkeys.PrefixByteUser}, []nkeys.PrefixByte{nkeys.PrefixByteAccount}); err != nil {
+		return err
+	}
+	if c.Nats.IssuerAccount != "" && !hasType(c.Nats.IssuerAccount, []nkeys.PrefixByte{nkeys.PrefixByteAccount}) {
+		return ErrInvalidIssuer
+	}
+	return nil
+}
+
+// SigningKeys lists the public signing keys of an operator or account.
+// NATS v2 JWTs carry scoped signing keys as objects such as
+// {"kind":"user_scope","key":"A..."}; only the key of those is kept.
+type SigningKeys []string
+
+// UnmarshalJSON accepts both bare keys and scoped signing key objects.
+func (sk *SigningKeys) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	keys := make(SigningKeys, 0, len(raw))
+	for _, r := range raw {
+		var key string
+		if err := json.Unmarshal(r, &key); err != nil {
+			var scope struct {
+				Key string `json:"key"`
+			}
+			if err := json.Unmarshal(r, &scope); err != nil || scope.Key == "" {
+				return ErrInvalidSigningKey
+			}
+			key = scope.Key
+		}
+		keys = append(keys, key)
+	}
+	*sk = keys
+	return nil
+}
+
+func allOfType(keys []string, pre nkeys.PrefixByte) bool {
+	for _, k := range keys {
+		if !hasType(k, []nkeys.PrefixByte{pre}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Encode sets the issuer, type, issue time and ID of c, validates it and
+// returns the JWT signed by kp. An unset issue time is taken from clock,
+// which may be nil for the system clock.
+func Encode(c Claims, kp nkeys.KeyPair, clock nkeys.Clock) (string, error) {
+	issuer, err := kp.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	cc := c.common()
+	cc.Issuer = issuer
+	if cc.IssuedAt == 0 {
+		cc.IssuedAt = nkeys.ClockOrSystem(clock).Now().Unix()
+	}
+	n := c.nats()
+	n.Type, n.Version = c.claimType(), version
+	if err := c.Validate(); err != nil {
+		return "", err
+	}
+	cc.ID = ""
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha512.Sum512_256(data)
+	cc.ID = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(sum[:])
+	if data, err = json.Marshal(c); err != nil {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString(data)
+	sig, err := kp.Sign([]byte(input))
+	if err != nil {
+		return "", err
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// Decode verifies the signature of token, decodes it into c and validates
+// it. vp, which may be nil, is applied to the issuer and to the validity
+// window of the claims.
+func Decode(token string, c Claims, vp *nkeys.VerifyPolicy) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrInvalidToken
+	}
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	hdr, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || json.Unmarshal(hdr, &h) != nil || h.Alg != "ed25519-nkey" {
+		return ErrInvalidToken
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return ErrInvalidToken
+	}
+	if c.nats().Type != c.claimType() {
+		return ErrInvalidType
+	}
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	cc := c.common()
+	if err := nkeys.VerifyWithPolicy(vp, cc.Issuer, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return err
+	}
+	return vp.CheckValidity(unix(cc.NotBefore), unix(cc.Expires))
+}
+
+func unix(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
@@ -0,0 +1,261 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package claims
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nkeys"
+)
+
+func TestUserClaims(t *testing.T) {
+	acc, _ := nkeys.CreateAccount()
+	user, _ := nkeys.CreateUser()
+	apk, _ := acc.PublicKey()
+	upk, _ := user.PublicKey()
+
+	uc := &UserClaims{Common: Common{Subject: upk, Name: "bob"}}
+	token, err := Encode(uc, acc, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if uc.Issuer != apk || uc.ID == "" || uc.Nats.Type != TypeUser {
+		t.Fatalf("Expected issuer, id and type to be set, got %+v", uc)
+	}
+
+	var got UserClaims
+	if err := Decode(token, &got, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got != *uc {
+		t.Fatalf("Expected %+v, got %+v", *uc, got)
+	}
+	var ac AccountClaims
+	if err := Decode(token, &ac, nil); err != ErrInvalidType {
+		t.Fatalf("Expected %v, got %v", ErrInvalidType, err)
+	}
+	if err := Decode(token[:len(token)-4]+"AAAA", &got, nil); err != nkeys.ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", nkeys.ErrInvalidSignature, err)
+	}
+}
+
+func TestClaimsWrongKeys(t *testing.T) {
+	op, _ := nkeys.CreateOperator()
+	acc, _ := nkeys.CreateAccount()
+	server, _ := nkeys.CreateServer()
+	user, _ := nkeys.CreateUser()
+	opk, _ := op.PublicKey()
+	apk, _ := acc.PublicKey()
+	upk, _ := user.PublicKey()
+
+	if _, err := Encode(&UserClaims{Common: Common{Subject: upk}}, server, nil); err != ErrInvalidIssuer {
+		t.Fatalf("Expected %v, got %v", ErrInvalidIssuer, err)
+	}
+	if _, err := Encode(&UserClaims{Common: Common{Subject: apk}}, acc, nil); err != ErrInvalidSubject {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSubject, err)
+	}
+	if _, err := Encode(&AccountClaims{Common: Common{Subject: apk}}, acc, nil); err != ErrInvalidIssuer {
+		t.Fatalf("Expected %v, got %v", ErrInvalidIssuer, err)
+	}
+	if _, err := Encode(&AccountClaims{Common: Common{Subject: apk}}, op, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	oc := &OperatorClaims{Common: Common{Subject: opk}}
+	oc.Nats.SigningKeys = []string{apk}
+	if _, err := Encode(oc, op, nil); err != ErrInvalidSubject {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSubject, err)
+	}
+	uc := &UserClaims{Common: Common{Subject: upk, IssuedAt: 10, Expires: 5}}
+	if _, err := Encode(uc, acc, nil); err != ErrInvalidExpiry {
+		t.Fatalf("Expected %v, got %v", ErrInvalidExpiry, err)
+	}
+}
+
+func TestDecodeWithPolicy(t *testing.T) {
+	acc, _ := nkeys.CreateAccount()
+	user, _ := nkeys.CreateUser()
+	upk, _ := user.PublicKey()
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	uc := &UserClaims{Common: Common{Subject: upk, Expires: now.Add(time.Hour).Unix()}}
+	vp := &nkeys.VerifyPolicy{Clock: nkeys.ClockFunc(func() time.Time { return now })}
+	token, err := Encode(uc, acc, vp.Clock)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var got UserClaims
+	if err := Decode(token, &got, vp); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got.IssuedAt != now.Unix() {
+		t.Fatalf("Expected the issue time from the clock, got %d", got.IssuedAt)
+	}
+	vp.Clock = nkeys.ClockFunc(func() time.Time { return now.Add(2 * time.Hour) })
+	if err := Decode(token, &got, vp); err != nkeys.ErrExpired {
+		t.Fatalf("Expected %v, got %v", nkeys.ErrExpired, err)
+	}
+	// A nil policy checks against the system clock.
+	if err := Decode(token, &got, nil); err != nkeys.ErrExpired {
+		t.Fatalf("Expected %v, got %v", nkeys.ErrExpired, err)
+	}
+}
+
+func TestServerConfigFromKeys(t *testing.T) {
+	op, _ := nkeys.CreateOperator()
+	sys, _ := nkeys.CreateAccount()
+	acc, _ := nkeys.CreateAccount()
+	spk, _ := sys.PublicKey()
+	apk, _ := acc.PublicKey()
+
+	sc, err := ServerConfigFromKeys(op, sys, acc)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sc.SystemAccount = spk
+	if len(sc.Operators) != 1 || len(sc.Preload) != 2 || len(sc.TrustedKeys) != 0 {
+		t.Fatalf("Unexpected config %+v", sc)
+	}
+	var ac AccountClaims
+	if err := Decode(sc.Preload[apk], &ac, nil); err != nil || ac.Subject != apk {
+		t.Fatalf("Expected a valid account JWT, got %v", err)
+	}
+	conf := sc.String()
+	for _, want := range []string{"operator: \"", "system_account: \"" + spk + "\"", "resolver: MEMORY", "  " + apk + ": \""} {
+		if !strings.Contains(conf, want) {
+			t.Fatalf("Expected %q in:\n%s", want, conf)
+		}
+	}
+
+	pub, _ := op.PublicOnly()
+	sc, err = ServerConfigFromKeys(pub)
+	if err != nil || len(sc.TrustedKeys) != 1 || len(sc.Operators) != 0 {
+		t.Fatalf("Expected a trusted key, got %+v (%v)", sc, err)
+	}
+	if _, err := ServerConfigFromKeys(pub, acc); err != ErrInvalidIssuer {
+		t.Fatalf("Expected %v, got %v", ErrInvalidIssuer, err)
+	}
+
+	u1, _ := nkeys.CreateUser()
+	u2, _ := nkeys.CreateUser()
+	sc, err = ServerConfigFromKeys(u1, u2)
+	if err != nil || len(sc.Users) != 2 || !strings.Contains(sc.String(), "{ nkey: \"U") {
+		t.Fatalf("Expected nkey users, got %+v (%v)", sc, err)
+	}
+	if _, err := ServerConfigFromKeys(op, u1); err != ErrConflictingAuth {
+		t.Fatalf("Expected %v, got %v", ErrConflictingAuth, err)
+	}
+	server, _ := nkeys.CreateServer()
+	if _, err := ServerConfigFromKeys(server); err != ErrInvalidSubject {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSubject, err)
+	}
+}
+
+func TestValidateChain(t *testing.T) {
+	op, _ := nkeys.CreateOperator()
+	other, _ := nkeys.CreateOperator()
+	acc, _ := nkeys.CreateAccount()
+	sk, _ := nkeys.CreateAccount()
+	user, _ := nkeys.CreateUser()
+	opk, _ := op.PublicKey()
+	otherpk, _ := other.PublicKey()
+	apk, _ := acc.PublicKey()
+	skpk, _ := sk.PublicKey()
+	upk, _ := user.PublicKey()
+
+	now := time.Now()
+	ac := &AccountClaims{Common: Common{Subject: apk, Expires: now.Add(time.Hour).Unix()}}
+	ac.Nats.SigningKeys = []string{skpk}
+	accountJWT, _ := Encode(ac, op, nil)
+	userJWT, _ := Encode(&UserClaims{Common: Common{Subject: upk}}, acc, nil)
+	uc := &UserClaims{Common: Common{Subject: upk}}
+	uc.Nats.IssuerAccount = apk
+	scopedJWT, _ := Encode(uc, sk, nil)
+
+	for _, token := range []string{userJWT, scopedJWT} {
+		c, err := ValidateChain(token, accountJWT, opk, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(c.Links) != 2 || c.Account.Subject != apk || c.User.Subject != upk {
+			t.Fatalf("Expected the full chain, got %+v", c)
+		}
+	}
+
+	// Signed by another operator.
+	c, err := ValidateChain(userJWT, accountJWT, otherpk, nil)
+	if err != ErrBrokenChain || c.Links[0].Err != ErrBrokenChain || c.Links[1].Err != nil {
+		t.Fatalf("Expected %v for the account link, got %v %+v", ErrBrokenChain, err, c)
+	}
+
+	// Scoped user without issuer_account.
+	unscoped, _ := Encode(&UserClaims{Common: Common{Subject: upk}}, sk, nil)
+	if _, err := ValidateChain(unscoped, accountJWT, opk, nil); err != ErrBrokenChain {
+		t.Fatalf("Expected %v, got %v", ErrBrokenChain, err)
+	}
+
+	// Expired account, reported with the link.
+	vp := &nkeys.VerifyPolicy{Clock: nkeys.ClockFunc(func() time.Time { return now.Add(2 * time.Hour) })}
+	c, err = ValidateChain(userJWT, accountJWT, opk, vp)
+	if err != nkeys.ErrExpired || c.Links[0].Err != nkeys.ErrExpired {
+		t.Fatalf("Expected %v, got %v", nkeys.ErrExpired, err)
+	}
+
+	if _, err := ValidateChain(accountJWT, accountJWT, opk, nil); err != ErrInvalidType {
+		t.Fatalf("Expected %v, got %v", ErrInvalidType, err)
+	}
+	if _, err := ValidateChain(userJWT, accountJWT, apk, nil); err != ErrInvalidIssuer {
+		t.Fatalf("Expected %v, got %v", ErrInvalidIssuer, err)
+	}
+}
+
+func TestScopedSigningKeys(t *testing.T) {
+	op, _ := nkeys.CreateOperator()
+	acc, _ := nkeys.CreateAccount()
+	sk, _ := nkeys.CreateAccount()
+	user, _ := nkeys.CreateUser()
+	opk, _ := op.PublicKey()
+	apk, _ := acc.PublicKey()
+	skpk, _ := sk.PublicKey()
+	upk, _ := user.PublicKey()
+
+	// Account claims as written by a NATS v2 issuer, with a plain and a
+	// scoped signing key.
+	fixture := `{"sub":"` + apk + `","nats":{"type":"account","version":2,"signing_keys":[` +
+		`"` + apk + `",{"kind":"user_scope","key":"` + skpk + `","role":"admin","template":{"pub":{"allow":["foo"]}}}]}}`
+	var ac AccountClaims
+	if err := json.Unmarshal([]byte(fixture), &ac); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ac.Nats.SigningKeys) != 2 || ac.Nats.SigningKeys[1] != skpk {
+		t.Fatalf("Expected the scoped key %s, got %v", skpk, ac.Nats.SigningKeys)
+	}
+	accountJWT, err := Encode(&ac, op, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	uc := &UserClaims{Common: Common{Subject: upk}}
+	uc.Nats.IssuerAccount = apk
+	userJWT, _ := Encode(uc, sk, nil)
+	if _, err := ValidateChain(userJWT, accountJWT, opk, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	bad := `{"nats":{"type":"account","signing_keys":[{"kind":"user_scope"}]}}`
+	if err := json.Unmarshal([]byte(bad), &ac); err != ErrInvalidSigningKey {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSigningKey, err)
+	}
+}
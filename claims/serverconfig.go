@@ -0,0 +1,156 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package claims
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nats-io/nkeys"
+)
+
+// ServerConfig is the authorization part of a NATS server configuration.
+// The server accepts either operator JWTs or trusted keys, and nkey users
+// only without either.
+type ServerConfig struct {
+	// Operators are operator JWTs, for the operator option.
+	Operators []string
+	// TrustedKeys are operator public keys, for the trusted_keys option.
+	TrustedKeys []string
+	// SystemAccount is the public key of the system account.
+	SystemAccount string
+	// Preload maps account public keys to their JWTs, for the memory
+	// resolver.
+	Preload map[string]string
+	// Users are user public keys allowed to connect with nkey
+	// authentication.
+	Users []string
+}
+
+// ServerConfigFromKeys builds the server configuration for the given keys.
+// Operators with a seed get a self-signed operator JWT; if any operator is
+// public only, all operators are listed as trusted keys instead. Accounts
+// are preloaded with JWTs signed by the one operator that has a seed.
+// Users are listed for nkey authentication and can not be combined with
+// operators or accounts.
+func ServerConfigFromKeys(kps ...nkeys.KeyPair) (*ServerConfig, error) {
+	var operators, signers, accounts, users []nkeys.KeyPair
+	publicOnly := false
+	for _, kp := range kps {
+		public, err := kp.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		switch nkeys.Prefix(public) {
+		case nkeys.PrefixByteOperator:
+			operators = append(operators, kp)
+			if _, err := kp.Seed(); err == nil {
+				signers = append(signers, kp)
+			} else {
+				publicOnly = true
+			}
+		case nkeys.PrefixByteAccount:
+			accounts = append(accounts, kp)
+		case nkeys.PrefixByteUser:
+			users = append(users, kp)
+		default:
+			return nil, ErrInvalidSubject
+		}
+	}
+	if len(users) > 0 && len(operators)+len(accounts) > 0 {
+		return nil, ErrConflictingAuth
+	}
+	if len(accounts) > 0 && len(signers) != 1 {
+		return nil, ErrInvalidIssuer
+	}
+
+	sc := &ServerConfig{}
+	for _, op := range operators {
+		public, _ := op.PublicKey()
+		if publicOnly {
+			sc.TrustedKeys = append(sc.TrustedKeys, public)
+			continue
+		}
+		token, err := Encode(&OperatorClaims{Common: Common{Subject: public}}, op, nil)
+		if err != nil {
+			return nil, err
+		}
+		sc.Operators = append(sc.Operators, token)
+	}
+	for _, acc := range accounts {
+		public, _ := acc.PublicKey()
+		token, err := Encode(&AccountClaims{Common: Common{Subject: public}}, signers[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		if sc.Preload == nil {
+			sc.Preload = make(map[string]string)
+		}
+		sc.Preload[public] = token
+	}
+	for _, u := range users {
+		public, _ := u.PublicKey()
+		sc.Users = append(sc.Users, public)
+	}
+	sort.Strings(sc.TrustedKeys)
+	sort.Strings(sc.Users)
+	return sc, nil
+}
+
+// String renders the configuration in the NATS server configuration format.
+func (sc *ServerConfig) String() string {
+	var b strings.Builder
+	switch len(sc.Operators) {
+	case 0:
+	case 1:
+		fmt.Fprintf(&b, "operator: %q\n", sc.Operators[0])
+	default:
+		writeList(&b, "operator", sc.Operators)
+	}
+	if len(sc.TrustedKeys) > 0 {
+		writeList(&b, "trusted_keys", sc.TrustedKeys)
+	}
+	if sc.SystemAccount != "" {
+		fmt.Fprintf(&b, "system_account: %q\n", sc.SystemAccount)
+	}
+	if len(sc.Preload) > 0 {
+		keys := make([]string, 0, len(sc.Preload))
+		for k := range sc.Preload {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString("resolver: MEMORY\nresolver_preload: {\n")
+		for _, k := range keys {
+			fmt.Fprintf(&b, "  %s: %q\n", k, sc.Preload[k])
+		}
+		b.WriteString("}\n")
+	}
+	if len(sc.Users) > 0 {
+		b.WriteString("authorization {\n  users = [\n")
+		for _, u := range sc.Users {
+			fmt.Fprintf(&b, "    { nkey: %q }\n", u)
+		}
+		b.WriteString("  ]\n}\n")
+	}
+	return b.String()
+}
+
+func writeList(b *strings.Builder, name string, values []string) {
+	fmt.Fprintf(b, "%s: [\n", name)
+	for _, v := range values {
+		fmt.Fprintf(b, "  %q\n", v)
+	}
+	b.WriteString("]\n")
+}
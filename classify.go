@@ -0,0 +1,197 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "io"
+
+// Classification is the sensitivity of key material.
+type Classification uint8
+
+const (
+	// ClassUnclassified is the classification of keys that were never
+	// classified.
+	ClassUnclassified Classification = iota
+	// ClassLeaf is key material whose loss only affects itself, such as
+	// user and server keys.
+	ClassLeaf
+	// ClassIntermediate is key material that issues leaf keys, such as
+	// account keys.
+	ClassIntermediate
+	// ClassRoot is key material at the top of a trust chain, such as
+	// operator keys.
+	ClassRoot
+)
+
+func (c Classification) String() string {
+	switch c {
+	case ClassUnclassified:
+		return "unclassified"
+	case ClassLeaf:
+		return "leaf"
+	case ClassIntermediate:
+		return "intermediate"
+	case ClassRoot:
+		return "root"
+	}
+	return "unknown"
+}
+
+// DefaultClassification returns the usual classification of keys of the
+// given type.
+func DefaultClassification(prefix PrefixByte) Classification {
+	switch prefix {
+	case PrefixByteOperator:
+		return ClassRoot
+	case PrefixByteAccount:
+		return ClassIntermediate
+	}
+	return ClassLeaf
+}
+
+// ClassifiedKeyPair is a KeyPair carrying a classification. Seed and
+// PrivateKey return ErrExportNotAllowed for classifications above
+// ClassLeaf, so generic tooling that exports through them, such as
+// SerializeState or a seed store, can not leak the key by accident.
+// Deliberate exports go through ExportSeed, which reports the reason to the
+// event handler, e.g. an audit log.
+type ClassifiedKeyPair struct {
+	// Clock stamps the reported events. Defaults to the system clock.
+	Clock Clock
+
+	kp      KeyPair
+	public  string
+	class   Classification
+	handler EventHandler
+}
+
+// Classify returns a KeyPair that delegates to kp and enforces the
+// classification on export. Forced exports are reported to handler, which
+// may be nil.
+func Classify(kp KeyPair, class Classification, handler EventHandler) (*ClassifiedKeyPair, error) {
+	if class == ClassUnclassified || class > ClassRoot {
+		return nil, ErrInvalidClassification
+	}
+	public, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return &ClassifiedKeyPair{kp: kp, public: public, class: class, handler: handler}, nil
+}
+
+// CreateClassifiedPair creates a KeyPair like CreatePair with the given
+// classification.
+func CreateClassifiedPair(prefix PrefixByte, class Classification, handler EventHandler) (*ClassifiedKeyPair, error) {
+	kp, err := CreatePair(prefix)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Classify(kp, class, handler)
+	if err != nil {
+		kp.Wipe()
+		return nil, err
+	}
+	return c, nil
+}
+
+// ClassificationOf returns the classification of kp, or ClassUnclassified
+// if it is not a ClassifiedKeyPair.
+func ClassificationOf(kp KeyPair) Classification {
+	if c, ok := kp.(*ClassifiedKeyPair); ok {
+		return c.class
+	}
+	return ClassUnclassified
+}
+
+// Classification returns the classification of the key.
+func (c *ClassifiedKeyPair) Classification() Classification {
+	return c.class
+}
+
+func (c *ClassifiedKeyPair) exportable() bool {
+	return c.class <= ClassLeaf
+}
+
+// ExportSeed returns the seed regardless of the classification. The reason
+// is required and is reported with EventExported.
+func (c *ClassifiedKeyPair) ExportSeed(reason string) ([]byte, error) {
+	if reason == "" {
+		return nil, ErrExportNotAllowed
+	}
+	seed, err := c.kp.Seed()
+	if err != nil {
+		return nil, err
+	}
+	if c.handler != nil {
+		c.handler(Event{Type: EventExported, PublicKey: c.public, Time: ClockOrSystem(c.Clock).Now(), Reason: reason})
+	}
+	return seed, nil
+}
+
+// Seed will return the encoded seed if the classification allows it.
+func (c *ClassifiedKeyPair) Seed() ([]byte, error) {
+	if !c.exportable() {
+		return nil, ErrExportNotAllowed
+	}
+	return c.kp.Seed()
+}
+
+// PublicKey will return the encoded public key.
+func (c *ClassifiedKeyPair) PublicKey() (string, error) {
+	return c.kp.PublicKey()
+}
+
+// PrivateKey will return the encoded private key if the classification
+// allows it.
+func (c *ClassifiedKeyPair) PrivateKey() ([]byte, error) {
+	if !c.exportable() {
+		return nil, ErrExportNotAllowed
+	}
+	return c.kp.PrivateKey()
+}
+
+// Sign will sign the input.
+func (c *ClassifiedKeyPair) Sign(input []byte) ([]byte, error) {
+	return c.kp.Sign(input)
+}
+
+// Verify will verify the input against a signature.
+func (c *ClassifiedKeyPair) Verify(input []byte, sig []byte) error {
+	return c.kp.Verify(input, sig)
+}
+
+// PublicOnly returns a public only copy of the KeyPair. Public keys are not
+// classified.
+func (c *ClassifiedKeyPair) PublicOnly() (KeyPair, error) {
+	return c.kp.PublicOnly()
+}
+
+// Wipe will wipe the underlying KeyPair.
+func (c *ClassifiedKeyPair) Wipe() {
+	c.kp.Wipe()
+}
+
+// Seal will seal the input.
+func (c *ClassifiedKeyPair) Seal(input []byte, recipient string) ([]byte, error) {
+	return c.kp.Seal(input, recipient)
+}
+
+// SealWithRand will seal the input.
+func (c *ClassifiedKeyPair) SealWithRand(input []byte, recipient string, rr io.Reader) ([]byte, error) {
+	return c.kp.SealWithRand(input, recipient, rr)
+}
+
+// Open will open the input.
+func (c *ClassifiedKeyPair) Open(input []byte, sender string) ([]byte, error) {
+	return c.kp.Open(input, sender)
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "crypto/ed25519"
+
+// CompactPublicKeySize is the length of the compact form of a public key:
+// the prefix byte followed by the raw 32 byte key.
+const CompactPublicKeySize = 1 + ed25519.PublicKeySize
+
+// ShortKey returns the first n characters of the public key, which keep
+// the type character, for display in logs. Short keys are not unique and
+// must not be used to look keys up.
+func ShortKey(pub string, n int) string {
+	if n < 0 {
+		n = 0
+	}
+	if n >= len(pub) {
+		return pub
+	}
+	return pub[:n]
+}
+
+// CompactPublicKey returns the compact binary form of the public key, for
+// storage in databases and logs in about a third of the space of the
+// encoded key. FromCompactPublicKey reverses it.
+func CompactPublicKey(pub string) ([]byte, error) {
+	if err := checkPublicKeyArg(pub); err != nil {
+		return nil, err
+	}
+	raw, err := decode([]byte(pub))
+	if err != nil {
+		return nil, err
+	}
+	if checkValidPublicPrefixByte(PrefixByte(raw[0])) != nil || len(raw) != CompactPublicKeySize {
+		return nil, ErrInvalidPublicKey
+	}
+	return raw, nil
+}
+
+// FromCompactPublicKey returns the encoded public key of a compact form
+// produced by CompactPublicKey.
+func FromCompactPublicKey(b []byte) (string, error) {
+	if len(b) != CompactPublicKeySize || checkValidPublicPrefixByte(PrefixByte(b[0])) != nil {
+		return "", ErrInvalidPublicKey
+	}
+	pub, err := Encode(PrefixByte(b[0]), b[1:])
+	if err != nil {
+		return "", err
+	}
+	return string(pub), nil
+}
@@ -0,0 +1,263 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compat exports exactly the API of the upstream nats-io/nkeys
+// package, with the same signatures, error values and messages, backed by
+// this module. Code written against upstream builds unchanged when its
+// import is replaced with
+//
+//	nkeys "github.com/nats-io/nkeys/compat"
+//
+// and back. The one difference of the root package that matters is that its
+// KeyPair interface has methods upstream lacks, so types implementing the
+// upstream KeyPair only satisfy the KeyPair of this package.
+package compat
+
+import (
+	"io"
+
+	"github.com/nats-io/nkeys"
+)
+
+// Version is the upstream version this package mirrors.
+const Version = nkeys.Version
+
+// KeyPair provides the central interface to nkeys.
+type KeyPair interface {
+	Seed() ([]byte, error)
+	PublicKey() (string, error)
+	PrivateKey() ([]byte, error)
+	// Sign is only supported on Non CurveKeyPairs
+	Sign(input []byte) ([]byte, error)
+	// Verify is only supported on Non CurveKeyPairs
+	Verify(input []byte, sig []byte) error
+	Wipe()
+	// Seal is only supported on CurveKeyPair
+	Seal(input []byte, recipient string) ([]byte, error)
+	// SealWithRand is only supported on CurveKeyPair
+	SealWithRand(input []byte, recipient string, rr io.Reader) ([]byte, error)
+	// Open is only supported on CurveKey
+	Open(input []byte, sender string) ([]byte, error)
+}
+
+// PrefixByte is a lead byte representing the type.
+type PrefixByte = nkeys.PrefixByte
+
+const (
+	PrefixByteSeed     = nkeys.PrefixByteSeed
+	PrefixBytePrivate  = nkeys.PrefixBytePrivate
+	PrefixByteServer   = nkeys.PrefixByteServer
+	PrefixByteCluster  = nkeys.PrefixByteCluster
+	PrefixByteOperator = nkeys.PrefixByteOperator
+	PrefixByteAccount  = nkeys.PrefixByteAccount
+	PrefixByteUser     = nkeys.PrefixByteUser
+	PrefixByteCurve    = nkeys.PrefixByteCurve
+	PrefixByteUnknown  = nkeys.PrefixByteUnknown
+)
+
+// XKeyVersionV1 is the version of sealed curve key messages.
+const XKeyVersionV1 = nkeys.XKeyVersionV1
+
+// Errors are the values of the root package, so they compare equal to the
+// errors returned by either package.
+const (
+	ErrInvalidPrefixByte        = nkeys.ErrInvalidPrefixByte
+	ErrInvalidKey               = nkeys.ErrInvalidKey
+	ErrInvalidPublicKey         = nkeys.ErrInvalidPublicKey
+	ErrInvalidPrivateKey        = nkeys.ErrInvalidPrivateKey
+	ErrInvalidSeedLen           = nkeys.ErrInvalidSeedLen
+	ErrInvalidSeed              = nkeys.ErrInvalidSeed
+	ErrInvalidEncoding          = nkeys.ErrInvalidEncoding
+	ErrInvalidSignature         = nkeys.ErrInvalidSignature
+	ErrCannotSign               = nkeys.ErrCannotSign
+	ErrPublicKeyOnly            = nkeys.ErrPublicKeyOnly
+	ErrIncompatibleKey          = nkeys.ErrIncompatibleKey
+	ErrInvalidChecksum          = nkeys.ErrInvalidChecksum
+	ErrNoSeedFound              = nkeys.ErrNoSeedFound
+	ErrInvalidNkeySeed          = nkeys.ErrInvalidNkeySeed
+	ErrInvalidUserSeed          = nkeys.ErrInvalidUserSeed
+	ErrInvalidRecipient         = nkeys.ErrInvalidRecipient
+	ErrInvalidSender            = nkeys.ErrInvalidSender
+	ErrInvalidCurveKey          = nkeys.ErrInvalidCurveKey
+	ErrInvalidCurveSeed         = nkeys.ErrInvalidCurveSeed
+	ErrInvalidEncrypted         = nkeys.ErrInvalidEncrypted
+	ErrInvalidEncVersion        = nkeys.ErrInvalidEncVersion
+	ErrCouldNotDecrypt          = nkeys.ErrCouldNotDecrypt
+	ErrInvalidCurveKeyOperation = nkeys.ErrInvalidCurveKeyOperation
+	ErrInvalidNKeyOperation     = nkeys.ErrInvalidNKeyOperation
+	ErrCannotOpen               = nkeys.ErrCannotOpen
+	ErrCannotSeal               = nkeys.ErrCannotSeal
+)
+
+// keyPair converts the result of a root package constructor. The root
+// package reports a seed passed as a public key and the reverse with errors
+// upstream lacks; they are mapped back to the errors upstream returns.
+func keyPair(kp nkeys.KeyPair, err error) (KeyPair, error) {
+	switch err {
+	case nil:
+		return kp, nil
+	case nkeys.ErrExpectedPublicKeyGotSeed:
+		return nil, ErrInvalidPublicKey
+	case nkeys.ErrExpectedSeedGotPublicKey:
+		return nil, ErrInvalidSeed
+	}
+	return nil, err
+}
+
+// CreateUser will create a User typed KeyPair.
+func CreateUser() (KeyPair, error) {
+	return keyPair(nkeys.CreateUser())
+}
+
+// CreateAccount will create an Account typed KeyPair.
+func CreateAccount() (KeyPair, error) {
+	return keyPair(nkeys.CreateAccount())
+}
+
+// CreateServer will create a Server typed KeyPair.
+func CreateServer() (KeyPair, error) {
+	return keyPair(nkeys.CreateServer())
+}
+
+// CreateCluster will create a Cluster typed KeyPair.
+func CreateCluster() (KeyPair, error) {
+	return keyPair(nkeys.CreateCluster())
+}
+
+// CreateOperator will create an Operator typed KeyPair.
+func CreateOperator() (KeyPair, error) {
+	return keyPair(nkeys.CreateOperator())
+}
+
+// CreatePair will create a KeyPair based on the rand entropy and a type/prefix byte.
+func CreatePair(prefix PrefixByte) (KeyPair, error) {
+	return keyPair(nkeys.CreatePair(prefix))
+}
+
+// CreatePairWithRand will create a KeyPair based on the rand reader and a type/prefix byte.
+func CreatePairWithRand(prefix PrefixByte, rr io.Reader) (KeyPair, error) {
+	return keyPair(nkeys.CreatePairWithRand(prefix, rr))
+}
+
+// CreateCurveKeys will create a Curve typed KeyPair.
+func CreateCurveKeys() (KeyPair, error) {
+	return keyPair(nkeys.CreateCurveKeys())
+}
+
+// CreateCurveKeysWithRand will create a Curve typed KeyPair with specified rand source.
+func CreateCurveKeysWithRand(rr io.Reader) (KeyPair, error) {
+	return keyPair(nkeys.CreateCurveKeysWithRand(rr))
+}
+
+// FromPublicKey will create a KeyPair capable of verifying signatures.
+func FromPublicKey(public string) (KeyPair, error) {
+	return keyPair(nkeys.FromPublicKey(public))
+}
+
+// FromSeed will create a KeyPair capable of signing and verifying signatures.
+func FromSeed(seed []byte) (KeyPair, error) {
+	return keyPair(nkeys.FromSeed(seed))
+}
+
+// FromRawSeed will create a KeyPair from the raw 32 byte seed for a given type.
+func FromRawSeed(prefix PrefixByte, rawSeed []byte) (KeyPair, error) {
+	return keyPair(nkeys.FromRawSeed(prefix, rawSeed))
+}
+
+// FromCurveSeed will create a curve key pair from seed.
+func FromCurveSeed(seed []byte) (KeyPair, error) {
+	return keyPair(nkeys.FromCurveSeed(seed))
+}
+
+// ParseDecoratedJWT takes a creds file and returns the JWT portion.
+func ParseDecoratedJWT(contents []byte) (string, error) {
+	return nkeys.ParseDecoratedJWT(contents)
+}
+
+// ParseDecoratedNKey takes a creds file, finds the NKey portion and creates a
+// key pair from it.
+func ParseDecoratedNKey(contents []byte) (KeyPair, error) {
+	return keyPair(nkeys.ParseDecoratedNKey(contents))
+}
+
+// ParseDecoratedUserNKey takes a creds file, finds the NKey portion and
+// creates a key pair from it. Similar to ParseDecoratedNKey but fails for
+// non-user keys.
+func ParseDecoratedUserNKey(contents []byte) (KeyPair, error) {
+	return keyPair(nkeys.ParseDecoratedUserNKey(contents))
+}
+
+// Encode will encode a raw key or seed with the prefix and crc16 and then base32 encoded.
+func Encode(prefix PrefixByte, src []byte) ([]byte, error) {
+	return nkeys.Encode(prefix, src)
+}
+
+// EncodeSeed will encode a raw key with the prefix and then seed prefix and crc16 and then base32 encoded.
+func EncodeSeed(public PrefixByte, src []byte) ([]byte, error) {
+	return nkeys.EncodeSeed(public, src)
+}
+
+// Decode will decode the base32 string and check crc16 and enforce the prefix is what is expected.
+func Decode(expectedPrefix PrefixByte, src []byte) ([]byte, error) {
+	return nkeys.Decode(expectedPrefix, src)
+}
+
+// DecodeSeed will decode the prefix and raw seed.
+func DecodeSeed(src []byte) (PrefixByte, []byte, error) {
+	return nkeys.DecodeSeed(src)
+}
+
+// IsValidEncoding will tell you if the encoding is a valid key.
+func IsValidEncoding(src []byte) bool {
+	return nkeys.IsValidEncoding(src)
+}
+
+// Prefix returns PrefixBytes of its input.
+func Prefix(src string) PrefixByte {
+	return nkeys.Prefix(src)
+}
+
+// IsValidPublicKey will decode and verify that the string is a valid encoded public key.
+func IsValidPublicKey(src string) bool {
+	return nkeys.IsValidPublicKey(src)
+}
+
+// IsValidPublicUserKey will decode and verify the string is a valid encoded Public User Key.
+func IsValidPublicUserKey(src string) bool {
+	return nkeys.IsValidPublicUserKey(src)
+}
+
+// IsValidPublicAccountKey will decode and verify the string is a valid encoded Public Account Key.
+func IsValidPublicAccountKey(src string) bool {
+	return nkeys.IsValidPublicAccountKey(src)
+}
+
+// IsValidPublicServerKey will decode and verify the string is a valid encoded Public Server Key.
+func IsValidPublicServerKey(src string) bool {
+	return nkeys.IsValidPublicServerKey(src)
+}
+
+// IsValidPublicClusterKey will decode and verify the string is a valid encoded Public Cluster Key.
+func IsValidPublicClusterKey(src string) bool {
+	return nkeys.IsValidPublicClusterKey(src)
+}
+
+// IsValidPublicOperatorKey will decode and verify the string is a valid encoded Public Operator Key.
+func IsValidPublicOperatorKey(src string) bool {
+	return nkeys.IsValidPublicOperatorKey(src)
+}
+
+// IsValidPublicCurveKey will decode and verify the string is a valid encoded Public Curve Key.
+func IsValidPublicCurveKey(src string) bool {
+	return nkeys.IsValidPublicCurveKey(src)
+}
@@ -0,0 +1,223 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compat
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"sort"
+	"testing"
+
+	"github.com/nats-io/nkeys"
+)
+
+// The upstream signatures. A change to any of them fails to compile.
+var (
+	_ func() (KeyPair, error)                                  = CreateUser
+	_ func() (KeyPair, error)                                  = CreateAccount
+	_ func() (KeyPair, error)                                  = CreateServer
+	_ func() (KeyPair, error)                                  = CreateCluster
+	_ func() (KeyPair, error)                                  = CreateOperator
+	_ func(PrefixByte) (KeyPair, error)                        = CreatePair
+	_ func(PrefixByte, io.Reader) (KeyPair, error)             = CreatePairWithRand
+	_ func() (KeyPair, error)                                  = CreateCurveKeys
+	_ func(io.Reader) (KeyPair, error)                         = CreateCurveKeysWithRand
+	_ func(string) (KeyPair, error)                            = FromPublicKey
+	_ func([]byte) (KeyPair, error)                            = FromSeed
+	_ func(PrefixByte, []byte) (KeyPair, error)                = FromRawSeed
+	_ func([]byte) (KeyPair, error)                            = FromCurveSeed
+	_ func([]byte) (string, error)                             = ParseDecoratedJWT
+	_ func([]byte) (KeyPair, error)                            = ParseDecoratedNKey
+	_ func([]byte) (KeyPair, error)                            = ParseDecoratedUserNKey
+	_ func(PrefixByte, []byte) ([]byte, error)                 = Encode
+	_ func(PrefixByte, []byte) ([]byte, error)                 = EncodeSeed
+	_ func(PrefixByte, []byte) ([]byte, error)                 = Decode
+	_ func([]byte) (PrefixByte, []byte, error)                 = DecodeSeed
+	_ func([]byte) bool                                        = IsValidEncoding
+	_ func(string) PrefixByte                                  = Prefix
+	_ func(string) bool                                        = IsValidPublicKey
+	_ func(string) bool                                        = IsValidPublicUserKey
+	_ func(string) bool                                        = IsValidPublicAccountKey
+	_ func(string) bool                                        = IsValidPublicServerKey
+	_ func(string) bool                                        = IsValidPublicClusterKey
+	_ func(string) bool                                        = IsValidPublicOperatorKey
+	_ func(string) bool                                        = IsValidPublicCurveKey
+	_ func(PrefixByte) string                                  = PrefixByte.String
+	_ func(KeyPair, []byte, string) ([]byte, error)            = KeyPair.Seal
+	_ func(KeyPair, []byte, string, io.Reader) ([]byte, error) = KeyPair.SealWithRand
+	_ KeyPair                                                  = nkeys.KeyPair(nil)
+)
+
+// upstreamErrors holds the upstream messages, which callers may match on.
+var upstreamErrors = map[error]string{
+	ErrInvalidPrefixByte:        "nkeys: invalid prefix byte",
+	ErrInvalidKey:               "nkeys: invalid key",
+	ErrInvalidPublicKey:         "nkeys: invalid public key",
+	ErrInvalidPrivateKey:        "nkeys: invalid private key",
+	ErrInvalidSeedLen:           "nkeys: invalid seed length",
+	ErrInvalidSeed:              "nkeys: invalid seed",
+	ErrInvalidEncoding:          "nkeys: invalid encoded key",
+	ErrInvalidSignature:         "nkeys: signature verification failed",
+	ErrCannotSign:               "nkeys: can not sign, no private key available",
+	ErrPublicKeyOnly:            "nkeys: no seed or private key available",
+	ErrIncompatibleKey:          "nkeys: incompatible key",
+	ErrInvalidChecksum:          "nkeys: invalid checksum",
+	ErrNoSeedFound:              "nkeys: no nkey seed found",
+	ErrInvalidNkeySeed:          "nkeys: doesn't contain a seed nkey",
+	ErrInvalidUserSeed:          "nkeys: doesn't contain an user seed nkey",
+	ErrInvalidRecipient:         "nkeys: not a valid recipient public curve key",
+	ErrInvalidSender:            "nkeys: not a valid sender public curve key",
+	ErrInvalidCurveKey:          "nkeys: not a valid curve key",
+	ErrInvalidCurveSeed:         "nkeys: not a valid curve seed",
+	ErrInvalidEncrypted:         "nkeys: encrypted input is not valid",
+	ErrInvalidEncVersion:        "nkeys: encrypted input wrong version",
+	ErrCouldNotDecrypt:          "nkeys: could not decrypt input",
+	ErrInvalidCurveKeyOperation: "nkeys: curve key is not valid for sign/verify",
+	ErrInvalidNKeyOperation:     "nkeys: only curve key can seal/open",
+	ErrCannotOpen:               "nkeys: cannot open no private curve key available",
+	ErrCannotSeal:               "nkeys: cannot seal no private curve key available",
+}
+
+func TestErrorMessages(t *testing.T) {
+	for err, msg := range upstreamErrors {
+		if err.Error() != msg {
+			t.Fatalf("Expected %q, got %q", msg, err.Error())
+		}
+	}
+}
+
+// exports returns the exported top level identifiers of the package in dir.
+func exports(t *testing.T, dir string) map[string]bool {
+	t.Helper()
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, nil, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	names := make(map[string]bool)
+	for name, pkg := range pkgs {
+		if name != "nkeys" && name != "compat" {
+			continue
+		}
+		for fname, f := range pkg.Files {
+			if len(fname) > 8 && fname[len(fname)-8:] == "_test.go" {
+				continue
+			}
+			for _, d := range f.Decls {
+				switch d := d.(type) {
+				case *ast.FuncDecl:
+					if d.Recv == nil && d.Name.IsExported() {
+						names[d.Name.Name] = true
+					}
+				case *ast.GenDecl:
+					for _, s := range d.Specs {
+						switch s := s.(type) {
+						case *ast.TypeSpec:
+							if s.Name.IsExported() {
+								names[s.Name.Name] = true
+							}
+						case *ast.ValueSpec:
+							for _, n := range s.Names {
+								if n.IsExported() {
+									names[n.Name] = true
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// TestExports checks that this package exports nothing beyond the upstream
+// API and that the root package still provides all of it.
+func TestExports(t *testing.T) {
+	upstream := []string{
+		"Version", "KeyPair", "PrefixByte", "XKeyVersionV1",
+		"PrefixByteSeed", "PrefixBytePrivate", "PrefixByteServer",
+		"PrefixByteCluster", "PrefixByteOperator", "PrefixByteAccount",
+		"PrefixByteUser", "PrefixByteCurve", "PrefixByteUnknown",
+		"CreateUser", "CreateAccount", "CreateServer", "CreateCluster",
+		"CreateOperator", "CreatePair", "CreatePairWithRand",
+		"CreateCurveKeys", "CreateCurveKeysWithRand", "FromPublicKey",
+		"FromSeed", "FromRawSeed", "FromCurveSeed", "ParseDecoratedJWT",
+		"ParseDecoratedNKey", "ParseDecoratedUserNKey", "Encode",
+		"EncodeSeed", "Decode", "DecodeSeed", "IsValidEncoding", "Prefix",
+		"IsValidPublicKey", "IsValidPublicUserKey", "IsValidPublicAccountKey",
+		"IsValidPublicServerKey", "IsValidPublicClusterKey",
+		"IsValidPublicOperatorKey", "IsValidPublicCurveKey",
+	}
+	compat := exports(t, ".")
+	root := exports(t, "..")
+	var extra []string
+	for _, name := range upstream {
+		if !compat[name] {
+			t.Fatalf("Expected %s to be exported", name)
+		}
+		if !root[name] {
+			t.Fatalf("Expected the root package to export %s", name)
+		}
+		delete(compat, name)
+	}
+	for name := range compat {
+		if len(name) < 3 || name[:3] != "Err" {
+			extra = append(extra, name)
+		} else if !root[name] {
+			t.Fatalf("Expected the root package to export %s", name)
+		}
+	}
+	sort.Strings(extra)
+	if len(extra) != 0 {
+		t.Fatalf("Expected no exports beyond upstream, got %v", extra)
+	}
+	if len(compat)-len(extra) != len(upstreamErrors) {
+		t.Fatalf("Expected %d errors, got %d", len(upstreamErrors), len(compat)-len(extra))
+	}
+}
+
+func TestBehavior(t *testing.T) {
+	kp, err := CreateUser()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	seed, _ := kp.Seed()
+	rkp, err := nkeys.FromSeed(seed)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sig, _ := rkp.Sign([]byte("hello"))
+	if err := kp.Verify([]byte("hello"), sig); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	pub, _ := kp.PublicKey()
+	if Prefix(pub) != PrefixByteUser {
+		t.Fatalf("Expected %v, got %v", PrefixByteUser, Prefix(pub))
+	}
+	// Errors are the same values, and failed constructors return a nil
+	// interface rather than a typed nil.
+	_, want := nkeys.FromSeed([]byte("SUBAD"))
+	if kp, err := FromSeed([]byte("SUBAD")); kp != nil || err != want {
+		t.Fatalf("Expected nil and %v, got %v and %v", want, kp, err)
+	}
+	// Swapped seeds and public keys fail as they do upstream.
+	if _, err := FromPublicKey(string(seed)); err != ErrInvalidPublicKey {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPublicKey, err)
+	}
+	if _, err := FromSeed([]byte(pub)); err != ErrInvalidSeed {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSeed, err)
+	}
+}
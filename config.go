@@ -0,0 +1,120 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// The signature header is made of '#' comment lines so that signed files
+// remain valid NATS server configuration.
+const (
+	configHeaderBegin = "# -----BEGIN NKEYS CONFIG SIGNATURE-----\n"
+	configHeaderEnd   = "# -----END NKEYS CONFIG SIGNATURE-----\n"
+	configSignerLine  = "# signer: "
+	configSigLine     = "# sig: "
+)
+
+// SignConfig returns config prefixed with a header block holding kp's public
+// key and its signature over config.
+func SignConfig(kp KeyPair, config []byte) ([]byte, error) {
+	pk, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := kp.Sign(configSignedBytes(pk, config))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(configHeaderBegin)
+	buf.WriteString(configSignerLine + pk + "\n")
+	buf.WriteString(configSigLine + base64.RawURLEncoding.EncodeToString(sig) + "\n")
+	buf.WriteString(configHeaderEnd)
+	buf.Write(config)
+	return buf.Bytes(), nil
+}
+
+// VerifyConfig checks that signed was produced by SignConfig with the key
+// public and returns the config without the header block.
+func VerifyConfig(public string, signed []byte) ([]byte, error) {
+	rest, ok := cutPrefix(signed, configHeaderBegin)
+	if !ok {
+		return nil, ErrInvalidSignedConfig
+	}
+	signer, rest, ok := cutHeaderLine(rest, configSignerLine)
+	if !ok {
+		return nil, ErrInvalidSignedConfig
+	}
+	encSig, rest, ok := cutHeaderLine(rest, configSigLine)
+	if !ok {
+		return nil, ErrInvalidSignedConfig
+	}
+	config, ok := cutPrefix(rest, configHeaderEnd)
+	if !ok {
+		return nil, ErrInvalidSignedConfig
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil {
+		return nil, ErrInvalidSignedConfig
+	}
+	if signer != public {
+		return nil, ErrInvalidSignature
+	}
+	if err := VerifyWithPolicy(nil, public, configSignedBytes(public, config), sig); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// LoadVerifiedConfig reads the config file at path and returns its contents
+// without the header block, refusing files not signed by public.
+func LoadVerifiedConfig(public string, path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return VerifyConfig(public, data)
+}
+
+func configSignedBytes(public string, config []byte) []byte {
+	e := canonical.NewEncoder("nkeys.SignedConfig")
+	e.Text(public)
+	e.Blob(config)
+	return e.Bytes()
+}
+
+func cutPrefix(b []byte, prefix string) ([]byte, bool) {
+	if !bytes.HasPrefix(b, []byte(prefix)) {
+		return nil, false
+	}
+	return b[len(prefix):], true
+}
+
+// cutHeaderLine returns the value of the header line starting with prefix.
+func cutHeaderLine(b []byte, prefix string) (string, []byte, bool) {
+	rest, ok := cutPrefix(b, prefix)
+	if !ok {
+		return "", nil, false
+	}
+	i := bytes.IndexByte(rest, '\n')
+	if i < 0 {
+		return "", nil, false
+	}
+	return string(rest[:i]), rest[i+1:], true
+}
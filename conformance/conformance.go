@@ -0,0 +1,186 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package conformance checks that alternative KeyPair implementations, such
+// as HSM, remote signer or enclave backends, behave like the in-memory
+// implementation. Call RunConformance from a test in the implementation's
+// package.
+package conformance
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nats-io/nkeys"
+)
+
+// KeyPairFactory creates a new KeyPair of the given type. Factories that do
+// not support a type return nkeys.ErrInvalidPrefixByte, and its checks are
+// skipped.
+type KeyPairFactory func(prefix nkeys.PrefixByte) (nkeys.KeyPair, error)
+
+// SigningTypes are the key types that sign.
+var SigningTypes = []nkeys.PrefixByte{
+	nkeys.PrefixByteOperator,
+	nkeys.PrefixByteAccount,
+	nkeys.PrefixByteUser,
+	nkeys.PrefixByteServer,
+	nkeys.PrefixByteCluster,
+}
+
+// RunConformance runs the conformance checks against the KeyPairs created
+// by impl as subtests of t. Implementations that can export their seed are
+// additionally compared with nkeys.FromSeed.
+func RunConformance(t *testing.T, impl KeyPairFactory) {
+	for _, prefix := range SigningTypes {
+		prefix := prefix
+		t.Run(prefix.String(), func(t *testing.T) {
+			kp := create(t, impl, prefix)
+			defer kp.Wipe()
+			checkSigning(t, kp, prefix)
+		})
+	}
+	t.Run(nkeys.PrefixByteCurve.String(), func(t *testing.T) {
+		kp := create(t, impl, nkeys.PrefixByteCurve)
+		defer kp.Wipe()
+		checkCurve(t, kp)
+	})
+}
+
+func create(t *testing.T, impl KeyPairFactory, prefix nkeys.PrefixByte) nkeys.KeyPair {
+	t.Helper()
+	kp, err := impl(prefix)
+	if err == nkeys.ErrInvalidPrefixByte {
+		t.Skipf("%s keys are not supported", prefix)
+	}
+	if err != nil {
+		t.Fatalf("Unexpected error creating %s key: %v", prefix, err)
+	}
+	return kp
+}
+
+func publicKey(t *testing.T, kp nkeys.KeyPair, prefix nkeys.PrefixByte) string {
+	t.Helper()
+	pk, err := kp.PublicKey()
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving public key: %v", err)
+	}
+	if !nkeys.IsValidPublicKey(pk) || nkeys.Prefix(pk) != prefix {
+		t.Fatalf("Expected a valid %s public key, got %q", prefix, pk)
+	}
+	return pk
+}
+
+func checkSigning(t *testing.T, kp nkeys.KeyPair, prefix nkeys.PrefixByte) {
+	pk := publicKey(t, kp, prefix)
+	ref, err := nkeys.FromPublicKey(pk)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing public key: %v", err)
+	}
+
+	for _, input := range [][]byte{nil, []byte("hello"), bytes.Repeat([]byte{0xAB}, 1<<16)} {
+		sig, err := kp.Sign(input)
+		if err != nil {
+			t.Fatalf("Unexpected error signing: %v", err)
+		}
+		if err := ref.Verify(input, sig); err != nil {
+			t.Fatalf("Expected the signature to verify with the public key, got %v", err)
+		}
+		if err := kp.Verify(input, sig); err != nil {
+			t.Fatalf("Expected the signature to verify with the key pair, got %v", err)
+		}
+		again, err := kp.Sign(input)
+		if err != nil || !bytes.Equal(sig, again) {
+			t.Fatalf("Expected ed25519 signatures to be deterministic")
+		}
+		bad := append([]byte{}, sig...)
+		bad[0] ^= 1
+		if err := kp.Verify(input, bad); err != nkeys.ErrInvalidSignature {
+			t.Fatalf("Expected %v for a modified signature, got %v", nkeys.ErrInvalidSignature, err)
+		}
+	}
+	if err := kp.Verify([]byte("hello"), []byte("short")); err != nkeys.ErrInvalidSignature {
+		t.Fatalf("Expected %v for a short signature, got %v", nkeys.ErrInvalidSignature, err)
+	}
+
+	if _, err := kp.Seal([]byte("hello"), pk); err != nkeys.ErrInvalidNKeyOperation {
+		t.Fatalf("Expected %v from Seal, got %v", nkeys.ErrInvalidNKeyOperation, err)
+	}
+	if _, err := kp.Open([]byte("hello"), pk); err != nkeys.ErrInvalidNKeyOperation {
+		t.Fatalf("Expected %v from Open, got %v", nkeys.ErrInvalidNKeyOperation, err)
+	}
+
+	public, err := kp.PublicOnly()
+	if err != nil {
+		t.Fatalf("Unexpected error from PublicOnly: %v", err)
+	}
+	if ppk := publicKey(t, public, prefix); ppk != pk {
+		t.Fatalf("Expected PublicOnly to keep %q, got %q", pk, ppk)
+	}
+	if _, err := public.Sign([]byte("hello")); err != nkeys.ErrCannotSign {
+		t.Fatalf("Expected %v from a public only key, got %v", nkeys.ErrCannotSign, err)
+	}
+
+	seed, err := kp.Seed()
+	if err != nil {
+		// Keys that can not be exported are only checked for consistency.
+		return
+	}
+	mem, err := nkeys.FromSeed(seed)
+	if err != nil {
+		t.Fatalf("Expected an exported seed to load, got %v", err)
+	}
+	defer mem.Wipe()
+	if mpk, _ := mem.PublicKey(); mpk != pk {
+		t.Fatalf("Expected the seed to produce %q, got %q", pk, mpk)
+	}
+	sig, _ := kp.Sign([]byte("hello"))
+	if msig, _ := mem.Sign([]byte("hello")); !bytes.Equal(sig, msig) {
+		t.Fatalf("Expected signatures to match the in-memory implementation")
+	}
+	priv, err := kp.PrivateKey()
+	if err != nil {
+		t.Fatalf("Unexpected error retrieving private key: %v", err)
+	}
+	if mpriv, _ := mem.PrivateKey(); !bytes.Equal(priv, mpriv) {
+		t.Fatalf("Expected private keys to match the in-memory implementation")
+	}
+}
+
+func checkCurve(t *testing.T, kp nkeys.KeyPair) {
+	pk := publicKey(t, kp, nkeys.PrefixByteCurve)
+	if _, err := kp.Sign([]byte("hello")); err != nkeys.ErrInvalidCurveKeyOperation {
+		t.Fatalf("Expected %v from Sign, got %v", nkeys.ErrInvalidCurveKeyOperation, err)
+	}
+	if err := kp.Verify([]byte("hello"), nil); err != nkeys.ErrInvalidCurveKeyOperation {
+		t.Fatalf("Expected %v from Verify, got %v", nkeys.ErrInvalidCurveKeyOperation, err)
+	}
+
+	peer, _ := nkeys.CreateCurveKeys()
+	defer peer.Wipe()
+	ppk, _ := peer.PublicKey()
+	sealed, err := kp.Seal([]byte("hello"), ppk)
+	if err != nil {
+		t.Fatalf("Unexpected error from Seal: %v", err)
+	}
+	if opened, err := peer.Open(sealed, pk); err != nil || string(opened) != "hello" {
+		t.Fatalf("Expected the peer to open the sealed message, got %q, %v", opened, err)
+	}
+	sealed, _ = peer.Seal([]byte("hello"), pk)
+	if opened, err := kp.Open(sealed, ppk); err != nil || string(opened) != "hello" {
+		t.Fatalf("Expected to open the peer's message, got %q, %v", opened, err)
+	}
+	if _, err := kp.Seal([]byte("hello"), "bad"); err != nkeys.ErrInvalidRecipient {
+		t.Fatalf("Expected %v, got %v", nkeys.ErrInvalidRecipient, err)
+	}
+}
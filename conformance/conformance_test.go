@@ -0,0 +1,40 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conformance
+
+import (
+	"testing"
+
+	"github.com/nats-io/nkeys"
+)
+
+func TestInMemory(t *testing.T) {
+	RunConformance(t, nkeys.CreatePair)
+}
+
+func TestEventKeyPair(t *testing.T) {
+	RunConformance(t, func(prefix nkeys.PrefixByte) (nkeys.KeyPair, error) {
+		return nkeys.CreatePairWithEvents(prefix, nil)
+	})
+}
+
+func TestFrozen(t *testing.T) {
+	RunConformance(t, func(prefix nkeys.PrefixByte) (nkeys.KeyPair, error) {
+		kp, err := nkeys.CreatePair(prefix)
+		if err != nil {
+			return nil, err
+		}
+		return nkeys.Freeze(kp)
+	})
+}
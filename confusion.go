@@ -0,0 +1,80 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+// PublicKeyString is an encoded public key that has been checked by
+// ParsePublicKey. Functions taking one can not be handed a seed by mistake.
+type PublicKeyString string
+
+// SeedBytes is an encoded seed that has been checked by ParseSeed.
+type SeedBytes []byte
+
+// ParsePublicKey checks that s is an encoded public key. Seeds are reported
+// with ErrExpectedPublicKeyGotSeed.
+func ParsePublicKey(s string) (PublicKeyString, error) {
+	if err := checkPublicKeyArg(s); err != nil {
+		return "", err
+	}
+	if !IsValidPublicKey(s) {
+		return "", ErrInvalidPublicKey
+	}
+	return PublicKeyString(s), nil
+}
+
+// ParseSeed checks that seed is an encoded seed. Public keys are reported
+// with ErrExpectedSeedGotPublicKey.
+func ParseSeed(seed []byte) (SeedBytes, error) {
+	if err := checkSeedArg(seed); err != nil {
+		return nil, err
+	}
+	if _, _, err := DecodeSeed(seed); err != nil {
+		return nil, err
+	}
+	return SeedBytes(seed), nil
+}
+
+// String returns the encoded public key.
+func (p PublicKeyString) String() string {
+	return string(p)
+}
+
+// KeyPair returns a KeyPair for verifying with the public key.
+func (p PublicKeyString) KeyPair() (KeyPair, error) {
+	return FromPublicKey(string(p))
+}
+
+// KeyPair returns the KeyPair of the seed.
+func (s SeedBytes) KeyPair() (KeyPair, error) {
+	return FromSeed(s)
+}
+
+// checkPublicKeyArg returns ErrExpectedPublicKeyGotSeed if public is a
+// valid seed. Other problems are left to the caller to report.
+func checkPublicKeyArg(public string) error {
+	if len(public) > 0 && public[0] == 'S' {
+		if _, _, err := DecodeSeed([]byte(public)); err == nil {
+			return ErrExpectedPublicKeyGotSeed
+		}
+	}
+	return nil
+}
+
+// checkSeedArg returns ErrExpectedSeedGotPublicKey if seed is a valid
+// public key.
+func checkSeedArg(seed []byte) error {
+	if len(seed) > 0 && seed[0] != 'S' && IsValidPublicKey(string(seed)) {
+		return ErrExpectedSeedGotPublicKey
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Cooperative seed generation protects keys of devices with poor entropy:
+//
+//  1. The device calls NewCooperativeSeed, which reads its own entropy and
+//     keeps it in memory.
+//  2. The provisioning server calls ContributeEntropy and sends the result
+//     to the device.
+//  3. The device calls Combine, which derives the seed from both parts.
+//
+// The server never sees the device entropy and nothing derived from it, so
+// the seed is at least as hard to guess for the server as the device
+// entropy, and at least as hard for anyone else as the server entropy. The
+// contribution must still travel over a confidential channel such as TLS:
+// whoever learns it is left with only the device entropy to guess. When the
+// server signs its contribution, Combine can check that it came from the
+// expected server.
+
+// CooperativeSeedVersionV1 prefixes entropy contributions.
+const CooperativeSeedVersionV1 = "nkc1"
+
+const cooperativeSeedSalt = "nkeys-cooperative-seed-v1"
+
+// CooperativeSeed is the device side of cooperative seed generation.
+type CooperativeSeed struct {
+	prefix PrefixByte
+
+	mu     sync.Mutex
+	device []byte
+}
+
+// NewCooperativeSeed reads the device entropy for a seed of the given type
+// from rr, which defaults to crypto/rand.
+func NewCooperativeSeed(prefix PrefixByte, rr io.Reader) (*CooperativeSeed, error) {
+	if _, err := EncodeSeed(prefix, make([]byte, seedLen)); err != nil {
+		return nil, err
+	}
+	if rr == nil {
+		rr = rand.Reader
+	}
+	device := make([]byte, seedLen)
+	if _, err := io.ReadFull(rr, device); err != nil {
+		return nil, err
+	}
+	return &CooperativeSeed{prefix: prefix, device: device}, nil
+}
+
+// ContributeEntropy returns the server contribution to a cooperative seed.
+// If server is not nil the contribution is signed with it.
+func ContributeEntropy(server KeyPair) (string, error) {
+	entropy := make([]byte, seedLen)
+	if _, err := io.ReadFull(rand.Reader, entropy); err != nil {
+		return "", err
+	}
+	defer wipeBytes(entropy)
+	var public string
+	var sig []byte
+	if server != nil {
+		var err error
+		if public, err = server.PublicKey(); err != nil {
+			return "", err
+		}
+		if sig, err = server.Sign(contributionSignedBytes(public, entropy)); err != nil {
+			return "", err
+		}
+	}
+	e := canonical.NewEncoder("nkeys.EntropyContribution")
+	e.Blob(entropy)
+	e.Text(public)
+	e.Blob(sig)
+	data := e.Bytes()
+	defer wipeBytes(data)
+	return CooperativeSeedVersionV1 + "." + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func contributionSignedBytes(public string, entropy []byte) []byte {
+	e := canonical.NewEncoder("nkeys.EntropyContribution.Signed")
+	e.Text(public)
+	e.Blob(entropy)
+	return e.Bytes()
+}
+
+// Combine derives the KeyPair from the device entropy and the contribution
+// made by ContributeEntropy. If server is not empty the contribution must
+// be signed by that public key. The device entropy is wiped after a
+// successful Combine, which can not be called again.
+func (c *CooperativeSeed) Combine(contribution string, server string) (KeyPair, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.device == nil {
+		return nil, ErrInvalidSeedContribution
+	}
+	if !strings.HasPrefix(contribution, CooperativeSeedVersionV1+".") {
+		return nil, ErrInvalidSeedContribution
+	}
+	data, err := base64.RawURLEncoding.DecodeString(contribution[len(CooperativeSeedVersionV1)+1:])
+	if err != nil {
+		return nil, ErrInvalidSeedContribution
+	}
+	defer wipeBytes(data)
+	d := canonical.NewDecoder("nkeys.EntropyContribution", data)
+	entropy, public, sig := d.Blob(), d.Text(), d.Blob()
+	if d.Finish() != nil || len(entropy) != seedLen {
+		return nil, ErrInvalidSeedContribution
+	}
+	if server != "" {
+		if public != server {
+			return nil, ErrInvalidSeedContribution
+		}
+		if err := VerifyWithPolicy(nil, public, contributionSignedBytes(public, entropy), sig); err != nil {
+			return nil, err
+		}
+	}
+
+	ikm := append(append([]byte{}, c.device...), entropy...)
+	defer wipeBytes(ikm)
+	raw := make([]byte, seedLen)
+	defer wipeBytes(raw)
+	kdf := hkdf.New(sha256.New, ikm, []byte(cooperativeSeedSalt), []byte{byte(c.prefix)})
+	if _, err := io.ReadFull(kdf, raw); err != nil {
+		return nil, err
+	}
+	seed, err := EncodeSeed(c.prefix, raw)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(seed)
+	kp, err := FromSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	wipeBytes(c.device)
+	c.device = nil
+	return kp, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+// maxAmbiguousChars bounds the number of characters with more than one
+// possible correction, as every combination has to be tried.
+const maxAmbiguousChars = 10
+
+// confusables maps characters outside of the base32 alphabet to the
+// characters they are commonly mistaken for.
+var confusables = map[byte][]byte{
+	'0': {'O'},
+	'8': {'B'},
+	'1': {'I', 'L'},
+}
+
+// Correction records a character replaced by CorrectKey.
+type Correction struct {
+	// Offset is the position in the normalized key.
+	Offset int
+	From   byte
+	To     byte
+}
+
+// CorrectKey is like NormalizeKey but also replaces commonly confused
+// characters (0 for O, 1 for I or L, 8 for B) when exactly one replacement
+// yields a valid checksum. The corrections made are returned so they can be
+// reported to the user. ErrAmbiguousCorrection is returned if more than one
+// replacement is valid.
+func CorrectKey(s string) (string, []Correction, error) {
+	key := normalizeKeyText(s)
+	_, err := decode([]byte(key))
+	if err == nil {
+		return key, nil, nil
+	}
+
+	var (
+		buf       = []byte(key)
+		positions []int
+		ambiguous int
+	)
+	for i, c := range buf {
+		if alts, ok := confusables[c]; ok {
+			positions = append(positions, i)
+			if len(alts) > 1 {
+				ambiguous++
+			}
+		}
+	}
+	if len(positions) == 0 || ambiguous > maxAmbiguousChars {
+		return "", nil, err
+	}
+
+	var found []byte
+	choice := make([]int, len(positions))
+	for {
+		for i, p := range positions {
+			buf[p] = confusables[key[p]][choice[i]]
+		}
+		if _, derr := decode(buf); derr == nil {
+			if found != nil {
+				return "", nil, ErrAmbiguousCorrection
+			}
+			found = append([]byte(nil), buf...)
+		}
+		if !nextChoice(choice, positions, key) {
+			break
+		}
+	}
+	if found == nil {
+		return "", nil, err
+	}
+	corrections := make([]Correction, 0, len(positions))
+	for _, p := range positions {
+		corrections = append(corrections, Correction{Offset: p, From: key[p], To: found[p]})
+	}
+	return string(found), corrections, nil
+}
+
+// nextChoice advances choice like an odometer and reports false once every
+// combination has been visited.
+func nextChoice(choice, positions []int, key string) bool {
+	for i := range choice {
+		choice[i]++
+		if choice[i] < len(confusables[key[positions[i]]]) {
+			return true
+		}
+		choice[i] = 0
+	}
+	return false
+}
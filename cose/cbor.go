@@ -0,0 +1,180 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cose
+
+import "encoding/binary"
+
+// CBOR major types used by COSE_Sign1.
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+const (
+	cborNull = 0xf6
+	// maxDepth bounds nesting in skipped header values.
+	maxDepth = 16
+)
+
+// appendHead appends the shortest head for major type m and argument n, as
+// required by deterministic encoding.
+func appendHead(b []byte, m byte, n uint64) []byte {
+	m <<= 5
+	switch {
+	case n < 24:
+		return append(b, m|byte(n))
+	case n <= 0xff:
+		return append(b, m|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, m|25), uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(b, m|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, m|27), n)
+}
+
+func appendInt(b []byte, n int64) []byte {
+	if n < 0 {
+		return appendHead(b, majorNegInt, uint64(-1-n))
+	}
+	return appendHead(b, majorUint, uint64(n))
+}
+
+func appendBytes(b []byte, data []byte) []byte {
+	return append(appendHead(b, majorBytes, uint64(len(data))), data...)
+}
+
+func appendText(b []byte, s string) []byte {
+	return append(appendHead(b, majorText, uint64(len(s))), s...)
+}
+
+// reader decodes the definite length subset of CBOR. Any error sets err and
+// makes later reads return zero values.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) fail() {
+	if r.err == nil {
+		r.err = ErrInvalidMessage
+	}
+	r.data = nil
+}
+
+func (r *reader) peekMajor() byte {
+	if r.err != nil || len(r.data) == 0 {
+		return 0xff
+	}
+	return r.data[0] >> 5
+}
+
+// head reads an item head and returns its major type and argument.
+func (r *reader) head() (byte, uint64) {
+	if r.err != nil || len(r.data) == 0 {
+		r.fail()
+		return 0, 0
+	}
+	m, info := r.data[0]>>5, r.data[0]&0x1f
+	r.data = r.data[1:]
+	if info < 24 {
+		return m, uint64(info)
+	}
+	if info > 27 {
+		// Reserved values and indefinite lengths.
+		r.fail()
+		return 0, 0
+	}
+	n := 1 << (info - 24)
+	if len(r.data) < n {
+		r.fail()
+		return 0, 0
+	}
+	var v uint64
+	for _, c := range r.data[:n] {
+		v = v<<8 | uint64(c)
+	}
+	r.data = r.data[n:]
+	return m, v
+}
+
+func (r *reader) expect(major byte) uint64 {
+	m, n := r.head()
+	if m != major {
+		r.fail()
+		return 0
+	}
+	return n
+}
+
+func (r *reader) bytes(major byte) []byte {
+	n := r.expect(major)
+	if r.err != nil || uint64(len(r.data)) < n {
+		r.fail()
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+// int reads an integer of major type 0 or 1.
+func (r *reader) int() (int64, bool) {
+	m, n := r.head()
+	if r.err != nil || n > 1<<63-1 {
+		return 0, false
+	}
+	switch m {
+	case majorUint:
+		return int64(n), true
+	case majorNegInt:
+		return -1 - int64(n), true
+	}
+	return 0, false
+}
+
+// skip reads and discards one item.
+func (r *reader) skip(depth int) {
+	if depth > maxDepth {
+		r.fail()
+		return
+	}
+	m, n := r.head()
+	if r.err != nil {
+		return
+	}
+	switch m {
+	case majorBytes, majorText:
+		if uint64(len(r.data)) < n {
+			r.fail()
+			return
+		}
+		r.data = r.data[n:]
+	case majorArray, majorMap:
+		if m == majorMap {
+			n *= 2
+		}
+		for i := uint64(0); i < n && r.err == nil; i++ {
+			r.skip(depth + 1)
+		}
+	case majorTag:
+		r.skip(depth + 1)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cose signs and verifies COSE_Sign1 messages (RFC 9052) with
+// nkeys, so that nkey identities can be used with CWT and other COSE based
+// protocols. Messages use the EdDSA algorithm and carry the encoded public
+// key of the signer as the key identifier in the protected header.
+package cose
+
+import (
+	"github.com/nats-io/nkeys"
+)
+
+// Errors
+const (
+	ErrInvalidMessage       = coseError("cose: invalid COSE_Sign1 message")
+	ErrUnsupportedAlgorithm = coseError("cose: unsupported algorithm")
+	ErrMissingKeyID         = coseError("cose: missing or invalid key identifier")
+	ErrDetachedPayload      = coseError("cose: payload is detached")
+)
+
+type coseError string
+
+func (e coseError) Error() string {
+	return string(e)
+}
+
+// Header labels and values from the IANA COSE registries.
+const (
+	HeaderAlgorithm = 1
+	HeaderKeyID     = 4
+	AlgorithmEdDSA  = -8
+	// TagSign1 is the CBOR tag of COSE_Sign1 messages.
+	TagSign1 = 18
+)
+
+const sign1Context = "Signature1"
+
+// Message is a verified COSE_Sign1 message.
+type Message struct {
+	// KeyID is the public key of the signer.
+	KeyID   string
+	Payload []byte
+	// Protected is the serialized protected header as signed.
+	Protected []byte
+}
+
+// Sign1 returns a tagged COSE_Sign1 message over payload signed by kp.
+// externalAAD is authenticated but not included in the message, and may be
+// nil.
+func Sign1(kp nkeys.KeyPair, payload []byte, externalAAD []byte) ([]byte, error) {
+	protected, sig, err := sign(kp, payload, externalAAD)
+	if err != nil {
+		return nil, err
+	}
+	b := appendHead(nil, majorTag, TagSign1)
+	b = appendHead(b, majorArray, 4)
+	b = appendBytes(b, protected)
+	b = appendHead(b, majorMap, 0)
+	b = appendBytes(b, payload)
+	return appendBytes(b, sig), nil
+}
+
+// Sign1Detached is like Sign1 but leaves the payload out of the message.
+// The verifier must supply it to VerifyDetached.
+func Sign1Detached(kp nkeys.KeyPair, payload []byte, externalAAD []byte) ([]byte, error) {
+	protected, sig, err := sign(kp, payload, externalAAD)
+	if err != nil {
+		return nil, err
+	}
+	b := appendHead(nil, majorTag, TagSign1)
+	b = appendHead(b, majorArray, 4)
+	b = appendBytes(b, protected)
+	b = appendHead(b, majorMap, 0)
+	b = append(b, cborNull)
+	return appendBytes(b, sig), nil
+}
+
+func sign(kp nkeys.KeyPair, payload []byte, externalAAD []byte) ([]byte, []byte, error) {
+	public, err := kp.PublicKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	if nkeys.AlgorithmOf(nkeys.Prefix(public)) != nkeys.AlgorithmEd25519 {
+		return nil, nil, ErrUnsupportedAlgorithm
+	}
+	protected := appendHead(nil, majorMap, 2)
+	protected = appendInt(protected, HeaderAlgorithm)
+	protected = appendInt(protected, AlgorithmEdDSA)
+	protected = appendInt(protected, HeaderKeyID)
+	protected = appendBytes(protected, []byte(public))
+	sig, err := kp.Sign(sigStructure(protected, externalAAD, payload))
+	if err != nil {
+		return nil, nil, err
+	}
+	return protected, sig, nil
+}
+
+// Verify checks a COSE_Sign1 message with an attached payload, tagged or
+// not, against the key named by its key identifier. vp may be nil.
+func Verify(msg []byte, externalAAD []byte, vp *nkeys.VerifyPolicy) (*Message, error) {
+	return verify(msg, nil, false, externalAAD, vp)
+}
+
+// VerifyDetached checks a COSE_Sign1 message made by Sign1Detached over
+// payload.
+func VerifyDetached(msg []byte, payload []byte, externalAAD []byte, vp *nkeys.VerifyPolicy) (*Message, error) {
+	return verify(msg, payload, true, externalAAD, vp)
+}
+
+func verify(msg []byte, detached []byte, isDetached bool, externalAAD []byte, vp *nkeys.VerifyPolicy) (*Message, error) {
+	r := &reader{data: msg}
+	if r.peekMajor() == majorTag && r.expect(majorTag) != TagSign1 {
+		return nil, ErrInvalidMessage
+	}
+	if r.expect(majorArray) != 4 {
+		return nil, ErrInvalidMessage
+	}
+	protected := r.bytes(majorBytes)
+	ph := &reader{data: protected}
+	alg, kid := readHeader(ph)
+	if ph.err != nil || len(ph.data) != 0 {
+		return nil, ErrInvalidMessage
+	}
+	ualg, ukid := readHeader(r)
+	var payload []byte
+	if len(r.data) > 0 && r.data[0] == cborNull {
+		r.data = r.data[1:]
+		if !isDetached {
+			return nil, ErrDetachedPayload
+		}
+		payload = detached
+	} else {
+		if isDetached {
+			return nil, ErrInvalidMessage
+		}
+		payload = r.bytes(majorBytes)
+	}
+	sig := r.bytes(majorBytes)
+	if r.err != nil || len(r.data) != 0 {
+		return nil, ErrInvalidMessage
+	}
+	// The algorithm must be protected; the key identifier may be in either
+	// header since a wrong one only makes verification fail.
+	if ualg != nil || alg == nil || *alg != AlgorithmEdDSA {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if kid == "" {
+		kid = ukid
+	}
+	if nkeys.AlgorithmOf(nkeys.Prefix(kid)) != nkeys.AlgorithmEd25519 {
+		return nil, ErrMissingKeyID
+	}
+	if err := nkeys.VerifyWithPolicy(vp, kid, sigStructure(protected, externalAAD, payload), sig); err != nil {
+		return nil, err
+	}
+	return &Message{KeyID: kid, Payload: payload, Protected: protected}, nil
+}
+
+// readHeader reads a header map and returns its algorithm and key
+// identifier, skipping other labels.
+func readHeader(r *reader) (*int64, string) {
+	var alg *int64
+	var kid string
+	n := r.expect(majorMap)
+	for i := uint64(0); i < n && r.err == nil; i++ {
+		if m := r.peekMajor(); m != majorUint && m != majorNegInt {
+			r.skip(0)
+			r.skip(0)
+			continue
+		}
+		label, _ := r.int()
+		switch label {
+		case HeaderAlgorithm:
+			v, ok := r.int()
+			if !ok {
+				r.fail()
+			}
+			alg = &v
+		case HeaderKeyID:
+			kid = string(r.bytes(majorBytes))
+		default:
+			r.skip(0)
+		}
+	}
+	return alg, kid
+}
+
+// sigStructure returns the Sig_structure that is signed for COSE_Sign1.
+func sigStructure(protected, externalAAD, payload []byte) []byte {
+	b := appendHead(nil, majorArray, 4)
+	b = appendText(b, sign1Context)
+	b = appendBytes(b, protected)
+	b = appendBytes(b, externalAAD)
+	return appendBytes(b, payload)
+}
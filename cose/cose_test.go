@@ -0,0 +1,91 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cose
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/nats-io/nkeys"
+)
+
+func TestSign1(t *testing.T) {
+	user, _ := nkeys.CreateUser()
+	public, _ := user.PublicKey()
+	msg, err := Sign1(user, []byte("hello"), []byte("aad"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Tag 18 followed by a four element array.
+	if !bytes.HasPrefix(msg, []byte{0xd2, 0x84}) {
+		t.Fatalf("Expected a tagged COSE_Sign1, got %x", msg[:2])
+	}
+	m, err := Verify(msg, []byte("aad"), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if m.KeyID != public || string(m.Payload) != "hello" {
+		t.Fatalf("Expected %v and hello, got %v and %s", public, m.KeyID, m.Payload)
+	}
+
+	// The signature is a plain EdDSA signature over the Sig_structure.
+	raw, _ := nkeys.Decode(nkeys.PrefixByteUser, []byte(public))
+	sig := msg[len(msg)-ed25519.SignatureSize:]
+	if !ed25519.Verify(raw, sigStructure(m.Protected, []byte("aad"), []byte("hello")), sig) {
+		t.Fatalf("Expected the signature to verify with crypto/ed25519")
+	}
+
+	if _, err := Verify(msg[1:], []byte("aad"), nil); err != nil {
+		t.Fatalf("Expected untagged messages to verify, got %v", err)
+	}
+	if _, err := Verify(msg, []byte("other"), nil); err != nkeys.ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", nkeys.ErrInvalidSignature, err)
+	}
+	tampered := append([]byte{}, msg...)
+	tampered[len(tampered)-ed25519.SignatureSize-3] ^= 1
+	if _, err := Verify(tampered, []byte("aad"), nil); err != nkeys.ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", nkeys.ErrInvalidSignature, err)
+	}
+	if _, err := Verify(msg[:len(msg)-1], []byte("aad"), nil); err != ErrInvalidMessage {
+		t.Fatalf("Expected %v, got %v", ErrInvalidMessage, err)
+	}
+
+	vp := &nkeys.VerifyPolicy{AllowedTypes: []nkeys.PrefixByte{nkeys.PrefixByteAccount}}
+	if _, err := Verify(msg, []byte("aad"), vp); err != nkeys.ErrKeyTypeNotAllowed {
+		t.Fatalf("Expected %v, got %v", nkeys.ErrKeyTypeNotAllowed, err)
+	}
+
+	curve, _ := nkeys.CreateCurveKeys()
+	if _, err := Sign1(curve, []byte("hello"), nil); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("Expected %v, got %v", ErrUnsupportedAlgorithm, err)
+	}
+}
+
+func TestSign1Detached(t *testing.T) {
+	user, _ := nkeys.CreateUser()
+	msg, err := Sign1Detached(user, []byte("hello"), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := Verify(msg, nil, nil); err != ErrDetachedPayload {
+		t.Fatalf("Expected %v, got %v", ErrDetachedPayload, err)
+	}
+	if _, err := VerifyDetached(msg, []byte("hello"), nil, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := VerifyDetached(msg, []byte("other"), nil, nil); err != nkeys.ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", nkeys.ErrInvalidSignature, err)
+	}
+}
@@ -52,7 +52,11 @@ var crc16tab = [256]uint16{
 
 // crc16 returns the 2-byte crc for the data provided.
 func crc16(data []byte) uint16 {
-	var crc uint16
+	return crc16Update(0, data)
+}
+
+// crc16Update continues a crc computation over more data.
+func crc16Update(crc uint16, data []byte) uint16 {
 	for _, b := range data {
 		crc = ((crc << 8) & 0xffff) ^ crc16tab[((crc>>8)^uint16(b))&0x00FF]
 	}
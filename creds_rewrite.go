@@ -0,0 +1,106 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// credsTokenRE matches the JWTs that ParseDecoratedJWT can read back.
+var credsTokenRE = regexp.MustCompile(`^[\w\-.=]+$`)
+
+// ReplaceDecoratedCreds returns a copy of the creds file contents with the
+// JWT and the seed replaced. An empty jwt or a nil seed keeps the current
+// one. Everything outside of the two blocks, such as comments and line
+// endings, is preserved.
+func ReplaceDecoratedCreds(contents []byte, jwt string, seed []byte) ([]byte, error) {
+	items := userConfigRE.FindAllSubmatchIndex(contents, -1)
+	if len(items) < 2 {
+		return nil, ErrInvalidCredsFile
+	}
+	if jwt != "" && !credsTokenRE.MatchString(jwt) {
+		return nil, ErrInvalidCredsFile
+	}
+	if seed != nil {
+		if err := checkSeedArg(seed); err != nil {
+			return nil, err
+		}
+		pb, raw, err := DecodeSeed(seed)
+		if err != nil {
+			return nil, err
+		}
+		wipeBytes(raw)
+		if pb != PrefixByteUser && pb != PrefixByteAccount && pb != PrefixByteOperator {
+			return nil, ErrInvalidNkeySeed
+		}
+	}
+
+	jwtStart, jwtEnd := items[0][2], items[0][3]
+	seedStart, seedEnd := items[1][2], items[1][3]
+	out := make([]byte, 0, len(contents)+len(jwt)+len(seed))
+	out = append(out, contents[:jwtStart]...)
+	if jwt != "" {
+		out = append(out, jwt...)
+	} else {
+		out = append(out, contents[jwtStart:jwtEnd]...)
+	}
+	out = append(out, contents[jwtEnd:seedStart]...)
+	if seed != nil {
+		out = append(out, seed...)
+	} else {
+		out = append(out, contents[seedStart:seedEnd]...)
+	}
+	return append(out, contents[seedEnd:]...), nil
+}
+
+// RewriteCredsFile replaces the JWT and the seed of the creds file at path
+// in place, as ReplaceDecoratedCreds does, keeping its permissions. The new
+// file is renamed over the old one, so clients reading it concurrently see
+// either the old or the new credentials, never a mix. Concurrent rewrites
+// are serialized with a lock on a sibling file with the ".lock" suffix,
+// which is left in place. Symbolic links are followed, so the target is
+// replaced rather than the link.
+func RewriteCredsFile(path string, jwt string, seed []byte) error {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	defer wipeBytes(contents)
+	out, err := ReplaceDecoratedCreds(contents, jwt, seed)
+	if err != nil {
+		return err
+	}
+	defer wipeBytes(out)
+	if bytes.Equal(out, contents) {
+		return nil
+	}
+	return writeFileAtomic(path, out, fi.Mode().Perm())
+}
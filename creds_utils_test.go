@@ -2,6 +2,10 @@ package nkeys
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -79,3 +83,52 @@ func Test_ParseDecoratedSeedAndJWT(t *testing.T) {
 		}
 	}
 }
+
+func Test_RewriteCredsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "user.creds")
+	creds := strings.Replace(decoratedCreds, "*\n", "*\n# rotated weekly\r\n", 1)
+	if err := os.WriteFile(path, []byte(creds), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	user, _ := CreateUser()
+	seed, _ := user.Seed()
+	if err := RewriteCredsFile(path, "", seed); err != nil {
+		t.Fatal(err)
+	}
+	if err := RewriteCredsFile(path, "a.b.c", nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(strings.Replace(creds, credsSeed, string(seed), 1), credsJwt, "a.b.c", 1)
+	if string(data) != want {
+		t.Fatalf("Expected %q, got %q", want, data)
+	}
+	if fi, _ := os.Stat(path); runtime.GOOS != "windows" && fi.Mode().Perm() != 0640 {
+		t.Fatalf("Expected %v, got %v", os.FileMode(0640), fi.Mode().Perm())
+	}
+
+	public, _ := user.PublicKey()
+	for _, tc := range []struct {
+		jwt  string
+		seed []byte
+		err  error
+	}{
+		{"a b", nil, ErrInvalidCredsFile},
+		{"", []byte(public), ErrExpectedSeedGotPublicKey},
+		{"", []byte("SUAOTBNEUHZDFJT3"), ErrInvalidChecksum},
+	} {
+		if err := RewriteCredsFile(path, tc.jwt, tc.seed); err != tc.err {
+			t.Fatalf("Expected %v, got %v", tc.err, err)
+		}
+	}
+	if _, err := ReplaceDecoratedCreds([]byte(credsJwt), "a.b.c", nil); err != ErrInvalidCredsFile {
+		t.Fatalf("Expected %v, got %v", ErrInvalidCredsFile, err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, data) {
+		t.Fatal("Expected failed rewrites to leave the file unchanged")
+	}
+}
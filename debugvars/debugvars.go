@@ -0,0 +1,49 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package debugvars exports the nkeys activity counters for operators. It
+// is kept apart from nkeys because importing expvar registers /debug/vars
+// on http.DefaultServeMux.
+package debugvars
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+
+	"github.com/nats-io/nkeys"
+)
+
+// DefaultName is the expvar name used by Publish when name is empty.
+const DefaultName = "nkeys"
+
+// Publish exports nkeys.ActivitySnapshot as the expvar name, so that it is
+// served on /debug/vars. Like expvar.Publish it panics if name is already
+// in use, so it should be called once at start up.
+func Publish(name string) {
+	if name == "" {
+		name = DefaultName
+	}
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return nkeys.ActivitySnapshot()
+	}))
+}
+
+// Handler returns an http.Handler serving nkeys.ActivitySnapshot as JSON,
+// for servers that do not use expvar.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(nkeys.ActivitySnapshot())
+	})
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debugvars
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nats-io/nkeys"
+)
+
+func TestPublish(t *testing.T) {
+	user, _ := nkeys.CreateUser()
+	sig, _ := user.Sign([]byte("hello"))
+	user.Verify([]byte("hello"), sig)
+
+	Publish("")
+	var a nkeys.Activity
+	if err := json.Unmarshal([]byte(expvar.Get(DefaultName).String()), &a); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if a.Created["user"] == 0 || a.Signed == 0 || a.Verified == 0 {
+		t.Fatalf("Expected user creation, signing and verification to be counted, got %+v", a)
+	}
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if err := json.NewDecoder(rec.Body).Decode(&a); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rec.Header().Get("Content-Type") != "application/json" || a.Signed == 0 {
+		t.Fatalf("Expected a JSON snapshot, got %+v", a)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// DeprecationAction is what happens when a deprecated key type is used.
+type DeprecationAction uint8
+
+const (
+	// DeprecationWarn reports the use to the DeprecationHandler.
+	DeprecationWarn DeprecationAction = iota + 1
+	// DeprecationRefuse fails the operation with ErrKeyTypeDeprecated.
+	DeprecationRefuse
+)
+
+// Deprecation marks a key type as being phased out.
+type Deprecation struct {
+	Prefix PrefixByte
+	Action DeprecationAction
+	// Message is passed on in warnings, e.g. a migration deadline.
+	Message string
+}
+
+// DeprecationNotice describes a use of a deprecated key type.
+type DeprecationNotice struct {
+	Deprecation
+	// Operation is "create", "load" or "parse".
+	Operation string
+}
+
+// DeprecationHandler receives notices for key types marked with
+// DeprecationWarn. It is called synchronously and must be safe for
+// concurrent use.
+type DeprecationHandler func(DeprecationNotice)
+
+type deprecations struct {
+	byPrefix map[PrefixByte]Deprecation
+	handler  DeprecationHandler
+}
+
+var deprecationValue atomic.Value
+
+// SetDeprecations replaces the deprecated key types of the package. They
+// apply to keys created with CreatePair and the Create functions, loaded
+// with FromSeed, FromCurveSeed, FromSeedWarm and FromPrivateKey and parsed
+// with FromPublicKey; keys already in memory and the lower level encoding
+// functions are not affected. Calling it without deprecations clears them.
+func SetDeprecations(handler DeprecationHandler, deps ...Deprecation) error {
+	d := deprecations{byPrefix: make(map[PrefixByte]Deprecation), handler: handler}
+	for _, dep := range deps {
+		if checkValidPublicPrefixByte(dep.Prefix) != nil {
+			return ErrInvalidPrefixByte
+		}
+		if dep.Action != DeprecationWarn && dep.Action != DeprecationRefuse {
+			return ErrInvalidKey
+		}
+		d.byPrefix[dep.Prefix] = dep
+	}
+	deprecationValue.Store(d)
+	return nil
+}
+
+// Deprecations returns the deprecated key types, ordered by prefix.
+func Deprecations() []Deprecation {
+	d, _ := deprecationValue.Load().(deprecations)
+	var out []Deprecation
+	for _, dep := range d.byPrefix {
+		out = append(out, dep)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Prefix < out[j].Prefix })
+	return out
+}
+
+// checkDeprecated applies the deprecation of prefix, if any, to op.
+func checkDeprecated(prefix PrefixByte, op string) error {
+	d, _ := deprecationValue.Load().(deprecations)
+	dep, ok := d.byPrefix[prefix]
+	if !ok {
+		return nil
+	}
+	if dep.Action == DeprecationRefuse {
+		return ErrKeyTypeDeprecated
+	}
+	if d.handler != nil {
+		d.handler(DeprecationNotice{dep, op})
+	}
+	return nil
+}
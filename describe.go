@@ -0,0 +1,154 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// KeyForm is the form of an encoded key.
+type KeyForm string
+
+const (
+	KeyFormPublic  KeyForm = "public"
+	KeyFormSeed    KeyForm = "seed"
+	KeyFormPrivate KeyForm = "private"
+	KeyFormUnknown KeyForm = "unknown"
+)
+
+// ChecksumStatus reports whether the checksum of an encoded key matched.
+type ChecksumStatus string
+
+const (
+	ChecksumValid   ChecksumStatus = "valid"
+	ChecksumInvalid ChecksumStatus = "invalid"
+	// ChecksumUnchecked is reported when the input is not base32 at all.
+	ChecksumUnchecked ChecksumStatus = "unchecked"
+)
+
+// KeyDescription describes an encoded key for tooling. It never holds
+// secret material: for seeds and private keys only the derived public key
+// is included.
+type KeyDescription struct {
+	Form      KeyForm        `json:"form"`
+	Type      string         `json:"type"`
+	Algorithm string         `json:"algorithm"`
+	Valid     bool           `json:"valid"`
+	Checksum  ChecksumStatus `json:"checksum"`
+	// Normalized is true if the input had to be normalized, e.g. because
+	// it was quoted or in lower case, see NormalizeKey.
+	Normalized bool `json:"normalized,omitempty"`
+	// PublicKey is the public key, derived for seeds. Private keys do not
+	// encode their type, so only their fingerprint is known.
+	PublicKey   string `json:"public_key,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// DescribeKey reports what key is: its form, type, algorithm, whether it
+// decodes and its checksum matches, and the public key and fingerprint it
+// stands for. It does not fail; problems are reported in the description.
+func DescribeKey(key string) *KeyDescription {
+	d := &KeyDescription{
+		Form:      KeyFormUnknown,
+		Type:      PrefixByteUnknown.String(),
+		Algorithm: AlgorithmUnknown.String(),
+		Checksum:  ChecksumUnchecked,
+	}
+	norm := normalizeKeyText(key)
+	d.Normalized = norm != key
+	raw, err := decode([]byte(norm))
+	switch err {
+	case nil:
+		d.Checksum = ChecksumValid
+	case ErrInvalidChecksum:
+		d.Checksum = ChecksumInvalid
+	}
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	defer wipeBytes(raw)
+
+	var public []byte
+	prefix := PrefixByte(raw[0])
+	switch {
+	case checkValidPublicPrefixByte(prefix) == nil:
+		d.Form, d.Type = KeyFormPublic, prefix.String()
+		d.Algorithm = AlgorithmOf(prefix).String()
+		public = raw[1:]
+		if AlgorithmOf(prefix) == AlgorithmEd25519 && len(public) != ed25519.PublicKeySize ||
+			prefix == PrefixByteCurve && len(public) != curveKeyLen {
+			d.Error = ErrInvalidPublicKey.Error()
+			return d
+		}
+		d.PublicKey = norm
+	case prefix == PrefixBytePrivate:
+		d.Form = KeyFormPrivate
+		priv := raw[1:]
+		switch len(priv) {
+		case ed25519.PrivateKeySize:
+			d.Algorithm = AlgorithmEd25519.String()
+			public = priv[ed25519.SeedSize:]
+		case curveKeyLen:
+			d.Algorithm = AlgorithmX25519.String()
+			pub, err := curve25519.X25519(priv, curve25519.Basepoint)
+			if err != nil {
+				d.Error = err.Error()
+				return d
+			}
+			public = pub
+		default:
+			d.Error = ErrInvalidPrivateKey.Error()
+			return d
+		}
+	case PrefixByte(raw[0]&248) == PrefixByteSeed:
+		d.Form = KeyFormSeed
+		pb, rawSeed, err := DecodeSeed([]byte(norm))
+		if err != nil {
+			d.Error = err.Error()
+			return d
+		}
+		defer wipeBytes(rawSeed)
+		d.Type, d.Algorithm = pb.String(), AlgorithmOf(pb).String()
+		var kp KeyPair
+		if pb == PrefixByteCurve {
+			kp, err = FromCurveSeed([]byte(norm))
+		} else {
+			kp, err = FromSeed([]byte(norm))
+		}
+		if err != nil {
+			d.Error = err.Error()
+			return d
+		}
+		defer kp.Wipe()
+		if d.PublicKey, err = kp.PublicKey(); err != nil {
+			d.Error = err.Error()
+			return d
+		}
+		pub, _ := decode([]byte(d.PublicKey))
+		public = pub[1:]
+	default:
+		d.Error = ErrInvalidPrefixByte.Error()
+		return d
+	}
+	sum := sha256.Sum256(public)
+	d.Fingerprint = "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+	d.Valid = true
+	return d
+}
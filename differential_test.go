@@ -0,0 +1,138 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+// The differential tests compare this package against the upstream
+// nats-io/nkeys implementation. Both share a module path, so upstream is
+// driven through its nk tool instead of being linked in. Build it with
+//
+//	GOBIN=/tmp go install github.com/nats-io/nkeys/nk@latest
+//
+// and point NKEYS_UPSTREAM_NK at the binary. Without it the tests are skipped.
+
+import (
+	"bytes"
+	"encoding/base64"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const upstreamEnv = "NKEYS_UPSTREAM_NK"
+
+func upstreamNK(t testing.TB) string {
+	path := os.Getenv(upstreamEnv)
+	if path == "" {
+		t.Skipf("%s not set, skipping differential test", upstreamEnv)
+	}
+	return path
+}
+
+// runUpstream runs the upstream nk tool and returns the lines it logged.
+func runUpstream(t testing.TB, nk string, args ...string) []string {
+	out, err := exec.Command(nk, args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Expected upstream nk %v to succeed, got %v: %s", args, err, out)
+	}
+	return strings.Fields(string(out))
+}
+
+// diffGenerate checks that both implementations derive the same seed and
+// public key from the same entropy.
+func diffGenerate(t testing.TB, nk string, dir string, prefix PrefixByte, typ string, entropy []byte) {
+	ef := filepath.Join(dir, "entropy")
+	if err := os.WriteFile(ef, entropy, 0600); err != nil {
+		t.Fatal(err)
+	}
+	kp, err := CreatePairWithRand(prefix, bytes.NewReader(entropy))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	seed, _ := kp.Seed()
+	pk, _ := kp.PublicKey()
+	got := runUpstream(t, nk, "-gen", typ, "-e", ef, "-pubout")
+	if len(got) != 2 || got[0] != string(seed) || got[1] != pk {
+		t.Fatalf("Expected upstream to produce %s %s, got %v", seed, pk, got)
+	}
+}
+
+// diffSign checks that both implementations produce the same signature.
+func diffSign(t testing.TB, nk string, dir string, seed []byte, msg []byte) {
+	kf := filepath.Join(dir, "seed.nk")
+	mf := filepath.Join(dir, "msg")
+	if err := os.WriteFile(kf, seed, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mf, msg, 0600); err != nil {
+		t.Fatal(err)
+	}
+	kp, err := FromSeed(seed)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sig, _ := kp.Sign(msg)
+	want := base64.RawURLEncoding.EncodeToString(sig)
+	got := runUpstream(t, nk, "-inkey", kf, "-sign", mf)
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("Expected upstream signature %s, got %v", want, got)
+	}
+}
+
+func TestDifferentialUpstream(t *testing.T) {
+	nk := upstreamNK(t)
+	dir := t.TempDir()
+	r := rand.New(rand.NewSource(1))
+	types := []struct {
+		prefix PrefixByte
+		name   string
+	}{
+		{PrefixByteOperator, "operator"},
+		{PrefixByteAccount, "account"},
+		{PrefixByteUser, "user"},
+		{PrefixByteServer, "server"},
+		{PrefixByteCluster, "cluster"},
+	}
+	for i := 0; i < 20; i++ {
+		typ := types[i%len(types)]
+		entropy := make([]byte, seedLen)
+		r.Read(entropy)
+		diffGenerate(t, nk, dir, typ.prefix, typ.name, entropy)
+
+		kp, _ := CreatePairWithRand(typ.prefix, bytes.NewReader(entropy))
+		seed, _ := kp.Seed()
+		msg := make([]byte, r.Intn(4096))
+		r.Read(msg)
+		diffSign(t, nk, dir, seed, msg)
+	}
+}
+
+func FuzzDifferentialUpstream(f *testing.F) {
+	f.Add(bytes.Repeat([]byte{1}, seedLen), []byte("hello"))
+	f.Add(make([]byte, seedLen), []byte{})
+	f.Fuzz(func(t *testing.T, entropy []byte, msg []byte) {
+		nk := upstreamNK(t)
+		if len(entropy) < seedLen {
+			return
+		}
+		entropy = entropy[:seedLen]
+		dir := t.TempDir()
+		diffGenerate(t, nk, dir, PrefixByteUser, "user", entropy)
+		kp, _ := CreatePairWithRand(PrefixByteUser, bytes.NewReader(entropy))
+		seed, _ := kp.Seed()
+		diffSign(t, nk, dir, seed, msg)
+	})
+}
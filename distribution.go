@@ -0,0 +1,132 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/ed25519"
+	"math"
+	"sort"
+)
+
+// MinDistributionSample is the number of distinct valid keys below which
+// AuditKeyDistribution skips its statistical tests.
+const MinDistributionSample = 256
+
+// DistributionThreshold is the z-score above which a statistical test of
+// AuditKeyDistribution reports an anomaly. It is high enough that a
+// healthy corpus trips none of the 257 tests in practice.
+const DistributionThreshold = 6.0
+
+// curveHighBit is the bit position of the most significant bit of an
+// X25519 public key, which is little endian and less than 2^255-19, so the
+// bit is always clear.
+const curveHighBit = 8*31 + 0
+
+// DistributionReport is the result of AuditKeyDistribution.
+type DistributionReport struct {
+	Keys    int `json:"keys"`
+	Invalid int `json:"invalid"`
+	// Duplicates lists the keys that occur more than once.
+	Duplicates []string `json:"duplicates,omitempty"`
+	// Tested is false if there were too few keys for the statistical tests.
+	Tested bool `json:"tested"`
+	// BiasedBits lists the bit positions of the raw public keys whose
+	// frequency of ones deviates by more than DistributionThreshold.
+	BiasedBits []int `json:"biased_bits,omitempty"`
+	// MaxBitZ is the largest absolute z-score of the bit frequency tests.
+	MaxBitZ float64 `json:"max_bit_z"`
+	// PrefixZ is the z-score of a chi-square test of the leading 4 bits of
+	// the raw public keys, which decide the characters following the type.
+	PrefixZ float64 `json:"prefix_z"`
+}
+
+// OK reports whether no anomaly was found.
+func (r *DistributionReport) OK() bool {
+	return len(r.Duplicates) == 0 && len(r.BiasedBits) == 0 && r.PrefixZ <= DistributionThreshold
+}
+
+// AuditKeyDistribution looks for signs of broken entropy in a corpus of
+// public keys generated independently, such as keys collected from a fleet
+// of devices: repeated keys, bits of the raw keys that are biased, and
+// biased leading characters. It can only detect gross failures; a corpus
+// that passes is not proof that the keys were generated securely. Invalid
+// keys are counted and otherwise ignored. The always clear high bit of
+// curve keys is left out of the bit frequency tests.
+func AuditKeyDistribution(keys []string) *DistributionReport {
+	r := &DistributionReport{Keys: len(keys)}
+	seen := make(map[string]int, len(keys))
+	var raws [][]byte
+	var curves []bool
+	for _, k := range keys {
+		raw, err := decode([]byte(k))
+		if err != nil || checkValidPublicPrefixByte(PrefixByte(raw[0])) != nil || len(raw) != 1+ed25519.PublicKeySize {
+			r.Invalid++
+			continue
+		}
+		seen[k]++
+		if seen[k] == 2 {
+			r.Duplicates = append(r.Duplicates, k)
+		}
+		if seen[k] == 1 {
+			raws = append(raws, raw[1:])
+			curves = append(curves, PrefixByte(raw[0]) == PrefixByteCurve)
+		}
+	}
+	sort.Strings(r.Duplicates)
+	if len(raws) < MinDistributionSample {
+		return r
+	}
+	r.Tested = true
+
+	n := float64(len(raws))
+	bits := 8 * ed25519.PublicKeySize
+	ones := make([]int, bits)
+	counted := make([]int, bits)
+	var nibbles [16]int
+	for j, raw := range raws {
+		for i := 0; i < bits; i++ {
+			if curves[j] && i == curveHighBit {
+				continue
+			}
+			ones[i] += int(raw[i/8]>>(7-i%8)) & 1
+			counted[i]++
+		}
+		nibbles[raw[0]>>4]++
+	}
+	for i := 0; i < bits; i++ {
+		if counted[i] < MinDistributionSample {
+			continue
+		}
+		m := float64(counted[i])
+		z := math.Abs(float64(ones[i])-m/2) / math.Sqrt(m/4)
+		r.MaxBitZ = math.Max(r.MaxBitZ, z)
+		if z > DistributionThreshold {
+			r.BiasedBits = append(r.BiasedBits, i)
+		}
+	}
+	var chi2 float64
+	for _, c := range nibbles {
+		d := float64(c) - n/16
+		chi2 += d * d / (n / 16)
+	}
+	r.PrefixZ = chiSquareZ(chi2, 15)
+	return r
+}
+
+// chiSquareZ converts a chi-square statistic with k degrees of freedom to
+// an approximate standard normal z-score (Wilson-Hilferty).
+func chiSquareZ(x float64, k float64) float64 {
+	v := 2 / (9 * k)
+	return (math.Cbrt(x/k) - (1 - v)) / math.Sqrt(v)
+}
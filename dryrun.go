@@ -0,0 +1,127 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"io"
+	"sync"
+	"time"
+)
+
+// DryRunRecord describes a signature that a DryRunKeyPair was asked for.
+type DryRunRecord struct {
+	PublicKey string    `json:"public_key"`
+	Time      time.Time `json:"time"`
+	// Digest is the SHA-256 of the input that would have been signed.
+	Digest []byte `json:"digest"`
+	Size   int    `json:"size"`
+}
+
+// DryRunKeyPair is a KeyPair that records the inputs it is asked to sign
+// instead of signing them, for testing pipelines and for previews in
+// administrative tools. Sign returns a signature of the usual length made
+// of zeroes, which never verifies. Since no real signature is produced the
+// wrapped KeyPair may be public only. All other methods are passed through.
+type DryRunKeyPair struct {
+	kp     KeyPair
+	public string
+	clock  Clock
+
+	mu      sync.Mutex
+	records []DryRunRecord
+}
+
+// DryRun returns a KeyPair that records signing requests for kp. The clock
+// stamps the records and defaults to SystemClock.
+func DryRun(kp KeyPair, clock Clock) (*DryRunKeyPair, error) {
+	public, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return &DryRunKeyPair{kp: kp, public: public, clock: ClockOrSystem(clock)}, nil
+}
+
+// Records returns the signing requests recorded so far, oldest first.
+func (d *DryRunKeyPair) Records() []DryRunRecord {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]DryRunRecord(nil), d.records...)
+}
+
+// Reset discards the recorded signing requests.
+func (d *DryRunKeyPair) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.records = nil
+}
+
+// Seed will return the encoded seed.
+func (d *DryRunKeyPair) Seed() ([]byte, error) {
+	return d.kp.Seed()
+}
+
+// PublicKey will return the encoded public key.
+func (d *DryRunKeyPair) PublicKey() (string, error) {
+	return d.public, nil
+}
+
+// PrivateKey will return the encoded private key.
+func (d *DryRunKeyPair) PrivateKey() ([]byte, error) {
+	return d.kp.PrivateKey()
+}
+
+// Sign records the input and returns a placeholder signature.
+func (d *DryRunKeyPair) Sign(input []byte) ([]byte, error) {
+	if Prefix(d.public) == PrefixByteCurve {
+		return nil, ErrInvalidCurveKeyOperation
+	}
+	sum := sha256.Sum256(input)
+	r := DryRunRecord{PublicKey: d.public, Time: d.clock.Now(), Digest: sum[:], Size: len(input)}
+	d.mu.Lock()
+	d.records = append(d.records, r)
+	d.mu.Unlock()
+	return make([]byte, ed25519.SignatureSize), nil
+}
+
+// Verify will verify the input against a signature.
+func (d *DryRunKeyPair) Verify(input []byte, sig []byte) error {
+	return d.kp.Verify(input, sig)
+}
+
+// PublicOnly returns a public only copy of the wrapped KeyPair.
+func (d *DryRunKeyPair) PublicOnly() (KeyPair, error) {
+	return d.kp.PublicOnly()
+}
+
+// Wipe will wipe the wrapped KeyPair.
+func (d *DryRunKeyPair) Wipe() {
+	d.kp.Wipe()
+}
+
+// Seal will seal the input.
+func (d *DryRunKeyPair) Seal(input []byte, recipient string) ([]byte, error) {
+	return d.kp.Seal(input, recipient)
+}
+
+// SealWithRand will seal the input.
+func (d *DryRunKeyPair) SealWithRand(input []byte, recipient string, rr io.Reader) ([]byte, error) {
+	return d.kp.SealWithRand(input, recipient, rr)
+}
+
+// Open will open the input.
+func (d *DryRunKeyPair) Open(input []byte, sender string) ([]byte, error) {
+	return d.kp.Open(input, sender)
+}
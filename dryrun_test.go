@@ -0,0 +1,54 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+	"time"
+)
+
+func TestDryRun(t *testing.T) {
+	user, _ := CreateUser()
+	pub, _ := user.PublicOnly()
+	now := time.Unix(1700000000, 0)
+	kp, err := DryRun(pub, ClockFunc(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sig, err := kp.Sign([]byte("payload"))
+	if err != nil {
+		t.Fatalf("Unexpected error signing: %v", err)
+	}
+	if err := user.Verify([]byte("payload"), sig); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	recs := kp.Records()
+	sum := sha256.Sum256([]byte("payload"))
+	if len(recs) != 1 || !bytes.Equal(recs[0].Digest, sum[:]) || recs[0].Size != 7 || !recs[0].Time.Equal(now) {
+		t.Fatalf("Unexpected records: %+v", recs)
+	}
+	kp.Reset()
+	if len(kp.Records()) != 0 {
+		t.Fatal("Expected no records after Reset")
+	}
+
+	curve, _ := CreateCurveKeys()
+	ckp, _ := DryRun(curve, nil)
+	if _, err := ckp.Sign([]byte("payload")); err != ErrInvalidCurveKeyOperation {
+		t.Fatalf("Expected %v, got %v", ErrInvalidCurveKeyOperation, err)
+	}
+}
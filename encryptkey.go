@@ -0,0 +1,81 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/sha256"
+	"io"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+	"golang.org/x/crypto/hkdf"
+)
+
+// encryptionKeySalt domain separates encryption key derivation from any
+// other use of the signing seed.
+const encryptionKeySalt = "nkeys-encryption-key-v1"
+
+// DeriveEncryptionKey derives a curve KeyPair for encryption from an
+// ed25519 signing KeyPair, so that one identity does not use the same key
+// for signing and encryption. The derivation is one way: the curve public
+// key can not be linked to the signing public key unless the holder
+// publishes a proof made with LinkEncryptionKey.
+func DeriveEncryptionKey(kp KeyPair) (KeyPair, error) {
+	seed, err := kp.Seed()
+	if err != nil {
+		return nil, err
+	}
+	prefix, raw, err := DecodeSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(raw)
+	if AlgorithmOf(prefix) != AlgorithmEd25519 {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	var ekp ckp
+	r := hkdf.New(sha256.New, raw, []byte(encryptionKeySalt), []byte{byte(prefix)})
+	if _, err := io.ReadFull(r, ekp.seed[:]); err != nil {
+		return nil, err
+	}
+	return &ekp, nil
+}
+
+// LinkEncryptionKey signs a statement that the curve public key encryption
+// belongs to the signer, for holders that choose to make the link public.
+func LinkEncryptionKey(signer KeyPair, encryption string) ([]byte, error) {
+	if !IsValidPublicCurveKey(encryption) {
+		return nil, ErrInvalidPublicKey
+	}
+	public, err := signer.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return signer.Sign(encryptionLink(public, encryption))
+}
+
+// VerifyEncryptionKeyLink checks a proof made by LinkEncryptionKey that the
+// curve public key encryption belongs to the signing public key.
+func VerifyEncryptionKeyLink(public string, encryption string, proof []byte) error {
+	if !IsValidPublicCurveKey(encryption) {
+		return ErrInvalidPublicKey
+	}
+	return VerifyWithPolicy(nil, public, encryptionLink(public, encryption), proof)
+}
+
+func encryptionLink(public, encryption string) []byte {
+	e := canonical.NewEncoder("nkeys.EncryptionKeyLink")
+	e.Text(public)
+	e.Text(encryption)
+	return e.Bytes()
+}
@@ -0,0 +1,267 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encrypted seed files are meant to be shared by all NATS tooling, so the
+// format is fully specified here. A file is a PEM block of type
+// EncryptedSeedPEMType whose bytes are, with integers in big endian:
+//
+//	magic   "NKES"
+//	version uint8, currently 1
+//	kdf     uint8, 1 = scrypt
+//	logN    uint8  scrypt cost parameter N = 1<<logN
+//	r       uint32
+//	p       uint32
+//	salt    16 bytes
+//	cipher  uint8, 1 = XChaCha20-Poly1305
+//	nonce   24 bytes
+//	sealed  the encoded seed, encrypted with the 32 byte key derived from
+//	        the password, with every preceding byte as associated data
+//
+// Readers must reject versions, KDFs and ciphers they do not know with an
+// *UnsupportedSeedFormatError rather than guessing.
+const (
+	EncryptedSeedPEMType = "NKEYS ENCRYPTED SEED"
+
+	// EncryptedSeedVersion is the newest format version this package writes
+	// and reads.
+	EncryptedSeedVersion = 1
+
+	KDFScrypt               = 1
+	CipherXChaCha20Poly1305 = 1
+)
+
+const (
+	encSeedMagic     = "NKES"
+	encSeedSaltLen   = 16
+	encSeedSaltOff   = 15
+	encSeedCipherOff = encSeedSaltOff + encSeedSaltLen
+	encSeedNonceOff  = encSeedCipherOff + 1
+	encSeedHeaderLen = encSeedNonceOff + chacha20poly1305.NonceSizeX
+	// maxScryptLogN, maxScryptR, maxScryptP and maxScryptMemory bound the
+	// work and memory an untrusted file can demand. Scrypt needs 128*N*r
+	// bytes of memory.
+	maxScryptLogN   = 22
+	maxScryptR      = 32
+	maxScryptP      = 16
+	maxScryptMemory = 1 << 30
+)
+
+// SeedEncryptionOptions tune EncryptSeed. The zero value selects the
+// defaults.
+type SeedEncryptionOptions struct {
+	// Version is the format version to write, for tools that must produce
+	// files readable by older peers. Zero selects EncryptedSeedVersion.
+	Version uint8
+	// ScryptLogN defaults to 15, ScryptR to 8 and ScryptP to 1.
+	ScryptLogN uint8
+	ScryptR    uint32
+	ScryptP    uint32
+	// Rand defaults to crypto/rand.Reader.
+	Rand io.Reader
+}
+
+// EncryptedSeedFormat describes the header of an encrypted seed.
+type EncryptedSeedFormat struct {
+	Version    uint8
+	KDF        uint8
+	ScryptLogN uint8
+	ScryptR    uint32
+	ScryptP    uint32
+	Cipher     uint8
+}
+
+// UnsupportedSeedFormatError is returned for encrypted seeds written with a
+// newer version, KDF or cipher than this package understands.
+type UnsupportedSeedFormatError struct {
+	Version uint8
+	KDF     uint8
+	Cipher  uint8
+}
+
+func (e *UnsupportedSeedFormatError) Error() string {
+	if e.Version != EncryptedSeedVersion {
+		return fmt.Sprintf("nkeys: encrypted seed format version %d is not supported, upgrade to read it", e.Version)
+	}
+	return fmt.Sprintf("nkeys: encrypted seed kdf %d or cipher %d is not supported", e.KDF, e.Cipher)
+}
+
+// EncryptSeed encrypts an encoded seed with a password and returns it as a
+// PEM encoded block. opts may be nil.
+func EncryptSeed(seed []byte, password []byte, opts *SeedEncryptionOptions) ([]byte, error) {
+	if _, _, err := DecodeSeed(seed); err != nil {
+		return nil, err
+	}
+	var o SeedEncryptionOptions
+	if opts != nil {
+		o = *opts
+	}
+	f := EncryptedSeedFormat{
+		Version:    o.Version,
+		KDF:        KDFScrypt,
+		ScryptLogN: o.ScryptLogN,
+		ScryptR:    o.ScryptR,
+		ScryptP:    o.ScryptP,
+		Cipher:     CipherXChaCha20Poly1305,
+	}
+	if f.Version == 0 {
+		f.Version = EncryptedSeedVersion
+	}
+	if f.ScryptLogN == 0 {
+		f.ScryptLogN = 15
+	}
+	if f.ScryptR == 0 {
+		f.ScryptR = 8
+	}
+	if f.ScryptP == 0 {
+		f.ScryptP = 1
+	}
+	if err := f.check(); err != nil {
+		return nil, err
+	}
+	rr := o.Rand
+	if rr == nil {
+		rr = rand.Reader
+	}
+
+	header := make([]byte, 0, encSeedHeaderLen)
+	header = append(header, encSeedMagic...)
+	header = append(header, f.Version, f.KDF, f.ScryptLogN)
+	header = binary.BigEndian.AppendUint32(header, f.ScryptR)
+	header = binary.BigEndian.AppendUint32(header, f.ScryptP)
+	random := make([]byte, encSeedSaltLen+chacha20poly1305.NonceSizeX)
+	if _, err := io.ReadFull(rr, random); err != nil {
+		return nil, err
+	}
+	salt, nonce := random[:encSeedSaltLen], random[encSeedSaltLen:]
+	header = append(header, salt...)
+	header = append(header, f.Cipher)
+	header = append(header, nonce...)
+
+	aead, err := f.aead(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	data := aead.Seal(header, nonce, seed, header)
+	return pem.EncodeToMemory(&pem.Block{Type: EncryptedSeedPEMType, Bytes: data}), nil
+}
+
+// DecryptSeed decrypts a seed produced by EncryptSeed and returns the
+// encoded seed. A wrong password yields ErrInvalidPassword.
+func DecryptSeed(data []byte, password []byte) ([]byte, error) {
+	raw, f, err := parseEncryptedSeed(data)
+	if err != nil {
+		return nil, err
+	}
+	header := raw[:encSeedHeaderLen]
+	salt := header[encSeedSaltOff:encSeedCipherOff]
+	nonce := header[encSeedNonceOff:]
+	aead, err := f.aead(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := aead.Open(nil, nonce, raw[encSeedHeaderLen:], header)
+	if err != nil {
+		return nil, ErrInvalidPassword
+	}
+	if _, _, err := DecodeSeed(seed); err != nil {
+		wipeBytes(seed)
+		return nil, ErrInvalidEncryptedSeed
+	}
+	return seed, nil
+}
+
+// FromEncryptedSeed decrypts the seed and creates a KeyPair from it.
+func FromEncryptedSeed(data []byte, password []byte) (KeyPair, error) {
+	seed, err := DecryptSeed(data, password)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(seed)
+	return FromSeed(seed)
+}
+
+// ReadEncryptedSeedFormat returns the header of an encrypted seed without
+// decrypting it, so tools can tell which version wrote it.
+func ReadEncryptedSeedFormat(data []byte) (EncryptedSeedFormat, error) {
+	_, f, err := parseEncryptedSeed(data)
+	return f, err
+}
+
+// IsEncryptedSeed reports whether data looks like an encrypted seed.
+func IsEncryptedSeed(data []byte) bool {
+	b, _ := pem.Decode(data)
+	return b != nil && b.Type == EncryptedSeedPEMType
+}
+
+func parseEncryptedSeed(data []byte) ([]byte, EncryptedSeedFormat, error) {
+	var f EncryptedSeedFormat
+	b, _ := pem.Decode(data)
+	if b == nil || b.Type != EncryptedSeedPEMType {
+		return nil, f, ErrInvalidEncryptedSeed
+	}
+	raw := b.Bytes
+	if len(raw) < len(encSeedMagic)+1 || string(raw[:len(encSeedMagic)]) != encSeedMagic {
+		return nil, f, ErrInvalidEncryptedSeed
+	}
+	f.Version = raw[len(encSeedMagic)]
+	if f.Version != EncryptedSeedVersion {
+		return nil, f, &UnsupportedSeedFormatError{Version: f.Version}
+	}
+	if len(raw) < encSeedHeaderLen+chacha20poly1305.Overhead {
+		return nil, f, ErrInvalidEncryptedSeed
+	}
+	f.KDF = raw[5]
+	f.ScryptLogN = raw[6]
+	f.ScryptR = binary.BigEndian.Uint32(raw[7:])
+	f.ScryptP = binary.BigEndian.Uint32(raw[11:])
+	f.Cipher = raw[encSeedCipherOff]
+	if err := f.check(); err != nil {
+		return nil, f, err
+	}
+	return raw, f, nil
+}
+
+func (f *EncryptedSeedFormat) check() error {
+	if f.Version != EncryptedSeedVersion || f.KDF != KDFScrypt || f.Cipher != CipherXChaCha20Poly1305 {
+		return &UnsupportedSeedFormatError{Version: f.Version, KDF: f.KDF, Cipher: f.Cipher}
+	}
+	if f.ScryptLogN == 0 || f.ScryptLogN > maxScryptLogN ||
+		f.ScryptR == 0 || f.ScryptR > maxScryptR || f.ScryptP == 0 || f.ScryptP > maxScryptP ||
+		128*uint64(f.ScryptR)<<f.ScryptLogN > maxScryptMemory {
+		return ErrInvalidEncryptedSeed
+	}
+	return nil
+}
+
+func (f *EncryptedSeedFormat) aead(password, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(password, salt, 1<<f.ScryptLogN, int(f.ScryptR), int(f.ScryptP), chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(key)
+	return chacha20poly1305.NewX(key)
+}
@@ -0,0 +1,240 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/pem"
+	"strings"
+	"testing"
+)
+
+// testEncryptedSeed is katSeed encrypted with the password "password",
+// scrypt logN 10 and every random byte set to 7. Other implementations of
+// the format should be able to open it.
+const testEncryptedSeed = `-----BEGIN NKEYS ENCRYPTED SEED-----
+TktFUwEBCgAAAAgAAAABBwcHBwcHBwcHBwcHBwcHBwEHBwcHBwcHBwcHBwcHBwcH
+BwcHBwcHBwe1I/6dRmjg80dTU+R/eQ/O2YXsJZlUaT/XP+tR4ai/yh0+du+4WvMy
+LVG3cNJPdwH5VX52n4hTO1Xr/iv2YbcxBcEi2wDiZGVtwg==
+-----END NKEYS ENCRYPTED SEED-----
+`
+
+func TestEncryptedSeedVector(t *testing.T) {
+	seed, err := DecryptSeed([]byte(testEncryptedSeed), []byte("password"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(seed) != katSeed {
+		t.Fatalf("Expected %q, got %q", katSeed, seed)
+	}
+	out, err := EncryptSeed([]byte(katSeed), []byte("password"), &SeedEncryptionOptions{
+		ScryptLogN: 10,
+		Rand:       bytes.NewReader(bytes.Repeat([]byte{7}, 40)),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(out) != testEncryptedSeed {
+		t.Fatalf("Expected output to match the test vector, got\n%s", out)
+	}
+}
+
+func TestEncryptedSeedRoundTrip(t *testing.T) {
+	user, _ := CreateUser()
+	seed, _ := user.Seed()
+	opts := &SeedEncryptionOptions{ScryptLogN: 10}
+	data, err := EncryptSeed(seed, []byte("secret"), opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !IsEncryptedSeed(data) || IsEncryptedSeed(seed) {
+		t.Fatalf("Expected IsEncryptedSeed to detect the format")
+	}
+	f, err := ReadEncryptedSeedFormat(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if f.Version != EncryptedSeedVersion || f.KDF != KDFScrypt || f.ScryptLogN != 10 {
+		t.Fatalf("Unexpected format %+v", f)
+	}
+	kp, err := FromEncryptedSeed(data, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	got, _ := kp.Seed()
+	if !bytes.Equal(got, seed) {
+		t.Fatalf("Expected seeds to match")
+	}
+	if _, err := DecryptSeed(data, []byte("wrong")); err != ErrInvalidPassword {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPassword, err)
+	}
+	if _, err := EncryptSeed([]byte("bad"), []byte("secret"), opts); err == nil {
+		t.Fatalf("Expected invalid seeds to be rejected")
+	}
+}
+
+func TestEncryptedSeedTampered(t *testing.T) {
+	b, _ := pem.Decode([]byte(testEncryptedSeed))
+
+	// Lowering the work factor is detected since the header is authenticated.
+	raw := append([]byte{}, b.Bytes...)
+	raw[6] = 9
+	data := pem.EncodeToMemory(&pem.Block{Type: EncryptedSeedPEMType, Bytes: raw})
+	if _, err := DecryptSeed(data, []byte("password")); err != ErrInvalidPassword {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPassword, err)
+	}
+
+	raw = append([]byte{}, b.Bytes...)
+	raw[6] = maxScryptLogN + 1
+	data = pem.EncodeToMemory(&pem.Block{Type: EncryptedSeedPEMType, Bytes: raw})
+	if _, err := DecryptSeed(data, []byte("password")); err != ErrInvalidEncryptedSeed {
+		t.Fatalf("Expected %v, got %v", ErrInvalidEncryptedSeed, err)
+	}
+
+	// Memory hungry parameters are rejected before running scrypt.
+	for _, tc := range []struct {
+		logN byte
+		r, p uint32
+	}{
+		{10, 1 << 20, 1},
+		{10, 8, 1 << 10},
+		{22, 8, 1},
+	} {
+		raw = append([]byte{}, b.Bytes...)
+		raw[6] = tc.logN
+		binary.BigEndian.PutUint32(raw[7:], tc.r)
+		binary.BigEndian.PutUint32(raw[11:], tc.p)
+		data = pem.EncodeToMemory(&pem.Block{Type: EncryptedSeedPEMType, Bytes: raw})
+		if _, err := DecryptSeed(data, []byte("password")); err != ErrInvalidEncryptedSeed {
+			t.Fatalf("Expected %v, got %v", ErrInvalidEncryptedSeed, err)
+		}
+	}
+
+	if _, err := DecryptSeed([]byte(katSeed), []byte("password")); err != ErrInvalidEncryptedSeed {
+		t.Fatalf("Expected %v, got %v", ErrInvalidEncryptedSeed, err)
+	}
+}
+
+func TestEncryptedSeedFutureVersion(t *testing.T) {
+	b, _ := pem.Decode([]byte(testEncryptedSeed))
+	raw := append([]byte{}, b.Bytes[:5]...)
+	raw[4] = EncryptedSeedVersion + 1
+	data := pem.EncodeToMemory(&pem.Block{Type: EncryptedSeedPEMType, Bytes: raw})
+	_, err := DecryptSeed(data, []byte("password"))
+	uerr, ok := err.(*UnsupportedSeedFormatError)
+	if !ok {
+		t.Fatalf("Expected *UnsupportedSeedFormatError, got %v", err)
+	}
+	if uerr.Version != EncryptedSeedVersion+1 {
+		t.Fatalf("Expected version %d, got %d", EncryptedSeedVersion+1, uerr.Version)
+	}
+	if _, err := EncryptSeed([]byte(katSeed), nil, &SeedEncryptionOptions{Version: 9}); err == nil {
+		t.Fatalf("Expected unknown versions to be rejected")
+	}
+}
+
+func TestSeedExchange(t *testing.T) {
+	user, _ := CreateUser()
+	seed, _ := user.Seed()
+
+	x, err := NewSeedExchange()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := SealForExchange(x.Request(), "AAAA-AAAA-AAAA-AAAA", seed); err != ErrInvalidExchangeCode {
+		t.Fatalf("Expected %v, got %v", ErrInvalidExchangeCode, err)
+	}
+	code := strings.ToLower(strings.ReplaceAll(x.Code(), "-", " "))
+	reply, err := SealForExchange(x.Request(), code, seed)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// A reply for another exchange must not be accepted.
+	other, _ := NewSeedExchange()
+	if _, err := other.Open(reply); err != ErrInvalidExchangeCode {
+		t.Fatalf("Expected %v, got %v", ErrInvalidExchangeCode, err)
+	}
+	if _, err := x.Open("nkx1.garbage!"); err != ErrInvalidSeedExchange {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSeedExchange, err)
+	}
+
+	got, err := x.Open(reply + "\n")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !bytes.Equal(got, seed) {
+		t.Fatalf("Expected %q, got %q", seed, got)
+	}
+	if _, err := x.Open(reply); err != ErrInvalidSeedExchange {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSeedExchange, err)
+	}
+}
+
+func TestCooperativeSeed(t *testing.T) {
+	server, _ := CreateOperator()
+	spub, _ := server.PublicKey()
+	// A device whose entropy source is stuck.
+	stuck := bytes.NewReader(make([]byte, 64))
+
+	a, err := NewCooperativeSeed(PrefixByteUser, stuck)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	contribution, err := ContributeEntropy(server)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	kp, err := a.Combine(contribution, spub)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pa, _ := kp.PublicKey()
+	if Prefix(pa) != PrefixByteUser {
+		t.Fatalf("Expected %v, got %v", PrefixByteUser, Prefix(pa))
+	}
+	if _, err := a.Combine(contribution, spub); err != ErrInvalidSeedContribution {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSeedContribution, err)
+	}
+
+	// The same device entropy with another contribution gives another key.
+	b, _ := NewCooperativeSeed(PrefixByteUser, stuck)
+	other, _ := ContributeEntropy(nil)
+	kb, err := b.Combine(other, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pb, _ := kb.PublicKey(); pb == pa {
+		t.Fatal("Expected different keys for different contributions")
+	}
+
+	c, _ := NewCooperativeSeed(PrefixByteCurve, nil)
+	if _, err := c.Combine(other, spub); err != ErrInvalidSeedContribution {
+		t.Fatalf("Expected unsigned contributions to be rejected, got %v", err)
+	}
+	if _, err := c.Combine("nkc1.garbage", ""); err != ErrInvalidSeedContribution {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSeedContribution, err)
+	}
+	kc, err := c.Combine(other, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pc, _ := kc.PublicKey(); !IsValidPublicCurveKey(pc) {
+		t.Fatalf("Expected a curve key, got %q", pc)
+	}
+	if _, err := NewCooperativeSeed(PrefixBytePrivate, nil); err == nil {
+		t.Fatal("Expected an invalid prefix to be rejected")
+	}
+}
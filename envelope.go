@@ -0,0 +1,166 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"sort"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// maxEnvelopeSignatures bounds the signatures of decoded envelopes.
+const maxEnvelopeSignatures = 256
+
+// EnvelopeSignature is one signature of an Envelope.
+type EnvelopeSignature struct {
+	Signer    string `json:"signer"`
+	Signature []byte `json:"sig"`
+}
+
+// Envelope carries a payload and the signatures of any number of keys over
+// it, for documents that need approval from several keys. Signers sign
+// independently and in any order; signatures are kept sorted by signer,
+// one per signer, so that equal envelopes have equal encodings.
+type Envelope struct {
+	Payload    []byte              `json:"payload"`
+	Signatures []EnvelopeSignature `json:"signatures"`
+}
+
+// NewEnvelope returns an unsigned envelope for payload.
+func NewEnvelope(payload []byte) *Envelope {
+	return &Envelope{Payload: append([]byte{}, payload...)}
+}
+
+func (e *Envelope) signedBytes() []byte {
+	enc := canonical.NewEncoder("nkeys.Envelope")
+	enc.Blob(e.Payload)
+	return enc.Bytes()
+}
+
+// Sign adds the signature of kp, replacing an earlier one by the same key.
+func (e *Envelope) Sign(kp KeyPair) error {
+	public, err := kp.PublicKey()
+	if err != nil {
+		return err
+	}
+	sig, err := kp.Sign(e.signedBytes())
+	if err != nil {
+		return err
+	}
+	i := sort.Search(len(e.Signatures), func(i int) bool { return e.Signatures[i].Signer >= public })
+	if i < len(e.Signatures) && e.Signatures[i].Signer == public {
+		e.Signatures[i].Signature = sig
+		return nil
+	}
+	e.Signatures = append(e.Signatures, EnvelopeSignature{})
+	copy(e.Signatures[i+1:], e.Signatures[i:])
+	e.Signatures[i] = EnvelopeSignature{Signer: public, Signature: sig}
+	return nil
+}
+
+// Signers returns the public keys that signed the envelope, without
+// verifying the signatures.
+func (e *Envelope) Signers() []string {
+	signers := make([]string, len(e.Signatures))
+	for i, s := range e.Signatures {
+		signers[i] = s.Signer
+	}
+	return signers
+}
+
+// canonicalOrder reports whether the signatures are sorted by signer
+// without duplicates.
+func (e *Envelope) canonicalOrder() bool {
+	for i := 1; i < len(e.Signatures); i++ {
+		if e.Signatures[i-1].Signer >= e.Signatures[i].Signer {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalBinary returns the canonical encoding of the envelope.
+func (e *Envelope) MarshalBinary() ([]byte, error) {
+	if !e.canonicalOrder() {
+		return nil, ErrInvalidEnvelope
+	}
+	enc := canonical.NewEncoder("nkeys.Envelope")
+	enc.Blob(e.Payload)
+	enc.Uint64(uint64(len(e.Signatures)))
+	for _, s := range e.Signatures {
+		enc.Text(s.Signer)
+		enc.Blob(s.Signature)
+	}
+	return enc.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the canonical encoding of an envelope. Encodings
+// with signatures out of order or repeated signers are rejected.
+func (e *Envelope) UnmarshalBinary(data []byte) error {
+	d := canonical.NewDecoder("nkeys.Envelope", data)
+	v := Envelope{Payload: d.Blob()}
+	n := d.Uint64()
+	if n > maxEnvelopeSignatures {
+		return ErrInvalidEnvelope
+	}
+	for i := uint64(0); i < n && d.Err() == nil; i++ {
+		v.Signatures = append(v.Signatures, EnvelopeSignature{Signer: d.Text(), Signature: d.Blob()})
+	}
+	if err := d.Finish(); err != nil {
+		return err
+	}
+	if !v.canonicalOrder() {
+		return ErrInvalidEnvelope
+	}
+	*e = v
+	return nil
+}
+
+// ThresholdPolicy requires signatures from at least Threshold of Signers.
+type ThresholdPolicy struct {
+	Signers   []string
+	Threshold int
+}
+
+// VerifyThreshold verifies every signature of the envelope and checks that
+// enough of the policy's signers signed it. It returns the signers from the
+// policy whose signatures counted. Signatures by keys outside the policy do
+// not count but must still be valid, as must the ones from keys the
+// VerifyPolicy vp rejects; any invalid signature fails the envelope.
+func (e *Envelope) VerifyThreshold(tp ThresholdPolicy, vp *VerifyPolicy) ([]string, error) {
+	allowed := make(map[string]bool, len(tp.Signers))
+	for _, s := range tp.Signers {
+		allowed[s] = true
+	}
+	if tp.Threshold <= 0 || tp.Threshold > len(allowed) {
+		return nil, ErrInvalidThreshold
+	}
+	if !e.canonicalOrder() {
+		return nil, ErrInvalidEnvelope
+	}
+	input := e.signedBytes()
+	var counted []string
+	for _, s := range e.Signatures {
+		if err := VerifyWithPolicy(nil, s.Signer, input, s.Signature); err != nil {
+			return nil, err
+		}
+		if allowed[s.Signer] && vp.CheckKey(s.Signer) == nil {
+			counted = append(counted, s.Signer)
+		}
+	}
+	if len(counted) < tp.Threshold {
+		return counted, ErrThresholdNotMet
+	}
+	return counted, nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// EphemeralOptions configures an EphemeralKeyPair. The zero value keeps the
+// key until Wipe is called.
+type EphemeralOptions struct {
+	// TTL, when set, wipes the key this long after it was created.
+	TTL time.Duration
+	// Context, when set, wipes the key once it is done.
+	Context context.Context
+	// OnWipe is called once the key has been wiped.
+	OnWipe func()
+}
+
+// EphemeralKeyPair is a KeyPair for short-lived identities, such as a
+// single connection, where persisting the key would be a bug. Seed and
+// PrivateKey always fail with ErrEphemeralKey. Once wiped, Sign, Seal and
+// Open fail with ErrExpired; the public key stays available, so signatures
+// made earlier can still be verified.
+type EphemeralKeyPair struct {
+	pub    KeyPair
+	public string
+	onWipe func()
+	done   chan struct{}
+
+	mu    sync.RWMutex
+	kp    KeyPair
+	timer *time.Timer
+	wiped bool
+}
+
+// Ephemeral creates a new EphemeralKeyPair of the given type.
+func Ephemeral(prefix PrefixByte, opts EphemeralOptions) (*EphemeralKeyPair, error) {
+	if opts.TTL < 0 {
+		return nil, ErrInvalidLifetime
+	}
+	kp, err := CreatePair(prefix)
+	if err != nil {
+		return nil, err
+	}
+	public, err := kp.PublicKey()
+	if err != nil {
+		kp.Wipe()
+		return nil, err
+	}
+	pub, err := kp.PublicOnly()
+	if err != nil {
+		kp.Wipe()
+		return nil, err
+	}
+	e := &EphemeralKeyPair{pub: pub, public: public, onWipe: opts.OnWipe, done: make(chan struct{}), kp: kp}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if opts.TTL > 0 {
+		e.timer = time.AfterFunc(opts.TTL, e.Wipe)
+	}
+	if ctx := opts.Context; ctx != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				e.Wipe()
+			case <-e.done:
+			}
+		}()
+	}
+	return e, nil
+}
+
+// Wiped reports whether the key has been wiped.
+func (e *EphemeralKeyPair) Wiped() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.wiped
+}
+
+// Seed always fails, ephemeral keys can not be exported.
+func (e *EphemeralKeyPair) Seed() ([]byte, error) {
+	return nil, ErrEphemeralKey
+}
+
+// PublicKey will return the encoded public key.
+func (e *EphemeralKeyPair) PublicKey() (string, error) {
+	return e.public, nil
+}
+
+// PrivateKey always fails, ephemeral keys can not be exported.
+func (e *EphemeralKeyPair) PrivateKey() ([]byte, error) {
+	return nil, ErrEphemeralKey
+}
+
+// Sign will sign the input unless the key has been wiped.
+func (e *EphemeralKeyPair) Sign(input []byte) ([]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.wiped {
+		return nil, ErrExpired
+	}
+	return e.kp.Sign(input)
+}
+
+// Verify will verify the input against a signature.
+func (e *EphemeralKeyPair) Verify(input []byte, sig []byte) error {
+	return e.pub.Verify(input, sig)
+}
+
+// PublicOnly returns a public only copy of the KeyPair.
+func (e *EphemeralKeyPair) PublicOnly() (KeyPair, error) {
+	return e.pub.PublicOnly()
+}
+
+// Wipe wipes the key now. It is safe to call more than once.
+func (e *EphemeralKeyPair) Wipe() {
+	e.mu.Lock()
+	if e.wiped {
+		e.mu.Unlock()
+		return
+	}
+	e.wiped = true
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	close(e.done)
+	e.kp.Wipe()
+	e.mu.Unlock()
+	if e.onWipe != nil {
+		e.onWipe()
+	}
+}
+
+// Seal will seal the input unless the key has been wiped.
+func (e *EphemeralKeyPair) Seal(input []byte, recipient string) ([]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.wiped {
+		return nil, ErrExpired
+	}
+	return e.kp.Seal(input, recipient)
+}
+
+// SealWithRand will seal the input unless the key has been wiped.
+func (e *EphemeralKeyPair) SealWithRand(input []byte, recipient string, rr io.Reader) ([]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.wiped {
+		return nil, ErrExpired
+	}
+	return e.kp.SealWithRand(input, recipient, rr)
+}
+
+// Open will open the input unless the key has been wiped.
+func (e *EphemeralKeyPair) Open(input []byte, sender string) ([]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.wiped {
+		return nil, ErrExpired
+	}
+	return e.kp.Open(input, sender)
+}
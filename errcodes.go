@@ -0,0 +1,163 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"encoding/base32"
+	"errors"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// errorCodes assigns every error a code that stays the same across
+// releases, unlike the message text. Codes are grouped by area and must
+// never be changed or reused; new errors get the next free code.
+var errorCodes = map[nkeysError]string{
+	// 01xx: encoding
+	ErrInvalidEncoding:     "NKEYS-0100",
+	ErrInvalidChecksum:     "NKEYS-0101",
+	ErrInvalidPrefixByte:   "NKEYS-0102",
+	ErrAmbiguousCorrection: "NKEYS-0103",
+
+	// 02xx: keys and seeds
+	ErrInvalidKey:               "NKEYS-0200",
+	ErrInvalidPublicKey:         "NKEYS-0201",
+	ErrInvalidPrivateKey:        "NKEYS-0202",
+	ErrInvalidSeedLen:           "NKEYS-0203",
+	ErrInvalidSeed:              "NKEYS-0204",
+	ErrPublicKeyOnly:            "NKEYS-0205",
+	ErrIncompatibleKey:          "NKEYS-0206",
+	ErrNoSeedFound:              "NKEYS-0207",
+	ErrInvalidNkeySeed:          "NKEYS-0208",
+	ErrInvalidUserSeed:          "NKEYS-0209",
+	ErrUnsupportedAlgorithm:     "NKEYS-0210",
+	ErrKeyNotFound:              "NKEYS-0211",
+	ErrInvalidLifetime:          "NKEYS-0212",
+	ErrInvalidClassification:    "NKEYS-0213",
+	ErrExpectedPublicKeyGotSeed: "NKEYS-0214",
+	ErrExpectedSeedGotPublicKey: "NKEYS-0215",
+
+	// 03xx: signing and verification
+	ErrInvalidSignature:    "NKEYS-0300",
+	ErrCannotSign:          "NKEYS-0301",
+	ErrVerificationFailed:  "NKEYS-0302",
+	ErrTooManyFailures:     "NKEYS-0303",
+	ErrInvalidSignerOutput: "NKEYS-0304",
+	ErrThresholdNotMet:     "NKEYS-0305",
+	ErrCircuitOpen:         "NKEYS-0306",
+
+	// 04xx: curve keys and encryption
+	ErrInvalidRecipient:         "NKEYS-0400",
+	ErrInvalidSender:            "NKEYS-0401",
+	ErrInvalidCurveKey:          "NKEYS-0402",
+	ErrInvalidCurveSeed:         "NKEYS-0403",
+	ErrInvalidEncrypted:         "NKEYS-0404",
+	ErrInvalidEncVersion:        "NKEYS-0405",
+	ErrCouldNotDecrypt:          "NKEYS-0406",
+	ErrInvalidCurveKeyOperation: "NKEYS-0407",
+	ErrInvalidNKeyOperation:     "NKEYS-0408",
+	ErrCannotOpen:               "NKEYS-0409",
+	ErrCannotSeal:               "NKEYS-0410",
+	ErrInvalidWrappedSeed:       "NKEYS-0411",
+
+	// 05xx: policies and trust
+	ErrOperationNotAllowed:    "NKEYS-0500",
+	ErrPayloadTooLarge:        "NKEYS-0501",
+	ErrContextNotAllowed:      "NKEYS-0502",
+	ErrKeyTypeNotAllowed:      "NKEYS-0503",
+	ErrKeyRevoked:             "NKEYS-0504",
+	ErrKeyRetired:             "NKEYS-0505",
+	ErrNotYetValid:            "NKEYS-0506",
+	ErrExpired:                "NKEYS-0507",
+	ErrKeyNotTrusted:          "NKEYS-0508",
+	ErrExportNotAllowed:       "NKEYS-0509",
+	ErrScopeNotAllowed:        "NKEYS-0510",
+	ErrEphemeralKey:           "NKEYS-0511",
+	ErrInvalidThreshold:       "NKEYS-0512",
+	ErrKeyTypeDeprecated:      "NKEYS-0513",
+	ErrSigningMaterial:        "NKEYS-0514",
+	ErrInsecureTrustSource:    "NKEYS-0515",
+	ErrTrustSourceTooLarge:    "NKEYS-0516",
+	ErrTrustSourceUnavailable: "NKEYS-0517",
+
+	// 06xx: crypto backend
+	ErrSelfTestFailed: "NKEYS-0600",
+	ErrInvalidBackend: "NKEYS-0601",
+	ErrBackendLocked:  "NKEYS-0602",
+
+	// 07xx: stored and transported formats
+	ErrInvalidManifest:          "NKEYS-0700",
+	ErrChunkMismatch:            "NKEYS-0701",
+	ErrInvalidEncryptedSeed:     "NKEYS-0702",
+	ErrInvalidPassword:          "NKEYS-0703",
+	ErrInvalidHandoverKey:       "NKEYS-0704",
+	ErrInvalidHandoverState:     "NKEYS-0705",
+	ErrInvalidImpersonation:     "NKEYS-0706",
+	ErrInvalidSignedConfig:      "NKEYS-0707",
+	ErrInvalidPossession:        "NKEYS-0708",
+	ErrInvalidBundle:            "NKEYS-0709",
+	ErrInvalidRevocationList:    "NKEYS-0710",
+	ErrDeltaBaseMismatch:        "NKEYS-0711",
+	ErrInvalidSeedExchange:      "NKEYS-0712",
+	ErrInvalidExchangeCode:      "NKEYS-0713",
+	ErrInvalidRotationStatement: "NKEYS-0714",
+	ErrInvalidEncryptedFile:     "NKEYS-0715",
+	ErrInvalidCredsFile:         "NKEYS-0716",
+	ErrInvalidSubkeyIssuance:    "NKEYS-0717",
+	ErrInvalidSeedContribution:  "NKEYS-0718",
+	ErrInvalidEnvelope:          "NKEYS-0719",
+	ErrInvalidTombstone:         "NKEYS-0720",
+	ErrInvalidJSON:              "NKEYS-0721",
+	ErrInvalidChallenge:         "NKEYS-0722",
+	ErrInvalidKeyRecord:         "NKEYS-0723",
+	ErrInvalidOwnership:         "NKEYS-0724",
+
+	// 09xx: routing
+	ErrNoBuckets: "NKEYS-0900",
+}
+
+// Codes of errors that are not nkeysError values.
+const (
+	codeInsecureSeedFile      = "NKEYS-0800"
+	codeUnsupportedSeedFormat = "NKEYS-0801"
+	codePinMismatch           = "NKEYS-0802"
+	codeMalformedEncoding     = "NKEYS-0803"
+)
+
+// ErrorCode returns the stable code of err, such as "NKEYS-0101" for a bad
+// checksum, so that support tooling and alerting do not depend on message
+// text. Wrapped errors are unwrapped. It returns "" for errors that do not
+// come from this package.
+func ErrorCode(err error) string {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch e := err.(type) {
+		case nkeysError:
+			return errorCodes[e]
+		case *InsecureSeedFileError:
+			return codeInsecureSeedFile
+		case *UnsupportedSeedFormatError:
+			return codeUnsupportedSeedFormat
+		case *PinMismatchError:
+			return codePinMismatch
+		case *PayloadSizeError:
+			return errorCodes[ErrPayloadTooLarge]
+		case base32.CorruptInputError:
+			return errorCodes[ErrInvalidEncoding]
+		}
+		if err == canonical.ErrInvalid {
+			return codeMalformedEncoding
+		}
+	}
+	return ""
+}
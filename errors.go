@@ -41,6 +41,66 @@ const (
 	ErrInvalidNKeyOperation     = nkeysError("nkeys: only curve key can seal/open")
 	ErrCannotOpen               = nkeysError("nkeys: cannot open no private curve key available")
 	ErrCannotSeal               = nkeysError("nkeys: cannot seal no private curve key available")
+	ErrSelfTestFailed           = nkeysError("nkeys: cryptographic self-test failed")
+	ErrInvalidBackend           = nkeysError("nkeys: invalid crypto backend")
+	ErrBackendLocked            = nkeysError("nkeys: crypto backend already selected")
+	ErrOperationNotAllowed      = nkeysError("nkeys: operation not allowed by policy")
+	ErrPayloadTooLarge          = nkeysError("nkeys: payload exceeds policy limit")
+	ErrContextNotAllowed        = nkeysError("nkeys: context not allowed by policy")
+	ErrKeyNotFound              = nkeysError("nkeys: key not found")
+	ErrInvalidManifest          = nkeysError("nkeys: invalid chunk manifest")
+	ErrChunkMismatch            = nkeysError("nkeys: chunk does not match manifest")
+	ErrKeyTypeNotAllowed        = nkeysError("nkeys: key type not allowed by policy")
+	ErrKeyRevoked               = nkeysError("nkeys: key has been revoked")
+	ErrKeyRetired               = nkeysError("nkeys: key has been rotated out")
+	ErrNotYetValid              = nkeysError("nkeys: not yet valid")
+	ErrExpired                  = nkeysError("nkeys: expired")
+	ErrInvalidEncryptedSeed     = nkeysError("nkeys: invalid encrypted seed")
+	ErrInvalidHandoverKey       = nkeysError("nkeys: invalid handover key")
+	ErrInvalidHandoverState     = nkeysError("nkeys: invalid handover state")
+	ErrVerificationFailed       = nkeysError("nkeys: verification failed")
+	ErrTooManyFailures          = nkeysError("nkeys: too many verification failures")
+	ErrInvalidWrappedSeed       = nkeysError("nkeys: invalid wrapped seed")
+	ErrUnsupportedAlgorithm     = nkeysError("nkeys: unsupported key algorithm")
+	ErrInvalidPassword          = nkeysError("nkeys: invalid password or corrupted encrypted seed")
+	ErrAmbiguousCorrection      = nkeysError("nkeys: key could be corrected in more than one way")
+	ErrInvalidLifetime          = nkeysError("nkeys: invalid key lifetime")
+	ErrInvalidImpersonation     = nkeysError("nkeys: invalid impersonation token")
+	ErrKeyNotTrusted            = nkeysError("nkeys: key is not trusted")
+	ErrInvalidSignedConfig      = nkeysError("nkeys: missing or malformed config signature")
+	ErrInvalidPossession        = nkeysError("nkeys: invalid proof of possession")
+	ErrInvalidBundle            = nkeysError("nkeys: invalid verification bundle")
+	ErrInvalidRevocationList    = nkeysError("nkeys: invalid revocation list")
+	ErrDeltaBaseMismatch        = nkeysError("nkeys: revocation delta does not apply to this list")
+	ErrInvalidSeedExchange      = nkeysError("nkeys: invalid seed exchange message")
+	ErrInvalidExchangeCode      = nkeysError("nkeys: seed exchange code does not match")
+	ErrNoBuckets                = nkeysError("nkeys: no buckets to map keys to")
+	ErrInvalidRotationStatement = nkeysError("nkeys: invalid rotation statement")
+	ErrInvalidClassification    = nkeysError("nkeys: invalid key classification")
+	ErrExportNotAllowed         = nkeysError("nkeys: export not allowed by key classification")
+	ErrInvalidEncryptedFile     = nkeysError("nkeys: invalid or truncated encrypted file")
+	ErrExpectedPublicKeyGotSeed = nkeysError("nkeys: expected a public key but got a seed")
+	ErrExpectedSeedGotPublicKey = nkeysError("nkeys: expected a seed but got a public key")
+	ErrInvalidCredsFile         = nkeysError("nkeys: invalid creds file")
+	ErrInvalidSubkeyIssuance    = nkeysError("nkeys: invalid subkey issuance")
+	ErrScopeNotAllowed          = nkeysError("nkeys: scope not allowed")
+	ErrEphemeralKey             = nkeysError("nkeys: ephemeral keys can not be exported")
+	ErrInvalidSignerOutput      = nkeysError("nkeys: signing command did not return a valid signature")
+	ErrInvalidSeedContribution  = nkeysError("nkeys: invalid seed entropy contribution")
+	ErrInvalidEnvelope          = nkeysError("nkeys: invalid signed envelope")
+	ErrInvalidThreshold         = nkeysError("nkeys: invalid signature threshold")
+	ErrThresholdNotMet          = nkeysError("nkeys: not enough valid signatures")
+	ErrInvalidTombstone         = nkeysError("nkeys: invalid key tombstone")
+	ErrInvalidJSON              = nkeysError("nkeys: invalid or non-canonicalizable JSON")
+	ErrInvalidChallenge         = nkeysError("nkeys: invalid ownership challenge")
+	ErrInvalidKeyRecord         = nkeysError("nkeys: invalid public key record")
+	ErrInvalidOwnership         = nkeysError("nkeys: proof of ownership is for another key")
+	ErrCircuitOpen              = nkeysError("nkeys: signer is failing, not retrying until cooldown")
+	ErrKeyTypeDeprecated        = nkeysError("nkeys: key type is deprecated")
+	ErrSigningMaterial          = nkeysError("nkeys: refusing to sign nkey material")
+	ErrInsecureTrustSource      = nkeysError("nkeys: trust sources must be files or https URLs")
+	ErrTrustSourceTooLarge      = nkeysError("nkeys: trust source is too large")
+	ErrTrustSourceUnavailable   = nkeysError("nkeys: trust source could not be fetched")
 )
 
 type nkeysError string
@@ -0,0 +1,199 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"io"
+	"time"
+)
+
+// EventType identifies a KeyPair lifecycle event.
+type EventType uint8
+
+const (
+	// EventCreated is emitted when a new KeyPair is generated.
+	EventCreated EventType = iota + 1
+	// EventLoaded is emitted when a KeyPair is loaded from a seed.
+	EventLoaded
+	// EventSigned is emitted after a successful Sign.
+	EventSigned
+	// EventWiped is emitted after Wipe.
+	EventWiped
+	// EventExported is emitted after the seed or private key is returned.
+	EventExported
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventCreated:
+		return "created"
+	case EventLoaded:
+		return "loaded"
+	case EventSigned:
+		return "signed"
+	case EventWiped:
+		return "wiped"
+	case EventExported:
+		return "exported"
+	}
+	return "unknown"
+}
+
+// Event describes something that happened to a KeyPair.
+type Event struct {
+	Type      EventType
+	PublicKey string
+	Time      time.Time
+	// Size is the length of the signed input for EventSigned.
+	Size int
+	// Reason is the justification given when an export was forced past
+	// the classification of the key, see ClassifiedKeyPair.ExportSeed.
+	Reason string
+}
+
+// EventHandler receives KeyPair events. It is called synchronously from the
+// goroutine performing the operation and must be safe for concurrent use.
+type EventHandler func(Event)
+
+// ChannelHandler returns an EventHandler that sends events to ch. Sends
+// block, so the consumer must keep draining ch.
+func ChannelHandler(ch chan<- Event) EventHandler {
+	return func(e Event) {
+		ch <- e
+	}
+}
+
+// EventKeyPair is a KeyPair that reports its lifecycle events to a handler.
+type EventKeyPair struct {
+	// Clock stamps the reported events. Defaults to the system clock.
+	Clock Clock
+
+	kp      KeyPair
+	public  string
+	handler EventHandler
+}
+
+// WithEvents returns a KeyPair that delegates to kp and reports signing,
+// exporting and wiping to handler.
+func WithEvents(kp KeyPair, handler EventHandler) (*EventKeyPair, error) {
+	public, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return &EventKeyPair{kp: kp, public: public, handler: handler}, nil
+}
+
+// CreatePairWithEvents creates a KeyPair like CreatePair and reports
+// EventCreated followed by its later events to handler.
+func CreatePairWithEvents(prefix PrefixByte, handler EventHandler) (*EventKeyPair, error) {
+	kp, err := CreatePair(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return withInitialEvent(kp, handler, EventCreated)
+}
+
+// FromSeedWithEvents loads a KeyPair like FromSeed and reports EventLoaded
+// followed by its later events to handler.
+func FromSeedWithEvents(seed []byte, handler EventHandler) (*EventKeyPair, error) {
+	kp, err := FromSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	return withInitialEvent(kp, handler, EventLoaded)
+}
+
+func withInitialEvent(kp KeyPair, handler EventHandler, t EventType) (*EventKeyPair, error) {
+	e, err := WithEvents(kp, handler)
+	if err != nil {
+		kp.Wipe()
+		return nil, err
+	}
+	e.emit(t, 0)
+	return e, nil
+}
+
+func (e *EventKeyPair) emit(t EventType, size int) {
+	if e.handler != nil {
+		e.handler(Event{Type: t, PublicKey: e.public, Time: ClockOrSystem(e.Clock).Now(), Size: size})
+	}
+}
+
+// Seed will return the encoded seed and report EventExported.
+func (e *EventKeyPair) Seed() ([]byte, error) {
+	seed, err := e.kp.Seed()
+	if err == nil {
+		e.emit(EventExported, 0)
+	}
+	return seed, err
+}
+
+// PublicKey will return the encoded public key.
+func (e *EventKeyPair) PublicKey() (string, error) {
+	return e.kp.PublicKey()
+}
+
+// PrivateKey will return the encoded private key and report EventExported.
+func (e *EventKeyPair) PrivateKey() ([]byte, error) {
+	priv, err := e.kp.PrivateKey()
+	if err == nil {
+		e.emit(EventExported, 0)
+	}
+	return priv, err
+}
+
+// Sign will sign the input and report EventSigned.
+func (e *EventKeyPair) Sign(input []byte) ([]byte, error) {
+	sig, err := e.kp.Sign(input)
+	if err == nil {
+		e.emit(EventSigned, len(input))
+	}
+	return sig, err
+}
+
+// Verify will verify the input against a signature.
+func (e *EventKeyPair) Verify(input []byte, sig []byte) error {
+	return e.kp.Verify(input, sig)
+}
+
+// PublicOnly returns a public only copy of the KeyPair reporting to the
+// same handler with the same clock.
+func (e *EventKeyPair) PublicOnly() (KeyPair, error) {
+	public, err := e.kp.PublicOnly()
+	if err != nil {
+		return nil, err
+	}
+	return &EventKeyPair{Clock: e.Clock, kp: public, public: e.public, handler: e.handler}, nil
+}
+
+// Wipe will wipe the underlying KeyPair and report EventWiped.
+func (e *EventKeyPair) Wipe() {
+	e.kp.Wipe()
+	e.emit(EventWiped, 0)
+}
+
+// Seal will seal the input.
+func (e *EventKeyPair) Seal(input []byte, recipient string) ([]byte, error) {
+	return e.kp.Seal(input, recipient)
+}
+
+// SealWithRand will seal the input.
+func (e *EventKeyPair) SealWithRand(input []byte, recipient string, rr io.Reader) ([]byte, error) {
+	return e.kp.SealWithRand(input, recipient, rr)
+}
+
+// Open will open the input.
+func (e *EventKeyPair) Open(input []byte, sender string) ([]byte, error) {
+	return e.kp.Open(input, sender)
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventKeyPair(t *testing.T) {
+	ch := make(chan Event, 16)
+	kp, err := CreatePairWithEvents(PrefixByteUser, ChannelHandler(ch))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	pk, _ := kp.PublicKey()
+	seed, _ := kp.Seed()
+	seed = append([]byte{}, seed...)
+	kp.Sign([]byte("hello"))
+	kp.Verify([]byte("hello"), nil)
+	kp.Wipe()
+
+	loaded, err := FromSeedWithEvents(seed, ChannelHandler(ch))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	public, _ := loaded.PublicOnly()
+	if _, err := public.Sign([]byte("hello")); err == nil {
+		t.Fatalf("Expected public only key to fail signing")
+	}
+	close(ch)
+
+	want := []EventType{EventCreated, EventExported, EventSigned, EventWiped, EventLoaded}
+	var got []EventType
+	for e := range ch {
+		if e.PublicKey != pk {
+			t.Fatalf("Expected event for %q, got %q", pk, e.PublicKey)
+		}
+		if e.Type == EventSigned && e.Size != 5 {
+			t.Fatalf("Expected size 5, got %d", e.Size)
+		}
+		got = append(got, e.Type)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected events %v, got %v", want, got)
+		}
+	}
+
+	now := time.Unix(1700000000, 0)
+	var stamped []Event
+	loaded, _ = FromSeedWithEvents(seed, func(e Event) { stamped = append(stamped, e) })
+	loaded.Clock = ClockFunc(func() time.Time { return now })
+	loaded.Sign([]byte("hello"))
+	if len(stamped) != 2 || !stamped[1].Time.Equal(now) {
+		t.Fatalf("Expected the signing event at %v, got %v", now, stamped)
+	}
+}
+
+func TestClassifiedKeyPair(t *testing.T) {
+	var events []Event
+	op, err := CreateClassifiedPair(PrefixByteOperator, DefaultClassification(PrefixByteOperator), func(e Event) {
+		events = append(events, e)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ClassificationOf(op) != ClassRoot {
+		t.Fatalf("Expected %v, got %v", ClassRoot, ClassificationOf(op))
+	}
+	if _, err := op.Seed(); err != ErrExportNotAllowed {
+		t.Fatalf("Expected %v, got %v", ErrExportNotAllowed, err)
+	}
+	if _, err := op.PrivateKey(); err != ErrExportNotAllowed {
+		t.Fatalf("Expected %v, got %v", ErrExportNotAllowed, err)
+	}
+	// Generic serialization goes through Seed and is refused too.
+	key, _ := NewHandoverKey()
+	if _, err := SerializeState(key, op); err != ErrExportNotAllowed {
+		t.Fatalf("Expected %v, got %v", ErrExportNotAllowed, err)
+	}
+	if _, err := op.ExportSeed(""); err != ErrExportNotAllowed {
+		t.Fatalf("Expected %v, got %v", ErrExportNotAllowed, err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("Expected no events, got %v", events)
+	}
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	op.Clock = ClockFunc(func() time.Time { return now })
+	seed, err := op.ExportSeed("offline backup")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := FromSeed(seed); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events) != 1 || events[0].Type != EventExported || events[0].Reason != "offline backup" || !events[0].Time.Equal(now) {
+		t.Fatalf("Expected an export event, got %v", events)
+	}
+	if _, err := op.Sign([]byte("hello")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	user, _ := CreateUser()
+	leaf, _ := Classify(user, ClassLeaf, nil)
+	if _, err := leaf.Seed(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := Classify(user, ClassUnclassified, nil); err != ErrInvalidClassification {
+		t.Fatalf("Expected %v, got %v", ErrInvalidClassification, err)
+	}
+	if ClassificationOf(user) != ClassUnclassified {
+		t.Fatalf("Expected %v, got %v", ClassUnclassified, ClassificationOf(user))
+	}
+}
+
+func TestActivitySnapshot(t *testing.T) {
+	before := ActivitySnapshot()
+	curve, _ := CreateCurveKeys()
+	user, _ := CreateUser()
+	sig, _ := user.Sign([]byte("hello"))
+	user.Verify([]byte("hello"), sig)
+	user.Verify([]byte("other"), sig)
+	IsValidPublicKey("UNOTAKEY")
+	curve.Wipe()
+
+	after := ActivitySnapshot()
+	if after.Created["user"] != before.Created["user"]+1 || after.Created["x25519"] != before.Created["x25519"]+1 {
+		t.Fatalf("Expected key creation to be counted by type, got %v", after.Created)
+	}
+	if after.Signed != before.Signed+1 {
+		t.Fatalf("Expected %v, got %v", before.Signed+1, after.Signed)
+	}
+	if after.Verified != before.Verified+1 || after.VerifyFailures != before.VerifyFailures+1 {
+		t.Fatalf("Expected one verification and one failure, got %+v", after)
+	}
+	if after.DecodeFailures <= before.DecodeFailures {
+		t.Fatalf("Expected decode failures to be counted, got %+v", after)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys_test
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/nats-io/nkeys"
+)
+
+// exampleSeed is a fixed user seed so that the examples have stable output.
+// Never embed real seeds in source code.
+var exampleSeed = []byte("SUAAAAICAMCAKBQHBAEQUCYMBUHA6EARCIJRIFIWC4MBSGQ3DQOR4H776Y")
+
+func ExampleCreateUser() {
+	user, err := nkeys.CreateUser()
+	if err != nil {
+		panic(err)
+	}
+	defer user.Wipe()
+
+	// The public key can be shared, the seed must be kept secret.
+	public, _ := user.PublicKey()
+	seed, _ := user.Seed()
+	fmt.Println(public[:1], len(public))
+	fmt.Println(string(seed[:2]), len(seed))
+	// Output:
+	// U 56
+	// SU 58
+}
+
+func ExampleFromSeed() {
+	user, err := nkeys.FromSeed(exampleSeed)
+	if err != nil {
+		panic(err)
+	}
+	defer user.Wipe()
+
+	public, _ := user.PublicKey()
+	fmt.Println(public)
+	// Output: UAB2CB576PHBBPQ5ODORRZ2LYCMWPZGWGCN2KDK7DXOIMZASKUY3RLKK
+}
+
+func ExampleFromPublicKey() {
+	// A KeyPair made from a public key can only verify.
+	user, err := nkeys.FromPublicKey("UAB2CB576PHBBPQ5ODORRZ2LYCMWPZGWGCN2KDK7DXOIMZASKUY3RLKK")
+	if err != nil {
+		panic(err)
+	}
+	_, err = user.Sign([]byte("hello"))
+	fmt.Println(err)
+	// Output: nkeys: can not sign, no private key available
+}
+
+func ExampleKeyPair_Sign() {
+	user, _ := nkeys.FromSeed(exampleSeed)
+	defer user.Wipe()
+
+	data := []byte("hello")
+	sig, err := user.Sign(data)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(len(sig), user.Verify(data, sig))
+	fmt.Println(user.Verify([]byte("tampered"), sig))
+	// Output:
+	// 64 <nil>
+	// nkeys: signature verification failed
+}
+
+// The challenge/response flow used by NATS servers to authenticate clients:
+// the server sends a random nonce, the client signs it with its seed and
+// returns its public key and the signature, and the server verifies the
+// signature with the public key alone.
+func Example_challengeResponse() {
+	// Server: send a fresh nonce.
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+
+	// Client: sign the nonce.
+	client, _ := nkeys.FromSeed(exampleSeed)
+	defer client.Wipe()
+	public, _ := client.PublicKey()
+	sig, _ := client.Sign(nonce)
+
+	// Server: check the key type and the signature.
+	if !nkeys.IsValidPublicUserKey(public) {
+		panic("not a user key")
+	}
+	verifier, _ := nkeys.FromPublicKey(public)
+	if err := verifier.Verify(nonce, sig); err != nil {
+		fmt.Println("rejected:", err)
+		return
+	}
+	fmt.Println("authenticated", public)
+	// Output: authenticated UAB2CB576PHBBPQ5ODORRZ2LYCMWPZGWGCN2KDK7DXOIMZASKUY3RLKK
+}
+
+func ExampleParseDecoratedUserNKey() {
+	// Creds files, as written by nsc, hold a user JWT and its seed.
+	creds := []byte(`-----BEGIN NATS USER JWT-----
+eyJ0eXAiOiJqd3QiLCJhbGciOiJlZDI1NTE5LW5rZXkifQ.e30.c2ln
+------END NATS USER JWT------
+
+************************* IMPORTANT *************************
+NKEY Seed printed below can be used to sign and prove identity.
+NKEYs are sensitive and should be treated as secrets.
+
+-----BEGIN USER NKEY SEED-----
+SUAAAAICAMCAKBQHBAEQUCYMBUHA6EARCIJRIFIWC4MBSGQ3DQOR4H776Y
+------END USER NKEY SEED------
+
+*************************************************************
+`)
+	jwt, err := nkeys.ParseDecoratedJWT(creds)
+	if err != nil {
+		panic(err)
+	}
+	user, err := nkeys.ParseDecoratedUserNKey(creds)
+	if err != nil {
+		panic(err)
+	}
+	defer user.Wipe()
+
+	public, _ := user.PublicKey()
+	fmt.Println(jwt[:10])
+	fmt.Println(public)
+	// Output:
+	// eyJ0eXAiOi
+	// UAB2CB576PHBBPQ5ODORRZ2LYCMWPZGWGCN2KDK7DXOIMZASKUY3RLKK
+}
+
+func ExampleVerifyWithPolicy() {
+	user, _ := nkeys.FromSeed(exampleSeed)
+	defer user.Wipe()
+	public, _ := user.PublicKey()
+	sig, _ := user.Sign([]byte("hello"))
+
+	// Only accept signatures made by account keys.
+	vp := &nkeys.VerifyPolicy{AllowedTypes: []nkeys.PrefixByte{nkeys.PrefixByteAccount}}
+	fmt.Println(nkeys.VerifyWithPolicy(vp, public, []byte("hello"), sig))
+	fmt.Println(nkeys.VerifyWithPolicy(nil, public, []byte("hello"), sig))
+	// Output:
+	// nkeys: key type not allowed by policy
+	// <nil>
+}
+
+func ExampleCreateCurveKeys() {
+	sender, _ := nkeys.CreateCurveKeys()
+	receiver, _ := nkeys.CreateCurveKeys()
+	defer sender.Wipe()
+	defer receiver.Wipe()
+	senderPub, _ := sender.PublicKey()
+	receiverPub, _ := receiver.PublicKey()
+
+	sealed, _ := sender.Seal([]byte("secret"), receiverPub)
+	opened, err := receiver.Open(sealed, senderPub)
+	fmt.Println(string(opened), err)
+	// Output: secret <nil>
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+	"golang.org/x/crypto/hkdf"
+)
+
+// A seed exchange hands a seed from a sender to a receiver over an
+// untrusted channel such as chat:
+//
+//  1. The receiver calls NewSeedExchange and pastes Request to the sender.
+//     The one-time Code is passed separately, e.g. read out over the phone.
+//  2. The sender calls SealForExchange with the request, the code and the
+//     seed, and pastes the result back.
+//  3. The receiver calls Open on the same SeedExchange.
+//
+// The code authenticates both messages, so that whoever relays them can not
+// substitute their own key or seed. The ephemeral curve key of the receiver
+// only lives in memory and is wiped after a successful Open.
+
+// SeedExchangeVersionV1 prefixes seed exchange requests and replies.
+const SeedExchangeVersionV1 = "nkx1"
+
+const (
+	exchangeCodeLen  = 16
+	exchangeCodeSalt = "nkeys-seed-exchange-v1"
+)
+
+// SeedExchange is the receiving side of a seed exchange.
+type SeedExchange struct {
+	mu     sync.Mutex
+	kp     KeyPair
+	public string
+	code   string
+}
+
+// NewSeedExchange creates an ephemeral curve key and a one-time code for
+// receiving a seed.
+func NewSeedExchange() (*SeedExchange, error) {
+	kp, err := CreateCurveKeys()
+	if err != nil {
+		return nil, err
+	}
+	public, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	raw := make([]byte, exchangeCodeLen*5/8)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return nil, err
+	}
+	return &SeedExchange{kp: kp, public: public, code: b32Enc.EncodeToString(raw)}, nil
+}
+
+// Code returns the one-time code, grouped for reading out loud.
+func (x *SeedExchange) Code() string {
+	var groups []string
+	for i := 0; i < len(x.code); i += 4 {
+		groups = append(groups, x.code[i:i+4])
+	}
+	return strings.Join(groups, "-")
+}
+
+// Request returns the text to send to the holder of the seed.
+func (x *SeedExchange) Request() string {
+	key := exchangeKey(x.code, x.public)
+	e := canonical.NewEncoder("nkeys.SeedExchangeRequest")
+	e.Text(x.public)
+	e.Blob(exchangeTag(key, "request", x.public))
+	return encodeExchange(e.Bytes())
+}
+
+// Open decrypts a reply made by SealForExchange and returns the seed. The
+// exchange can not be used again after a successful Open.
+func (x *SeedExchange) Open(reply string) ([]byte, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.kp == nil {
+		return nil, ErrInvalidSeedExchange
+	}
+	data, err := decodeExchange(reply)
+	if err != nil {
+		return nil, err
+	}
+	d := canonical.NewDecoder("nkeys.SeedExchangeReply", data)
+	sender, sealed, tag := d.Text(), d.Blob(), d.Blob()
+	if d.Finish() != nil || !IsValidPublicCurveKey(sender) {
+		return nil, ErrInvalidSeedExchange
+	}
+	key := exchangeKey(x.code, x.public)
+	if !hmac.Equal(tag, exchangeTag(key, "reply", sender, string(sealed))) {
+		return nil, ErrInvalidExchangeCode
+	}
+	seed, err := x.kp.Open(sealed, sender)
+	if err != nil {
+		return nil, ErrInvalidSeedExchange
+	}
+	if _, _, err := DecodeSeed(seed); err != nil {
+		wipeBytes(seed)
+		return nil, ErrInvalidSeedExchange
+	}
+	x.kp.Wipe()
+	x.kp = nil
+	return seed, nil
+}
+
+// SealForExchange encrypts seed to the receiver of request, after checking
+// that the request was made with code.
+func SealForExchange(request, code string, seed []byte) (string, error) {
+	if _, _, err := DecodeSeed(seed); err != nil {
+		return "", err
+	}
+	data, err := decodeExchange(request)
+	if err != nil {
+		return "", err
+	}
+	d := canonical.NewDecoder("nkeys.SeedExchangeRequest", data)
+	receiver, tag := d.Text(), d.Blob()
+	if d.Finish() != nil || !IsValidPublicCurveKey(receiver) {
+		return "", ErrInvalidSeedExchange
+	}
+	key := exchangeKey(normalizeExchangeCode(code), receiver)
+	if !hmac.Equal(tag, exchangeTag(key, "request", receiver)) {
+		return "", ErrInvalidExchangeCode
+	}
+
+	ephemeral, err := CreateCurveKeys()
+	if err != nil {
+		return "", err
+	}
+	defer ephemeral.Wipe()
+	sender, err := ephemeral.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := ephemeral.Seal(seed, receiver)
+	if err != nil {
+		return "", err
+	}
+	e := canonical.NewEncoder("nkeys.SeedExchangeReply")
+	e.Text(sender)
+	e.Blob(sealed)
+	e.Blob(exchangeTag(key, "reply", sender, string(sealed)))
+	return encodeExchange(e.Bytes()), nil
+}
+
+// normalizeExchangeCode accepts codes typed in lower case or without the
+// separators.
+func normalizeExchangeCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+func exchangeKey(code, receiver string) []byte {
+	key := make([]byte, sha256.Size)
+	r := hkdf.New(sha256.New, []byte(code), []byte(exchangeCodeSalt), []byte(receiver))
+	io.ReadFull(r, key)
+	return key
+}
+
+func exchangeTag(key []byte, kind string, fields ...string) []byte {
+	e := canonical.NewEncoder("nkeys.SeedExchangeTag")
+	e.Text(kind)
+	e.Strings(fields)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(e.Bytes())
+	return mac.Sum(nil)
+}
+
+func encodeExchange(data []byte) string {
+	return SeedExchangeVersionV1 + "." + base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeExchange(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, SeedExchangeVersionV1+".") {
+		return nil, ErrInvalidSeedExchange
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s[len(SeedExchangeVersionV1)+1:])
+	if err != nil {
+		return nil, ErrInvalidSeedExchange
+	}
+	return data, nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultExecSignerTimeout bounds a signing command when no timeout is set.
+const DefaultExecSignerTimeout = 10 * time.Second
+
+// ExecSignerPublicKeyEnv is set in the environment of signing commands to
+// the public key whose signature is requested.
+const ExecSignerPublicKeyEnv = "NKEYS_PUBLIC_KEY"
+
+// maxExecSignerOutput bounds what is read from a signing command.
+const maxExecSignerOutput = 4096
+
+// ExecSignerOptions configures the command run by an exec signer.
+type ExecSignerOptions struct {
+	Command string
+	Args    []string
+	// Timeout kills the command if it runs longer. It defaults to
+	// DefaultExecSignerTimeout.
+	Timeout time.Duration
+}
+
+// execSigner is a public only ed25519 KeyPair that signs by running a
+// command.
+type execSigner struct {
+	public string
+	pub    KeyPair
+	opts   ExecSignerOptions
+}
+
+// NewExecSigner returns a KeyPair for the ed25519 public key that signs by
+// running an external command, for signing infrastructure without Go
+// bindings. The command receives the input on stdin and
+// ExecSignerPublicKeyEnv in its environment, and must print the signature
+// as raw bytes, base64 or hex. Signatures are verified before they are
+// returned, so a misbehaving command can not produce bad signatures.
+func NewExecSigner(public string, opts ExecSignerOptions) (KeyPair, error) {
+	pub, err := FromPublicKey(public)
+	if err != nil {
+		return nil, err
+	}
+	if AlgorithmOf(Prefix(public)) != AlgorithmEd25519 {
+		return nil, ErrInvalidCurveKeyOperation
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultExecSignerTimeout
+	}
+	opts.Args = append([]string{}, opts.Args...)
+	return &execSigner{public: public, pub: pub, opts: opts}, nil
+}
+
+// Seed will return an error since the seed is held by the command.
+func (e *execSigner) Seed() ([]byte, error) {
+	return nil, ErrPublicKeyOnly
+}
+
+// PublicKey will return the encoded public key.
+func (e *execSigner) PublicKey() (string, error) {
+	return e.public, nil
+}
+
+// PrivateKey will return an error since the key is held by the command.
+func (e *execSigner) PrivateKey() ([]byte, error) {
+	return nil, ErrPublicKeyOnly
+}
+
+// Sign runs the command and returns the verified signature.
+func (e *execSigner) Sign(input []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.opts.Timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.opts.Command, e.opts.Args...)
+	cmd.Env = append(os.Environ(), ExecSignerPublicKeyEnv+"="+e.public)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &limitedWriter{w: &stdout, n: maxExecSignerOutput}
+	cmd.Stderr = &limitedWriter{w: &stderr, n: maxExecSignerOutput}
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("nkeys: %s: %w: %s", e.opts.Command, err, msg)
+		}
+		return nil, fmt.Errorf("nkeys: %s: %w", e.opts.Command, err)
+	}
+	sig, err := parseSignerOutput(stdout.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if err := e.pub.Verify(input, sig); err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+// parseSignerOutput accepts a raw signature, or one encoded with base64 or
+// hex followed by optional whitespace.
+func parseSignerOutput(out []byte) ([]byte, error) {
+	if len(out) == ed25519.SignatureSize {
+		return append([]byte{}, out...), nil
+	}
+	text := string(bytes.TrimSpace(out))
+	for _, dec := range []func(string) ([]byte, error){
+		base64.StdEncoding.DecodeString,
+		base64.RawStdEncoding.DecodeString,
+		base64.URLEncoding.DecodeString,
+		base64.RawURLEncoding.DecodeString,
+		hex.DecodeString,
+	} {
+		if sig, err := dec(text); err == nil && len(sig) == ed25519.SignatureSize {
+			return sig, nil
+		}
+	}
+	return nil, ErrInvalidSignerOutput
+}
+
+// Verify will verify the input against a signature utilizing the public key.
+func (e *execSigner) Verify(input []byte, sig []byte) error {
+	return e.pub.Verify(input, sig)
+}
+
+// PublicOnly returns a public only KeyPair.
+func (e *execSigner) PublicOnly() (KeyPair, error) {
+	return e.pub.PublicOnly()
+}
+
+// Wipe does nothing, the signer holds no secrets.
+func (e *execSigner) Wipe() {}
+
+// Seal is only supported on CurveKeyPair
+func (e *execSigner) Seal(input []byte, recipient string) ([]byte, error) {
+	return nil, ErrInvalidNKeyOperation
+}
+
+// SealWithRand is only supported on CurveKeyPair
+func (e *execSigner) SealWithRand(input []byte, recipient string, rr io.Reader) ([]byte, error) {
+	return nil, ErrInvalidNKeyOperation
+}
+
+// Open is only supported on CurveKey
+func (e *execSigner) Open(input []byte, sender string) ([]byte, error) {
+	return nil, ErrInvalidNKeyOperation
+}
+
+// limitedWriter fails writes past n bytes, so a runaway command can not
+// exhaust memory.
+type limitedWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > l.n {
+		return 0, ErrInvalidSignerOutput
+	}
+	l.n -= len(p)
+	return l.w.Write(p)
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Encrypted files start with a header holding the public curve key of the
+// sender and a random file key sealed to the recipient with xkeys. The
+// content follows in chunks encrypted with ChaCha20-Poly1305 under a key
+// derived from the file key and the header. Chunk nonces hold a counter and
+// a flag marking the last chunk, so reordered, dropped or truncated chunks
+// fail to decrypt.
+
+// FileVersionV1 starts files produced by EncryptStream.
+const FileVersionV1 = "nkf1"
+
+const (
+	fileChunkSize = 64 * 1024
+	fileKeyLen    = chacha20poly1305.KeySize
+	fileKeySalt   = "nkeys-file-v1"
+	// maxFileHeaderField bounds header fields read before authentication.
+	maxFileHeaderField = 1024
+)
+
+// EncryptStream encrypts everything read from r to the public curve key
+// recipient and writes it to w. The sender curve KeyPair is revealed to the
+// recipient by DecryptStream; use CreateCurveKeys for an anonymous sender.
+func EncryptStream(w io.Writer, r io.Reader, sender KeyPair, recipient string) error {
+	senderPub, err := sender.PublicKey()
+	if err != nil {
+		return err
+	}
+	fileKey := make([]byte, fileKeyLen)
+	if _, err := io.ReadFull(rand.Reader, fileKey); err != nil {
+		return err
+	}
+	defer wipeBytes(fileKey)
+	sealed, err := sender.Seal(fileKey, recipient)
+	if err != nil {
+		return err
+	}
+
+	header := []byte(FileVersionV1)
+	header = appendField(header, []byte(senderPub))
+	header = appendField(header, sealed)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	aead, err := fileAEAD(fileKey, header)
+	if err != nil {
+		return err
+	}
+
+	// Read one chunk ahead to know which chunk is the last.
+	buf := make([]byte, fileChunkSize)
+	next := make([]byte, fileChunkSize)
+	n, err := io.ReadFull(r, buf)
+	for counter := uint64(0); ; counter++ {
+		last := true
+		var m int
+		if err == nil {
+			m, err = io.ReadFull(r, next)
+			last = m == 0 && err == io.EOF
+		}
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		ct := aead.Seal(nil, fileNonce(counter, last), buf[:n], nil)
+		if _, err := w.Write(binary.BigEndian.AppendUint32(nil, uint32(len(ct)))); err != nil {
+			return err
+		}
+		if _, err := w.Write(ct); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		buf, next, n = next, buf, m
+	}
+}
+
+// DecryptStream decrypts a stream produced by EncryptStream with the
+// recipient curve KeyPair, writes the content to w and returns the public
+// curve key of the sender. Content is written as it is authenticated, so
+// on error whatever was written to w must be discarded.
+func DecryptStream(w io.Writer, r io.Reader, recipient KeyPair) (string, error) {
+	magic := make([]byte, len(FileVersionV1))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != FileVersionV1 {
+		return "", ErrInvalidEncryptedFile
+	}
+	senderPub, err := readField(r)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := readField(r)
+	if err != nil {
+		return "", err
+	}
+	fileKey, err := recipient.Open(sealed, string(senderPub))
+	if err != nil {
+		return "", err
+	}
+	defer wipeBytes(fileKey)
+	header := []byte(FileVersionV1)
+	header = appendField(header, senderPub)
+	header = appendField(header, sealed)
+	aead, err := fileAEAD(fileKey, header)
+	if err != nil {
+		return "", ErrInvalidEncryptedFile
+	}
+
+	var size [4]byte
+	buf := make([]byte, fileChunkSize+aead.Overhead())
+	// A failed Open may overwrite its destination, so decrypt into a
+	// separate buffer to keep the ciphertext for the second attempt.
+	out := make([]byte, fileChunkSize)
+	for counter := uint64(0); ; counter++ {
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return "", ErrInvalidEncryptedFile
+		}
+		n := binary.BigEndian.Uint32(size[:])
+		if int(n) > len(buf) {
+			return "", ErrInvalidEncryptedFile
+		}
+		if _, err := io.ReadFull(r, buf[:n]); err != nil {
+			return "", ErrInvalidEncryptedFile
+		}
+		// A chunk that only opens as the last one ends the stream.
+		last := false
+		plain, err := aead.Open(out[:0], fileNonce(counter, false), buf[:n], nil)
+		if err != nil {
+			last = true
+			if plain, err = aead.Open(out[:0], fileNonce(counter, true), buf[:n], nil); err != nil {
+				return "", ErrInvalidEncryptedFile
+			}
+		}
+		if _, err := w.Write(plain); err != nil {
+			return "", err
+		}
+		if last {
+			var extra [1]byte
+			if _, err := io.ReadFull(r, extra[:]); !errors.Is(err, io.EOF) {
+				return "", ErrInvalidEncryptedFile
+			}
+			return string(senderPub), nil
+		}
+	}
+}
+
+func fileAEAD(fileKey, header []byte) (cipher.AEAD, error) {
+	salt := sha256.Sum256(header)
+	key := make([]byte, chacha20poly1305.KeySize)
+	defer wipeBytes(key)
+	kdf := hkdf.New(sha256.New, fileKey, salt[:], []byte(fileKeySalt))
+	if _, err := io.ReadFull(kdf, key); err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
+
+// fileNonce is the big endian chunk counter followed by the last chunk flag.
+func fileNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+func appendField(b, field []byte) []byte {
+	return append(binary.BigEndian.AppendUint16(b, uint16(len(field))), field...)
+}
+
+func readField(r io.Reader) ([]byte, error) {
+	var size [2]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, ErrInvalidEncryptedFile
+	}
+	n := binary.BigEndian.Uint16(size[:])
+	if n > maxFileHeaderField {
+		return nil, ErrInvalidEncryptedFile
+	}
+	field := make([]byte, n)
+	if _, err := io.ReadFull(r, field); err != nil {
+		return nil, ErrInvalidEncryptedFile
+	}
+	return field, nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/rand"
+	"io"
+)
+
+// frozen is an immutable ed25519 KeyPair. Everything is computed by Freeze,
+// so no method takes a lock.
+type frozen struct {
+	backend Backend
+	public  string
+	pub     []byte
+	// seed and priv are nil for public only keys.
+	seed []byte
+	priv []byte
+}
+
+// Freeze returns an immutable copy of kp that is safe for unbounded
+// concurrent use without any internal locking, for hot signing and
+// verification loops. The encoded keys and the expanded private key are
+// computed once up front. kp itself is left untouched. Curve keys hold no
+// lazily computed state and are returned as a copy.
+func Freeze(kp KeyPair) (KeyPair, error) {
+	public, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if Prefix(public) == PrefixByteCurve {
+		seed, err := kp.Seed()
+		if err == ErrPublicKeyOnly {
+			return FromPublicKey(public)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return fromCurveSeed(seed)
+	}
+	raw, err := Decode(Prefix(public), []byte(public))
+	if err != nil {
+		return nil, err
+	}
+	f := &frozen{backend: currentBackend(), public: public, pub: raw}
+	seed, err := kp.Seed()
+	if err == ErrPublicKeyOnly {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, rawSeed, err := DecodeSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(rawSeed)
+	if _, f.priv, err = f.backend.NewKeyFromSeed(rawSeed); err != nil {
+		return nil, err
+	}
+	f.seed = append([]byte{}, seed...)
+	return f, nil
+}
+
+// Seed will return the encoded seed.
+func (f *frozen) Seed() ([]byte, error) {
+	if f.seed == nil {
+		return nil, ErrPublicKeyOnly
+	}
+	return f.seed, nil
+}
+
+// PublicKey will return the encoded public key.
+func (f *frozen) PublicKey() (string, error) {
+	return f.public, nil
+}
+
+// PrivateKey will return the encoded private key.
+func (f *frozen) PrivateKey() ([]byte, error) {
+	if f.priv == nil {
+		return nil, ErrPublicKeyOnly
+	}
+	return Encode(PrefixBytePrivate, f.priv)
+}
+
+// Sign will sign the input with the expanded private key.
+func (f *frozen) Sign(input []byte) ([]byte, error) {
+	if f.priv == nil {
+		return nil, ErrCannotSign
+	}
+	return f.backend.Sign(f.priv, input)
+}
+
+// Verify will verify the input against a signature utilizing the public key.
+func (f *frozen) Verify(input []byte, sig []byte) error {
+	if !f.backend.Verify(f.pub, input, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// PublicOnly returns a frozen KeyPair holding only the public key.
+func (f *frozen) PublicOnly() (KeyPair, error) {
+	return &frozen{backend: f.backend, public: f.public, pub: f.pub}, nil
+}
+
+// Wipe will randomize the seed and private key. Unlike the other methods it
+// must not be called concurrently with Sign.
+func (f *frozen) Wipe() {
+	io.ReadFull(rand.Reader, f.seed)
+	io.ReadFull(rand.Reader, f.priv)
+}
+
+// Seal is only supported on CurveKeyPair
+func (f *frozen) Seal(input []byte, recipient string) ([]byte, error) {
+	return nil, ErrInvalidNKeyOperation
+}
+
+// SealWithRand is only supported on CurveKeyPair
+func (f *frozen) SealWithRand(input []byte, recipient string, rr io.Reader) ([]byte, error) {
+	return nil, ErrInvalidNKeyOperation
+}
+
+// Open is only supported on CurveKey
+func (f *frozen) Open(input []byte, sender string) ([]byte, error) {
+	return nil, ErrInvalidNKeyOperation
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Public key records keep public keys in version control, one key per file
+// with fixed field order so that pull requests show meaningful diffs:
+//
+//	# nkeys public key record v1
+//	name: acme-prod
+//	type: account
+//	public_key: ADB...
+//	fingerprint: SHA256:...
+//	comment: production account
+//
+// Files are named after the type and fingerprint of the key, e.g.
+// account-3f1c0e9ad2b47765.pub, so that renaming a key does not rename its
+// file and two files can never hold the same key.
+
+// KeyRecordExt is the file extension of public key records.
+const KeyRecordExt = ".pub"
+
+const keyRecordHeader = "# nkeys public key record v1"
+
+// KeyRecord is a public key with metadata for version control.
+type KeyRecord struct {
+	PublicKey string
+	// Name and Comment are single lines of free text.
+	Name    string
+	Comment string
+}
+
+// Type returns the key type, e.g. "account".
+func (r *KeyRecord) Type() string {
+	return Prefix(r.PublicKey).String()
+}
+
+// FileName returns the file name of the record.
+func (r *KeyRecord) FileName() string {
+	raw, err := decode([]byte(r.PublicKey))
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(raw[1:])
+	return r.Type() + "-" + hex.EncodeToString(sum[:8]) + KeyRecordExt
+}
+
+func (r *KeyRecord) check() error {
+	if err := checkPublicKeyArg(r.PublicKey); err != nil {
+		return err
+	}
+	if !IsValidPublicKey(r.PublicKey) {
+		return ErrInvalidPublicKey
+	}
+	if strings.ContainsAny(r.Name, "\r\n") || strings.ContainsAny(r.Comment, "\r\n") {
+		return ErrInvalidKeyRecord
+	}
+	return nil
+}
+
+// MarshalText encodes the record in the format shown above.
+func (r *KeyRecord) MarshalText() ([]byte, error) {
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+	fp, err := Fingerprint(r.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	b.WriteString(keyRecordHeader + "\n")
+	writeRecordField(&b, "name", r.Name)
+	writeRecordField(&b, "type", r.Type())
+	writeRecordField(&b, "public_key", r.PublicKey)
+	writeRecordField(&b, "fingerprint", fp)
+	writeRecordField(&b, "comment", r.Comment)
+	return b.Bytes(), nil
+}
+
+// writeRecordField avoids trailing whitespace for empty values, which
+// editors and linters tend to strip.
+func writeRecordField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name + ":")
+	if value = strings.TrimSpace(value); value != "" {
+		b.WriteString(" " + value)
+	}
+	b.WriteByte('\n')
+}
+
+// UnmarshalText parses a record written by MarshalText. The fields must be
+// in order, and the type and fingerprint must match the public key.
+func (r *KeyRecord) UnmarshalText(data []byte) error {
+	fields := []string{"name", "type", "public_key", "fingerprint", "comment"}
+	values := make([]string, 0, len(fields))
+	s := bufio.NewScanner(bytes.NewReader(data))
+	if !s.Scan() || s.Text() != keyRecordHeader {
+		return ErrInvalidKeyRecord
+	}
+	for _, f := range fields {
+		if !s.Scan() {
+			return ErrInvalidKeyRecord
+		}
+		line := strings.TrimRight(s.Text(), "\r")
+		if !strings.HasPrefix(line, f+":") {
+			return ErrInvalidKeyRecord
+		}
+		values = append(values, strings.TrimSpace(line[len(f)+1:]))
+	}
+	if s.Scan() || s.Err() != nil {
+		return ErrInvalidKeyRecord
+	}
+	rec := KeyRecord{Name: values[0], PublicKey: values[2], Comment: values[4]}
+	if err := rec.check(); err != nil {
+		return err
+	}
+	if fp, _ := Fingerprint(rec.PublicKey); values[1] != rec.Type() || values[3] != fp {
+		return ErrInvalidKeyRecord
+	}
+	*r = rec
+	return nil
+}
+
+// ExportKeyRecords writes one file per record to dir, replacing existing
+// files of the same keys.
+func ExportKeyRecords(dir string, records ...KeyRecord) error {
+	for i := range records {
+		data, err := records[i].MarshalText()
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(filepath.Join(dir, records[i].FileName()), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportKeyRecords reads and validates every record in dir, sorted by file
+// name. Files must be named by KeyRecord.FileName, so that a record edited
+// to hold another key, or a duplicate, is rejected. Failures are reported
+// as a *TrustSourceError naming the file.
+func ImportKeyRecords(dir string) ([]KeyRecord, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+KeyRecordExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	var records []KeyRecord
+	for _, path := range matches {
+		rec, err := readKeyRecord(path)
+		if err != nil {
+			return nil, &TrustSourceError{Source: path, Err: err}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func readKeyRecord(path string) (KeyRecord, error) {
+	var rec KeyRecord
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+	if err := rec.UnmarshalText(data); err != nil {
+		return rec, err
+	}
+	if filepath.Base(path) != rec.FileName() {
+		return rec, fmt.Errorf("%w: file name does not match the key, expected %s", ErrInvalidKeyRecord, rec.FileName())
+	}
+	return rec, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeyRecords(t *testing.T) {
+	op, _ := CreateOperator()
+	acc, _ := CreateAccount()
+	opk, _ := op.PublicKey()
+	apk, _ := acc.PublicKey()
+
+	dir := t.TempDir()
+	err := ExportKeyRecords(dir,
+		KeyRecord{PublicKey: opk, Name: "acme", Comment: "root of trust"},
+		KeyRecord{PublicKey: apk, Name: "acme-prod"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	records, err := ImportKeyRecords(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Sorted by file name, so by type first.
+	if len(records) != 2 || records[0].PublicKey != apk || records[1].Comment != "root of trust" {
+		t.Fatalf("Unexpected records %+v", records)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, records[0].FileName()))
+	fp, _ := Fingerprint(apk)
+	want := "# nkeys public key record v1\nname: acme-prod\ntype: account\npublic_key: " + apk +
+		"\nfingerprint: " + fp + "\ncomment:\n"
+	if string(data) != want {
+		t.Fatalf("Expected %q, got %q", want, data)
+	}
+
+	ts, err := NewTrustStore(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !ts.IsTrusted(opk) || !ts.IsTrusted(apk) {
+		t.Fatalf("Unexpected trusted keys %v", ts.Keys())
+	}
+
+	// A record edited to hold another key no longer matches its file name.
+	other, _ := CreateAccount()
+	rec := KeyRecord{PublicKey: "", Name: "acme-prod"}
+	rec.PublicKey, _ = other.PublicKey()
+	data, _ = rec.MarshalText()
+	os.WriteFile(filepath.Join(dir, records[0].FileName()), data, 0644)
+	var serr *TrustSourceError
+	if _, err := ImportKeyRecords(dir); !errors.As(err, &serr) || !errors.Is(err, ErrInvalidKeyRecord) {
+		t.Fatalf("Expected a TrustSourceError for %v, got %v", ErrInvalidKeyRecord, err)
+	}
+
+	tampered := []byte(strings.Replace(want, "type: account", "type: operator", 1))
+	if err := rec.UnmarshalText(tampered); err != ErrInvalidKeyRecord {
+		t.Fatalf("Expected %v, got %v", ErrInvalidKeyRecord, err)
+	}
+	if _, err := (&KeyRecord{PublicKey: apk, Name: "a\nb"}).MarshalText(); err != ErrInvalidKeyRecord {
+		t.Fatalf("Expected %v, got %v", ErrInvalidKeyRecord, err)
+	}
+}
@@ -2,6 +2,7 @@ module github.com/nats-io/nkeys
 
 go 1.19
 
-require golang.org/x/crypto v0.6.0
-
-require golang.org/x/sys v0.5.0 // indirect
+require (
+	golang.org/x/crypto v0.6.0
+	golang.org/x/sys v0.5.0
+)
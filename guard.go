@@ -0,0 +1,129 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"io"
+)
+
+const (
+	MaterialPrivateKey Material = "private_key"
+	MaterialEnvelope   Material = "envelope"
+)
+
+// DetectMaterial reports whether data is, or contains, secret nkey
+// material or a signed envelope of this package, and which. Seeds and
+// private keys are found anywhere in data, e.g. inside a JSON document;
+// encrypted seeds and envelopes must be the whole of data. Public keys are
+// not reported, since they are commonly signed. It returns "" if nothing
+// was found.
+func DetectMaterial(data []byte) Material {
+	if IsEncryptedSeed(data) {
+		return MaterialEncryptedSeed
+	}
+	var env Envelope
+	if env.UnmarshalBinary(data) == nil && len(env.Signatures) > 0 {
+		return MaterialEnvelope
+	}
+	for _, tok := range bytes.FieldsFunc(data, isNotBase32) {
+		switch len(tok) {
+		case EncodedSeedLen:
+			if _, raw, err := DecodeSeed(tok); err == nil {
+				wipeBytes(raw)
+				return MaterialSeed
+			}
+		case EncodedPrivateKeyLen, EncodedCurvePrivateKeyLen:
+			if raw, err := Decode(PrefixBytePrivate, tok); err == nil {
+				wipeBytes(raw)
+				return MaterialPrivateKey
+			}
+		}
+	}
+	return ""
+}
+
+// GuardKeyPair is a KeyPair that refuses to sign nkey material, see
+// DetectMaterial. A service that signs whatever its callers send can
+// otherwise be tricked into signing a seed, which then shows up in logs and
+// audit trails next to a valid signature, or into countersigning an
+// envelope it never meant to endorse.
+type GuardKeyPair struct {
+	kp KeyPair
+}
+
+// WithArtifactGuard returns a KeyPair that delegates to kp but refuses to
+// sign nkey material with ErrSigningMaterial.
+func WithArtifactGuard(kp KeyPair) *GuardKeyPair {
+	return &GuardKeyPair{kp}
+}
+
+// Sign will sign the input unless it holds nkey material.
+func (g *GuardKeyPair) Sign(input []byte) ([]byte, error) {
+	if DetectMaterial(input) != "" {
+		return nil, ErrSigningMaterial
+	}
+	return g.kp.Sign(input)
+}
+
+// SignUnchecked will sign the input without looking at it, for callers
+// that deliberately sign nkey material.
+func (g *GuardKeyPair) SignUnchecked(input []byte) ([]byte, error) {
+	return g.kp.Sign(input)
+}
+
+// Seed will return the seed of the wrapped KeyPair.
+func (g *GuardKeyPair) Seed() ([]byte, error) {
+	return g.kp.Seed()
+}
+
+// PublicKey will return the encoded public key.
+func (g *GuardKeyPair) PublicKey() (string, error) {
+	return g.kp.PublicKey()
+}
+
+// PrivateKey will return the private key of the wrapped KeyPair.
+func (g *GuardKeyPair) PrivateKey() ([]byte, error) {
+	return g.kp.PrivateKey()
+}
+
+// Verify will verify the input against a signature.
+func (g *GuardKeyPair) Verify(input []byte, sig []byte) error {
+	return g.kp.Verify(input, sig)
+}
+
+// PublicOnly returns a public only copy of the KeyPair.
+func (g *GuardKeyPair) PublicOnly() (KeyPair, error) {
+	return g.kp.PublicOnly()
+}
+
+// Wipe will wipe the wrapped KeyPair.
+func (g *GuardKeyPair) Wipe() {
+	g.kp.Wipe()
+}
+
+// Seal will seal the input.
+func (g *GuardKeyPair) Seal(input []byte, recipient string) ([]byte, error) {
+	return g.kp.Seal(input, recipient)
+}
+
+// SealWithRand will seal the input.
+func (g *GuardKeyPair) SealWithRand(input []byte, recipient string, rr io.Reader) ([]byte, error) {
+	return g.kp.SealWithRand(input, recipient, rr)
+}
+
+// Open will open the input.
+func (g *GuardKeyPair) Open(input []byte, sender string) ([]byte, error) {
+	return g.kp.Open(input, sender)
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "testing"
+
+func TestArtifactGuard(t *testing.T) {
+	kp, _ := CreateAccount()
+	seed, _ := kp.Seed()
+	priv, _ := kp.PrivateKey()
+	pk, _ := kp.PublicKey()
+	ckp, _ := CreateCurveKeys()
+	cpriv, _ := ckp.PrivateKey()
+
+	g := WithArtifactGuard(kp)
+	env := NewEnvelope([]byte("payload"))
+	if err := env.Sign(g); err != nil {
+		t.Fatalf("Expected envelopes to be signable, got %v", err)
+	}
+	signed, _ := env.MarshalBinary()
+	encrypted, _ := EncryptSeed(seed, []byte("password"), &SeedEncryptionOptions{ScryptLogN: 10})
+
+	for material, input := range map[Material][]byte{
+		MaterialSeed:          seed,
+		MaterialPrivateKey:    priv,
+		MaterialEnvelope:      signed,
+		MaterialEncryptedSeed: encrypted,
+		"":                    []byte(`{"sub":"` + pk + `"}`),
+	} {
+		if got := DetectMaterial(input); got != material {
+			t.Fatalf("Expected %q, got %q", material, got)
+		}
+	}
+	if got := DetectMaterial(cpriv); got != MaterialPrivateKey {
+		t.Fatalf("Expected %q, got %q", MaterialPrivateKey, got)
+	}
+
+	embedded := []byte(`{"note":"oops","seed":"` + string(seed) + `"}`)
+	if _, err := g.Sign(embedded); err != ErrSigningMaterial {
+		t.Fatalf("Expected %v, got %v", ErrSigningMaterial, err)
+	}
+	sig, err := g.SignUnchecked(embedded)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := g.Verify(embedded, sig); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := g.Sign([]byte("hello " + pk)); err != nil {
+		t.Fatalf("Expected public keys to be signable, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// HandoverVersionV1 prefixes state produced by SerializeState.
+const HandoverVersionV1 = "nkh1"
+
+// HandoverKeySize is the size of handover keys.
+const HandoverKeySize = chacha20poly1305.KeySize
+
+// NewHandoverKey returns a random key for SerializeState. It is meant to be
+// passed to the successor process over an inherited file descriptor or
+// similar channel that never touches disk.
+func NewHandoverKey() ([]byte, error) {
+	key := make([]byte, HandoverKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// SerializeState encrypts the seeds of the key pairs with the handover key,
+// so that a process performing a seamless restart can pass its live signing
+// identities to its successor.
+func SerializeState(handoverKey []byte, kps ...KeyPair) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(handoverKey)
+	if err != nil {
+		return nil, ErrInvalidHandoverKey
+	}
+	e := canonical.NewEncoder("nkeys.HandoverState")
+	e.Uint64(uint64(len(kps)))
+	for _, kp := range kps {
+		seed, err := kp.Seed()
+		if err != nil {
+			return nil, err
+		}
+		e.Blob(seed)
+	}
+	plain := e.Bytes()
+	defer wipeBytes(plain)
+
+	out := make([]byte, len(HandoverVersionV1)+chacha20poly1305.NonceSizeX)
+	copy(out, HandoverVersionV1)
+	if _, err := io.ReadFull(rand.Reader, out[len(HandoverVersionV1):]); err != nil {
+		return nil, err
+	}
+	nonce := out[len(HandoverVersionV1):]
+	return aead.Seal(out, nonce, plain, []byte(HandoverVersionV1)), nil
+}
+
+// RestoreState decrypts state produced by SerializeState and returns the
+// key pairs in their original order.
+func RestoreState(handoverKey []byte, state []byte) ([]KeyPair, error) {
+	aead, err := chacha20poly1305.NewX(handoverKey)
+	if err != nil {
+		return nil, ErrInvalidHandoverKey
+	}
+	hdr := len(HandoverVersionV1) + chacha20poly1305.NonceSizeX
+	if len(state) < hdr+aead.Overhead() {
+		return nil, ErrInvalidHandoverState
+	}
+	if !bytes.Equal(state[:len(HandoverVersionV1)], []byte(HandoverVersionV1)) {
+		return nil, ErrInvalidEncVersion
+	}
+	plain, err := aead.Open(nil, state[len(HandoverVersionV1):hdr], state[hdr:], []byte(HandoverVersionV1))
+	if err != nil {
+		return nil, ErrInvalidHandoverState
+	}
+	defer wipeBytes(plain)
+
+	d := canonical.NewDecoder("nkeys.HandoverState", plain)
+	n := d.Uint64()
+	var kps []KeyPair
+	for i := uint64(0); i < n && d.Err() == nil; i++ {
+		seed := d.Blob()
+		if d.Err() != nil {
+			break
+		}
+		kp, err := FromSeed(seed)
+		if err != nil {
+			wipeKeyPairs(kps)
+			return nil, err
+		}
+		kps = append(kps, kp)
+	}
+	if err := d.Finish(); err != nil {
+		wipeKeyPairs(kps)
+		return nil, ErrInvalidHandoverState
+	}
+	return kps, nil
+}
+
+func wipeKeyPairs(kps []KeyPair) {
+	for _, kp := range kps {
+		kp.Wipe()
+	}
+}
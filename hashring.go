@@ -0,0 +1,147 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultVirtualNodes is the number of points per bucket used when
+// NewConsistentHash is given zero.
+const DefaultVirtualNodes = 128
+
+// ConsistentHash maps public keys to buckets, such as shards or servers,
+// so that adding or removing a bucket only moves the keys of that bucket.
+//
+// The mapping only depends on the bucket names and the number of virtual
+// nodes, so every process, and every implementation following the same
+// rules, agrees on it: the point of virtual node i of a bucket is the first
+// 8 bytes, big endian, of SHA-256 of the bucket name, "#" and i in decimal.
+// A key is hashed the same way from its encoded public key and belongs to
+// the bucket owning the first point at or after it, wrapping around.
+type ConsistentHash struct {
+	mu       sync.RWMutex
+	replicas int
+	points   []uint64
+	owners   map[uint64]string
+	buckets  map[string]struct{}
+}
+
+// NewConsistentHash creates a ConsistentHash with replicas virtual nodes
+// per bucket holding the given buckets.
+func NewConsistentHash(replicas int, buckets ...string) *ConsistentHash {
+	if replicas <= 0 {
+		replicas = DefaultVirtualNodes
+	}
+	ch := &ConsistentHash{
+		replicas: replicas,
+		owners:   make(map[uint64]string),
+		buckets:  make(map[string]struct{}),
+	}
+	ch.Add(buckets...)
+	return ch
+}
+
+// Add adds buckets. Buckets that are already present are ignored.
+func (ch *ConsistentHash) Add(buckets ...string) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.add(buckets)
+	ch.rebuild()
+}
+
+func (ch *ConsistentHash) add(buckets []string) {
+	for _, b := range buckets {
+		if _, ok := ch.buckets[b]; ok {
+			continue
+		}
+		ch.buckets[b] = struct{}{}
+		for i := 0; i < ch.replicas; i++ {
+			p := hashPoint(b + "#" + strconv.Itoa(i))
+			// On the unlikely collision the smaller name wins, so that the
+			// result does not depend on the order buckets were added in.
+			if owner, ok := ch.owners[p]; ok && owner < b {
+				continue
+			}
+			ch.owners[p] = b
+		}
+	}
+}
+
+// Remove removes a bucket.
+func (ch *ConsistentHash) Remove(bucket string) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if _, ok := ch.buckets[bucket]; !ok {
+		return
+	}
+	delete(ch.buckets, bucket)
+	// Points the bucket won in a collision go back to the other owner.
+	buckets := ch.names()
+	ch.owners = make(map[uint64]string)
+	ch.buckets = make(map[string]struct{})
+	ch.add(buckets)
+	ch.rebuild()
+}
+
+// Buckets returns the buckets in sorted order.
+func (ch *ConsistentHash) Buckets() []string {
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	return ch.names()
+}
+
+func (ch *ConsistentHash) names() []string {
+	names := make([]string, 0, len(ch.buckets))
+	for b := range ch.buckets {
+		names = append(names, b)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the bucket for the public key.
+func (ch *ConsistentHash) Get(public string) (string, error) {
+	if !IsValidPublicKey(public) {
+		return "", ErrInvalidPublicKey
+	}
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	if len(ch.points) == 0 {
+		return "", ErrNoBuckets
+	}
+	h := hashPoint(public)
+	i := sort.Search(len(ch.points), func(i int) bool { return ch.points[i] >= h })
+	if i == len(ch.points) {
+		i = 0
+	}
+	return ch.owners[ch.points[i]], nil
+}
+
+func (ch *ConsistentHash) rebuild() {
+	ch.points = ch.points[:0]
+	for p := range ch.owners {
+		ch.points = append(ch.points, p)
+	}
+	sort.Slice(ch.points, func(i, j int) bool { return ch.points[i] < ch.points[j] })
+}
+
+func hashPoint(s string) uint64 {
+	sum := sha256.Sum256([]byte(s))
+	return binary.BigEndian.Uint64(sum[:8])
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "testing"
+
+func TestConsistentHash(t *testing.T) {
+	var keys []string
+	for i := 0; i < 1000; i++ {
+		a, _ := CreateAccount()
+		pk, _ := a.PublicKey()
+		keys = append(keys, pk)
+	}
+
+	if _, err := NewConsistentHash(0).Get(keys[0]); err != ErrNoBuckets {
+		t.Fatalf("Expected %v, got %v", ErrNoBuckets, err)
+	}
+	ch := NewConsistentHash(0, "a", "b", "c")
+	if _, err := ch.Get("bad"); err != ErrInvalidPublicKey {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPublicKey, err)
+	}
+
+	// The mapping does not depend on the order buckets are added in.
+	other := NewConsistentHash(0, "c", "a")
+	other.Add("b")
+	before := make(map[string]string)
+	counts := make(map[string]int)
+	for _, pk := range keys {
+		b, _ := ch.Get(pk)
+		if o, _ := other.Get(pk); o != b {
+			t.Fatalf("Expected %q, got %q", b, o)
+		}
+		before[pk] = b
+		counts[b]++
+	}
+	for _, b := range ch.Buckets() {
+		if counts[b] < 200 {
+			t.Fatalf("Expected an even spread, got %v", counts)
+		}
+	}
+
+	// Only the keys of a removed bucket move.
+	ch.Remove("b")
+	for _, pk := range keys {
+		b, _ := ch.Get(pk)
+		if before[pk] != "b" && b != before[pk] {
+			t.Fatalf("Expected %q to stay in %q, got %q", pk, before[pk], b)
+		}
+		if b == "b" {
+			t.Fatalf("Expected %q to move out of the removed bucket", pk)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/rand"
+	"io"
+	"time"
+)
+
+// Pinger is implemented by KeyPairs whose private key lives in an external
+// store, such as an agent or an HSM, to report whether the store is
+// reachable and still holds the key.
+type Pinger interface {
+	Ping() error
+}
+
+// KeyHealth is the result of CheckKey.
+type KeyHealth struct {
+	PublicKey string
+	// StoreLatency is the duration of Ping, zero if the KeyPair is not a
+	// Pinger.
+	StoreLatency time.Duration
+	// Latency is the duration of the sign and verify, or seal and open,
+	// round trip.
+	Latency time.Duration
+	// Err is the first failure, nil if the key is healthy.
+	Err error
+}
+
+// Healthy reports whether the check passed.
+func (h *KeyHealth) Healthy() bool {
+	return h.Err == nil
+}
+
+const healthCheckContext = "nkeys-health-check:"
+
+// CheckKey checks that kp can still be used, for readiness probes of
+// services that depend on signing. It pings the backing store of Pingers,
+// then signs a random challenge and verifies the signature against the
+// public key independently of kp. Curve keys seal and open a challenge
+// instead. The challenge is prefixed so that the signature can not be
+// mistaken for one over a nonce or other protocol message.
+func CheckKey(kp KeyPair) *KeyHealth {
+	h := &KeyHealth{}
+	h.PublicKey, h.Err = kp.PublicKey()
+	if h.Err != nil {
+		return h
+	}
+	if p, ok := kp.(Pinger); ok {
+		start := time.Now()
+		h.Err = p.Ping()
+		h.StoreLatency = time.Since(start)
+		if h.Err != nil {
+			return h
+		}
+	}
+	challenge := make([]byte, len(healthCheckContext)+32)
+	copy(challenge, healthCheckContext)
+	if _, h.Err = io.ReadFull(rand.Reader, challenge[len(healthCheckContext):]); h.Err != nil {
+		return h
+	}
+	start := time.Now()
+	if Prefix(h.PublicKey) == PrefixByteCurve {
+		h.Err = checkSealOpen(kp, h.PublicKey, challenge)
+	} else {
+		h.Err = checkSignVerify(kp, h.PublicKey, challenge)
+	}
+	h.Latency = time.Since(start)
+	return h
+}
+
+func checkSignVerify(kp KeyPair, public string, challenge []byte) error {
+	sig, err := kp.Sign(challenge)
+	if err != nil {
+		return err
+	}
+	return VerifyWithPolicy(nil, public, challenge, sig)
+}
+
+func checkSealOpen(kp KeyPair, public string, challenge []byte) error {
+	sealed, err := kp.Seal(challenge, public)
+	if err != nil {
+		return err
+	}
+	opened, err := kp.Open(sealed, public)
+	if err != nil {
+		return err
+	}
+	if string(opened) != string(challenge) {
+		return ErrCouldNotDecrypt
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "testing"
+
+func TestCheckKey(t *testing.T) {
+	for _, create := range []func() (KeyPair, error){CreateUser, CreateCurveKeys} {
+		kp, _ := create()
+		h := CheckKey(kp)
+		if !h.Healthy() || h.Latency == 0 {
+			t.Fatalf("Expected a healthy key, got %+v", h)
+		}
+		if pk, _ := kp.PublicKey(); h.PublicKey != pk {
+			t.Fatalf("Expected %v, got %v", pk, h.PublicKey)
+		}
+	}
+
+	user, _ := CreateUser()
+	if h := CheckKey(WithPolicy(user, Policy{Allowed: VerifyOnly})); h.Err != ErrOperationNotAllowed {
+		t.Fatalf("Expected %v, got %v", ErrOperationNotAllowed, h.Err)
+	}
+	public, _ := user.PublicOnly()
+	if h := CheckKey(public); h.Err != ErrCannotSign {
+		t.Fatalf("Expected %v, got %v", ErrCannotSign, h.Err)
+	}
+}
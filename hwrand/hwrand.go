@@ -0,0 +1,154 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hwrand reads entropy from hardware random number generators, such
+// as /dev/hwrng or a TPM, for appliances that must generate keys from
+// hardware randomness. Every reader runs the continuous health tests of
+// NIST SP 800-90B section 4.4 and fails rather than return output from a
+// stuck or biased source. Pass a reader to nkeys.CreatePairWithRand:
+//
+//	r, err := hwrand.Open(hwrand.DefaultDevice)
+//	...
+//	defer r.Close()
+//	kp, err := nkeys.CreatePairWithRand(nkeys.PrefixByteServer, r)
+package hwrand
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Errors
+const (
+	ErrHealthTest = hwrandError("hwrand: entropy source failed health test")
+)
+
+type hwrandError string
+
+func (e hwrandError) Error() string {
+	return string(e)
+}
+
+// DefaultDevice is the Linux hardware random number generator device.
+const DefaultDevice = "/dev/hwrng"
+
+// Health test parameters for byte samples, assuming a conservative
+// min-entropy of one bit per byte and a false positive rate of 2^-20.
+const (
+	repetitionCutoff = 21
+	proportionWindow = 512
+	proportionCutoff = 410
+	// startupSamples are tested and discarded when a reader is created.
+	startupSamples = 1024
+)
+
+// Reader is a health tested entropy source.
+type Reader struct {
+	mu     sync.Mutex
+	src    io.Reader
+	closer io.Closer
+	failed bool
+
+	// Repetition count test state.
+	last     byte
+	repeated int
+	// Adaptive proportion test state.
+	first   byte
+	matches int
+	seen    int
+}
+
+// NewReader wraps src with the health tests. The startup samples are read
+// and tested before NewReader returns.
+func NewReader(src io.Reader) (*Reader, error) {
+	r := &Reader{src: src}
+	if c, ok := src.(io.Closer); ok {
+		r.closer = c
+	}
+	startup := make([]byte, startupSamples)
+	if _, err := io.ReadFull(r, startup); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Open opens a hardware random number generator device such as
+// DefaultDevice.
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Read fills p from the source. Once a health test failed every Read
+// returns ErrHealthTest.
+func (r *Reader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failed {
+		return 0, ErrHealthTest
+	}
+	n, err := r.src.Read(p)
+	for i := 0; i < n; i++ {
+		if !r.test(p[i]) {
+			r.failed = true
+			for j := range p[:n] {
+				p[j] = 0
+			}
+			return 0, ErrHealthTest
+		}
+	}
+	return n, err
+}
+
+// test runs the repetition count and adaptive proportion tests on b.
+func (r *Reader) test(b byte) bool {
+	if r.repeated > 0 && b == r.last {
+		r.repeated++
+	} else {
+		r.last, r.repeated = b, 1
+	}
+	if r.repeated >= repetitionCutoff {
+		return false
+	}
+
+	if r.seen == 0 {
+		r.first, r.matches = b, 1
+	} else if b == r.first {
+		r.matches++
+	}
+	r.seen++
+	if r.matches >= proportionCutoff {
+		return false
+	}
+	if r.seen == proportionWindow {
+		r.seen = 0
+	}
+	return true
+}
+
+// Close closes the source if it is an io.Closer.
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
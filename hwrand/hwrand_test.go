@@ -0,0 +1,106 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hwrand
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/nats-io/nkeys"
+)
+
+type constReader byte
+
+func (c constReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(c)
+	}
+	return len(p), nil
+}
+
+// biasedReader returns 0 for most samples without long runs.
+type biasedReader struct{ n int }
+
+func (b *biasedReader) Read(p []byte) (int, error) {
+	for i := range p {
+		b.n++
+		if b.n%8 == 0 {
+			p[i] = byte(b.n)
+		} else {
+			p[i] = 0
+		}
+	}
+	return len(p), nil
+}
+
+func TestHealthTests(t *testing.T) {
+	r, err := NewReader(rand.Reader)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	kp, err := nkeys.CreatePairWithRand(nkeys.PrefixByteServer, r)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if pk, _ := kp.PublicKey(); !nkeys.IsValidPublicServerKey(pk) {
+		t.Fatalf("Expected a server key, got %q", pk)
+	}
+
+	if _, err := NewReader(constReader(7)); err != ErrHealthTest {
+		t.Fatalf("Expected %v, got %v", ErrHealthTest, err)
+	}
+	if _, err := NewReader(&biasedReader{}); err != ErrHealthTest {
+		t.Fatalf("Expected %v, got %v", ErrHealthTest, err)
+	}
+}
+
+// fakeTPM answers TPM2_GetRandom commands from crypto/rand.
+type fakeTPM struct {
+	resp bytes.Buffer
+}
+
+func (f *fakeTPM) Write(cmd []byte) (int, error) {
+	if len(cmd) != 12 || binary.BigEndian.Uint32(cmd[6:]) != tpmCCGetRandom {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := int(binary.BigEndian.Uint16(cmd[10:]))
+	resp := make([]byte, 12+n)
+	binary.BigEndian.PutUint16(resp[0:], tpmSTNoSessions)
+	binary.BigEndian.PutUint32(resp[2:], uint32(len(resp)))
+	binary.BigEndian.PutUint16(resp[10:], uint16(n))
+	rand.Read(resp[12:])
+	f.resp.Write(resp)
+	return len(cmd), nil
+}
+
+func (f *fakeTPM) Read(p []byte) (int, error) {
+	return f.resp.Read(p)
+}
+
+func TestTPMReader(t *testing.T) {
+	r, err := NewTPMReader(&fakeTPM{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	buf := make([]byte, 100)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if bytes.Equal(buf, make([]byte, 100)) {
+		t.Fatalf("Expected random bytes")
+	}
+}
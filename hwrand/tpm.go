@@ -0,0 +1,96 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hwrand
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// DefaultTPMDevice is the Linux TPM 2.0 resource manager device.
+const DefaultTPMDevice = "/dev/tpmrm0"
+
+const (
+	tpmSTNoSessions  = 0x8001
+	tpmCCGetRandom   = 0x0000017B
+	tpmMaxResponse   = 4096
+	tpmRandomRequest = 32
+)
+
+// tpmRNG reads random bytes with the TPM2_GetRandom command.
+type tpmRNG struct {
+	rw io.ReadWriter
+}
+
+// NewTPMReader returns a health tested reader that draws entropy from the
+// TPM 2.0 reachable over rw with TPM2_GetRandom.
+func NewTPMReader(rw io.ReadWriter) (*Reader, error) {
+	return NewReader(&tpmRNG{rw})
+}
+
+// OpenTPM opens a TPM 2.0 device such as DefaultTPMDevice as an entropy
+// source.
+func OpenTPM(path string) (*Reader, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewTPMReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
+
+func (t *tpmRNG) Read(p []byte) (int, error) {
+	n := len(p)
+	if n > tpmRandomRequest {
+		n = tpmRandomRequest
+	}
+	var cmd [12]byte
+	binary.BigEndian.PutUint16(cmd[0:], tpmSTNoSessions)
+	binary.BigEndian.PutUint32(cmd[2:], uint32(len(cmd)))
+	binary.BigEndian.PutUint32(cmd[6:], tpmCCGetRandom)
+	binary.BigEndian.PutUint16(cmd[10:], uint16(n))
+	if _, err := t.rw.Write(cmd[:]); err != nil {
+		return 0, err
+	}
+	resp := make([]byte, tpmMaxResponse)
+	m, err := t.rw.Read(resp)
+	if err != nil {
+		return 0, err
+	}
+	resp = resp[:m]
+	if len(resp) < 10 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if rc := binary.BigEndian.Uint32(resp[6:]); rc != 0 {
+		return 0, fmt.Errorf("hwrand: TPM2_GetRandom failed with response code %#x", rc)
+	}
+	if len(resp) < 12 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	size := int(binary.BigEndian.Uint16(resp[10:]))
+	if size > n || len(resp) < 12+size {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if size == 0 {
+		return 0, io.ErrNoProgress
+	}
+	return copy(p, resp[12:12+size]), nil
+}
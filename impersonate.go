@@ -0,0 +1,119 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"time"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+	"github.com/nats-io/nkeys/internal/sigtoken"
+)
+
+// MaxImpersonationTTL bounds the lifetime of impersonation tokens.
+const MaxImpersonationTTL = sigtoken.MaxTTL
+
+// Impersonation allows an admin key to act as a user, for example in
+// support workflows. Every token has a unique ID for audit logs.
+type Impersonation struct {
+	ID       string    `json:"jti"`
+	Admin    string    `json:"iss"`
+	Target   string    `json:"sub"`
+	Scope    []string  `json:"scope,omitempty"`
+	IssuedAt time.Time `json:"iat"`
+	Expires  time.Time `json:"exp"`
+}
+
+// Allows reports whether scope was granted.
+func (im *Impersonation) Allows(scope string) bool {
+	for _, s := range im.Scope {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// signedBytes is the canonical encoding of the impersonation, which is what
+// the admin signs so that the signature can not be used for anything else.
+func (im *Impersonation) signedBytes() []byte {
+	e := canonical.NewEncoder("nkeys.Impersonation")
+	e.Text(im.ID)
+	e.Text(im.Admin)
+	e.Text(im.Target)
+	e.Strings(im.Scope)
+	e.Time(im.IssuedAt)
+	e.Time(im.Expires)
+	return e.Bytes()
+}
+
+// MintImpersonationToken returns a token allowing admin to act as the user
+// target for ttl from the time of clock, which may be nil for the system
+// clock, limited to scope. The token is the base64url encoded JSON
+// Impersonation and signature separated by a '.'.
+func MintImpersonationToken(admin KeyPair, target string, ttl time.Duration, scope []string, clock Clock) (string, error) {
+	if !IsValidPublicUserKey(target) {
+		return "", ErrInvalidPublicKey
+	}
+	iat, exp, ok := sigtoken.Window(ClockOrSystem(clock).Now(), ttl, MaxImpersonationTTL)
+	if !ok {
+		return "", ErrInvalidLifetime
+	}
+	pk, err := admin.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	id, err := sigtoken.NewID(nil)
+	if err != nil {
+		return "", err
+	}
+	im := Impersonation{
+		ID:       id,
+		Admin:    pk,
+		Target:   target,
+		Scope:    scope,
+		IssuedAt: iat,
+		Expires:  exp,
+	}
+	sig, err := admin.Sign(im.signedBytes())
+	if err != nil {
+		return "", err
+	}
+	payload, err := sigtoken.Payload(im)
+	if err != nil {
+		return "", err
+	}
+	return sigtoken.Join(payload, sig), nil
+}
+
+// VerifyImpersonationToken verifies a token made by MintImpersonationToken,
+// applies the policy to the admin key and checks the validity window.
+// Callers must still check that the admin is one they trust, for example
+// with the policy's AllowedTypes and Revocation.
+func VerifyImpersonationToken(token string, vp *VerifyPolicy) (*Impersonation, error) {
+	var im Impersonation
+	_, sig, ok := sigtoken.Split(token, &im)
+	if !ok {
+		return nil, ErrInvalidImpersonation
+	}
+	if !IsValidPublicUserKey(im.Target) || im.Expires.Sub(im.IssuedAt) > MaxImpersonationTTL {
+		return nil, ErrInvalidImpersonation
+	}
+	if err := VerifyWithPolicy(vp, im.Admin, im.signedBytes(), sig); err != nil {
+		return nil, err
+	}
+	if err := vp.CheckValidity(im.IssuedAt, im.Expires); err != nil {
+		return nil, err
+	}
+	return &im, nil
+}
@@ -0,0 +1,245 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package canonical implements the deterministic binary encoding used by
+// the MarshalBinary methods of nkeys records. Every value is written in a
+// fixed order with explicit lengths, and maps are written in key order, so
+// equal values always produce identical bytes.
+package canonical
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+	"time"
+)
+
+// Version is the encoding version written after the record tag.
+const Version byte = 1
+
+// ErrInvalid is returned when decoding malformed or mistagged input.
+var ErrInvalid = errors.New("nkeys: invalid canonical encoding")
+
+// maxLen bounds decoded lengths to guard against hostile input.
+const maxLen = 1 << 24
+
+// Encoder appends canonically encoded fields.
+type Encoder struct {
+	buf []byte
+}
+
+// NewEncoder starts a record identified by tag.
+func NewEncoder(tag string) *Encoder {
+	e := &Encoder{}
+	e.Text(tag)
+	e.buf = append(e.buf, Version)
+	return e
+}
+
+// Bytes returns the encoded record.
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+func (e *Encoder) Uint64(v uint64) {
+	e.buf = binary.BigEndian.AppendUint64(e.buf, v)
+}
+
+func (e *Encoder) Int64(v int64) {
+	e.Uint64(uint64(v))
+}
+
+func (e *Encoder) Bool(v bool) {
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *Encoder) Blob(b []byte) {
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *Encoder) Text(s string) {
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// Time writes t with nanosecond precision. Location and monotonic clock
+// readings are not encoded.
+func (e *Encoder) Time(t time.Time) {
+	e.Bool(!t.IsZero())
+	if !t.IsZero() {
+		e.Int64(t.UnixNano())
+	}
+}
+
+func (e *Encoder) Strings(ss []string) {
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(len(ss)))
+	for _, s := range ss {
+		e.Text(s)
+	}
+}
+
+// Uint32Map writes the map sorted by key.
+func (e *Encoder) Uint32Map(m map[string]uint32) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(len(keys)))
+	for _, k := range keys {
+		e.Text(k)
+		e.Uint64(uint64(m[k]))
+	}
+}
+
+// Decoder reads fields written by an Encoder. Errors are sticky and
+// reported by Finish.
+type Decoder struct {
+	buf []byte
+	err error
+}
+
+// NewDecoder checks the record tag and version.
+func NewDecoder(tag string, data []byte) *Decoder {
+	d := &Decoder{buf: data}
+	if d.Text() != tag {
+		d.err = ErrInvalid
+	}
+	if v := d.next(1); d.err == nil && v[0] != Version {
+		d.err = ErrInvalid
+	}
+	return d
+}
+
+// Err returns the first error encountered so far.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// Finish returns the first error encountered, or ErrInvalid if input remains.
+func (d *Decoder) Finish() error {
+	if d.err == nil && len(d.buf) != 0 {
+		d.err = ErrInvalid
+	}
+	return d.err
+}
+
+func (d *Decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.buf) {
+		d.err = ErrInvalid
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *Decoder) length() int {
+	b := d.next(4)
+	if d.err != nil {
+		return 0
+	}
+	n := binary.BigEndian.Uint32(b)
+	if n > maxLen {
+		d.err = ErrInvalid
+		return 0
+	}
+	return int(n)
+}
+
+func (d *Decoder) Uint64() uint64 {
+	b := d.next(8)
+	if d.err != nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (d *Decoder) Int64() int64 {
+	return int64(d.Uint64())
+}
+
+func (d *Decoder) Bool() bool {
+	b := d.next(1)
+	if d.err != nil {
+		return false
+	}
+	if b[0] > 1 {
+		d.err = ErrInvalid
+	}
+	return b[0] == 1
+}
+
+func (d *Decoder) Blob() []byte {
+	n := d.length()
+	b := d.next(n)
+	if d.err != nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+func (d *Decoder) Text() string {
+	n := d.length()
+	b := d.next(n)
+	if d.err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// Time returns times in UTC.
+func (d *Decoder) Time() time.Time {
+	if !d.Bool() {
+		return time.Time{}
+	}
+	return time.Unix(0, d.Int64()).UTC()
+}
+
+func (d *Decoder) Strings() []string {
+	n := d.length()
+	if d.err != nil || n == 0 {
+		return nil
+	}
+	var ss []string
+	for i := 0; i < n && d.err == nil; i++ {
+		ss = append(ss, d.Text())
+	}
+	return ss
+}
+
+// Uint32Map rejects maps whose keys are not strictly increasing, so that
+// every map has exactly one valid encoding.
+func (d *Decoder) Uint32Map() map[string]uint32 {
+	n := d.length()
+	m := make(map[string]uint32)
+	prev := ""
+	for i := 0; i < n && d.err == nil; i++ {
+		k := d.Text()
+		v := d.Uint64()
+		if (i > 0 && k <= prev) || v > 1<<32-1 {
+			d.err = ErrInvalid
+		}
+		m[k] = uint32(v)
+		prev = k
+	}
+	return m
+}
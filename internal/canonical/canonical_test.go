@@ -0,0 +1,116 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package canonical
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMapOrderingIsDeterministic(t *testing.T) {
+	forward := make(map[string]uint32)
+	backward := make(map[string]uint32)
+	for i := 0; i < 100; i++ {
+		forward[fmt.Sprintf("tenant-%03d", i)] = uint32(i)
+	}
+	for i := 99; i >= 0; i-- {
+		backward[fmt.Sprintf("tenant-%03d", i)] = uint32(i)
+	}
+	encode := func(m map[string]uint32) []byte {
+		e := NewEncoder("test")
+		e.Uint32Map(m)
+		return e.Bytes()
+	}
+	expected := encode(forward)
+	// Map iteration order is randomized by the runtime, so repeating the
+	// encoding many times would surface any dependency on it.
+	for i := 0; i < 50; i++ {
+		if !bytes.Equal(encode(forward), expected) || !bytes.Equal(encode(backward), expected) {
+			t.Fatal("Expected map encoding to be independent of iteration order")
+		}
+	}
+
+	d := NewDecoder("test", expected)
+	m := d.Uint32Map()
+	if err := d.Finish(); err != nil {
+		t.Fatalf("Unexpected error decoding: %v", err)
+	}
+	if !reflect.DeepEqual(m, forward) {
+		t.Fatal("Expected decoded map to match")
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	e := NewEncoder("test")
+	e.Text("b")
+	e.Uint64(1)
+	good := e.Bytes()
+
+	// Unsorted map keys have no valid encoding.
+	e = NewEncoder("test")
+	e.buf = append(e.buf, 0, 0, 0, 2)
+	e.Text("b")
+	e.Uint64(1)
+	e.Text("a")
+	e.Uint64(2)
+	d := NewDecoder("test", e.Bytes())
+	d.Uint32Map()
+	if err := d.Finish(); err != ErrInvalid {
+		t.Fatalf("Expected ErrInvalid for unsorted map, got %v", err)
+	}
+
+	for name, data := range map[string][]byte{
+		"truncated": good[:len(good)-1],
+		"trailing":  append(append([]byte{}, good...), 0),
+	} {
+		d := NewDecoder("test", data)
+		d.Text()
+		d.Uint64()
+		if err := d.Finish(); err != ErrInvalid {
+			t.Fatalf("Expected ErrInvalid for %s input, got %v", name, err)
+		}
+	}
+	d = NewDecoder("other", good)
+	d.Text()
+	d.Uint64()
+	if err := d.Finish(); err != ErrInvalid {
+		t.Fatalf("Expected ErrInvalid for wrong tag, got %v", err)
+	}
+}
+
+func TestTime(t *testing.T) {
+	loc := time.FixedZone("X", 3600)
+	now := time.Now().In(loc)
+	e := NewEncoder("test")
+	e.Time(now)
+	e.Time(time.Time{})
+	d := NewDecoder("test", e.Bytes())
+	t1, t2 := d.Time(), d.Time()
+	if err := d.Finish(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !t1.Equal(now) || !t2.IsZero() {
+		t.Fatalf("Unexpected times %v, %v", t1, t2)
+	}
+
+	e2 := NewEncoder("test")
+	e2.Time(now.UTC().Round(0))
+	e2.Time(time.Time{})
+	if !bytes.Equal(e.Bytes(), e2.Bytes()) {
+		t.Fatal("Expected location and monotonic reading to be ignored")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package shamir implements Shamir secret sharing over GF(2^8) with the AES
+// polynomial. Each byte of the secret is the constant term of its own
+// random polynomial of degree threshold-1, and share i holds the
+// evaluations at x = i.
+package shamir
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrInvalidThreshold is returned by Split for impossible share counts.
+var ErrInvalidThreshold = errors.New("shamir: threshold must be between 2 and the number of shares, at most 255")
+
+var gfExp [510]byte
+var gfLog [256]byte
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		gfExp[i] = x
+		gfExp[i+255] = x
+		gfLog[x] = byte(i)
+		// Multiply by the generator 3.
+		x ^= gfDouble(x)
+	}
+}
+
+func gfDouble(x byte) byte {
+	if x&0x80 != 0 {
+		return x<<1 ^ 0x1b
+	}
+	return x << 1
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// Split returns n shares of secret, any threshold of which recover it.
+// Share i is evaluated at x = i+1. threshold must be between 2 and n, and
+// n at most 255.
+func Split(secret []byte, n, threshold int, rr io.Reader) ([][]byte, error) {
+	if threshold < 2 || threshold > n || n > 255 {
+		return nil, ErrInvalidThreshold
+	}
+	coeffs := make([]byte, threshold-1)
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret))
+	}
+	for b, s := range secret {
+		if _, err := io.ReadFull(rr, coeffs); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			x := byte(i + 1)
+			// Horner's rule.
+			var y byte
+			for c := len(coeffs) - 1; c >= 0; c-- {
+				y = gfMul(y, x) ^ coeffs[c]
+			}
+			shares[i][b] = gfMul(y, x) ^ s
+		}
+	}
+	Wipe(coeffs)
+	return shares, nil
+}
+
+// Combine interpolates the shares ys taken at the distinct non-zero points
+// xs at zero. The shares must have the same length.
+func Combine(xs []byte, ys [][]byte) []byte {
+	secret := make([]byte, len(ys[0]))
+	for i, xi := range xs {
+		// Lagrange basis polynomial i at zero.
+		l := byte(1)
+		for j, xj := range xs {
+			if i != j {
+				l = gfMul(l, gfDiv(xj, xj^xi))
+			}
+		}
+		for b := range secret {
+			secret[b] ^= gfMul(l, ys[i][b])
+		}
+	}
+	return secret
+}
+
+// Wipe zeroes b.
+func Wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shamir
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestShamir(t *testing.T) {
+	secret := []byte("a secret of some length")
+	parts, err := Split(secret, 5, 3, strings.NewReader(strings.Repeat("x", 1024)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, idx := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}} {
+		var xs []byte
+		var ys [][]byte
+		for _, i := range idx {
+			xs = append(xs, byte(i+1))
+			ys = append(ys, parts[i])
+		}
+		if got := Combine(xs, ys); !bytes.Equal(got, secret) {
+			t.Fatalf("Expected %q, got %q", secret, got)
+		}
+	}
+	if got := Combine([]byte{1, 2}, parts[:2]); bytes.Equal(got, secret) {
+		t.Fatalf("Expected fewer shares than the threshold not to restore")
+	}
+	if _, err := Split(secret, 2, 3, strings.NewReader("")); err != ErrInvalidThreshold {
+		t.Fatalf("Expected %v, got %v", ErrInvalidThreshold, err)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sigtoken implements the framing shared by the signed tokens of
+// nkeys: the base64url encoded JSON claims and the base64url encoded
+// signature, separated by a '.'. What is signed is up to the caller.
+package sigtoken
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// MaxTTL bounds the lifetime of short-lived tokens.
+const MaxTTL = 24 * time.Hour
+
+// NewID returns a random 128 bit token ID for the jti claim, base64url
+// encoded. r defaults to crypto/rand.Reader.
+func NewID(r io.Reader) (string, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+	var id [16]byte
+	if _, err := io.ReadFull(r, id[:]); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(id[:]), nil
+}
+
+// Window returns the iat and exp claims of a token valid for ttl from now,
+// truncated to the second. It reports false unless 0 < ttl <= max.
+func Window(now time.Time, ttl, max time.Duration) (time.Time, time.Time, bool) {
+	if ttl <= 0 || ttl > max {
+		return time.Time{}, time.Time{}, false
+	}
+	iat := now.UTC().Truncate(time.Second)
+	return iat, iat.Add(ttl), true
+}
+
+// Payload returns the encoded claims.
+func Payload(claims interface{}) (string, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Join returns the token of an encoded payload and its signature.
+func Join(payload string, sig []byte) string {
+	return payload + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// Split decodes the claims of token into claims and returns the encoded
+// payload and the signature. It reports false for malformed tokens.
+func Split(token string, claims interface{}) (string, []byte, bool) {
+	i := strings.IndexByte(token, '.')
+	if i < 0 {
+		return "", nil, false
+	}
+	payload := token[:i]
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil {
+		return "", nil, false
+	}
+	if err := json.Unmarshal(data, claims); err != nil {
+		return "", nil, false
+	}
+	return payload, sig, true
+}
@@ -0,0 +1,58 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigtoken
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	type claims struct {
+		ID string `json:"jti"`
+	}
+	id, err := NewID(bytes.NewReader(make([]byte, 16)))
+	if err != nil || id != "AAAAAAAAAAAAAAAAAAAAAA" {
+		t.Fatalf("Expected a zero ID, got %q (%v)", id, err)
+	}
+	payload, err := Payload(claims{id})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	tok := Join(payload, []byte("sig"))
+	var c claims
+	p, sig, ok := Split(tok, &c)
+	if !ok || p != payload || string(sig) != "sig" || c.ID != id {
+		t.Fatalf("Expected the token to round trip, got %q %q %+v", p, sig, c)
+	}
+	for _, bad := range []string{"nodot", "!." + "c2ln", payload + ".!", "bm90anNvbg.c2ln"} {
+		if _, _, ok := Split(bad, &c); ok {
+			t.Fatalf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestWindow(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 500, time.UTC)
+	iat, exp, ok := Window(now, time.Hour, MaxTTL)
+	if !ok || !iat.Equal(now.Truncate(time.Second)) || !exp.Equal(iat.Add(time.Hour)) {
+		t.Fatalf("Unexpected window %v - %v", iat, exp)
+	}
+	for _, ttl := range []time.Duration{0, -time.Second, MaxTTL + time.Second} {
+		if _, _, ok := Window(now, ttl, MaxTTL); ok {
+			t.Fatalf("Expected ttl %v to be rejected", ttl)
+		}
+	}
+}
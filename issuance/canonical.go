@@ -0,0 +1,94 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuance
+
+import "github.com/nats-io/nkeys/internal/canonical"
+
+// MarshalBinary returns the canonical encoding of the Challenge.
+func (c Challenge) MarshalBinary() ([]byte, error) {
+	e := canonical.NewEncoder("issuance.Challenge")
+	e.Text(c.ID)
+	e.Text(c.Issuer)
+	e.Blob(c.Nonce)
+	e.Time(c.Expires)
+	return e.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the canonical encoding of a Challenge.
+func (c *Challenge) UnmarshalBinary(data []byte) error {
+	d := canonical.NewDecoder("issuance.Challenge", data)
+	v := Challenge{
+		ID:      d.Text(),
+		Issuer:  d.Text(),
+		Nonce:   d.Blob(),
+		Expires: d.Time(),
+	}
+	if err := d.Finish(); err != nil {
+		return err
+	}
+	*c = v
+	return nil
+}
+
+// MarshalBinary returns the canonical encoding of the Attestation.
+func (a Attestation) MarshalBinary() ([]byte, error) {
+	e := canonical.NewEncoder("issuance.Attestation")
+	e.Text(a.ChallengeID)
+	e.Text(a.Device)
+	e.Blob(a.Signature)
+	return e.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the canonical encoding of an Attestation.
+func (a *Attestation) UnmarshalBinary(data []byte) error {
+	d := canonical.NewDecoder("issuance.Attestation", data)
+	v := Attestation{
+		ChallengeID: d.Text(),
+		Device:      d.Text(),
+		Signature:   d.Blob(),
+	}
+	if err := d.Finish(); err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// MarshalBinary returns the canonical encoding of the Credential.
+func (c Credential) MarshalBinary() ([]byte, error) {
+	e := canonical.NewEncoder("issuance.Credential")
+	e.Text(c.ID)
+	e.Text(c.Subject)
+	e.Text(c.Issuer)
+	e.Time(c.IssuedAt)
+	e.Time(c.Expires)
+	return e.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the canonical encoding of a Credential.
+func (c *Credential) UnmarshalBinary(data []byte) error {
+	d := canonical.NewDecoder("issuance.Credential", data)
+	v := Credential{
+		ID:       d.Text(),
+		Subject:  d.Text(),
+		Issuer:   d.Text(),
+		IssuedAt: d.Time(),
+		Expires:  d.Time(),
+	}
+	if err := d.Finish(); err != nil {
+		return err
+	}
+	*c = v
+	return nil
+}
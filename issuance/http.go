@@ -0,0 +1,75 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuance
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// maxRequestSize bounds the attestation body a client may send.
+const maxRequestSize = 4096
+
+// IssueResponse is returned by the issue endpoint.
+type IssueResponse struct {
+	Credential Credential `json:"credential"`
+	Token      string     `json:"token"`
+}
+
+// Handler returns an http.Handler serving POST /challenge, which returns a
+// Challenge, and POST /issue, which accepts an Attestation and returns an
+// IssueResponse.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/challenge", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		c, err := s.Challenge()
+		if err == ErrTooManyChallenges {
+			http.Error(w, "too many challenges", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			http.Error(w, "could not create challenge", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, c)
+	})
+	mux.HandleFunc("/issue", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var a Attestation
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&a); err != nil {
+			http.Error(w, "invalid attestation", http.StatusBadRequest)
+			return
+		}
+		cred, token, err := s.Issue(a)
+		if err != nil {
+			// Do not tell the client which check failed.
+			http.Error(w, "attestation rejected", http.StatusForbidden)
+			return
+		}
+		writeJSON(w, IssueResponse{cred, token})
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,288 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package issuance is a reference implementation of device onboarding.
+// An agent proves possession of a device nkey by signing a server issued
+// challenge and receives a credential signed by an account key in return.
+package issuance
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"time"
+
+	"github.com/nats-io/nkeys"
+	"github.com/nats-io/nkeys/internal/sigtoken"
+)
+
+// Errors
+const (
+	ErrUnknownChallenge  = issuanceError("issuance: unknown or already used challenge")
+	ErrExpiredChallenge  = issuanceError("issuance: challenge expired")
+	ErrInvalidDevice     = issuanceError("issuance: invalid device public key")
+	ErrInvalidIssuer     = issuanceError("issuance: issuer must be an account key pair")
+	ErrInvalidToken      = issuanceError("issuance: invalid credential token")
+	ErrNotAuthorized     = issuanceError("issuance: device not authorized")
+	ErrTooManyChallenges = issuanceError("issuance: too many outstanding challenges")
+)
+
+type issuanceError string
+
+func (e issuanceError) Error() string {
+	return string(e)
+}
+
+const (
+	nonceLen = 32
+	// attestContext is prepended to what the device signs so that the
+	// signature can not be replayed as a signature over anything else.
+	attestContext = "nkeys-attest-v1\n"
+)
+
+// Challenge is a one time nonce that a device must sign.
+type Challenge struct {
+	ID      string    `json:"id"`
+	Issuer  string    `json:"issuer"`
+	Nonce   []byte    `json:"nonce"`
+	Expires time.Time `json:"expires"`
+}
+
+// Attestation is a device's response to a Challenge.
+type Attestation struct {
+	ChallengeID string `json:"challenge_id"`
+	Device      string `json:"device"`
+	Signature   []byte `json:"sig"`
+}
+
+// Credential is issued to a device after a successful attestation.
+type Credential struct {
+	ID       string    `json:"jti"`
+	Subject  string    `json:"sub"`
+	Issuer   string    `json:"iss"`
+	IssuedAt time.Time `json:"iat"`
+	Expires  time.Time `json:"exp"`
+}
+
+// signedMessage returns the bytes a device signs in response to c.
+func (c *Challenge) signedMessage() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(attestContext)
+	buf.WriteString(c.ID)
+	buf.WriteByte('\n')
+	buf.WriteString(c.Issuer)
+	buf.WriteByte('\n')
+	buf.Write(c.Nonce)
+	return buf.Bytes()
+}
+
+// Respond is called by the agent to prove possession of device, which must
+// be able to sign.
+func Respond(device nkeys.KeyPair, c Challenge) (Attestation, error) {
+	pk, err := device.PublicKey()
+	if err != nil {
+		return Attestation{}, err
+	}
+	sig, err := device.Sign(c.signedMessage())
+	if err != nil {
+		return Attestation{}, err
+	}
+	return Attestation{ChallengeID: c.ID, Device: pk, Signature: sig}, nil
+}
+
+// ChallengeStore holds outstanding challenges. Take must remove the challenge
+// so that each one can be answered at most once, which is what protects the
+// server against replayed attestations.
+type ChallengeStore interface {
+	Put(c Challenge) error
+	// Take returns and removes the challenge, or ErrUnknownChallenge.
+	Take(id string) (Challenge, error)
+}
+
+// CredentialStore records issued credentials.
+type CredentialStore interface {
+	Save(c Credential) error
+}
+
+// Authorizer decides whether a device that proved possession of its key
+// may receive a credential. A nil Authorizer allows all devices.
+type Authorizer func(device string) error
+
+// Server issues challenges and credentials.
+type Server struct {
+	issuer      nkeys.KeyPair
+	issuerPK    string
+	challenges  ChallengeStore
+	credentials CredentialStore
+
+	// ChallengeTTL is how long a challenge may be answered. Defaults to one minute.
+	ChallengeTTL time.Duration
+	// CredentialTTL is the lifetime of issued credentials. Zero means no expiry.
+	CredentialTTL time.Duration
+	// DeviceTypes are the accepted device key types. Defaults to users.
+	DeviceTypes []nkeys.PrefixByte
+	// Authorize is consulted after a device proved possession of its key.
+	Authorize Authorizer
+	// Rand is the entropy source for challenges. Defaults to crypto/rand.
+	Rand io.Reader
+	// Clock is the source of time for expirations. Defaults to nkeys.SystemClock.
+	Clock nkeys.Clock
+}
+
+// NewServer creates a Server issuing credentials signed by the account key
+// pair issuer. credentials may be nil.
+func NewServer(issuer nkeys.KeyPair, challenges ChallengeStore, credentials CredentialStore) (*Server, error) {
+	if err := nkeys.CompatibleKeyPair(issuer, nkeys.PrefixByteAccount); err != nil {
+		return nil, ErrInvalidIssuer
+	}
+	if _, err := issuer.Sign(nil); err != nil {
+		return nil, ErrInvalidIssuer
+	}
+	pk, err := issuer.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		issuer:       issuer,
+		issuerPK:     pk,
+		challenges:   challenges,
+		credentials:  credentials,
+		ChallengeTTL: time.Minute,
+		DeviceTypes:  []nkeys.PrefixByte{nkeys.PrefixByteUser},
+	}, nil
+}
+
+func (s *Server) rand() io.Reader {
+	if s.Rand == nil {
+		return rand.Reader
+	}
+	return s.Rand
+}
+
+func (s *Server) newID() (string, error) {
+	return sigtoken.NewID(s.rand())
+}
+
+// Challenge creates and stores a new challenge.
+func (s *Server) Challenge() (Challenge, error) {
+	id, err := s.newID()
+	if err != nil {
+		return Challenge{}, err
+	}
+	c := Challenge{
+		ID:      id,
+		Issuer:  s.issuerPK,
+		Nonce:   make([]byte, nonceLen),
+		Expires: nkeys.ClockOrSystem(s.Clock).Now().Add(s.ChallengeTTL),
+	}
+	if _, err := io.ReadFull(s.rand(), c.Nonce); err != nil {
+		return Challenge{}, err
+	}
+	if err := s.challenges.Put(c); err != nil {
+		return Challenge{}, err
+	}
+	return c, nil
+}
+
+// Issue verifies the attestation and returns a signed credential token.
+func (s *Server) Issue(a Attestation) (Credential, string, error) {
+	c, err := s.challenges.Take(a.ChallengeID)
+	if err != nil {
+		return Credential{}, "", err
+	}
+	now := nkeys.ClockOrSystem(s.Clock).Now()
+	if now.After(c.Expires) {
+		return Credential{}, "", ErrExpiredChallenge
+	}
+	device, err := nkeys.FromPublicKey(a.Device)
+	if err != nil {
+		return Credential{}, "", ErrInvalidDevice
+	}
+	if err := nkeys.CompatibleKeyPair(device, s.DeviceTypes...); err != nil {
+		return Credential{}, "", ErrInvalidDevice
+	}
+	if err := device.Verify(c.signedMessage(), a.Signature); err != nil {
+		return Credential{}, "", err
+	}
+	if s.Authorize != nil {
+		if err := s.Authorize(a.Device); err != nil {
+			return Credential{}, "", err
+		}
+	}
+
+	id, err := s.newID()
+	if err != nil {
+		return Credential{}, "", err
+	}
+	cred := Credential{
+		ID:       id,
+		Subject:  a.Device,
+		Issuer:   s.issuerPK,
+		IssuedAt: now.UTC().Truncate(time.Second),
+	}
+	if s.CredentialTTL > 0 {
+		cred.Expires = cred.IssuedAt.Add(s.CredentialTTL)
+	}
+	token, err := cred.sign(s.issuer)
+	if err != nil {
+		return Credential{}, "", err
+	}
+	if s.credentials != nil {
+		if err := s.credentials.Save(cred); err != nil {
+			return Credential{}, "", err
+		}
+	}
+	return cred, token, nil
+}
+
+// sign returns the token form of the credential: the base64url encoded
+// JSON claims and signature separated by a '.'.
+func (c *Credential) sign(issuer nkeys.KeyPair) (string, error) {
+	payload, err := sigtoken.Payload(c)
+	if err != nil {
+		return "", err
+	}
+	sig, err := issuer.Sign([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+	return sigtoken.Join(payload, sig), nil
+}
+
+// ParseCredential verifies a credential token against the issuer embedded
+// in it and returns the credential. Callers must still check that the
+// issuer is one they trust.
+func ParseCredential(token string) (Credential, error) {
+	return ParseCredentialWithPolicy(token, nil)
+}
+
+// ParseCredentialWithPolicy is like ParseCredential but also applies the
+// verification policy to the issuer and checks the credential has not
+// expired, allowing for the policy's clock skew.
+func ParseCredentialWithPolicy(token string, vp *nkeys.VerifyPolicy) (Credential, error) {
+	var c Credential
+	payload, sig, ok := sigtoken.Split(token, &c)
+	if !ok {
+		return c, ErrInvalidToken
+	}
+	if !nkeys.IsValidPublicAccountKey(c.Issuer) {
+		return c, ErrInvalidIssuer
+	}
+	if err := nkeys.VerifyWithPolicy(vp, c.Issuer, []byte(payload), sig); err != nil {
+		return c, err
+	}
+	if err := vp.CheckValidity(c.IssuedAt, c.Expires); err != nil {
+		return c, err
+	}
+	return c, nil
+}
@@ -0,0 +1,289 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuance
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nkeys"
+)
+
+func newTestServer(t *testing.T) (*Server, *MemoryStore) {
+	t.Helper()
+	account, err := nkeys.CreateAccount()
+	if err != nil {
+		t.Fatalf("Unexpected error creating account: %v", err)
+	}
+	store := NewMemoryStore()
+	s, err := NewServer(account, store, store)
+	if err != nil {
+		t.Fatalf("Unexpected error creating server: %v", err)
+	}
+	return s, store
+}
+
+func TestIssue(t *testing.T) {
+	s, store := newTestServer(t)
+	s.CredentialTTL = time.Hour
+	device, _ := nkeys.CreateUser()
+	dpk, _ := device.PublicKey()
+
+	c, err := s.Challenge()
+	if err != nil {
+		t.Fatalf("Unexpected error creating challenge: %v", err)
+	}
+	a, err := Respond(device, c)
+	if err != nil {
+		t.Fatalf("Unexpected error responding: %v", err)
+	}
+	cred, token, err := s.Issue(a)
+	if err != nil {
+		t.Fatalf("Unexpected error issuing: %v", err)
+	}
+	if cred.Subject != dpk {
+		t.Fatalf("Expected subject %q, got %q", dpk, cred.Subject)
+	}
+	if !cred.Expires.Equal(cred.IssuedAt.Add(time.Hour)) {
+		t.Fatalf("Expected credential to expire after CredentialTTL")
+	}
+	parsed, err := ParseCredential(token)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing credential: %v", err)
+	}
+	if parsed.ID != cred.ID || parsed.Issuer != cred.Issuer {
+		t.Fatalf("Expected parsed credential to match issued one")
+	}
+	if n := len(store.Credentials()); n != 1 {
+		t.Fatalf("Expected 1 stored credential, got %d", n)
+	}
+
+	// Replay of the same attestation must fail.
+	if _, _, err := s.Issue(a); err != ErrUnknownChallenge {
+		t.Fatalf("Expected ErrUnknownChallenge on replay, got %v", err)
+	}
+}
+
+func TestIssueRejects(t *testing.T) {
+	s, _ := newTestServer(t)
+	device, _ := nkeys.CreateUser()
+	other, _ := nkeys.CreateUser()
+
+	// Signature by a different key.
+	c, _ := s.Challenge()
+	a, _ := Respond(other, c)
+	a.Device, _ = device.PublicKey()
+	if _, _, err := s.Issue(a); err != nkeys.ErrInvalidSignature {
+		t.Fatalf("Expected ErrInvalidSignature, got %v", err)
+	}
+
+	// Wrong device type.
+	server, _ := nkeys.CreateServer()
+	c, _ = s.Challenge()
+	a, _ = Respond(server, c)
+	if _, _, err := s.Issue(a); err != ErrInvalidDevice {
+		t.Fatalf("Expected ErrInvalidDevice, got %v", err)
+	}
+
+	// Expired challenge.
+	s.ChallengeTTL = -time.Second
+	c, _ = s.Challenge()
+	a, _ = Respond(device, c)
+	if _, _, err := s.Issue(a); err != ErrExpiredChallenge {
+		t.Fatalf("Expected ErrExpiredChallenge, got %v", err)
+	}
+
+	// Authorizer.
+	s.ChallengeTTL = time.Minute
+	s.Authorize = func(string) error { return ErrNotAuthorized }
+	c, _ = s.Challenge()
+	a, _ = Respond(device, c)
+	if _, _, err := s.Issue(a); err != ErrNotAuthorized {
+		t.Fatalf("Expected ErrNotAuthorized, got %v", err)
+	}
+}
+
+func TestNewServerRequiresAccount(t *testing.T) {
+	user, _ := nkeys.CreateUser()
+	if _, err := NewServer(user, NewMemoryStore(), nil); err != ErrInvalidIssuer {
+		t.Fatalf("Expected ErrInvalidIssuer, got %v", err)
+	}
+	account, _ := nkeys.CreateAccount()
+	pk, _ := account.PublicKey()
+	public, _ := nkeys.FromPublicKey(pk)
+	if _, err := NewServer(public, NewMemoryStore(), nil); err != ErrInvalidIssuer {
+		t.Fatalf("Expected ErrInvalidIssuer for public only key, got %v", err)
+	}
+}
+
+func TestParseCredentialTampered(t *testing.T) {
+	s, _ := newTestServer(t)
+	device, _ := nkeys.CreateUser()
+	c, _ := s.Challenge()
+	a, _ := Respond(device, c)
+	_, token, err := s.Issue(a)
+	if err != nil {
+		t.Fatalf("Unexpected error issuing: %v", err)
+	}
+	if _, err := ParseCredential(token[1:]); err == nil {
+		t.Fatal("Expected an error parsing a tampered token")
+	}
+	if _, err := ParseCredential("nodot"); err != ErrInvalidToken {
+		t.Fatalf("Expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s, _ := newTestServer(t)
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	device, _ := nkeys.CreateUser()
+
+	resp, err := http.Post(ts.URL+"/challenge", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error requesting challenge: %v", err)
+	}
+	var c Challenge
+	err = json.NewDecoder(resp.Body).Decode(&c)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error decoding challenge: %v", err)
+	}
+
+	a, _ := Respond(device, c)
+	body, _ := json.Marshal(a)
+	resp, err = http.Post(ts.URL+"/issue", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Unexpected error issuing: %v", err)
+	}
+	var ir IssueResponse
+	err = json.NewDecoder(resp.Body).Decode(&ir)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error decoding response: %v", err)
+	}
+	if _, err := ParseCredential(ir.Token); err != nil {
+		t.Fatalf("Unexpected error parsing token: %v", err)
+	}
+
+	resp, err = http.Post(ts.URL+"/issue", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Unexpected error issuing: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected replay to be forbidden, got %d", resp.StatusCode)
+	}
+}
+
+func TestCanonicalEncoding(t *testing.T) {
+	s, _ := newTestServer(t)
+	device, _ := nkeys.CreateUser()
+	c, _ := s.Challenge()
+	a, _ := Respond(device, c)
+	cred, _, err := s.Issue(a)
+	if err != nil {
+		t.Fatalf("Unexpected error issuing: %v", err)
+	}
+
+	data, _ := c.MarshalBinary()
+	var c2 Challenge
+	if err := c2.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c2.ID != c.ID || !bytes.Equal(c2.Nonce, c.Nonce) || !c2.Expires.Equal(c.Expires) {
+		t.Fatal("Expected challenge to round trip")
+	}
+
+	data, _ = a.MarshalBinary()
+	var a2 Attestation
+	if err := a2.UnmarshalBinary(data); err != nil || !bytes.Equal(a2.Signature, a.Signature) {
+		t.Fatalf("Expected attestation to round trip: %v", err)
+	}
+
+	data, _ = cred.MarshalBinary()
+	var cred2 Credential
+	if err := cred2.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if again, _ := cred2.MarshalBinary(); !bytes.Equal(again, data) {
+		t.Fatal("Expected credential encoding to be deterministic")
+	}
+}
+
+func TestIssueClock(t *testing.T) {
+	s, _ := newTestServer(t)
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Clock = nkeys.ClockFunc(func() time.Time { return now })
+	s.CredentialTTL = time.Hour
+	device, _ := nkeys.CreateUser()
+
+	c, _ := s.Challenge()
+	if !c.Expires.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Expected challenge expiry from the clock, got %v", c.Expires)
+	}
+	a, _ := Respond(device, c)
+	now = now.Add(30 * time.Second)
+	cred, token, err := s.Issue(a)
+	if err != nil {
+		t.Fatalf("Unexpected error issuing: %v", err)
+	}
+	if !cred.IssuedAt.Equal(now) || !cred.Expires.Equal(now.Add(time.Hour)) {
+		t.Fatalf("Expected credential times from the clock, got %v - %v", cred.IssuedAt, cred.Expires)
+	}
+	// Expired by the system clock, even without a policy.
+	if _, err := ParseCredential(token); err != nkeys.ErrExpired {
+		t.Fatalf("Expected %v, got %v", nkeys.ErrExpired, err)
+	}
+	vp := &nkeys.VerifyPolicy{Clock: s.Clock}
+	if _, err := ParseCredentialWithPolicy(token, vp); err != nil {
+		t.Fatalf("Unexpected error parsing: %v", err)
+	}
+
+	c, _ = s.Challenge()
+	a, _ = Respond(device, c)
+	now = now.Add(2 * time.Minute)
+	if _, _, err := s.Issue(a); err != ErrExpiredChallenge {
+		t.Fatalf("Expected ErrExpiredChallenge, got %v", err)
+	}
+}
+
+func TestMemoryStoreLimit(t *testing.T) {
+	s, store := newTestServer(t)
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Clock = nkeys.ClockFunc(func() time.Time { return now })
+	store.Clock = s.Clock
+	store.MaxChallenges = 2
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Challenge(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if _, err := s.Challenge(); err != ErrTooManyChallenges {
+		t.Fatalf("Expected %v, got %v", ErrTooManyChallenges, err)
+	}
+	// Expired challenges make room.
+	now = now.Add(2 * time.Minute)
+	if _, err := s.Challenge(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := len(store.challenges); n != 1 {
+		t.Fatalf("Expected expired challenges to be dropped, got %d", n)
+	}
+}
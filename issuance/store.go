@@ -0,0 +1,95 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuance
+
+import (
+	"sync"
+
+	"github.com/nats-io/nkeys"
+)
+
+// DefaultMaxChallenges is the default limit of outstanding challenges of a
+// MemoryStore.
+const DefaultMaxChallenges = 10000
+
+// MemoryStore is an in-memory ChallengeStore and CredentialStore suitable
+// for a single server process.
+type MemoryStore struct {
+	// MaxChallenges limits the outstanding challenges, since anyone can
+	// request one. Expired challenges are dropped to make room; when
+	// none have expired Put fails with ErrTooManyChallenges. Zero means
+	// DefaultMaxChallenges.
+	MaxChallenges int
+	// Clock defaults to SystemClock.
+	Clock nkeys.Clock
+
+	mu          sync.Mutex
+	challenges  map[string]Challenge
+	credentials []Credential
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{challenges: make(map[string]Challenge)}
+}
+
+// Put stores the challenge.
+func (m *MemoryStore) Put(c Challenge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	limit := m.MaxChallenges
+	if limit <= 0 {
+		limit = DefaultMaxChallenges
+	}
+	if len(m.challenges) >= limit {
+		now := nkeys.ClockOrSystem(m.Clock).Now()
+		for id, old := range m.challenges {
+			if now.After(old.Expires) {
+				delete(m.challenges, id)
+			}
+		}
+		if len(m.challenges) >= limit {
+			return ErrTooManyChallenges
+		}
+	}
+	m.challenges[c.ID] = c
+	return nil
+}
+
+// Take returns and removes the challenge.
+func (m *MemoryStore) Take(id string) (Challenge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.challenges[id]
+	if !ok {
+		return Challenge{}, ErrUnknownChallenge
+	}
+	delete(m.challenges, id)
+	return c, nil
+}
+
+// Save records the credential.
+func (m *MemoryStore) Save(c Credential) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.credentials = append(m.credentials, c)
+	return nil
+}
+
+// Credentials returns the credentials issued so far.
+func (m *MemoryStore) Credentials() []Credential {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Credential{}, m.credentials...)
+}
@@ -0,0 +1,214 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+// SignJSON signs the JSON Canonicalization Scheme (RFC 8785) form of v, as
+// marshaled by encoding/json. Parties that serialize the same data with
+// different key order, whitespace or number formatting sign the same bytes,
+// so the signature can be checked against any of their encodings.
+func SignJSON(kp KeyPair, v interface{}) ([]byte, error) {
+	data, err := marshalCanonicalJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	return kp.Sign(data)
+}
+
+// VerifyJSON verifies a signature made by SignJSON. v is marshaled and
+// canonicalized the same way; pass a json.RawMessage to verify JSON text as
+// received.
+func VerifyJSON(public string, v interface{}, sig []byte) error {
+	data, err := marshalCanonicalJSON(v)
+	if err != nil {
+		return err
+	}
+	return VerifyWithPolicy(nil, public, data, sig)
+}
+
+// CanonicalJSON returns the RFC 8785 canonical form of the JSON text data.
+// Input with duplicate object keys or numbers out of the float64 range
+// returns ErrInvalidJSON.
+func CanonicalJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var buf bytes.Buffer
+	if err := writeCanonicalJSON(&buf, dec); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, ErrInvalidJSON
+	}
+	return buf.Bytes(), nil
+}
+
+func marshalCanonicalJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return CanonicalJSON(data)
+}
+
+type jsonMember struct {
+	key   string
+	utf16 []uint16
+	value []byte
+}
+
+func writeCanonicalJSON(buf *bytes.Buffer, dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return ErrInvalidJSON
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '[' {
+			buf.WriteByte('[')
+			for i := 0; dec.More(); i++ {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				if err := writeCanonicalJSON(buf, dec); err != nil {
+					return err
+				}
+			}
+			buf.WriteByte(']')
+		} else {
+			if err := writeCanonicalObject(buf, dec); err != nil {
+				return err
+			}
+		}
+		// Consume the closing delimiter.
+		if _, err := dec.Token(); err != nil {
+			return ErrInvalidJSON
+		}
+	case string:
+		writeCanonicalString(buf, t)
+	case json.Number:
+		f, err := strconv.ParseFloat(string(t), 64)
+		if err != nil {
+			return ErrInvalidJSON
+		}
+		buf.WriteString(formatCanonicalNumber(f))
+	case bool:
+		buf.WriteString(strconv.FormatBool(t))
+	case nil:
+		buf.WriteString("null")
+	}
+	return nil
+}
+
+// writeCanonicalObject writes the members of an object sorted by the UTF-16
+// code units of their keys.
+func writeCanonicalObject(buf *bytes.Buffer, dec *json.Decoder) error {
+	var members []jsonMember
+	seen := make(map[string]bool)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return ErrInvalidJSON
+		}
+		key := tok.(string)
+		if seen[key] {
+			return ErrInvalidJSON
+		}
+		seen[key] = true
+		var value bytes.Buffer
+		if err := writeCanonicalJSON(&value, dec); err != nil {
+			return err
+		}
+		members = append(members, jsonMember{key, utf16.Encode([]rune(key)), value.Bytes()})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		a, b := members[i].utf16, members[j].utf16
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	buf.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeCanonicalString(buf, m.key)
+		buf.WriteByte(':')
+		buf.Write(m.value)
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// writeCanonicalString escapes only what RFC 8785 requires.
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[r>>4])
+				buf.WriteByte(hex[r&0xf])
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// formatCanonicalNumber formats f like ECMAScript's Number.prototype.toString.
+func formatCanonicalNumber(f float64) string {
+	if f == 0 {
+		return "0"
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs < 1e-6 || abs >= 1e21 {
+		format = 'e'
+	}
+	s := strconv.FormatFloat(f, format, -1, 64)
+	if format == 'e' {
+		// Go pads the exponent to two digits, ECMAScript does not.
+		if n := len(s); n >= 4 && s[n-4] == 'e' && s[n-3] == '-' && s[n-2] == '0' {
+			s = s[:n-2] + s[n-1:]
+		}
+	}
+	return s
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"container/list"
+	"sync"
+)
+
+// KeyCacheStats are the counters of a KeyCache.
+type KeyCacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// Size is the number of cached keys, including pinned ones.
+	Size   int
+	Pinned int
+}
+
+type cachedKey struct {
+	public string
+	kp     KeyPair
+	pinned bool
+}
+
+// KeyCache caches the results of FromPublicKey for services that verify
+// traffic from many signers. Least recently used keys are evicted once the
+// cache is full; pinned keys, such as operators and accounts, are never
+// evicted and do not count towards the size. It is safe for concurrent use.
+type KeyCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     *list.List // front is most recently used, pinned keys excluded
+	stats   KeyCacheStats
+}
+
+// NewKeyCache returns a cache holding up to size unpinned keys.
+func NewKeyCache(size int) *KeyCache {
+	if size < 1 {
+		size = 1
+	}
+	return &KeyCache{size: size, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// Get returns the public KeyPair of public, decoding it on a miss. Invalid
+// keys are not cached. The KeyPair is shared by all callers and must not be
+// wiped.
+func (c *KeyCache) Get(public string) (KeyPair, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[public]; ok {
+		ck := e.Value.(*cachedKey)
+		if !ck.pinned {
+			c.lru.MoveToFront(e)
+		}
+		c.stats.Hits++
+		c.mu.Unlock()
+		return ck.kp, nil
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	kp, err := FromPublicKey(public)
+	if err != nil {
+		return nil, err
+	}
+	return c.add(public, kp, false), nil
+}
+
+// add caches kp unless public was added concurrently, and returns the
+// cached KeyPair.
+func (c *KeyCache) add(public string, kp KeyPair, pinned bool) KeyPair {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[public]; ok {
+		ck := e.Value.(*cachedKey)
+		if pinned && !ck.pinned {
+			c.pinLocked(e)
+		}
+		return ck.kp
+	}
+	c.insertLocked(&cachedKey{public, kp, pinned})
+	return kp
+}
+
+// insertLocked adds ck to the cache, evicting the least recently used keys
+// if needed.
+func (c *KeyCache) insertLocked(ck *cachedKey) {
+	if ck.pinned {
+		// Pinned keys are not in the LRU list, the element only holds them.
+		c.entries[ck.public] = &list.Element{Value: ck}
+		c.stats.Pinned++
+		return
+	}
+	c.entries[ck.public] = c.lru.PushFront(ck)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedKey).public)
+		c.stats.Evictions++
+	}
+}
+
+// Pin caches public and keeps it cached until Unpin.
+func (c *KeyCache) Pin(public string) error {
+	c.mu.Lock()
+	if e, ok := c.entries[public]; ok {
+		if !e.Value.(*cachedKey).pinned {
+			c.pinLocked(e)
+		}
+		c.mu.Unlock()
+		return nil
+	}
+	c.mu.Unlock()
+
+	kp, err := FromPublicKey(public)
+	if err != nil {
+		return err
+	}
+	c.add(public, kp, true)
+	return nil
+}
+
+func (c *KeyCache) pinLocked(e *list.Element) {
+	ck := e.Value.(*cachedKey)
+	c.lru.Remove(e)
+	ck.pinned = true
+	c.insertLocked(ck)
+}
+
+// Unpin makes public evictable again. It is kept as the most recently used
+// key.
+func (c *KeyCache) Unpin(public string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[public]
+	if !ok || !e.Value.(*cachedKey).pinned {
+		return
+	}
+	ck := e.Value.(*cachedKey)
+	ck.pinned = false
+	c.stats.Pinned--
+	c.insertLocked(ck)
+}
+
+// Verify verifies sig of input by public using the cached key.
+func (c *KeyCache) Verify(public string, input []byte, sig []byte) error {
+	kp, err := c.Get(public)
+	if err != nil {
+		return err
+	}
+	return kp.Verify(input, sig)
+}
+
+// Purge removes all unpinned keys.
+func (c *KeyCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		delete(c.entries, e.Value.(*cachedKey).public)
+	}
+	c.lru.Init()
+}
+
+// Stats returns the current counters.
+func (c *KeyCache) Stats() KeyCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Size = len(c.entries)
+	return s
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "testing"
+
+func TestKeyCache(t *testing.T) {
+	var keys []string
+	for i := 0; i < 4; i++ {
+		kp, _ := CreateUser()
+		pk, _ := kp.PublicKey()
+		keys = append(keys, pk)
+	}
+	op, _ := CreateOperator()
+	oppk, _ := op.PublicKey()
+
+	c := NewKeyCache(2)
+	if err := c.Pin(oppk); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, pk := range keys {
+		if _, err := c.Get(pk); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	kp, _ := c.Get(keys[3])
+	again, _ := c.Get(keys[3])
+	if kp != again {
+		t.Fatalf("Expected the cached KeyPair to be reused")
+	}
+	s := c.Stats()
+	if s.Hits != 2 || s.Misses != 4 || s.Evictions != 2 || s.Size != 3 || s.Pinned != 1 {
+		t.Fatalf("Expected 2 hits, 4 misses, 2 evictions and 3 keys, got %+v", s)
+	}
+
+	sig, _ := op.Sign([]byte("hello"))
+	if err := c.Verify(oppk, []byte("hello"), sig); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := c.Verify(keys[0], []byte("hello"), sig); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	if _, err := c.Get("invalid"); err == nil {
+		t.Fatalf("Expected an error for an invalid key")
+	}
+
+	c.Purge()
+	if s := c.Stats(); s.Size != 1 {
+		t.Fatalf("Expected only the pinned key, got %+v", s)
+	}
+	// Unpinned keys are evictable again.
+	c.Unpin(oppk)
+	c.Get(keys[0])
+	c.Get(keys[1])
+	if s := c.Stats(); s.Size != 2 || s.Pinned != 0 {
+		t.Fatalf("Expected the operator to be evicted, got %+v", s)
+	}
+}
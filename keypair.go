@@ -14,16 +14,24 @@
 package nkeys
 
 import (
-	"bytes"
 	"crypto/rand"
 	"io"
-
-	"golang.org/x/crypto/ed25519"
+	"sync"
 )
 
 // kp is the internal struct for a kepypair using seed.
 type kp struct {
 	seed []byte
+
+	// mu guards the lazily computed encodings below.
+	mu      sync.Mutex
+	public  string
+	private []byte
+
+	// rawPub and rawPriv are retained by PrecomputeKeys so Sign and Verify
+	// skip decoding the seed and NewKeyFromSeed on every call.
+	rawPub  []byte
+	rawPriv []byte
 }
 
 // All seeds are 32 bytes long.
@@ -36,8 +44,11 @@ func CreatePair(prefix PrefixByte) (KeyPair, error) {
 
 // CreatePair will create a KeyPair based on the rand reader and a type/prefix byte. rand can be nil.
 func CreatePairWithRand(prefix PrefixByte, rr io.Reader) (KeyPair, error) {
+	if err := checkDeprecated(prefix, "create"); err != nil {
+		return nil, err
+	}
 	if prefix == PrefixByteCurve {
-		return CreateCurveKeysWithRand(rr)
+		return createCurveKeys(rr)
 	}
 	if rr == nil {
 		rr = rand.Reader
@@ -53,7 +64,8 @@ func CreatePairWithRand(prefix PrefixByte, rr io.Reader) (KeyPair, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &kp{seed}, nil
+	countCreated(prefix)
+	return &kp{seed: seed}, nil
 }
 
 // rawSeed will return the raw, decoded 64 byte seed.
@@ -63,18 +75,57 @@ func (pair *kp) rawSeed() ([]byte, error) {
 }
 
 // keys will return a 32 byte public key and a 64 byte private key utilizing the seed.
-func (pair *kp) keys() (ed25519.PublicKey, ed25519.PrivateKey, error) {
+func (pair *kp) keys() ([]byte, []byte, error) {
 	raw, err := pair.rawSeed()
 	if err != nil {
 		return nil, nil, err
 	}
-	return ed25519.GenerateKey(bytes.NewReader(raw))
+	return currentBackend().NewKeyFromSeed(raw)
 }
 
-// Wipe will randomize the contents of the seed key
+// Wipe will randomize the contents of the seed key and the cached private key.
 func (pair *kp) Wipe() {
+	pair.mu.Lock()
+	defer pair.mu.Unlock()
 	io.ReadFull(rand.Reader, pair.seed)
 	pair.seed = nil
+	io.ReadFull(rand.Reader, pair.private)
+	pair.private = nil
+	pair.public = ""
+	io.ReadFull(rand.Reader, pair.rawPriv)
+	pair.rawPriv = nil
+	pair.rawPub = nil
+}
+
+// PrecomputeKeys makes kp retain its raw ed25519 keys until Wipe, so that
+// repeated Sign and Verify calls skip the base32 decoding of the seed and
+// NewKeyFromSeed, at the cost of keeping another copy of the private key in
+// memory. Sign still hashes the seed into the signing scalar and nonce
+// prefix on every call; those are not cached. KeyPairs that are not
+// ed25519 seeds return ErrInvalidNKeyOperation.
+func PrecomputeKeys(k KeyPair) error {
+	pair, ok := k.(*kp)
+	if !ok {
+		return ErrInvalidNKeyOperation
+	}
+	pair.mu.Lock()
+	defer pair.mu.Unlock()
+	if pair.rawPriv != nil {
+		return nil
+	}
+	pub, priv, err := pair.keys()
+	if err != nil {
+		return err
+	}
+	pair.rawPub, pair.rawPriv = pub, priv
+	return nil
+}
+
+// precomputed returns the keys retained by PrecomputeKeys, if any.
+func (pair *kp) precomputed() ([]byte, []byte) {
+	pair.mu.Lock()
+	defer pair.mu.Unlock()
+	return pair.rawPub, pair.rawPriv
 }
 
 // Seed will return the encoded seed.
@@ -83,13 +134,18 @@ func (pair *kp) Seed() ([]byte, error) {
 }
 
 // PublicKey will return the encoded public key associated with the KeyPair.
-// All KeyPairs have a public key.
+// All KeyPairs have a public key. The result is computed once and cached.
 func (pair *kp) PublicKey() (string, error) {
+	pair.mu.Lock()
+	defer pair.mu.Unlock()
+	if pair.public != "" {
+		return pair.public, nil
+	}
 	public, raw, err := DecodeSeed(pair.seed)
 	if err != nil {
 		return "", err
 	}
-	pub, _, err := ed25519.GenerateKey(bytes.NewReader(raw))
+	pub, _, err := currentBackend().NewKeyFromSeed(raw)
 	if err != nil {
 		return "", err
 	}
@@ -97,37 +153,65 @@ func (pair *kp) PublicKey() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return string(pk), nil
+	pair.public = string(pk)
+	return pair.public, nil
 }
 
-// PrivateKey will return the encoded private key for KeyPair.
+// PrivateKey will return the encoded private key for KeyPair. The result is
+// computed once and cached until Wipe; callers receive their own copy.
 func (pair *kp) PrivateKey() ([]byte, error) {
-	_, priv, err := pair.keys()
-	if err != nil {
-		return nil, err
+	pair.mu.Lock()
+	defer pair.mu.Unlock()
+	if pair.private == nil {
+		_, priv, err := pair.keys()
+		if err != nil {
+			return nil, err
+		}
+		if pair.private, err = Encode(PrefixBytePrivate, priv); err != nil {
+			return nil, err
+		}
 	}
-	return Encode(PrefixBytePrivate, priv)
+	return append([]byte{}, pair.private...), nil
 }
 
 // Sign will sign the input with KeyPair's private key.
 func (pair *kp) Sign(input []byte) ([]byte, error) {
-	_, priv, err := pair.keys()
-	if err != nil {
-		return nil, err
+	_, priv := pair.precomputed()
+	if priv == nil {
+		var err error
+		if _, priv, err = pair.keys(); err != nil {
+			return nil, err
+		}
+	}
+	sig, err := currentBackend().Sign(priv, input)
+	if err == nil {
+		countSigned()
 	}
-	return ed25519.Sign(priv, input), nil
+	return sig, err
 }
 
 // Verify will verify the input against a signature utilizing the public key.
 func (pair *kp) Verify(input []byte, sig []byte) error {
-	pub, _, err := pair.keys()
-	if err != nil {
-		return err
+	pub, _ := pair.precomputed()
+	if pub == nil {
+		var err error
+		if pub, _, err = pair.keys(); err != nil {
+			return err
+		}
 	}
-	if !ed25519.Verify(pub, input, sig) {
-		return ErrInvalidSignature
+	if !currentBackend().Verify(pub, input, sig) {
+		return countVerify(ErrInvalidSignature)
 	}
-	return nil
+	return countVerify(nil)
+}
+
+// PublicOnly returns a KeyPair holding only the public key.
+func (pair *kp) PublicOnly() (KeyPair, error) {
+	pk, err := pair.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return FromPublicKey(pk)
 }
 
 // Seal is only supported on CurveKeyPair
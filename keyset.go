@@ -0,0 +1,73 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "sync"
+
+// KeySet is a set of decoded public keys for finding which of them made a
+// signature. Keys are decoded once, so a KeySet should be reused when the
+// same candidates are checked repeatedly. It is safe for concurrent use.
+type KeySet struct {
+	mu   sync.RWMutex
+	keys []*PreparedKey
+	// last is the index of the most recent match, tried first since
+	// rotating signers tend to sign many messages in a row.
+	last int
+}
+
+// NewKeySet decodes the public keys and prepares them for verification,
+// see PrepareVerify. Curve keys can not sign and are rejected.
+func NewKeySet(publics ...string) (*KeySet, error) {
+	ks := &KeySet{keys: make([]*PreparedKey, 0, len(publics))}
+	for _, public := range publics {
+		pk, err := PrepareVerify(public)
+		if err != nil {
+			return nil, err
+		}
+		ks.keys = append(ks.keys, pk)
+	}
+	return ks, nil
+}
+
+// VerifyAny returns the public key that made sig over input, or
+// ErrInvalidSignature if none of them did.
+func (ks *KeySet) VerifyAny(input []byte, sig []byte) (string, error) {
+	ks.mu.RLock()
+	last := ks.last
+	n := len(ks.keys)
+	ks.mu.RUnlock()
+	for i := 0; i < n; i++ {
+		idx := (last + i) % n
+		k := ks.keys[idx]
+		if k.verify(input, sig) {
+			if idx != last {
+				ks.mu.Lock()
+				ks.last = idx
+				ks.mu.Unlock()
+			}
+			return k.public, nil
+		}
+	}
+	return "", ErrInvalidSignature
+}
+
+// VerifyAny returns which of the public keys made sig over input. Use a
+// KeySet when checking against the same keys repeatedly.
+func VerifyAny(publics []string, input []byte, sig []byte) (string, error) {
+	ks, err := NewKeySet(publics...)
+	if err != nil {
+		return "", err
+	}
+	return ks.VerifyAny(input, sig)
+}
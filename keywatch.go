@@ -0,0 +1,109 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// KeySighting records that a public key was seen in use by a tenant. The
+// role is the prefix of the public key.
+type KeySighting struct {
+	PublicKey string
+	Tenant    string
+	FirstSeen time.Time
+}
+
+// Role returns the prefix of the sighted public key.
+func (s KeySighting) Role() PrefixByte {
+	return Prefix(s.PublicKey)
+}
+
+// KeyReuse reports a raw key that was seen under more than one prefix or
+// by more than one tenant. Sharing a key between roles or tenants defeats
+// their separation and usually points at a provisioning mistake.
+type KeyReuse struct {
+	Fingerprint string
+	Sightings   []KeySighting
+}
+
+// KeyWatch is a registry of public keys in use, keyed by raw key so that the
+// same key is recognized under any prefix. It is safe for concurrent use.
+type KeyWatch struct {
+	// Clock defaults to SystemClock.
+	Clock Clock
+	// OnReuse, when set, is called whenever an observation adds a sighting
+	// to a reused key.
+	OnReuse func(KeyReuse)
+
+	mu   sync.Mutex
+	seen map[string][]KeySighting
+}
+
+// Observe records that tenant uses public. If the raw key has also been
+// seen with another prefix or tenant, the reuse is returned, otherwise nil.
+// Tenant may be empty for single tenant deployments.
+func (w *KeyWatch) Observe(public, tenant string) (*KeyReuse, error) {
+	if err := checkPublicKeyArg(public); err != nil {
+		return nil, err
+	}
+	fp, err := Fingerprint(public)
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	if w.seen == nil {
+		w.seen = make(map[string][]KeySighting)
+	}
+	sightings := w.seen[fp]
+	added := true
+	for _, s := range sightings {
+		if s.PublicKey == public && s.Tenant == tenant {
+			added = false
+			break
+		}
+	}
+	if added {
+		now := ClockOrSystem(w.Clock).Now()
+		sightings = append(sightings, KeySighting{public, tenant, now})
+		w.seen[fp] = sightings
+	}
+	var reuse *KeyReuse
+	if len(sightings) > 1 {
+		reuse = &KeyReuse{fp, append([]KeySighting(nil), sightings...)}
+	}
+	onReuse := w.OnReuse
+	w.mu.Unlock()
+
+	if reuse != nil && added && onReuse != nil {
+		onReuse(*reuse)
+	}
+	return reuse, nil
+}
+
+// Reuses returns every reused key, ordered by fingerprint.
+func (w *KeyWatch) Reuses() []KeyReuse {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var out []KeyReuse
+	for fp, sightings := range w.seen {
+		if len(sightings) > 1 {
+			out = append(out, KeyReuse{fp, append([]KeySighting(nil), sightings...)})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Fingerprint < out[j].Fingerprint })
+	return out
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "testing"
+
+func TestKeyWatch(t *testing.T) {
+	user, _ := CreateUser()
+	upk, _ := user.PublicKey()
+	raw, _ := Decode(PrefixByteUser, []byte(upk))
+	apk, _ := Encode(PrefixByteAccount, raw)
+	other, _ := CreateUser()
+	opk, _ := other.PublicKey()
+
+	var reported []KeyReuse
+	w := &KeyWatch{OnReuse: func(r KeyReuse) { reported = append(reported, r) }}
+	for _, tenant := range []string{"acme", "acme"} {
+		if r, err := w.Observe(upk, tenant); err != nil || r != nil {
+			t.Fatalf("Expected no reuse, got %v %v", r, err)
+		}
+	}
+	if r, _ := w.Observe(opk, "globex"); r != nil {
+		t.Fatalf("Expected no reuse, got %v", r)
+	}
+
+	// The same raw key as an account.
+	r, err := w.Observe(string(apk), "acme")
+	if err != nil || r == nil || len(r.Sightings) != 2 {
+		t.Fatalf("Expected reuse across roles, got %v %v", r, err)
+	}
+	if r.Sightings[1].Role() != PrefixByteAccount {
+		t.Fatalf("Expected %v, got %v", PrefixByteAccount, r.Sightings[1].Role())
+	}
+	// The same user key in another tenant.
+	if r, _ = w.Observe(upk, "globex"); r == nil || len(r.Sightings) != 3 {
+		t.Fatalf("Expected reuse across tenants, got %v", r)
+	}
+	if len(reported) != 2 {
+		t.Fatalf("Expected 2 reports, got %d", len(reported))
+	}
+	if reuses := w.Reuses(); len(reuses) != 1 || reuses[0].Fingerprint != r.Fingerprint {
+		t.Fatalf("Expected a single reused key, got %v", reuses)
+	}
+
+	seed, _ := user.Seed()
+	if _, err := w.Observe(string(seed), "acme"); err != ErrExpectedPublicKeyGotSeed {
+		t.Fatalf("Expected %v, got %v", ErrExpectedPublicKeyGotSeed, err)
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package macaroon implements attenuable capability tokens rooted in an
+// account nkey. The account signs the first block of caveats; anyone
+// holding a token can append further caveats offline, and each caveat only
+// narrows what the token grants.
+//
+// Unlike classic macaroons, which chain HMACs under a secret shared with
+// the verifier, blocks are chained with signatures: every block names the
+// public key of a fresh key pair that must sign the next block, and the
+// token carries the seed of the last one as its proof. Appending a block
+// replaces the proof, so earlier blocks can not be removed or changed, and
+// tokens can be verified by anyone who knows the account public key.
+package macaroon
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nkeys"
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// Errors
+const (
+	ErrInvalidIssuer     = macaroonError("macaroon: issuer must be an account key")
+	ErrInvalidToken      = macaroonError("macaroon: invalid token")
+	ErrInvalidChain      = macaroonError("macaroon: broken caveat chain")
+	ErrSubjectNotAllowed = macaroonError("macaroon: subject not allowed")
+	ErrTooManyMessages   = macaroonError("macaroon: message limit exceeded")
+)
+
+type macaroonError string
+
+func (e macaroonError) Error() string {
+	return string(e)
+}
+
+// Version prefixes encoded tokens.
+const Version = "nkm1"
+
+// maxBlocks bounds the caveat chain of decoded tokens.
+const maxBlocks = 64
+
+// Caveat restricts what a token grants. Zero fields add no restriction.
+type Caveat struct {
+	// Subjects is an allow-list of subjects, which may use the * and >
+	// wildcards.
+	Subjects []string
+	// Expires is when the token stops being valid.
+	Expires time.Time
+	// MaxMsgs is the number of messages the token may be used for.
+	MaxMsgs uint64
+}
+
+type block struct {
+	Caveat
+	// Next is the public key that must sign the following block.
+	Next      string
+	Signature []byte
+}
+
+// Token is a capability granted by an account, narrowed by its caveats.
+// Tokens are bearer credentials: whoever holds one can use and attenuate it.
+type Token struct {
+	Account string
+	ID      string
+	blocks  []block
+	// proof is the seed of the key named by the last block.
+	proof []byte
+}
+
+// Mint creates a token signed by the account key with the first caveat.
+func Mint(account nkeys.KeyPair, caveat Caveat) (*Token, error) {
+	public, err := account.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if nkeys.Prefix(public) != nkeys.PrefixByteAccount {
+		return nil, ErrInvalidIssuer
+	}
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return nil, err
+	}
+	t := &Token{Account: public, ID: base64.RawURLEncoding.EncodeToString(id)}
+	return t.append(account, caveat)
+}
+
+// Attenuate returns a copy of the token with caveat appended. t is left
+// untouched and remains usable.
+func (t *Token) Attenuate(caveat Caveat) (*Token, error) {
+	kp, err := nkeys.FromSeed(t.proof)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	defer kp.Wipe()
+	return t.append(kp, caveat)
+}
+
+// Caveats returns the caveats of the token, starting with the one set by
+// the account.
+func (t *Token) Caveats() []Caveat {
+	cs := make([]Caveat, len(t.blocks))
+	for i, b := range t.blocks {
+		cs[i] = b.Caveat
+	}
+	return cs
+}
+
+func (t *Token) append(signer nkeys.KeyPair, caveat Caveat) (*Token, error) {
+	next, err := nkeys.CreateUser()
+	if err != nil {
+		return nil, err
+	}
+	defer next.Wipe()
+	b := block{Caveat: caveat}
+	b.Subjects = append([]string(nil), caveat.Subjects...)
+	if b.Next, err = next.PublicKey(); err != nil {
+		return nil, err
+	}
+	if b.Signature, err = signer.Sign(t.signedBytes(len(t.blocks), b)); err != nil {
+		return nil, err
+	}
+	seed, err := next.Seed()
+	if err != nil {
+		return nil, err
+	}
+	n := &Token{Account: t.Account, ID: t.ID}
+	n.blocks = append(append(n.blocks, t.blocks...), b)
+	n.proof = append([]byte(nil), seed...)
+	return n, nil
+}
+
+// signedBytes is what the signer of block i signs. It binds the token and
+// the signature of the previous block.
+func (t *Token) signedBytes(i int, b block) []byte {
+	e := canonical.NewEncoder("nkeys.macaroon.Block")
+	e.Text(t.Account)
+	e.Text(t.ID)
+	e.Uint64(uint64(i))
+	if i > 0 {
+		e.Blob(t.blocks[i-1].Signature)
+	}
+	e.Strings(b.Subjects)
+	e.Time(b.Expires)
+	e.Uint64(b.MaxMsgs)
+	e.Text(b.Next)
+	return e.Bytes()
+}
+
+// Encode returns the token as text. The text contains the proof, so it must
+// be handled like any other secret.
+func (t *Token) Encode() string {
+	e := canonical.NewEncoder("nkeys.macaroon.Token")
+	e.Text(t.Account)
+	e.Text(t.ID)
+	e.Uint64(uint64(len(t.blocks)))
+	for _, b := range t.blocks {
+		e.Strings(b.Subjects)
+		e.Time(b.Expires)
+		e.Uint64(b.MaxMsgs)
+		e.Text(b.Next)
+		e.Blob(b.Signature)
+	}
+	e.Blob(t.proof)
+	return Version + "." + base64.RawURLEncoding.EncodeToString(e.Bytes())
+}
+
+// Decode parses a token produced by Encode. It does not verify it.
+func Decode(s string) (*Token, error) {
+	if !strings.HasPrefix(s, Version+".") {
+		return nil, ErrInvalidToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s[len(Version)+1:])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	d := canonical.NewDecoder("nkeys.macaroon.Token", raw)
+	t := &Token{Account: d.Text(), ID: d.Text()}
+	n := d.Uint64()
+	if n == 0 || n > maxBlocks {
+		return nil, ErrInvalidToken
+	}
+	for i := uint64(0); i < n && d.Err() == nil; i++ {
+		var b block
+		b.Subjects = d.Strings()
+		b.Expires = d.Time()
+		b.MaxMsgs = d.Uint64()
+		b.Next = d.Text()
+		b.Signature = d.Blob()
+		t.blocks = append(t.blocks, b)
+	}
+	t.proof = d.Blob()
+	if err := d.Finish(); err != nil {
+		return nil, ErrInvalidToken
+	}
+	return t, nil
+}
+
+// Grant is what a verified token allows: the intersection of its caveats.
+type Grant struct {
+	Account string
+	ID      string
+	// Expires is the earliest expiry of the caveats, zero if none expires.
+	Expires time.Time
+	// MaxMsgs is the lowest message limit of the caveats, zero if none.
+	MaxMsgs  uint64
+	subjects [][]string
+}
+
+// Verify checks the chain of signatures and the proof of the token and
+// returns what it grants. vp, which may be nil, is applied to the account
+// key and to the expiry.
+func Verify(t *Token, vp *nkeys.VerifyPolicy) (*Grant, error) {
+	if nkeys.Prefix(t.Account) != nkeys.PrefixByteAccount {
+		return nil, ErrInvalidIssuer
+	}
+	if len(t.blocks) == 0 {
+		return nil, ErrInvalidToken
+	}
+	g := &Grant{Account: t.Account, ID: t.ID}
+	signer := t.Account
+	for i, b := range t.blocks {
+		var p *nkeys.VerifyPolicy
+		if i == 0 {
+			p = vp
+		}
+		if err := nkeys.VerifyWithPolicy(p, signer, t.signedBytes(i, b), b.Signature); err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			return nil, ErrInvalidChain
+		}
+		if !b.Expires.IsZero() && (g.Expires.IsZero() || b.Expires.Before(g.Expires)) {
+			g.Expires = b.Expires
+		}
+		if b.MaxMsgs > 0 && (g.MaxMsgs == 0 || b.MaxMsgs < g.MaxMsgs) {
+			g.MaxMsgs = b.MaxMsgs
+		}
+		if len(b.Subjects) > 0 {
+			g.subjects = append(g.subjects, b.Subjects)
+		}
+		signer = b.Next
+	}
+	kp, err := nkeys.FromSeed(t.proof)
+	if err != nil {
+		return nil, ErrInvalidChain
+	}
+	defer kp.Wipe()
+	if public, err := kp.PublicKey(); err != nil || public != signer {
+		return nil, ErrInvalidChain
+	}
+	if err := vp.CheckValidity(time.Time{}, g.Expires); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Allows reports whether the grant allows publishing msgs messages in total
+// on subject.
+func (g *Grant) Allows(subject string, msgs uint64) error {
+	if g.MaxMsgs > 0 && msgs > g.MaxMsgs {
+		return ErrTooManyMessages
+	}
+	for _, list := range g.subjects {
+		if !matchAny(list, subject) {
+			return ErrSubjectNotAllowed
+		}
+	}
+	return nil
+}
+
+func matchAny(patterns []string, subject string) bool {
+	for _, p := range patterns {
+		if matchSubject(p, subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSubject matches a subject against a pattern in which * matches one
+// token and a trailing > matches one or more.
+func matchSubject(pattern, subject string) bool {
+	pts := strings.Split(pattern, ".")
+	sts := strings.Split(subject, ".")
+	for i, pt := range pts {
+		if pt == ">" && i == len(pts)-1 {
+			return len(sts) > i
+		}
+		if i >= len(sts) || pt != "*" && pt != sts[i] || sts[i] == "" {
+			return false
+		}
+	}
+	return len(pts) == len(sts)
+}
@@ -0,0 +1,111 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package macaroon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nkeys"
+)
+
+func TestAttenuate(t *testing.T) {
+	account, _ := nkeys.CreateAccount()
+	now := time.Unix(1700000000, 0)
+	vp := &nkeys.VerifyPolicy{Clock: nkeys.ClockFunc(func() time.Time { return now })}
+
+	root, err := Mint(account, Caveat{Subjects: []string{"orders.>"}, MaxMsgs: 100})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	narrow, err := root.Attenuate(Caveat{Subjects: []string{"orders.*.created"}, Expires: now.Add(time.Hour), MaxMsgs: 500})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tok, err := Decode(narrow.Encode())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	g, err := Verify(tok, vp)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if g.MaxMsgs != 100 || !g.Expires.Equal(now.Add(time.Hour)) {
+		t.Fatalf("Unexpected grant: %+v", g)
+	}
+	for _, tc := range []struct {
+		subject string
+		msgs    uint64
+		err     error
+	}{
+		{"orders.eu.created", 100, nil},
+		{"orders.eu.deleted", 1, ErrSubjectNotAllowed},
+		{"orders.eu.created", 101, ErrTooManyMessages},
+		{"billing.eu.created", 1, ErrSubjectNotAllowed},
+	} {
+		if err := g.Allows(tc.subject, tc.msgs); err != tc.err {
+			t.Fatalf("Expected %v for %q, got %v", tc.err, tc.subject, err)
+		}
+	}
+	if g, err := Verify(root, vp); err != nil || g.Allows("orders.eu.deleted", 1) != nil {
+		t.Fatalf("Expected the original token to stay usable, got %v", err)
+	}
+
+	// Dropping the last caveat leaves a proof that does not match.
+	stripped := *tok
+	stripped.blocks = stripped.blocks[:1]
+	if _, err := Verify(&stripped, vp); err != ErrInvalidChain {
+		t.Fatalf("Expected %v, got %v", ErrInvalidChain, err)
+	}
+	// Widening a caveat breaks its signature.
+	tok.blocks[1].Subjects = []string{">"}
+	if _, err := Verify(tok, vp); err != ErrInvalidChain {
+		t.Fatalf("Expected %v, got %v", ErrInvalidChain, err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := Verify(narrow, vp); err != nkeys.ErrExpired {
+		t.Fatalf("Expected %v, got %v", nkeys.ErrExpired, err)
+	}
+	other, _ := nkeys.CreateAccount()
+	otherPub, _ := other.PublicKey()
+	forged := *root
+	forged.Account = otherPub
+	if _, err := Verify(&forged, nil); err != nkeys.ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", nkeys.ErrInvalidSignature, err)
+	}
+	user, _ := nkeys.CreateUser()
+	if _, err := Mint(user, Caveat{}); err != ErrInvalidIssuer {
+		t.Fatalf("Expected %v, got %v", ErrInvalidIssuer, err)
+	}
+}
+
+func TestMatchSubject(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, subject string
+		want             bool
+	}{
+		{"a.b", "a.b", true},
+		{"a.*", "a.b", true},
+		{"a.*", "a.b.c", false},
+		{"a.>", "a.b.c", true},
+		{"a.>", "a", false},
+		{">", "a", true},
+		{"a.b", "a..", false},
+	} {
+		if got := matchSubject(tc.pattern, tc.subject); got != tc.want {
+			t.Fatalf("Expected %v for %q against %q, got %v", tc.want, tc.subject, tc.pattern, got)
+		}
+	}
+}
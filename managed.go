@@ -0,0 +1,219 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultRegenerateRetry is the delay before retrying a failed regeneration.
+const DefaultRegenerateRetry = time.Minute
+
+// ManagedKeyOptions configures a ManagedKeyPair.
+type ManagedKeyOptions struct {
+	// Lifetime is the intended lifetime of every key. Required.
+	Lifetime time.Duration
+	// RenewBefore is how long before expiry the key is regenerated. It
+	// defaults to a tenth of Lifetime.
+	RenewBefore time.Duration
+	// Provider returns the replacement key. It defaults to creating a new
+	// key of the same type with CreatePair.
+	Provider func() (KeyPair, error)
+	// OnRegenerate is called after the replacement key has been installed.
+	// The old key is wiped once it returns.
+	OnRegenerate func(old, new KeyPair)
+	// OnError is called when Provider fails. Regeneration is retried after
+	// RetryInterval, which defaults to DefaultRegenerateRetry.
+	OnError       func(error)
+	RetryInterval time.Duration
+	// Clock defaults to SystemClock. It is used for ExpiresAt only; the
+	// regeneration timer always runs on the system clock.
+	Clock Clock
+}
+
+// ManagedKeyPair is a KeyPair with an intended lifetime that replaces its
+// key shortly before it expires, for systems that rotate server or cluster
+// keys on a schedule. All KeyPair methods use the current key.
+type ManagedKeyPair struct {
+	opts ManagedKeyOptions
+
+	mu      sync.RWMutex
+	kp      KeyPair
+	expires time.Time
+	timer   *time.Timer
+	stopped bool
+}
+
+// NewManagedKeyPair manages kp, which expires Lifetime from now.
+func NewManagedKeyPair(kp KeyPair, opts ManagedKeyOptions) (*ManagedKeyPair, error) {
+	if opts.Lifetime <= 0 || opts.RenewBefore < 0 || opts.RenewBefore >= opts.Lifetime {
+		return nil, ErrInvalidLifetime
+	}
+	if opts.RenewBefore == 0 {
+		opts.RenewBefore = opts.Lifetime / 10
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = DefaultRegenerateRetry
+	}
+	if opts.Provider == nil {
+		public, err := kp.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		prefix := Prefix(public)
+		opts.Provider = func() (KeyPair, error) { return CreatePair(prefix) }
+	}
+	m := &ManagedKeyPair{opts: opts}
+	m.install(kp)
+	return m, nil
+}
+
+// install makes kp current and schedules its regeneration. m.mu must be held
+// or m must not be shared yet.
+func (m *ManagedKeyPair) install(kp KeyPair) {
+	m.kp = kp
+	m.expires = ClockOrSystem(m.opts.Clock).Now().Add(m.opts.Lifetime)
+	if !m.stopped {
+		m.schedule(m.opts.Lifetime - m.opts.RenewBefore)
+	}
+}
+
+func (m *ManagedKeyPair) schedule(d time.Duration) {
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.timer = time.AfterFunc(d, m.regenerate)
+}
+
+func (m *ManagedKeyPair) regenerate() {
+	if err := m.Regenerate(); err != nil {
+		m.mu.Lock()
+		if !m.stopped {
+			m.schedule(m.opts.RetryInterval)
+		}
+		m.mu.Unlock()
+		if m.opts.OnError != nil {
+			m.opts.OnError(err)
+		}
+	}
+}
+
+// Regenerate replaces the key now, restarting its lifetime. Keys returned
+// by Current before the call are wiped once OnRegenerate returns.
+func (m *ManagedKeyPair) Regenerate() error {
+	kp, err := m.opts.Provider()
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	old := m.kp
+	m.install(kp)
+	m.mu.Unlock()
+
+	if m.opts.OnRegenerate != nil {
+		m.opts.OnRegenerate(old, kp)
+	}
+	old.Wipe()
+	return nil
+}
+
+// Current returns the current key.
+func (m *ManagedKeyPair) Current() KeyPair {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.kp
+}
+
+// ExpiresAt returns when the current key expires.
+func (m *ManagedKeyPair) ExpiresAt() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.expires
+}
+
+// Stop cancels scheduled regeneration. The current key stays usable and
+// Regenerate can still be called.
+func (m *ManagedKeyPair) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopped = true
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+}
+
+// Seed will return the encoded seed of the current key.
+func (m *ManagedKeyPair) Seed() ([]byte, error) {
+	return m.Current().Seed()
+}
+
+// PublicKey will return the encoded public key of the current key.
+func (m *ManagedKeyPair) PublicKey() (string, error) {
+	return m.Current().PublicKey()
+}
+
+// PrivateKey will return the encoded private key of the current key.
+func (m *ManagedKeyPair) PrivateKey() ([]byte, error) {
+	return m.Current().PrivateKey()
+}
+
+// Sign will sign the input with the current key.
+func (m *ManagedKeyPair) Sign(input []byte) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.kp.Sign(input)
+}
+
+// Verify will verify the input against a signature with the current key.
+func (m *ManagedKeyPair) Verify(input []byte, sig []byte) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.kp.Verify(input, sig)
+}
+
+// PublicOnly returns a public only copy of the current key.
+func (m *ManagedKeyPair) PublicOnly() (KeyPair, error) {
+	return m.Current().PublicOnly()
+}
+
+// Wipe stops regeneration and wipes the current key.
+func (m *ManagedKeyPair) Wipe() {
+	m.Stop()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.kp.Wipe()
+}
+
+// Seal will seal the input with the current key.
+func (m *ManagedKeyPair) Seal(input []byte, recipient string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.kp.Seal(input, recipient)
+}
+
+// SealWithRand will seal the input with the current key.
+func (m *ManagedKeyPair) SealWithRand(input []byte, recipient string, rr io.Reader) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.kp.SealWithRand(input, recipient, rr)
+}
+
+// Open will open the input with the current key.
+func (m *ManagedKeyPair) Open(input []byte, sender string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.kp.Open(input, sender)
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"io"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// DefaultChunkSize is the chunk size used by SignChunks when none is given.
+const DefaultChunkSize = 1 << 20
+
+// Chunk describes one range of an artifact.
+type Chunk struct {
+	Offset int64
+	Length int64
+	Hash   [sha256.Size]byte
+}
+
+// Manifest is a signed list of chunk hashes covering an artifact. Because
+// every chunk is hashed separately, any range of the artifact can be
+// verified without reading the rest of it.
+type Manifest struct {
+	Signer    string
+	ChunkSize int64
+	Size      int64
+	Chunks    []Chunk
+	Signature []byte
+}
+
+// SignChunks hashes size bytes of r in chunks of chunkSize and signs the
+// resulting manifest with kp. A chunkSize of zero uses DefaultChunkSize.
+func SignChunks(kp KeyPair, r io.ReaderAt, size int64, chunkSize int64) (*Manifest, error) {
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize < 0 || size < 0 {
+		return nil, ErrInvalidManifest
+	}
+	pk, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{Signer: pk, ChunkSize: chunkSize, Size: size}
+	for off := int64(0); off < size; off += chunkSize {
+		n := chunkSize
+		if off+n > size {
+			n = size - off
+		}
+		h := sha256.New()
+		if _, err := io.Copy(h, io.NewSectionReader(r, off, n)); err != nil {
+			return nil, err
+		}
+		c := Chunk{Offset: off, Length: n}
+		h.Sum(c.Hash[:0])
+		m.Chunks = append(m.Chunks, c)
+	}
+	if m.Signature, err = kp.Sign(m.signedBytes()); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// signedBytes is the canonical encoding of everything but the signature.
+func (m *Manifest) signedBytes() []byte {
+	e := canonical.NewEncoder("nkeys.Manifest")
+	m.encode(e)
+	return e.Bytes()
+}
+
+func (m *Manifest) encode(e *canonical.Encoder) {
+	e.Text(m.Signer)
+	e.Int64(m.ChunkSize)
+	e.Int64(m.Size)
+	e.Uint64(uint64(len(m.Chunks)))
+	for _, c := range m.Chunks {
+		e.Int64(c.Offset)
+		e.Int64(c.Length)
+		e.Blob(c.Hash[:])
+	}
+}
+
+// Verify checks that the manifest was signed by public and that its chunks
+// exactly cover the artifact.
+func (m *Manifest) Verify(public string) error {
+	return m.VerifyWithPolicy(nil, public)
+}
+
+// VerifyWithPolicy is like Verify but also applies the verification policy
+// to the signer.
+func (m *Manifest) VerifyWithPolicy(vp *VerifyPolicy, public string) error {
+	if m.Signer != public {
+		return ErrInvalidSignature
+	}
+	if err := VerifyWithPolicy(vp, public, m.signedBytes(), m.Signature); err != nil {
+		return err
+	}
+	var off int64
+	for _, c := range m.Chunks {
+		if c.Offset != off || c.Length <= 0 || c.Length > m.ChunkSize {
+			return ErrInvalidManifest
+		}
+		off += c.Length
+	}
+	if off != m.Size {
+		return ErrInvalidManifest
+	}
+	return nil
+}
+
+// VerifyChunk checks data against the hash of chunk i. The manifest itself
+// must have been verified with Verify.
+func (m *Manifest) VerifyChunk(i int, data []byte) error {
+	if i < 0 || i >= len(m.Chunks) {
+		return ErrInvalidManifest
+	}
+	c := m.Chunks[i]
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != c.Length || subtle.ConstantTimeCompare(sum[:], c.Hash[:]) != 1 {
+		return ErrChunkMismatch
+	}
+	return nil
+}
+
+// VerifyRange reads every chunk overlapping [off, off+n) from r and checks
+// it against the manifest. The manifest itself must have been verified with
+// Verify.
+func (m *Manifest) VerifyRange(r io.ReaderAt, off, n int64) error {
+	if off < 0 || n < 0 || off+n > m.Size {
+		return ErrInvalidManifest
+	}
+	for i, c := range m.Chunks {
+		if c.Offset+c.Length <= off || c.Offset >= off+n {
+			continue
+		}
+		buf := make([]byte, c.Length)
+		if _, err := r.ReadAt(buf, c.Offset); err != nil && err != io.EOF {
+			return err
+		}
+		if err := m.VerifyChunk(i, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalBinary returns the canonical encoding of the Manifest.
+func (m *Manifest) MarshalBinary() ([]byte, error) {
+	e := canonical.NewEncoder("nkeys.SignedManifest")
+	m.encode(e)
+	e.Blob(m.Signature)
+	return e.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the canonical encoding of a Manifest.
+func (m *Manifest) UnmarshalBinary(data []byte) error {
+	d := canonical.NewDecoder("nkeys.SignedManifest", data)
+	v := Manifest{
+		Signer:    d.Text(),
+		ChunkSize: d.Int64(),
+		Size:      d.Int64(),
+	}
+	n := d.Uint64()
+	for i := uint64(0); i < n && d.Err() == nil; i++ {
+		var c Chunk
+		c.Offset = d.Int64()
+		c.Length = d.Int64()
+		hash := d.Blob()
+		if d.Err() == nil && len(hash) != sha256.Size {
+			return canonical.ErrInvalid
+		}
+		copy(c.Hash[:], hash)
+		v.Chunks = append(v.Chunks, c)
+	}
+	v.Signature = d.Blob()
+	if err := d.Finish(); err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignChunks(t *testing.T) {
+	owner, _ := CreateAccount()
+	pk, _ := owner.PublicKey()
+	artifact := make([]byte, 10_000)
+	rand.Read(artifact)
+
+	m, err := SignChunks(owner, bytes.NewReader(artifact), int64(len(artifact)), 4096)
+	if err != nil {
+		t.Fatalf("Unexpected error signing chunks: %v", err)
+	}
+	if len(m.Chunks) != 3 || m.Chunks[2].Length != 10_000-2*4096 {
+		t.Fatalf("Unexpected chunks %+v", m.Chunks)
+	}
+	if err := m.Verify(pk); err != nil {
+		t.Fatalf("Unexpected error verifying manifest: %v", err)
+	}
+	other, _ := CreateAccount()
+	opk, _ := other.PublicKey()
+	if err := m.Verify(opk); err != ErrInvalidSignature {
+		t.Fatalf("Expected ErrInvalidSignature for another signer, got %v", err)
+	}
+
+	// Only the chunks overlapping the range are read.
+	tampered := append([]byte{}, artifact...)
+	tampered[9000] ^= 0xff
+	if err := m.VerifyRange(bytes.NewReader(tampered), 100, 8000); err != nil {
+		t.Fatalf("Unexpected error verifying untouched range: %v", err)
+	}
+	if err := m.VerifyRange(bytes.NewReader(tampered), 8500, 100); err != ErrChunkMismatch {
+		t.Fatalf("Expected ErrChunkMismatch, got %v", err)
+	}
+	if err := m.VerifyRange(bytes.NewReader(artifact), 0, 20_000); err != ErrInvalidManifest {
+		t.Fatalf("Expected ErrInvalidManifest for an out of bounds range, got %v", err)
+	}
+	if err := m.VerifyChunk(1, artifact[4096:8192]); err != nil {
+		t.Fatalf("Unexpected error verifying chunk: %v", err)
+	}
+
+	// Altering the manifest breaks the signature.
+	m.Chunks[0].Hash[0] ^= 0xff
+	if err := m.Verify(pk); err != ErrInvalidSignature {
+		t.Fatalf("Expected ErrInvalidSignature for altered manifest, got %v", err)
+	}
+	m.Chunks[0].Hash[0] ^= 0xff
+
+	data, _ := m.MarshalBinary()
+	var m2 Manifest
+	if err := m2.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unexpected error unmarshaling: %v", err)
+	}
+	if err := m2.Verify(pk); err != nil {
+		t.Fatalf("Unexpected error verifying decoded manifest: %v", err)
+	}
+	if err := m2.UnmarshalBinary(data[:len(data)-3]); err == nil {
+		t.Fatal("Expected an error for a truncated manifest")
+	}
+}
+
+func TestSignChunksEmpty(t *testing.T) {
+	owner, _ := CreateUser()
+	pk, _ := owner.PublicKey()
+	m, err := SignChunks(owner, bytes.NewReader(nil), 0, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if m.ChunkSize != DefaultChunkSize || len(m.Chunks) != 0 {
+		t.Fatalf("Unexpected manifest %+v", m)
+	}
+	if err := m.Verify(pk); err != nil {
+		t.Fatalf("Unexpected error verifying: %v", err)
+	}
+	if _, err := SignChunks(owner, bytes.NewReader(nil), 10, -1); err != ErrInvalidManifest {
+		t.Fatalf("Expected ErrInvalidManifest, got %v", err)
+	}
+}
+
+func TestSignedConfig(t *testing.T) {
+	op, _ := CreateOperator()
+	opk, _ := op.PublicKey()
+	config := []byte("port: 4222\nauthorization { token: \"s3cr3t\" }\n")
+
+	signed, err := SignConfig(op, config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "server.conf")
+	if err := os.WriteFile(path, signed, 0600); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	got, err := LoadVerifiedConfig(opk, path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !bytes.Equal(got, config) {
+		t.Fatalf("Expected %q, got %q", config, got)
+	}
+
+	tampered := bytes.Replace(signed, []byte("4222"), []byte("4223"), 1)
+	if _, err := VerifyConfig(opk, tampered); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	other, _ := CreateOperator()
+	otherpk, _ := other.PublicKey()
+	if _, err := VerifyConfig(otherpk, signed); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	if _, err := VerifyConfig(opk, config); err != ErrInvalidSignedConfig {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignedConfig, err)
+	}
+}
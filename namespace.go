@@ -0,0 +1,195 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// namespaceSalt domain separates namespace derivation from any other use
+// of the master seed.
+const namespaceSalt = "nkeys-namespace-v1"
+
+// Namespace deterministically derives per tenant key pairs from a master
+// seed. Each tenant has a generation that is bumped by Rotate, so the same
+// (tenant, role, generation) always yields the same key pair.
+type Namespace struct {
+	mu          sync.RWMutex
+	master      []byte
+	generations map[string]uint32
+}
+
+// NewNamespace creates a Namespace from an encoded master seed. generations
+// restores the tenant generations previously returned by Generations and
+// may be nil.
+func NewNamespace(masterSeed []byte, generations map[string]uint32) (*Namespace, error) {
+	_, raw, err := DecodeSeed(masterSeed)
+	if err != nil {
+		return nil, err
+	}
+	ns := &Namespace{
+		master:      append([]byte{}, raw...),
+		generations: make(map[string]uint32, len(generations)),
+	}
+	for t, g := range generations {
+		ns.generations[t] = g
+	}
+	return ns, nil
+}
+
+// Add registers a tenant at generation zero. Adding a known tenant is a no-op.
+func (ns *Namespace) Add(tenant string) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if _, ok := ns.generations[tenant]; !ok {
+		ns.generations[tenant] = 0
+	}
+}
+
+// Remove forgets a tenant. Its keys can still be derived with KeyPairAt.
+func (ns *Namespace) Remove(tenant string) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	delete(ns.generations, tenant)
+}
+
+// Rotate bumps the generation of the tenant, registering it if needed,
+// and returns the new generation.
+func (ns *Namespace) Rotate(tenant string) uint32 {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.generations[tenant]++
+	return ns.generations[tenant]
+}
+
+// Generation returns the current generation of the tenant.
+func (ns *Namespace) Generation(tenant string) (uint32, error) {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+	g, ok := ns.generations[tenant]
+	if !ok {
+		return 0, ErrKeyNotFound
+	}
+	return g, nil
+}
+
+// Generations returns a copy of all tenant generations so that they can be
+// persisted and passed to NewNamespace.
+func (ns *Namespace) Generations() map[string]uint32 {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+	m := make(map[string]uint32, len(ns.generations))
+	for t, g := range ns.generations {
+		m[t] = g
+	}
+	return m
+}
+
+// Tenants returns the registered tenants in lexical order.
+func (ns *Namespace) Tenants() []string {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+	tenants := make([]string, 0, len(ns.generations))
+	for t := range ns.generations {
+		tenants = append(tenants, t)
+	}
+	sort.Strings(tenants)
+	return tenants
+}
+
+// KeyPair returns the key pair for the tenant's role at its current generation.
+func (ns *Namespace) KeyPair(tenant string, role PrefixByte) (KeyPair, error) {
+	g, err := ns.Generation(tenant)
+	if err != nil {
+		return nil, err
+	}
+	return ns.KeyPairAt(tenant, role, g)
+}
+
+// KeyPairAt returns the key pair for the tenant's role at the given
+// generation, for example to verify signatures made before a rotation.
+func (ns *Namespace) KeyPairAt(tenant string, role PrefixByte, generation uint32) (KeyPair, error) {
+	if err := checkValidPublicPrefixByte(role); err != nil {
+		return nil, err
+	}
+	var info []byte
+	info = binary.BigEndian.AppendUint32(info, uint32(len(tenant)))
+	info = append(info, tenant...)
+	info = append(info, byte(role))
+	info = binary.BigEndian.AppendUint32(info, generation)
+
+	var raw [seedLen]byte
+	ns.mu.RLock()
+	if ns.master == nil {
+		ns.mu.RUnlock()
+		return nil, ErrInvalidSeed
+	}
+	r := hkdf.New(sha256.New, ns.master, []byte(namespaceSalt), info)
+	_, err := io.ReadFull(r, raw[:])
+	ns.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if role == PrefixByteCurve {
+		var kp ckp
+		kp.seed = raw
+		return &kp, nil
+	}
+	return FromRawSeed(role, raw[:])
+}
+
+// TenantKey is a tenant's public key for a role.
+type TenantKey struct {
+	Tenant     string
+	Generation uint32
+	PublicKey  string
+}
+
+// Enumerate returns the current public key of every tenant for the role.
+func (ns *Namespace) Enumerate(role PrefixByte) ([]TenantKey, error) {
+	var keys []TenantKey
+	for _, t := range ns.Tenants() {
+		g, err := ns.Generation(t)
+		if err != nil {
+			// Removed concurrently.
+			continue
+		}
+		kp, err := ns.KeyPairAt(t, role, g)
+		if err != nil {
+			return nil, err
+		}
+		pk, err := kp.PublicKey()
+		kp.Wipe()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, TenantKey{t, g, pk})
+	}
+	return keys, nil
+}
+
+// Wipe will randomize the master seed.
+func (ns *Namespace) Wipe() {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	io.ReadFull(rand.Reader, ns.master)
+	ns.master = nil
+}
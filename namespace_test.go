@@ -0,0 +1,110 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "testing"
+
+func publicKeyAt(t *testing.T, ns *Namespace, tenant string, role PrefixByte, g uint32) string {
+	t.Helper()
+	kp, err := ns.KeyPairAt(tenant, role, g)
+	if err != nil {
+		t.Fatalf("Unexpected error deriving key pair: %v", err)
+	}
+	pk, _ := kp.PublicKey()
+	return pk
+}
+
+func TestNamespaceDeterministic(t *testing.T) {
+	op, _ := CreateOperator()
+	seed, _ := op.Seed()
+	ns1, _ := NewNamespace(seed, nil)
+	ns2, _ := NewNamespace(seed, nil)
+
+	a1 := publicKeyAt(t, ns1, "acme", PrefixByteAccount, 0)
+	if a2 := publicKeyAt(t, ns2, "acme", PrefixByteAccount, 0); a1 != a2 {
+		t.Fatalf("Expected the same key from the same master seed")
+	}
+	if !IsValidPublicAccountKey(a1) {
+		t.Fatalf("Expected an account key, got %q", a1)
+	}
+	for _, other := range []string{
+		publicKeyAt(t, ns1, "acme", PrefixByteUser, 0),
+		publicKeyAt(t, ns1, "acme", PrefixByteAccount, 1),
+		publicKeyAt(t, ns1, "acm", PrefixByteAccount, 0),
+	} {
+		if other[1:] == a1[1:] {
+			t.Fatalf("Expected distinct keys for distinct inputs")
+		}
+	}
+	if !IsValidPublicCurveKey(publicKeyAt(t, ns1, "acme", PrefixByteCurve, 0)) {
+		t.Fatal("Expected a curve key")
+	}
+	if _, err := ns1.KeyPairAt("acme", PrefixByteSeed, 0); err != ErrInvalidPrefixByte {
+		t.Fatalf("Expected ErrInvalidPrefixByte, got %v", err)
+	}
+
+	other, _ := CreateOperator()
+	oseed, _ := other.Seed()
+	ns3, _ := NewNamespace(oseed, nil)
+	if publicKeyAt(t, ns3, "acme", PrefixByteAccount, 0) == a1 {
+		t.Fatal("Expected a different master seed to yield different keys")
+	}
+}
+
+func TestNamespaceRotate(t *testing.T) {
+	op, _ := CreateOperator()
+	seed, _ := op.Seed()
+	ns, _ := NewNamespace(seed, nil)
+
+	if _, err := ns.KeyPair("acme", PrefixByteAccount); err != ErrKeyNotFound {
+		t.Fatalf("Expected ErrKeyNotFound for unknown tenant, got %v", err)
+	}
+	ns.Add("acme")
+	ns.Add("globex")
+	kp, _ := ns.KeyPair("acme", PrefixByteAccount)
+	before, _ := kp.PublicKey()
+
+	if g := ns.Rotate("acme"); g != 1 {
+		t.Fatalf("Expected generation 1, got %d", g)
+	}
+	kp, _ = ns.KeyPair("acme", PrefixByteAccount)
+	after, _ := kp.PublicKey()
+	if before == after {
+		t.Fatal("Expected rotation to change the key")
+	}
+
+	keys, err := ns.Enumerate(PrefixByteAccount)
+	if err != nil {
+		t.Fatalf("Unexpected error enumerating: %v", err)
+	}
+	if len(keys) != 2 || keys[0].Tenant != "acme" || keys[0].PublicKey != after || keys[0].Generation != 1 {
+		t.Fatalf("Unexpected keys %+v", keys)
+	}
+
+	// Restoring from persisted generations yields the same keys.
+	restored, _ := NewNamespace(seed, ns.Generations())
+	kp, _ = restored.KeyPair("acme", PrefixByteAccount)
+	if pk, _ := kp.PublicKey(); pk != after {
+		t.Fatal("Expected restored namespace to derive the same key")
+	}
+
+	ns.Remove("globex")
+	if tenants := ns.Tenants(); len(tenants) != 1 || tenants[0] != "acme" {
+		t.Fatalf("Unexpected tenants %v", tenants)
+	}
+	ns.Wipe()
+	if _, err := ns.KeyPair("acme", PrefixByteAccount); err != ErrInvalidSeed {
+		t.Fatalf("Expected ErrInvalidSeed after wipe, got %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/nats-io/nkeys"
+	"github.com/nats-io/nkeys/agent"
+)
+
+func usage() {
+	log.Fatalf(`Usage: nk-agent [options]
+    -sock <path>          Socket path, defaults to $%[1]s or a new temporary socket
+    -l                    List the public keys held by the agent
+    -a <file>             Add the seed in <file> to the agent
+    -exe <paths>          With -a, comma separated executables allowed to sign with the key
+    -c                    With -a, require confirmation of every signature
+    -askpass <cmd>        Program the agent runs to confirm signatures, approving if it exits with 0
+    -d <public>           Remove the key from the agent
+    -D                    Remove all keys from the agent
+    -x                    Lock the agent with a passphrase read from stdin
+    -X                    Unlock the agent with a passphrase read from stdin
+
+Without -l, -a, -d, -D, -x or -X the agent is started.
+`, agent.SocketEnv)
+}
+
+func main() {
+	var sock = flag.String("sock", "", "Socket path")
+	var list = flag.Bool("l", false, "List keys")
+	var add = flag.String("a", "", "Add the seed in <file>")
+	var remove = flag.String("d", "", "Remove <public>")
+	var removeAll = flag.Bool("D", false, "Remove all keys")
+	var lock = flag.Bool("x", false, "Lock the agent")
+	var unlock = flag.Bool("X", false, "Unlock the agent")
+	var exes = flag.String("exe", "", "Executables allowed to sign with the added key")
+	var confirm = flag.Bool("c", false, "Require confirmation of every signature with the added key")
+	var askpass = flag.String("askpass", "", "Program confirming signatures")
+
+	log.SetFlags(0)
+	log.SetOutput(os.Stdout)
+
+	flag.Usage = usage
+	flag.Parse()
+
+	if !*list && *add == "" && *remove == "" && !*removeAll && !*lock && !*unlock {
+		serve(*sock, *askpass)
+		return
+	}
+
+	c, err := agent.Dial(*sock)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+
+	switch {
+	case *list:
+		keys, err := c.List()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, k := range keys {
+			log.Printf("%s", k)
+		}
+	case *add != "":
+		kp, err := nkeys.FromSeedFile(*add)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer kp.Wipe()
+		kc := agent.KeyConstraints{Confirm: *confirm}
+		if *exes != "" {
+			kc.Executables = strings.Split(*exes, ",")
+		}
+		if err := c.AddConstrained(kp, kc); err != nil {
+			log.Fatal(err)
+		}
+		pk, _ := kp.PublicKey()
+		log.Printf("Added %s", pk)
+	case *remove != "":
+		if err := c.Remove(*remove); err != nil {
+			log.Fatal(err)
+		}
+	case *removeAll:
+		if err := c.RemoveAll(); err != nil {
+			log.Fatal(err)
+		}
+	case *lock:
+		if err := c.Lock(readPassphrase()); err != nil {
+			log.Fatal(err)
+		}
+	case *unlock:
+		if err := c.Unlock(readPassphrase()); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func readPassphrase() []byte {
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatal(err)
+	}
+	return []byte(strings.TrimRight(line, "\r\n"))
+}
+
+func serve(sock string, askpass string) {
+	if sock == "" {
+		dir, err := os.MkdirTemp("", "nk-agent-")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		sock = filepath.Join(dir, "agent.sock")
+	}
+	l, err := listenPrivate(sock)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.Remove(sock)
+	if runtime.GOOS != "linux" {
+		log.Printf("Peer credentials are not available on %s, any process that can open %s may use the agent", runtime.GOOS, sock)
+	}
+	a := agent.New()
+	if askpass != "" {
+		a.Confirm = func(r agent.ConfirmRequest) bool {
+			msg := fmt.Sprintf("Allow %s (pid %d) to sign %d bytes with %s?", r.Peer.Exe, r.Peer.PID, r.Size, r.PublicKey)
+			return exec.Command(askpass, msg).Run() == nil
+		}
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		l.Close()
+	}()
+
+	log.Printf("%s=%s; export %s;", agent.SocketEnv, sock, agent.SocketEnv)
+	a.Serve(l)
+	a.RemoveAll()
+}
+
+// listenPrivate listens on sock without it ever being reachable by other
+// users: the socket is created inside a new directory only the user can
+// access and moved to sock once its mode is 0600.
+func listenPrivate(sock string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(sock), ".nk-agent-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	// The socket is removed by serve, not by Close, since it was moved.
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, sock); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
@@ -0,0 +1,185 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// The cache agent keeps decrypted seeds in memory for a limited time so
+// that encrypted seed files do not prompt on every use, similar to
+// ssh-agent. Seeds are only ever held in memory and passed over a unix
+// socket inside a directory only the user can access.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nats-io/nkeys/agent"
+)
+
+type agentRequest struct {
+	Op   string `json:"op"`
+	ID   string `json:"id"`
+	Seed []byte `json:"seed,omitempty"`
+}
+
+type agentResponse struct {
+	Seed  []byte `json:"seed,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type cachedSeed struct {
+	seed  []byte
+	timer *time.Timer
+}
+
+type seedCache struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	seeds map[string]*cachedSeed
+}
+
+func (c *seedCache) put(id string, seed []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(id)
+	cs := &cachedSeed{seed: append([]byte{}, seed...)}
+	cs.timer = time.AfterFunc(c.ttl, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.seeds[id] == cs {
+			c.removeLocked(id)
+		}
+	})
+	c.seeds[id] = cs
+}
+
+func (c *seedCache) get(id string) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cs, ok := c.seeds[id]; ok {
+		return append([]byte{}, cs.seed...)
+	}
+	return nil
+}
+
+func (c *seedCache) removeLocked(id string) {
+	if cs, ok := c.seeds[id]; ok {
+		cs.timer.Stop()
+		wipeSlice(cs.seed)
+		delete(c.seeds, id)
+	}
+}
+
+func (c *seedCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id := range c.seeds {
+		c.removeLocked(id)
+	}
+}
+
+func (c *seedCache) serve(conn net.Conn) {
+	defer conn.Close()
+	var req agentRequest
+	var resp agentResponse
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	switch req.Op {
+	case "get":
+		resp.Seed = c.get(req.ID)
+	case "put":
+		c.put(req.ID, req.Seed)
+		wipeSlice(req.Seed)
+	case "clear":
+		c.clear()
+	default:
+		resp.Error = "unknown operation"
+	}
+	json.NewEncoder(conn).Encode(&resp)
+	wipeSlice(resp.Seed)
+}
+
+// runAgent serves the seed cache until interrupted.
+func runAgent(ttl time.Duration) {
+	dir, err := os.MkdirTemp("", "nk-agent-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	sock := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		os.RemoveAll(dir)
+		log.Fatal(err)
+	}
+	cache := &seedCache{ttl: ttl, seeds: make(map[string]*cachedSeed)}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		l.Close()
+	}()
+
+	fmt.Printf("%s=%s; export %s;\n", agent.SocketEnv, sock, agent.SocketEnv)
+	fmt.Fprintf(os.Stderr, "nk agent caching seeds for %v, press Ctrl-C to stop\n", ttl)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			break
+		}
+		go cache.serve(conn)
+	}
+	cache.clear()
+	os.RemoveAll(dir)
+}
+
+func agentCall(req *agentRequest) (*agentResponse, error) {
+	sock := os.Getenv(agent.SocketEnv)
+	if sock == "" {
+		return nil, fmt.Errorf("%s is not set", agent.SocketEnv)
+	}
+	conn, err := net.DialTimeout("unix", sock, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp agentResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("agent: %s", resp.Error)
+	}
+	return &resp, nil
+}
+
+// encryptedSeedID identifies an encrypted seed file by its contents, so
+// that edits to the file invalidate the cached seed.
+func encryptedSeedID(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
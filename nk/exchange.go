@@ -0,0 +1,89 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// Seed exchange lets a seed be handed over a chat or ticket without ever
+// pasting it in the clear. The receiver runs nk -receive, which prints a
+// request to paste to the sender and a one-time code to read out to them
+// over another channel. The sender runs nk -send with the request, enters
+// the code and pastes the reply back to the waiting receiver.
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/nats-io/nkeys"
+)
+
+func receiveSeed(outFile string) {
+	// Fail before the exchange starts rather than after the seed arrived.
+	f, err := os.OpenFile(outFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	x, err := nkeys.NewSeedExchange()
+	if err != nil {
+		os.Remove(outFile)
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "Send this request to the holder of the seed:\n\n")
+	fmt.Fprintf(os.Stdout, "%s\n\n", x.Request())
+	fmt.Fprintf(os.Stderr, "Tell them this code over a different channel: %s\n\n", x.Code())
+	fmt.Fprint(os.Stderr, "Paste their reply: ")
+	reply, err := stdin.ReadString('\n')
+	if err != nil && reply == "" {
+		os.Remove(outFile)
+		log.Fatal(err)
+	}
+	seed, err := x.Open(reply)
+	if err != nil {
+		os.Remove(outFile)
+		log.Fatal(err)
+	}
+	defer wipeSlice(seed)
+	kp, err := nkeys.FromSeed(seed)
+	if err != nil {
+		os.Remove(outFile)
+		log.Fatal(err)
+	}
+	if _, err := f.Write(append(seed, '\n')); err != nil {
+		os.Remove(outFile)
+		log.Fatal(err)
+	}
+	pub, _ := kp.PublicKey()
+	fmt.Fprintf(os.Stderr, "Stored seed for %s in %s\n", pub, outFile)
+}
+
+func sendSeed(request, keyFile string) {
+	if keyFile == "" {
+		log.Fatalf("Send requires a seed via -inkey <file>")
+	}
+	seed := readSeedFile(keyFile)
+	defer wipeSlice(seed)
+	fmt.Fprint(os.Stderr, "Enter the code from the receiver: ")
+	code, err := stdin.ReadBytes('\n')
+	if err != nil && len(code) == 0 {
+		log.Fatal(err)
+	}
+	reply, err := nkeys.SealForExchange(request, string(bytes.TrimSpace(code)), seed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "Send this reply back to the receiver:\n\n")
+	log.Printf("%s", reply)
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nats-io/nkeys"
+)
+
+// encFileExt is appended to encrypted files.
+const encFileExt = ".nkf"
+
+// encryptFile encrypts fname to the public curve key recipient. Without a
+// sender seed an ephemeral key is used, so the file is anonymous.
+func encryptFile(fname, recipient, keyFile, outFile string) {
+	if recipient == "" {
+		log.Fatalf("Encrypting a file requires a public curve key via -to <key>")
+	}
+	if !nkeys.IsValidPublicCurveKey(recipient) {
+		// Allow a file holding the key.
+		recipient = string(readKeyFile(recipient))
+	}
+	var sender nkeys.KeyPair
+	var err error
+	if keyFile != "" {
+		sender, err = nkeys.FromCurveSeed(readSeedFile(keyFile))
+	} else {
+		sender, err = nkeys.CreateCurveKeys()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer sender.Wipe()
+	if outFile == "" {
+		outFile = fname + encFileExt
+	}
+
+	in, err := os.Open(fname)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+	writeAtomic(outFile, func(w *bufio.Writer) error {
+		return nkeys.EncryptStream(w, bufio.NewReader(in), sender, recipient)
+	})
+}
+
+// decryptFile decrypts fname with the curve seed in keyFile and prints the
+// public key of the sender.
+func decryptFile(fname, keyFile, outFile string) {
+	if keyFile == "" {
+		log.Fatalf("Decrypting a file requires a curve seed via -inkey <file>")
+	}
+	recipient, err := nkeys.FromCurveSeed(readSeedFile(keyFile))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer recipient.Wipe()
+	if outFile == "" {
+		if !strings.HasSuffix(fname, encFileExt) {
+			log.Fatalf("Decrypting %s requires an output file via -out <file>", fname)
+		}
+		outFile = strings.TrimSuffix(fname, encFileExt)
+	}
+
+	in, err := os.Open(fname)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+	var sender string
+	writeAtomic(outFile, func(w *bufio.Writer) error {
+		sender, err = nkeys.DecryptStream(w, bufio.NewReader(in), recipient)
+		return err
+	})
+	log.Printf("Decrypted %s from %s", outFile, sender)
+}
+
+// writeAtomic only creates outFile if write succeeds, so that partial
+// output of a failed decryption is never left behind.
+func writeAtomic(outFile string, write func(w *bufio.Writer) error) {
+	if _, err := os.Stat(outFile); err == nil {
+		log.Fatalf("%s already exists", outFile)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(outFile), "."+filepath.Base(outFile)+"-*")
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(tmp)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), outFile)
+	}
+	if err != nil {
+		// log.Fatal does not run deferred calls.
+		os.Remove(tmp.Name())
+		log.Fatal(fmt.Errorf("%s: %w", outFile, err))
+	}
+}
@@ -18,14 +18,20 @@ import (
 	"crypto/rand"
 	"encoding/base32"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/nats-io/nkeys"
+	"github.com/nats-io/nkeys/agent"
 )
 
 // this will be set during compilation when a release is made on tools
@@ -46,6 +52,20 @@ func usage() {
     -e                    Entropy file, e.g. /dev/urandom
     -pre <vanity>         Attempt to generate public key given prefix, e.g. nk -gen user -pre derek
     -maxpre <N>           Maximum attempts at generating the correct key prefix, default is 10,000,000
+    -encrypt              Encrypt the generated seed with a passphrase, used with -gen
+    -agent                Run an agent caching decrypted seeds in memory, see NKEY_AGENT_SOCK
+    -ttl <duration>       How long the agent caches decrypted seeds, default is 15m
+    -receive <file>       Receive a seed from another user over an encrypted exchange and store it in <file>
+    -send <request>       Send the seed in -inkey <keyfile> to the user who made the exchange <request>
+    -encfile <file>       Encrypt <file> to the curve key -to <key|file>, optionally from the curve seed -inkey <keyfile>
+    -decfile <file>       Decrypt <file> with the curve seed -inkey <keyfile>
+    -to <key|file>        Recipient public curve key
+    -out <file>           Output file for -encfile and -decfile
+    -describe <file>      Describe the key, seed or private key in <file> as JSON, "-" reads stdin
+    -export <dir>         Write the public key of -pubin <file> or -inkey <file> as a key record to <dir>
+    -name <name>          Name of the exported key record
+    -comment <text>       Comment of the exported key record
+    -validate <dir>       Validate the key records in <dir>
 `)
 }
 
@@ -70,6 +90,20 @@ func main() {
 	var version = flag.Bool("v", false, "Show version")
 	var vanPre = flag.String("pre", "", "Attempt to generate public key given prefix, e.g. nk -gen user -pre derek")
 	var vanMax = flag.Int("maxpre", defaultVanMax, "Maximum attempts at generating the correct key prefix")
+	var encrypt = flag.Bool("encrypt", false, "Encrypt the generated seed with a passphrase")
+	var agent = flag.Bool("agent", false, "Run an agent caching decrypted seeds in memory")
+	var ttl = flag.Duration("ttl", 15*time.Minute, "How long the agent caches decrypted seeds")
+	var receive = flag.String("receive", "", "Receive a seed over an encrypted exchange and store it in <file>")
+	var send = flag.String("send", "", "Send the seed in -inkey <keyfile> to the user who made the exchange <request>")
+	var encFile = flag.String("encfile", "", "Encrypt <file> to the curve key -to <key|file>")
+	var decFile = flag.String("decfile", "", "Decrypt <file> with the curve seed -inkey <keyfile>")
+	var to = flag.String("to", "", "Recipient public curve key")
+	var outFile = flag.String("out", "", "Output file for -encfile and -decfile")
+	var describe = flag.String("describe", "", "Describe the key in <file> as JSON")
+	var export = flag.String("export", "", "Write the public key as a key record to <dir>")
+	var name = flag.String("name", "", "Name of the exported key record")
+	var comment = flag.String("comment", "", "Comment of the exported key record")
+	var validate = flag.String("validate", "", "Validate the key records in <dir>")
 
 	log.SetFlags(0)
 	log.SetOutput(os.Stdout)
@@ -81,6 +115,11 @@ func main() {
 		fmt.Printf("nk version %s\n", Version)
 	}
 
+	if *agent {
+		runAgent(*ttl)
+		return
+	}
+
 	// Create Key
 	if *keyType != "" {
 		var kp KeyPair
@@ -94,6 +133,18 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
+		if *encrypt {
+			pw, err := readNewPassword()
+			if err != nil {
+				log.Fatal(err)
+			}
+			enc, err := nkeys.EncryptSeed(seed, pw, nil)
+			wipeSlice(pw)
+			if err != nil {
+				log.Fatal(err)
+			}
+			seed = bytes.TrimSpace(enc)
+		}
 		log.Printf("%s", seed)
 		if *pubout || *vanPre != "" {
 			pub, _ := kp.PublicKey()
@@ -107,6 +158,41 @@ func main() {
 		log.Fatalf("Entropy file only used when creating keys with -gen")
 	}
 
+	// Seed exchange
+	if *receive != "" {
+		receiveSeed(*receive)
+		return
+	}
+	if *send != "" {
+		sendSeed(*send, *keyFile)
+		return
+	}
+
+	if *describe != "" {
+		describeKey(*describe)
+		return
+	}
+
+	// Key records
+	if *export != "" {
+		exportRecord(*export, *keyFile, *pubFile, *name, *comment)
+		return
+	}
+	if *validate != "" {
+		validateRecords(*validate)
+		return
+	}
+
+	// File encryption
+	if *encFile != "" {
+		encryptFile(*encFile, *to, *keyFile, *outFile)
+		return
+	}
+	if *decFile != "" {
+		decryptFile(*decFile, *keyFile, *outFile)
+		return
+	}
+
 	// Sign
 	if *signFile != "" {
 		sign(*signFile, *keyFile)
@@ -129,7 +215,7 @@ func main() {
 }
 
 func printPublicFromSeed(keyFile string) {
-	seed := readKeyFile(keyFile)
+	seed := readSeedFile(keyFile)
 	kp, err := nkeys.FromSeed(seed)
 	if err != nil {
 		log.Fatal(err)
@@ -142,7 +228,7 @@ func sign(fname, keyFile string) {
 	if keyFile == "" {
 		log.Fatalf("Sign requires a seed/private key via -inkey <file>")
 	}
-	seed := readKeyFile(keyFile)
+	seed := readSeedFile(keyFile)
 	kp, err := nkeys.FromSeed(seed)
 	if err != nil {
 		log.Fatal(err)
@@ -170,7 +256,7 @@ func verify(fname, keyFile, pubFile, sigFile string) {
 	var err error
 	var kp nkeys.KeyPair
 	if keyFile != "" {
-		seed := readKeyFile(keyFile)
+		seed := readSeedFile(keyFile)
 		kp, err = nkeys.FromSeed(seed)
 	} else {
 		// Public Key
@@ -200,6 +286,60 @@ func verify(fname, keyFile, pubFile, sigFile string) {
 	log.Printf("Verified OK")
 }
 
+// describeKey prints the description of a key read from a file rather than
+// the command line, so that seeds do not end up in the shell history.
+func describeKey(fname string) {
+	var contents []byte
+	var err error
+	if fname == "-" {
+		contents, err = io.ReadAll(stdin)
+	} else {
+		contents, err = os.ReadFile(fname)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer wipeSlice(contents)
+	out, err := json.MarshalIndent(nkeys.DescribeKey(string(bytes.TrimSpace(contents))), "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%s", out)
+}
+
+func exportRecord(dir, keyFile, pubFile, name, comment string) {
+	var public string
+	switch {
+	case pubFile != "":
+		public = string(readKeyFile(pubFile))
+	case keyFile != "":
+		kp, err := nkeys.FromSeed(readSeedFile(keyFile))
+		if err != nil {
+			log.Fatal(err)
+		}
+		public, _ = kp.PublicKey()
+		kp.Wipe()
+	default:
+		log.Fatalf("Export requires a public key via -pubin or a seed via -inkey")
+	}
+	rec := nkeys.KeyRecord{PublicKey: public, Name: name, Comment: comment}
+	if err := nkeys.ExportKeyRecords(dir, rec); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%s", filepath.Join(dir, rec.FileName()))
+}
+
+func validateRecords(dir string) {
+	records, err := nkeys.ImportKeyRecords(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, r := range records {
+		log.Printf("%s\t%s\t%s", r.FileName(), r.Type(), r.Name)
+	}
+	log.Printf("%d key records OK", len(records))
+}
+
 func preForType(keyType string) nkeys.PrefixByte {
 	keyType = strings.ToLower(keyType)
 	switch keyType {
@@ -303,6 +443,55 @@ func createVanityKey(keyType, vanity, entropy string, max int) KeyPair {
 	return nil
 }
 
+// readSeedFile refuses seed files that are readable by others, like ssh does.
+func readSeedFile(filename string) []byte {
+	if err := nkeys.CheckSeedFile(filename); err != nil {
+		var ierr *nkeys.InsecureSeedFileError
+		if errors.As(err, &ierr) {
+			log.Fatalf("%v, fix with: chmod 600 %s", err, filename)
+		}
+		log.Fatal(err)
+	}
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if nkeys.IsEncryptedSeed(contents) {
+		return decryptSeedFile(filename, contents)
+	}
+	wipeSlice(contents)
+	return readKeyFile(filename)
+}
+
+// decryptSeedFile returns the seed from the agent cache if possible, and
+// otherwise prompts for the passphrase and caches the seed in the agent.
+func decryptSeedFile(filename string, contents []byte) []byte {
+	useAgent := os.Getenv(agent.SocketEnv) != ""
+	id := encryptedSeedID(contents)
+	if useAgent {
+		if resp, err := agentCall(&agentRequest{Op: "get", ID: id}); err != nil {
+			fmt.Fprintf(os.Stderr, "nk: %v\n", err)
+		} else if resp.Seed != nil {
+			return resp.Seed
+		}
+	}
+	pw, err := readPassword(fmt.Sprintf("Enter passphrase for %s: ", filename))
+	if err != nil {
+		log.Fatal(err)
+	}
+	seed, err := nkeys.DecryptSeed(contents, pw)
+	wipeSlice(pw)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if useAgent {
+		if _, err := agentCall(&agentRequest{Op: "put", ID: id, Seed: seed}); err != nil {
+			fmt.Fprintf(os.Stderr, "nk: %v\n", err)
+		}
+	}
+	return seed
+}
+
 func readKeyFile(filename string) []byte {
 	var key []byte
 	contents, err := os.ReadFile(filename)
@@ -0,0 +1,69 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// stdin is shared so that buffered input is not lost between prompts.
+var stdin = bufio.NewReader(os.Stdin)
+
+// readPassword prompts on stderr and reads a password from stdin without
+// echoing it. When stdin is not a terminal a single line is read instead,
+// so that passwords can be piped in by scripts.
+func readPassword(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	defer fmt.Fprintln(os.Stderr)
+
+	fd := int(os.Stdin.Fd())
+	if isTerminal(fd) {
+		restore, err := disableEcho(fd)
+		if err != nil {
+			return nil, err
+		}
+		defer restore()
+	}
+	line, err := stdin.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// readNewPassword prompts for a password twice and checks that both match.
+func readNewPassword() ([]byte, error) {
+	pw, err := readPassword("Enter passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(pw) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	confirm, err := readPassword("Confirm passphrase: ")
+	if err != nil {
+		wipeSlice(pw)
+		return nil, err
+	}
+	defer wipeSlice(confirm)
+	if !bytes.Equal(pw, confirm) {
+		wipeSlice(pw)
+		return nil, errors.New("passphrases do not match")
+	}
+	return pw, nil
+}
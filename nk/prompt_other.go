@@ -0,0 +1,32 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// isTerminal treats character devices as terminals so that disableEcho
+// refuses to prompt rather than echoing the password.
+func isTerminal(fd int) bool {
+	fi, err := os.Stdin.Stat()
+	return err != nil || fi.Mode()&os.ModeCharDevice != 0
+}
+
+func disableEcho(fd int) (func(), error) {
+	return nil, errors.New("hidden password prompts are not supported on this platform, pipe the password to stdin")
+}
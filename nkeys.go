@@ -16,12 +16,22 @@
 // It also supports encryption via x25519 keys and is compatible with https://pkg.go.dev/golang.org/x/crypto/nacl/box.
 package nkeys
 
-import "io"
+import (
+	"crypto/ed25519"
+	"crypto/subtle"
+	"io"
+)
 
 // Version is our current version
 const Version = "0.4.4"
 
 // KeyPair provides the central interface to nkeys.
+//
+// The KeyPairs returned by this package are safe for concurrent use by
+// multiple goroutines, with the exception of Wipe: once Wipe has been
+// called, or while it runs, other methods may fail or return stale results,
+// so callers must ensure the KeyPair is no longer in use before wiping it.
+// PublicKey and PrivateKey are computed once and cached.
 type KeyPair interface {
 	Seed() ([]byte, error)
 	PublicKey() (string, error)
@@ -37,6 +47,8 @@ type KeyPair interface {
 	SealWithRand(input []byte, recipient string, rr io.Reader) ([]byte, error)
 	// Open is only supported on CurveKey
 	Open(input []byte, sender string) ([]byte, error)
+	// PublicOnly returns a copy of the KeyPair without any secret material.
+	PublicOnly() (KeyPair, error)
 }
 
 // CreateUser will create a User typed KeyPair.
@@ -66,6 +78,9 @@ func CreateOperator() (KeyPair, error) {
 
 // FromPublicKey will create a KeyPair capable of verifying signatures.
 func FromPublicKey(public string) (KeyPair, error) {
+	if err := checkPublicKeyArg(public); err != nil {
+		return nil, err
+	}
 	raw, err := decode([]byte(public))
 	if err != nil {
 		return nil, err
@@ -74,20 +89,93 @@ func FromPublicKey(public string) (KeyPair, error) {
 	if err := checkValidPublicPrefixByte(pre); err != nil {
 		return nil, ErrInvalidPublicKey
 	}
-	return &pub{pre, raw[1:]}, nil
+	if err := checkDeprecated(pre, "parse"); err != nil {
+		return nil, err
+	}
+	switch AlgorithmOf(pre) {
+	case AlgorithmEd25519, AlgorithmX25519:
+		return &pub{pre: pre, pub: raw[1:]}, nil
+	}
+	return nil, ErrUnsupportedAlgorithm
 }
 
 // FromSeed will create a KeyPair capable of signing and verifying signatures.
 func FromSeed(seed []byte) (KeyPair, error) {
 	prefix, _, err := DecodeSeed(seed)
 	if err != nil {
+		if cerr := checkSeedArg(seed); cerr != nil {
+			return nil, cerr
+		}
+		return nil, err
+	}
+	if err := checkDeprecated(prefix, "load"); err != nil {
+		return nil, err
+	}
+	switch AlgorithmOf(prefix) {
+	case AlgorithmX25519:
+		return fromCurveSeed(seed)
+	case AlgorithmEd25519:
+		copy := append([]byte{}, seed...)
+		return &kp{seed: copy}, nil
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// FromExpandedPrivateKey will create a KeyPair from a raw 64 byte ed25519
+// private key, as returned by crypto/ed25519 and many external key stores.
+// The key is the 32 byte seed followed by the 32 byte public key, and the
+// public half must match the one derived from the seed.
+func FromExpandedPrivateKey(prefix PrefixByte, priv []byte) (KeyPair, error) {
+	rawSeed, err := expandedSeed(priv)
+	if err != nil {
+		return nil, err
+	}
+	return FromRawSeed(prefix, rawSeed)
+}
+
+// expandedSeed returns the seed half of a 64 byte ed25519 private key after
+// checking that its public half matches the seed.
+func expandedSeed(priv []byte) ([]byte, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidPrivateKey
+	}
+	derived := ed25519.NewKeyFromSeed(priv[:ed25519.SeedSize])
+	defer wipeBytes(derived)
+	if subtle.ConstantTimeCompare(derived[ed25519.SeedSize:], priv[ed25519.SeedSize:]) != 1 {
+		return nil, ErrInvalidPrivateKey
+	}
+	return priv[:ed25519.SeedSize], nil
+}
+
+// FromPrivateKey will create a KeyPair from an encoded private key as
+// returned by PrivateKey. The encoding does not record the key type, so
+// prefix must name it: PrefixByteCurve for curve keys, otherwise the type of
+// the ed25519 key, such as PrefixByteUser. The seed is reconstructed, so the
+// result round-trips through Seed and PrivateKey.
+func FromPrivateKey(prefix PrefixByte, private []byte) (KeyPair, error) {
+	raw, err := Decode(PrefixBytePrivate, private)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(raw)
+	if err := checkDeprecated(prefix, "load"); err != nil {
 		return nil, err
 	}
-	if prefix == PrefixByteCurve {
-		return FromCurveSeed(seed)
+	switch AlgorithmOf(prefix) {
+	case AlgorithmX25519:
+		if len(raw) != curveKeyLen {
+			return nil, ErrInvalidPrivateKey
+		}
+		var kp ckp
+		copy(kp.seed[:], raw)
+		return &kp, nil
+	case AlgorithmEd25519:
+		if prefix == PrefixBytePrivate {
+			return nil, ErrInvalidPrefixByte
+		}
+		return FromExpandedPrivateKey(prefix, raw)
 	}
-	copy := append([]byte{}, seed...)
-	return &kp{copy}, nil
+	return nil, ErrInvalidPrefixByte
 }
 
 // FromRawSeed will create a KeyPair from the raw 32 byte seed for a given type.
@@ -96,5 +184,5 @@ func FromRawSeed(prefix PrefixByte, rawSeed []byte) (KeyPair, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &kp{seed}, nil
+	return &kp{seed: seed}, nil
 }
@@ -15,14 +15,26 @@ package nkeys
 
 import (
 	"bytes"
+	"context"
+	"crypto/ed25519"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"io"
 	"os"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
-
-	"golang.org/x/crypto/ed25519"
+	"time"
 )
 
 func TestVersion(t *testing.T) {
@@ -98,7 +110,7 @@ func TestSeed(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Unexpected error reading from crypto/rand: %v", err)
 	}
-	// Seeds need to be 64 bytes
+	// Seeds need to be 32 bytes, or 64 byte ed25519 private keys
 	if _, err := EncodeSeed(PrefixByteUser, rawKeyShort[:]); err != ErrInvalidSeedLen {
 		t.Fatalf("Did not receive ErrInvalidSeed error, received %v", err)
 	}
@@ -429,7 +441,7 @@ func TestKeyPairFailures(t *testing.T) {
 	if _, err := CreatePair(PrefixBytePrivate); err == nil {
 		t.Fatal("Expected an error with non-public prefix")
 	}
-	kpbad := &kp{[]byte("SEEDBAD")}
+	kpbad := &kp{seed: []byte("SEEDBAD")}
 	if _, _, err := kpbad.keys(); err == nil {
 		t.Fatal("Expected an error decoding keys with a bad seed")
 	}
@@ -514,6 +526,35 @@ func TestFromRawSeed(t *testing.T) {
 	}
 }
 
+func TestEncodeSeedPrivateKey(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	want, _ := EncodeSeed(PrefixByteUser, priv.Seed())
+	seed, err := EncodeSeed(PrefixByteUser, priv)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !bytes.Equal(seed, want) {
+		t.Fatalf("Expected %s, got %s", want, seed)
+	}
+	user, err := FromRawSeed(PrefixByteUser, priv)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if useed, _ := user.Seed(); !bytes.Equal(useed, want) {
+		t.Fatalf("Expected %s, got %s", want, useed)
+	}
+
+	mismatched := append(ed25519.PrivateKey{}, priv...)
+	mismatched[63] ^= 1
+	if _, err := EncodeSeed(PrefixByteUser, mismatched); err != ErrInvalidPrivateKey {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPrivateKey, err)
+	}
+	// Curve keys have no expanded form.
+	if _, err := EncodeSeed(PrefixByteCurve, priv); err != ErrInvalidSeedLen {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSeedLen, err)
+	}
+}
+
 func TestWipe(t *testing.T) {
 	user, err := CreateUser()
 	if err != nil {
@@ -583,6 +624,71 @@ func BenchmarkSign(b *testing.B) {
 	}
 }
 
+// BenchmarkSignPrecomputed only saves the seed decoding and NewKeyFromSeed
+// of BenchmarkSign; the signing scalar is still derived per signature.
+func BenchmarkSignPrecomputed(b *testing.B) {
+	user, err := CreateUser()
+	if err != nil {
+		b.Fatalf("Error creating User Nkey: %v", err)
+	}
+	if err := PrecomputeKeys(user); err != nil {
+		b.Fatalf("Error precomputing keys: %v", err)
+	}
+	nonce := make([]byte, nonceLen)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := user.Sign(nonce); err != nil {
+			b.Fatalf("Error signing nonce: %v", err)
+		}
+	}
+}
+
+// BenchmarkColdStart measures loading a seed and signing one nonce, as a
+// serverless function does on every invocation.
+func BenchmarkColdStart(b *testing.B) {
+	user, _ := CreateUser()
+	seed, _ := user.Seed()
+	nonce := make([]byte, nonceLen)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		kp, err := FromSeed(seed)
+		if err != nil {
+			b.Fatalf("Error loading seed: %v", err)
+		}
+		if _, err := kp.PublicKey(); err != nil {
+			b.Fatalf("Error getting public key: %v", err)
+		}
+		if _, err := kp.Sign(nonce); err != nil {
+			b.Fatalf("Error signing nonce: %v", err)
+		}
+	}
+}
+
+func BenchmarkColdStartWarm(b *testing.B) {
+	user, _ := CreateUser()
+	seed, _ := user.Seed()
+	public, _ := user.PublicKey()
+	nonce := make([]byte, nonceLen)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		kp, err := FromSeedWarm(seed, public)
+		if err != nil {
+			b.Fatalf("Error loading seed: %v", err)
+		}
+		if _, err := kp.PublicKey(); err != nil {
+			b.Fatalf("Error getting public key: %v", err)
+		}
+		if _, err := kp.Sign(nonce); err != nil {
+			b.Fatalf("Error signing nonce: %v", err)
+		}
+	}
+}
+
 func BenchmarkVerify(b *testing.B) {
 	data := make([]byte, nonceRawLen)
 	nonce := make([]byte, nonceLen)
@@ -637,6 +743,34 @@ func BenchmarkPublicVerify(b *testing.B) {
 	}
 }
 
+func BenchmarkPreparedVerify(b *testing.B) {
+	data := make([]byte, nonceRawLen)
+	nonce := make([]byte, nonceLen)
+	rand.Read(data)
+	base64.RawURLEncoding.Encode(nonce, data)
+
+	user, err := CreateUser()
+	if err != nil {
+		b.Fatalf("Error creating User Nkey: %v", err)
+	}
+	sig, err := user.Sign(nonce)
+	if err != nil {
+		b.Fatalf("Error sigining nonce: %v", err)
+	}
+	pk, _ := user.PublicKey()
+	prepared, err := PrepareVerify(pk)
+	if err != nil {
+		b.Fatalf("Could not prepare public key: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := prepared.Verify(nonce, sig); err != nil {
+			b.Fatalf("Error verifying nonce: %v", err)
+		}
+	}
+}
+
 func TestValidateKeyPairRole(t *testing.T) {
 	okp, err := CreateOperator()
 	if err != nil {
@@ -729,3 +863,1193 @@ func TestSealOpen(t *testing.T) {
 	testSealOpen(t, PrefixByteAccount)
 	testSealOpen(t, PrefixByteUser)
 }
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatalf("Expected self-test to pass, got %v", err)
+	}
+}
+
+type brokenBackend struct {
+	stdBackend
+}
+
+func (brokenBackend) Sign(private []byte, message []byte) ([]byte, error) {
+	return make([]byte, ed25519.SignatureSize), nil
+}
+
+func TestSelfTestBeforeSetBackend(t *testing.T) {
+	prev, prevOnSet := backendValue.Load(), selfTestOnSet
+	defer func() {
+		backendValue.Store(prev)
+		selfTestOnSet = prevOnSet
+	}()
+	atomic.StoreInt32(&backendLocked, 0)
+	selfTestOnSet = true
+
+	if err := SelfTest(); err != nil {
+		t.Fatalf("Expected self-test to pass, got %v", err)
+	}
+	if err := SetBackend(brokenBackend{}); err != ErrSelfTestFailed {
+		t.Fatalf("Expected %v, got %v", ErrSelfTestFailed, err)
+	}
+	cb := &countingBackend{}
+	if err := SetBackend(cb); err != nil {
+		t.Fatalf("Expected the self-tested backend to be selected, got %v", err)
+	}
+	if cb.signs != 1 {
+		t.Fatalf("Expected the backend to be self-tested, got %d signatures", cb.signs)
+	}
+}
+
+type countingBackend struct {
+	stdBackend
+	signs int
+}
+
+func (b *countingBackend) Sign(private []byte, message []byte) ([]byte, error) {
+	b.signs++
+	return b.stdBackend.Sign(private, message)
+}
+
+func TestBackend(t *testing.T) {
+	prev := backendValue.Load()
+	defer func() {
+		backendValue.Store(prev)
+	}()
+	atomic.StoreInt32(&backendLocked, 0)
+
+	if err := SetBackend(nil); err != ErrInvalidBackend {
+		t.Fatalf("Expected ErrInvalidBackend, got %v", err)
+	}
+	cb := &countingBackend{}
+	if err := SetBackend(cb); err != nil {
+		t.Fatalf("Unexpected error setting backend: %v", err)
+	}
+	if err := SetBackend(stdBackend{}); err != ErrBackendLocked {
+		t.Fatalf("Expected ErrBackendLocked, got %v", err)
+	}
+	// Not counting the self-test under SelfTestEnv.
+	cb.signs = 0
+	user, _ := CreateUser()
+	sig, err := user.Sign([]byte("hello"))
+	if err != nil {
+		t.Fatalf("Unexpected error signing: %v", err)
+	}
+	if cb.signs != 1 {
+		t.Fatalf("Expected the backend to be used for signing, got %d calls", cb.signs)
+	}
+	if err := user.Verify([]byte("hello"), sig); err != nil {
+		t.Fatalf("Unexpected error verifying: %v", err)
+	}
+}
+
+func TestFromExpandedPrivateKey(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Unexpected error generating key: %v", err)
+	}
+	kp, err := FromExpandedPrivateKey(PrefixByteAccount, priv)
+	if err != nil {
+		t.Fatalf("Unexpected error from FromExpandedPrivateKey: %v", err)
+	}
+	pk, _ := kp.PublicKey()
+	raw, err := Decode(PrefixByteAccount, []byte(pk))
+	if err != nil {
+		t.Fatalf("Unexpected error decoding public key: %v", err)
+	}
+	if !bytes.Equal(raw, priv[ed25519.SeedSize:]) {
+		t.Fatalf("Expected public key to match the expanded private key")
+	}
+	sig, _ := kp.Sign([]byte("hello"))
+	if !bytes.Equal(sig, ed25519.Sign(priv, []byte("hello"))) {
+		t.Fatalf("Expected signatures to match")
+	}
+
+	if _, err := FromExpandedPrivateKey(PrefixByteAccount, priv[:32]); err != ErrInvalidPrivateKey {
+		t.Fatalf("Expected ErrInvalidPrivateKey for short key, got %v", err)
+	}
+	bad := append([]byte{}, priv...)
+	bad[63] ^= 0x01
+	if _, err := FromExpandedPrivateKey(PrefixByteAccount, bad); err != ErrInvalidPrivateKey {
+		t.Fatalf("Expected ErrInvalidPrivateKey for mismatched public half, got %v", err)
+	}
+	if _, err := FromExpandedPrivateKey(PrefixByteSeed, priv); err != ErrInvalidPrefixByte {
+		t.Fatalf("Expected ErrInvalidPrefixByte, got %v", err)
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	user, _ := CreateUser()
+	pk, _ := user.PublicKey()
+	seed, _ := user.Seed()
+
+	for _, in := range []string{
+		pk,
+		"  " + pk + "\n",
+		strings.ToLower(pk),
+		`"` + pk + `"`,
+		"'" + pk + "'",
+		"` " + pk + " `",
+		pk + "===",
+		"\t\"" + strings.ToLower(pk) + "==\"\r\n",
+	} {
+		out, err := NormalizeKey(in)
+		if err != nil {
+			t.Fatalf("Unexpected error normalizing %q: %v", in, err)
+		}
+		if out != pk {
+			t.Fatalf("Expected %q, got %q", pk, out)
+		}
+	}
+	if out, err := NormalizeKey(" " + string(seed) + " "); err != nil || out != string(seed) {
+		t.Fatalf("Expected seed to normalize, got %q, %v", out, err)
+	}
+	for _, in := range []string{"", `""`, `"` + pk, pk[:len(pk)-1], "U" + pk} {
+		if _, err := NormalizeKey(in); err == nil {
+			t.Fatalf("Expected an error normalizing %q", in)
+		}
+	}
+}
+
+func TestPublicOnly(t *testing.T) {
+	user, _ := CreateUser()
+	pk, _ := user.PublicKey()
+	sig, _ := user.Sign([]byte("hello"))
+
+	public, err := user.PublicOnly()
+	if err != nil {
+		t.Fatalf("Unexpected error from PublicOnly: %v", err)
+	}
+	if ppk, _ := public.PublicKey(); ppk != pk {
+		t.Fatalf("Expected public key %q, got %q", pk, ppk)
+	}
+	if _, err := public.Seed(); err != ErrPublicKeyOnly {
+		t.Fatalf("Expected ErrPublicKeyOnly, got %v", err)
+	}
+	if _, err := public.Sign([]byte("hello")); err != ErrCannotSign {
+		t.Fatalf("Expected ErrCannotSign, got %v", err)
+	}
+	if err := public.Verify([]byte("hello"), sig); err != nil {
+		t.Fatalf("Unexpected error verifying: %v", err)
+	}
+
+	// A copy of a public key must not be affected by wiping the original.
+	clone, _ := public.PublicOnly()
+	public.Wipe()
+	if err := clone.Verify([]byte("hello"), sig); err != nil {
+		t.Fatalf("Expected clone to survive wipe of the original: %v", err)
+	}
+
+	curve, _ := CreateCurveKeys()
+	cpk, _ := curve.PublicKey()
+	cpub, err := curve.PublicOnly()
+	if err != nil {
+		t.Fatalf("Unexpected error from PublicOnly: %v", err)
+	}
+	if got, _ := cpub.PublicKey(); got != cpk {
+		t.Fatalf("Expected curve public key %q, got %q", cpk, got)
+	}
+	if _, err := cpub.Seal([]byte("x"), cpk); err != ErrCannotSeal {
+		t.Fatalf("Expected ErrCannotSeal, got %v", err)
+	}
+}
+
+// Generated with openssl using an ed25519 key, a self signed certificate,
+// PBE-SHA1-3DES encryption and the password "secret".
+const testPKCS12 = "MIIC5gIBAzCCAqwGCSqGSIb3DQEHAaCCAp0EggKZMIIClTCCAecGCSqGSIb3DQEHBqCCAdgwggHUAgEAMIIBzQYJKoZIhvcNAQcBMBwGCiqGSIb3DQEMAQMwDgQIiJtih+jGcH4CAggAgIIBoFhIU6DrJBNkLKX3Vu5vBQxyraU8G6owCojCHAD7RxzdLCQ850gZKU1o5X0VS+wRhDbnZbNC01eHfkD+bn1hajh2i82jIuBnrHlBMgBtlrMeUz9aIOe07XRWo7wkUJ/F79o2truqAT5BcBr/U8//ZZLBvmDB0x/eKfzXrz3AbeDJIg/quiKrBRwFAAl2dz7zz1zVR0hqzSgb0ALJTUyxcSt78TIwWYHkc9Hpykzi6QkMGmjhC007iUA3nDPGqHyr7DKlADNJttgtqM/QCdxA56koc6jEWxH1/IkScHko76716QdVadCH5WWAoUorwlmwFwySYVwYBR0wOqdndkgH9xZ+6lGUyKeGpamxO29ujxk2cc3idSkIPd0iD4L9vjDeFdJsvpxv6/RrJHtLWbebtSPFXObCHrfhW0rofVRQBrEFWORXBC8yX1DxZ/UDWi1Acp2OjJ2KD34gm+KRpL9glNxbsMA1x4n+VuFALAKpBu0LWDo3VsBb9okFlL89f2cTY20mTw1ziG5MVIHb271ZLXgfxvGquxVJUvuMTgkE42pEMIGnBgkqhkiG9w0BBwGggZkEgZYwgZMwgZAGCyqGSIb3DQEMCgECoFowWDAcBgoqhkiG9w0BDAEDMA4ECAzoZs/nm7i3AgIIAAQ4Gt5/3Y3BC+08e3+Lz2JzLyqLCzSO6+dVf7E1ARbfO1AqN/vj/fR/0yuosxXhJnlR5fWvdn4pqYwxJTAjBgkqhkiG9w0BCRUxFgQU2xX/n5GsNA6wM+0oRDOZhjM7GyowMTAhMAkGBSsOAwIaBQAEFHvz5g2Ut0KPOc9+a935xFgfz1KaBAgH2tZQNncengICCAA="
+
+func TestImportPKCS12(t *testing.T) {
+	data, _ := base64.StdEncoding.DecodeString(testPKCS12)
+	kp, err := ImportPKCS12(PrefixByteAccount, data, "secret")
+	if err != nil {
+		t.Fatalf("Unexpected error importing PKCS#12: %v", err)
+	}
+	pk, _ := kp.PublicKey()
+	raw, err := Decode(PrefixByteAccount, []byte(pk))
+	if err != nil {
+		t.Fatalf("Unexpected error decoding public key: %v", err)
+	}
+	expected, _ := hex.DecodeString("3c1a8a7f7882bb2f0aea93d565f66906cbc8ea8c959569796adecd3e4046b7c1")
+	if !bytes.Equal(raw, expected) {
+		t.Fatalf("Expected imported public key to match the bundle")
+	}
+	if _, err := ImportPKCS12(PrefixByteAccount, data, "wrong"); err == nil {
+		t.Fatal("Expected an error with the wrong password")
+	}
+	if _, err := ImportPKCS12(PrefixByteSeed, data, "secret"); err != ErrInvalidPrefixByte {
+		t.Fatalf("Expected ErrInvalidPrefixByte, got %v", err)
+	}
+}
+
+type namedEntropy struct {
+	io.Reader
+}
+
+func (namedEntropy) EntropySource() string {
+	return "test-trng"
+}
+
+func TestCreatePairWithReport(t *testing.T) {
+	kp, r, err := CreatePairWithReport(PrefixByteAccount, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error creating pair: %v", err)
+	}
+	pk, _ := kp.PublicKey()
+	if r.PublicKey != pk || r.Type != "account" || r.Algorithm != "ed25519" {
+		t.Fatalf("Unexpected report %+v", r)
+	}
+	if r.LibraryVersion != Version || r.EntropySource != "crypto/rand" || r.Started.IsZero() {
+		t.Fatalf("Unexpected report %+v", r)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Unexpected error serializing report: %v", err)
+	}
+	seed, _ := kp.Seed()
+	if bytes.Contains(data, seed) {
+		t.Fatal("Report must not contain the seed")
+	}
+
+	_, r, err = CreatePairWithReport(PrefixByteCurve, namedEntropy{rand.Reader})
+	if err != nil {
+		t.Fatalf("Unexpected error creating pair: %v", err)
+	}
+	if r.Algorithm != "x25519" || r.EntropySource != "test-trng" {
+		t.Fatalf("Unexpected report %+v", r)
+	}
+	if _, _, err := CreatePairWithReport(PrefixByteSeed, nil); err == nil {
+		t.Fatal("Expected an error for an invalid prefix")
+	}
+}
+
+func TestEncodeToDecodeFrom(t *testing.T) {
+	var buf bytes.Buffer
+	var raws [][]byte
+	for i := 0; i < 3; i++ {
+		var raw [32]byte
+		io.ReadFull(rand.Reader, raw[:])
+		raws = append(raws, raw[:])
+		if err := EncodeTo(&buf, PrefixByteUser, raw[:]); err != nil {
+			t.Fatalf("Unexpected error from EncodeTo: %v", err)
+		}
+		expected, _ := Encode(PrefixByteUser, raw[:])
+		if !bytes.HasSuffix(buf.Bytes(), expected) {
+			t.Fatalf("Expected EncodeTo to match Encode")
+		}
+		buf.WriteString("\n")
+	}
+	if err := EncodeTo(&buf, PrefixByte(3), raws[0]); err != ErrInvalidPrefixByte {
+		t.Fatalf("Expected ErrInvalidPrefixByte, got %v", err)
+	}
+
+	r := bytes.NewReader(append([]byte("  "), buf.Bytes()...))
+	for i := 0; i < 3; i++ {
+		raw, err := DecodeFrom(r, PrefixByteUser)
+		if err != nil {
+			t.Fatalf("Unexpected error from DecodeFrom: %v", err)
+		}
+		if !bytes.Equal(raw, raws[i]) {
+			t.Fatalf("Expected decoded key %d to match", i)
+		}
+	}
+	if _, err := DecodeFrom(r, PrefixByteUser); err != io.EOF {
+		t.Fatalf("Expected io.EOF, got %v", err)
+	}
+
+	if _, err := DecodeFrom(strings.NewReader(strings.Repeat("A", 200)), PrefixByteUser); err != ErrInvalidEncoding {
+		t.Fatalf("Expected ErrInvalidEncoding for an overlong token, got %v", err)
+	}
+	var pbuf bytes.Buffer
+	EncodeTo(&pbuf, PrefixByteAccount, raws[0])
+	if _, err := DecodeFrom(&pbuf, PrefixByteUser); err != ErrInvalidPrefixByte {
+		t.Fatalf("Expected ErrInvalidPrefixByte, got %v", err)
+	}
+}
+
+func TestConcurrentKeyEncoding(t *testing.T) {
+	user, _ := CreateUser()
+	want, _ := user.PublicKey()
+	wantPriv, _ := user.PrivateKey()
+	pk, _ := FromPublicKey(want)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, _ := user.PublicKey(); got != want {
+				errs <- fmt.Errorf("Expected %q, got %q", want, got)
+			}
+			if got, _ := pk.PublicKey(); got != want {
+				errs <- fmt.Errorf("Expected %q, got %q", want, got)
+			}
+			priv, _ := user.PrivateKey()
+			if !bytes.Equal(priv, wantPriv) {
+				errs <- fmt.Errorf("Expected private keys to match")
+			}
+			// Callers must not be able to corrupt the cache.
+			priv[0] = 'X'
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	user.Wipe()
+	if _, err := user.PublicKey(); err == nil {
+		t.Fatalf("Expected an error after Wipe")
+	}
+	if _, err := user.PrivateKey(); err == nil {
+		t.Fatalf("Expected an error after Wipe")
+	}
+}
+
+func TestAlgorithm(t *testing.T) {
+	user, _ := CreateUser()
+	curve, _ := CreateCurveKeys()
+	upk, _ := user.PublicKey()
+	useed, _ := user.Seed()
+	upriv, _ := user.PrivateKey()
+	cpk, _ := curve.PublicKey()
+	cseed, _ := curve.Seed()
+
+	for key, want := range map[string]AlgorithmID{
+		upk:           AlgorithmEd25519,
+		string(useed): AlgorithmEd25519,
+		string(upriv): AlgorithmEd25519,
+		cpk:           AlgorithmX25519,
+		string(cseed): AlgorithmX25519,
+	} {
+		got, err := Algorithm(key)
+		if err != nil {
+			t.Fatalf("Expected no error for %q, got %v", key, err)
+		}
+		if got != want {
+			t.Fatalf("Expected %v for %q, got %v", want, key, got)
+		}
+	}
+	if _, err := Algorithm("BAD"); err == nil {
+		t.Fatalf("Expected an error for an invalid key")
+	}
+	if a := (KeyInfo{PublicKey: cpk}).Algorithm(); a != AlgorithmX25519 {
+		t.Fatalf("Expected %v, got %v", AlgorithmX25519, a)
+	}
+}
+
+func TestHandoverState(t *testing.T) {
+	user, _ := CreateUser()
+	curve, _ := CreateCurveKeys()
+	key, err := NewHandoverKey()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	state, err := SerializeState(key, user, curve)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	useed, _ := user.Seed()
+	if bytes.Contains(state, useed) {
+		t.Fatalf("Expected the state to be encrypted")
+	}
+	kps, err := RestoreState(key, state)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(kps) != 2 {
+		t.Fatalf("Expected 2 key pairs, got %d", len(kps))
+	}
+	for i, kp := range []KeyPair{user, curve} {
+		want, _ := kp.PublicKey()
+		got, _ := kps[i].PublicKey()
+		if got != want {
+			t.Fatalf("Expected %q, got %q", want, got)
+		}
+	}
+
+	other, _ := NewHandoverKey()
+	if _, err := RestoreState(other, state); err != ErrInvalidHandoverState {
+		t.Fatalf("Expected %v, got %v", ErrInvalidHandoverState, err)
+	}
+	if _, err := RestoreState(key[:8], state); err != ErrInvalidHandoverKey {
+		t.Fatalf("Expected %v, got %v", ErrInvalidHandoverKey, err)
+	}
+	pub, _ := user.PublicOnly()
+	if _, err := SerializeState(key, pub); err != ErrPublicKeyOnly {
+		t.Fatalf("Expected %v, got %v", ErrPublicKeyOnly, err)
+	}
+}
+
+func TestCorrectKey(t *testing.T) {
+	seed := "SUAGAUMVFLH2ZQRMPF3MMUZPWQMHXVSSMEDIQZJVDFBEMIYSW6O73H6ABM"
+	typed := strings.NewReplacer("FL", "F1", "DI", "D1", "6O", "60", "FB", "F8").Replace(seed)
+
+	out, corrections, err := CorrectKey(" " + strings.ToLower(typed) + "\n")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if out != seed {
+		t.Fatalf("Expected %q, got %q", seed, out)
+	}
+	if len(corrections) != 4 {
+		t.Fatalf("Expected 4 corrections, got %+v", corrections)
+	}
+	for _, c := range corrections {
+		if typed[c.Offset] != c.From || seed[c.Offset] != c.To {
+			t.Fatalf("Unexpected correction %+v", c)
+		}
+	}
+
+	if out, corrections, err := CorrectKey(seed); err != nil || out != seed || len(corrections) != 0 {
+		t.Fatalf("Expected valid keys to pass unchanged, got %q, %+v, %v", out, corrections, err)
+	}
+	if _, _, err := CorrectKey(strings.Replace(typed, "M", "N", 1)); err == nil {
+		t.Fatalf("Expected keys with other errors to be rejected")
+	}
+}
+
+func TestManagedKeyPair(t *testing.T) {
+	if _, err := NewManagedKeyPair(nil, ManagedKeyOptions{}); err != ErrInvalidLifetime {
+		t.Fatalf("Expected %v, got %v", ErrInvalidLifetime, err)
+	}
+
+	server, _ := CreateServer()
+	first, _ := server.PublicKey()
+	type rotation struct{ old, new string }
+	ch := make(chan rotation, 1)
+	var calls int32
+	m, err := NewManagedKeyPair(server, ManagedKeyOptions{
+		Lifetime:      time.Hour,
+		RenewBefore:   time.Hour - 10*time.Millisecond,
+		RetryInterval: time.Hour,
+		// Only regenerate once so the test sees a stable key.
+		Provider: func() (KeyPair, error) {
+			if atomic.AddInt32(&calls, 1) > 1 {
+				return nil, ErrInvalidLifetime
+			}
+			return CreateServer()
+		},
+		OnRegenerate: func(old, new KeyPair) {
+			opk, _ := old.PublicKey()
+			npk, _ := new.PublicKey()
+			ch <- rotation{opk, npk}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer m.Wipe()
+	if d := time.Until(m.ExpiresAt()); d < 59*time.Minute || d > time.Hour {
+		t.Fatalf("Expected the key to expire in an hour, got %v", d)
+	}
+
+	var r rotation
+	select {
+	case r = <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the key to be regenerated")
+	}
+	pk, _ := m.PublicKey()
+	if r.old != first || r.new != pk || pk == first || !IsValidPublicServerKey(pk) {
+		t.Fatalf("Unexpected rotation %+v to %q", r, pk)
+	}
+	sig, err := m.Sign([]byte("hello"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := m.Current().Verify([]byte("hello"), sig); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestPrecomputeKeys(t *testing.T) {
+	user, _ := CreateUser()
+	if err := PrecomputeKeys(user); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sig, err := user.Sign([]byte("hello"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	seed, _ := user.Seed()
+	plain, _ := FromSeed(seed)
+	if psig, _ := plain.Sign([]byte("hello")); !bytes.Equal(sig, psig) {
+		t.Fatalf("Expected precomputed signatures to match")
+	}
+	if err := user.Verify([]byte("hello"), sig); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	user.Wipe()
+	if _, err := user.Sign([]byte("hello")); err == nil {
+		t.Fatalf("Expected signing with a wiped key to fail")
+	}
+
+	curve, _ := CreateCurveKeys()
+	if err := PrecomputeKeys(curve); err != ErrInvalidNKeyOperation {
+		t.Fatalf("Expected %v, got %v", ErrInvalidNKeyOperation, err)
+	}
+}
+
+func TestFromSeedWarm(t *testing.T) {
+	user, _ := CreateUser()
+	seed, _ := user.Seed()
+	public, _ := user.PublicKey()
+	for _, pk := range []string{public, ""} {
+		warm, err := FromSeedWarm(seed, pk)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got, _ := warm.PublicKey(); got != public {
+			t.Fatalf("Expected %v, got %v", public, got)
+		}
+		sig, err := warm.Sign([]byte("hello"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := user.Verify([]byte("hello"), sig); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	other, _ := CreateUser()
+	otherPub, _ := other.PublicKey()
+	if _, err := FromSeedWarm(seed, otherPub); err != ErrIncompatibleKey {
+		t.Fatalf("Expected %v, got %v", ErrIncompatibleKey, err)
+	}
+	account, _ := CreateAccount()
+	accountPub, _ := account.PublicKey()
+	if _, err := FromSeedWarm(seed, accountPub); err != ErrIncompatibleKey {
+		t.Fatalf("Expected %v, got %v", ErrIncompatibleKey, err)
+	}
+	curve, _ := CreateCurveKeys()
+	curveSeed, _ := curve.Seed()
+	if _, err := FromSeedWarm(curveSeed, ""); err != ErrInvalidNKeyOperation {
+		t.Fatalf("Expected %v, got %v", ErrInvalidNKeyOperation, err)
+	}
+}
+
+func TestFromPrivateKey(t *testing.T) {
+	for _, prefix := range []PrefixByte{PrefixByteUser, PrefixByteOperator, PrefixByteCurve} {
+		kp, _ := CreatePair(prefix)
+		seed, _ := kp.Seed()
+		private, _ := kp.PrivateKey()
+		restored, err := FromPrivateKey(prefix, private)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if rseed, _ := restored.Seed(); !bytes.Equal(seed, rseed) {
+			t.Fatalf("Expected %s, got %s", seed, rseed)
+		}
+		if rpriv, _ := restored.PrivateKey(); !bytes.Equal(private, rpriv) {
+			t.Fatalf("Expected %s, got %s", private, rpriv)
+		}
+	}
+
+	user, _ := CreateUser()
+	private, _ := user.PrivateKey()
+	if _, err := FromPrivateKey(PrefixByteCurve, private); err != ErrInvalidPrivateKey {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPrivateKey, err)
+	}
+	if _, err := FromPrivateKey(PrefixBytePrivate, private); err != ErrInvalidPrefixByte {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPrefixByte, err)
+	}
+	seed, _ := user.Seed()
+	if _, err := FromPrivateKey(PrefixByteUser, seed); err != ErrInvalidPrefixByte {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPrefixByte, err)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	// Every error declared in errors.go must have a unique code.
+	f, err := parser.ParseFile(token.NewFileSet(), "errors.go", nil, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error parsing errors.go: %v", err)
+	}
+	seen := make(map[string]string)
+	for _, code := range errorCodes {
+		if seen[code] != "" {
+			t.Fatalf("Code %s is used twice", code)
+		}
+		seen[code] = code
+	}
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			for _, name := range spec.(*ast.ValueSpec).Names {
+				if !strings.HasPrefix(name.Name, "Err") {
+					continue
+				}
+				lit := spec.(*ast.ValueSpec).Values[0].(*ast.CallExpr).Args[0].(*ast.BasicLit).Value
+				msg, _ := strconv.Unquote(lit)
+				if errorCodes[nkeysError(msg)] == "" {
+					t.Fatalf("Expected %s to have an error code", name.Name)
+				}
+			}
+		}
+	}
+
+	if code := ErrorCode(fmt.Errorf("loading: %w", ErrInvalidChecksum)); code != "NKEYS-0101" {
+		t.Fatalf("Expected NKEYS-0101, got %q", code)
+	}
+	if _, err := Decode(PrefixByteUser, []byte("!!!")); ErrorCode(err) != "NKEYS-0100" {
+		t.Fatalf("Expected NKEYS-0100 for %v, got %q", err, ErrorCode(err))
+	}
+	if code := ErrorCode(&PinMismatchError{}); code != "NKEYS-0802" {
+		t.Fatalf("Expected NKEYS-0802, got %q", code)
+	}
+	if code := ErrorCode(io.EOF); code != "" {
+		t.Fatalf("Expected no code, got %q", code)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	user, _ := CreateUser()
+	frozen, err := Freeze(user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				sig, err := frozen.Sign([]byte("hello"))
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+					return
+				}
+				if err := frozen.Verify([]byte("hello"), sig); err != nil {
+					t.Errorf("Expected no error, got %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	pk, _ := user.PublicKey()
+	public, _ := FromPublicKey(pk)
+	fpub, err := Freeze(public)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sig, _ := frozen.Sign([]byte("hello"))
+	if err := fpub.Verify([]byte("hello"), sig); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := fpub.Sign([]byte("hello")); err != ErrCannotSign {
+		t.Fatalf("Expected %v, got %v", ErrCannotSign, err)
+	}
+}
+
+func TestDescribeKey(t *testing.T) {
+	user, _ := CreateUser()
+	seed, _ := user.Seed()
+	pk, _ := user.PublicKey()
+	priv, _ := user.PrivateKey()
+	fp, _ := Fingerprint(pk)
+	curve, _ := CreateCurveKeys()
+	cseed, _ := curve.Seed()
+	cpk, _ := curve.PublicKey()
+	cpriv, _ := curve.PrivateKey()
+	cfp, _ := Fingerprint(cpk)
+
+	bad := []byte(pk)
+	bad[10] = 'A' + (bad[10]-'A'+1)%26
+
+	for _, tc := range []struct {
+		key  string
+		want KeyDescription
+	}{
+		{pk, KeyDescription{Form: KeyFormPublic, Type: "user", Algorithm: "ed25519", Valid: true, Checksum: ChecksumValid, PublicKey: pk, Fingerprint: fp}},
+		{" '" + string(seed) + "' ", KeyDescription{Form: KeyFormSeed, Type: "user", Algorithm: "ed25519", Valid: true, Checksum: ChecksumValid, Normalized: true, PublicKey: pk, Fingerprint: fp}},
+		{string(priv), KeyDescription{Form: KeyFormPrivate, Type: "unknown", Algorithm: "ed25519", Valid: true, Checksum: ChecksumValid, Fingerprint: fp}},
+		{string(cseed), KeyDescription{Form: KeyFormSeed, Type: "x25519", Algorithm: "x25519", Valid: true, Checksum: ChecksumValid, PublicKey: cpk, Fingerprint: cfp}},
+		{string(cpriv), KeyDescription{Form: KeyFormPrivate, Type: "unknown", Algorithm: "x25519", Valid: true, Checksum: ChecksumValid, Fingerprint: cfp}},
+		{string(bad), KeyDescription{Form: KeyFormUnknown, Type: "unknown", Algorithm: "unknown", Checksum: ChecksumInvalid, Error: ErrInvalidChecksum.Error()}},
+	} {
+		if got := DescribeKey(tc.key); *got != tc.want {
+			t.Fatalf("Expected %+v, got %+v", tc.want, *got)
+		}
+	}
+	if d := DescribeKey("not a key!"); d.Valid || d.Checksum != ChecksumUnchecked || d.Error == "" {
+		t.Fatalf("Expected an unchecked invalid description, got %+v", d)
+	}
+}
+
+func TestSeedPublicKeyConfusion(t *testing.T) {
+	user, _ := CreateUser()
+	seed, _ := user.Seed()
+	pk, _ := user.PublicKey()
+	sig, _ := user.Sign([]byte("hello"))
+	curve, _ := CreateCurveKeys()
+	cseed, _ := curve.Seed()
+	cpk, _ := curve.PublicKey()
+
+	if _, err := FromPublicKey(string(seed)); err != ErrExpectedPublicKeyGotSeed {
+		t.Fatalf("Expected %v, got %v", ErrExpectedPublicKeyGotSeed, err)
+	}
+	if err := VerifyWithPolicy(nil, string(seed), []byte("hello"), sig); err != ErrExpectedPublicKeyGotSeed {
+		t.Fatalf("Expected %v, got %v", ErrExpectedPublicKeyGotSeed, err)
+	}
+	if _, err := curve.Seal([]byte("hello"), string(cseed)); err != ErrExpectedPublicKeyGotSeed {
+		t.Fatalf("Expected %v, got %v", ErrExpectedPublicKeyGotSeed, err)
+	}
+	if _, err := FromSeed([]byte(pk)); err != ErrExpectedSeedGotPublicKey {
+		t.Fatalf("Expected %v, got %v", ErrExpectedSeedGotPublicKey, err)
+	}
+	if _, err := FromCurveSeed([]byte(cpk)); err != ErrExpectedSeedGotPublicKey {
+		t.Fatalf("Expected %v, got %v", ErrExpectedSeedGotPublicKey, err)
+	}
+
+	if _, err := ParsePublicKey(string(seed)); err != ErrExpectedPublicKeyGotSeed {
+		t.Fatalf("Expected %v, got %v", ErrExpectedPublicKeyGotSeed, err)
+	}
+	p, err := ParsePublicKey(pk)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	vkp, _ := p.KeyPair()
+	if err := vkp.Verify([]byte("hello"), sig); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := ParseSeed([]byte(pk)); err != ErrExpectedSeedGotPublicKey {
+		t.Fatalf("Expected %v, got %v", ErrExpectedSeedGotPublicKey, err)
+	}
+	s, err := ParseSeed(seed)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if kp, _ := s.KeyPair(); kp == nil {
+		t.Fatalf("Expected a KeyPair")
+	}
+}
+
+func TestAuditKeyDistribution(t *testing.T) {
+	keys := make([]string, 0, 512)
+	for i := 0; i < 512; i++ {
+		kp, _ := CreateUser()
+		pub, _ := kp.PublicKey()
+		keys = append(keys, pub)
+	}
+	r := AuditKeyDistribution(append(keys, "invalid"))
+	if !r.Tested || !r.OK() || r.Invalid != 1 {
+		t.Fatalf("Expected a healthy report, got %+v", r)
+	}
+
+	// Keys whose leading bits are stuck.
+	biased := make([]string, 0, 512)
+	raw := make([]byte, ed25519.PublicKeySize)
+	for i := 0; i < 512; i++ {
+		rand.Read(raw)
+		raw[0] &= 0x0f
+		pub, _ := Encode(PrefixByteUser, raw)
+		biased = append(biased, string(pub))
+	}
+	biased = append(biased, biased[0])
+	r = AuditKeyDistribution(biased)
+	if r.OK() || len(r.Duplicates) != 1 || len(r.BiasedBits) != 4 || r.PrefixZ <= DistributionThreshold {
+		t.Fatalf("Expected duplicates and biased bits, got %+v", r)
+	}
+
+	// The high bit of curve keys is always clear and must not be reported.
+	curves := make([]string, 0, 512)
+	for i := 0; i < 512; i++ {
+		kp, _ := CreateCurveKeys()
+		pub, _ := kp.PublicKey()
+		curves = append(curves, pub)
+	}
+	if r := AuditKeyDistribution(curves); !r.Tested || !r.OK() {
+		t.Fatalf("Expected a healthy curve report, got %+v", r)
+	}
+	if r := AuditKeyDistribution(append(curves[:256], keys[:256]...)); !r.Tested || !r.OK() {
+		t.Fatalf("Expected a healthy mixed report, got %+v", r)
+	}
+
+	if r := AuditKeyDistribution(keys[:10]); r.Tested || !r.OK() {
+		t.Fatalf("Expected a small corpus to skip the statistical tests, got %+v", r)
+	}
+}
+
+func TestCompactPublicKey(t *testing.T) {
+	for _, create := range []func() (KeyPair, error){CreateUser, CreateOperator, CreateCurveKeys} {
+		kp, _ := create()
+		pub, _ := kp.PublicKey()
+		c, err := CompactPublicKey(pub)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(c) != CompactPublicKeySize {
+			t.Fatalf("Expected %d, got %d", CompactPublicKeySize, len(c))
+		}
+		back, err := FromCompactPublicKey(c)
+		if err != nil || back != pub {
+			t.Fatalf("Expected %q, got %q (%v)", pub, back, err)
+		}
+		if s := ShortKey(pub, 8); s != pub[:8] {
+			t.Fatalf("Expected %q, got %q", pub[:8], s)
+		}
+	}
+
+	user, _ := CreateUser()
+	seed, _ := user.Seed()
+	if _, err := CompactPublicKey(string(seed)); err != ErrExpectedPublicKeyGotSeed {
+		t.Fatalf("Expected %v, got %v", ErrExpectedPublicKeyGotSeed, err)
+	}
+	if _, err := FromCompactPublicKey(append([]byte{byte(PrefixByteSeed)}, make([]byte, 32)...)); err != ErrInvalidPublicKey {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPublicKey, err)
+	}
+	if _, err := FromCompactPublicKey([]byte{byte(PrefixByteUser)}); err != ErrInvalidPublicKey {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPublicKey, err)
+	}
+	if s := ShortKey("UABC", 10); s != "UABC" {
+		t.Fatalf("Expected %q, got %q", "UABC", s)
+	}
+}
+
+func TestHexAndBase64Seeds(t *testing.T) {
+	user, _ := CreateUser()
+	pub, _ := user.PublicKey()
+	h, err := ExportHexSeed(user)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	b, err := ExportBase64Seed(user)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	seed, _ := user.Seed()
+	_, raw, _ := DecodeSeed(seed)
+	priv := ed25519.NewKeyFromSeed(raw)
+
+	for _, tc := range []struct {
+		name string
+		from func() (KeyPair, error)
+	}{
+		{"hex", func() (KeyPair, error) { return FromHexSeed(PrefixByteUser, h) }},
+		{"upper hex", func() (KeyPair, error) { return FromHexSeed(PrefixByteUser, strings.ToUpper(h)+"\n") }},
+		{"expanded hex", func() (KeyPair, error) { return FromHexSeed(PrefixByteUser, hex.EncodeToString(priv)) }},
+		{"base64", func() (KeyPair, error) { return FromBase64Seed(PrefixByteUser, b) }},
+		{"base64url", func() (KeyPair, error) {
+			return FromBase64Seed(PrefixByteUser, base64.RawURLEncoding.EncodeToString(raw))
+		}},
+	} {
+		kp, err := tc.from()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if got, _ := kp.PublicKey(); got != pub {
+			t.Fatalf("%s: expected %q, got %q", tc.name, pub, got)
+		}
+	}
+
+	ckp, err := FromHexSeed(PrefixByteCurve, h)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cpub, _ := ckp.PublicKey()
+	if _, err := ckp.Seal([]byte("x"), cpub); err != nil {
+		t.Fatalf("Expected a curve key pair, got %v", err)
+	}
+	if _, err := FromHexSeed(PrefixByteUser, "zz"); err != ErrInvalidEncoding {
+		t.Fatalf("Expected %v, got %v", ErrInvalidEncoding, err)
+	}
+	if _, err := FromHexSeed(PrefixByteUser, h[:32]); err != ErrInvalidSeedLen {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSeedLen, err)
+	}
+	if _, err := FromHexSeed(PrefixByteCurve, hex.EncodeToString(priv)); err != ErrInvalidSeedLen {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSeedLen, err)
+	}
+	pubOnly, _ := user.PublicOnly()
+	if _, err := ExportHexSeed(pubOnly); err != ErrPublicKeyOnly {
+		t.Fatalf("Expected %v, got %v", ErrPublicKeyOnly, err)
+	}
+}
+
+func TestEphemeral(t *testing.T) {
+	wiped := make(chan struct{})
+	e, err := Ephemeral(PrefixByteUser, EphemeralOptions{TTL: 50 * time.Millisecond, OnWipe: func() { close(wiped) }})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := e.Seed(); err != ErrEphemeralKey {
+		t.Fatalf("Expected %v, got %v", ErrEphemeralKey, err)
+	}
+	if _, err := e.PrivateKey(); err != ErrEphemeralKey {
+		t.Fatalf("Expected %v, got %v", ErrEphemeralKey, err)
+	}
+	sig, err := e.Sign([]byte("hello"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	select {
+	case <-wiped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the key to be wiped after its TTL")
+	}
+	if _, err := e.Sign([]byte("hello")); err != ErrExpired {
+		t.Fatalf("Expected %v, got %v", ErrExpired, err)
+	}
+	if err := e.Verify([]byte("hello"), sig); err != nil {
+		t.Fatalf("Expected earlier signatures to verify, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c, _ := Ephemeral(PrefixByteCurve, EphemeralOptions{Context: ctx})
+	cpub, _ := c.PublicKey()
+	if _, err := c.Seal([]byte("x"), cpub); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cancel()
+	for i := 0; !c.Wiped(); i++ {
+		if i == 500 {
+			t.Fatal("Expected the key to be wiped when the context is done")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := c.Seal([]byte("x"), cpub); err != ErrExpired {
+		t.Fatalf("Expected %v, got %v", ErrExpired, err)
+	}
+	c.Wipe()
+}
+
+// TestExecSignerHelper is the signing command run by TestExecSigner.
+func TestExecSignerHelper(t *testing.T) {
+	seed := os.Getenv("TEST_EXEC_SIGNER_SEED")
+	if seed == "" {
+		return
+	}
+	kp, _ := FromSeed([]byte(seed))
+	input, _ := io.ReadAll(os.Stdin)
+	sig, _ := kp.Sign(input)
+	switch os.Getenv("TEST_EXEC_SIGNER_MODE") {
+	case "hex":
+		fmt.Println(hex.EncodeToString(sig))
+	case "raw":
+		os.Stdout.Write(sig)
+	case "sleep":
+		time.Sleep(time.Minute)
+	default:
+		fmt.Println(base64.StdEncoding.EncodeToString(sig))
+	}
+	os.Exit(0)
+}
+
+func TestExecSigner(t *testing.T) {
+	user, _ := CreateUser()
+	seed, _ := user.Seed()
+	pub, _ := user.PublicKey()
+	opts := ExecSignerOptions{Command: os.Args[0], Args: []string{"-test.run=TestExecSignerHelper"}}
+	t.Setenv("TEST_EXEC_SIGNER_SEED", string(seed))
+
+	kp, err := NewExecSigner(pub, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, mode := range []string{"base64", "hex", "raw"} {
+		t.Setenv("TEST_EXEC_SIGNER_MODE", mode)
+		sig, err := kp.Sign([]byte("hello"))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", mode, err)
+		}
+		if err := user.Verify([]byte("hello"), sig); err != nil {
+			t.Fatalf("%s: unexpected error: %v", mode, err)
+		}
+	}
+	if _, err := kp.Seed(); err != ErrPublicKeyOnly {
+		t.Fatalf("Expected %v, got %v", ErrPublicKeyOnly, err)
+	}
+
+	// A command signing with another key is caught.
+	other, _ := CreateUser()
+	opub, _ := other.PublicKey()
+	wrong, _ := NewExecSigner(opub, opts)
+	t.Setenv("TEST_EXEC_SIGNER_MODE", "base64")
+	if _, err := wrong.Sign([]byte("hello")); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+
+	t.Setenv("TEST_EXEC_SIGNER_MODE", "sleep")
+	opts.Timeout = 100 * time.Millisecond
+	slow, _ := NewExecSigner(pub, opts)
+	if _, err := slow.Sign([]byte("hello")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if _, err := parseSignerOutput([]byte("not a signature\n")); err != ErrInvalidSignerOutput {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignerOutput, err)
+	}
+	curve, _ := CreateCurveKeys()
+	cpub, _ := curve.PublicKey()
+	if _, err := NewExecSigner(cpub, opts); err != ErrInvalidCurveKeyOperation {
+		t.Fatalf("Expected %v, got %v", ErrInvalidCurveKeyOperation, err)
+	}
+}
+
+func TestEncodedArtifactsArePortable(t *testing.T) {
+	creators := []func() (KeyPair, error){
+		CreateUser, CreateAccount, CreateServer, CreateCluster, CreateOperator, CreateCurveKeys,
+	}
+	for i := 0; i < 20; i++ {
+		for _, create := range creators {
+			kp, _ := create()
+			pk, _ := kp.PublicKey()
+			seed, _ := kp.Seed()
+			priv, _ := kp.PrivateKey()
+			for want, key := range map[KeyForm][]byte{
+				KeyFormPublic: []byte(pk), KeyFormSeed: seed, KeyFormPrivate: priv,
+			} {
+				form, err := ValidateEncoded(key)
+				if err != nil || form != want {
+					t.Fatalf("Expected %v, got %v %v for %q", want, form, err, key)
+				}
+				if got := SanitizeForFilename(string(key)); got != string(key) {
+					t.Fatalf("Expected %q, got %q", key, got)
+				}
+			}
+			if len(pk) != EncodedPublicKeyLen || len(seed) != EncodedSeedLen {
+				t.Fatalf("Expected fixed lengths, got %d and %d", len(pk), len(seed))
+			}
+		}
+	}
+
+	kp, _ := CreateUser()
+	pk, _ := kp.PublicKey()
+	if _, err := ValidateEncoded([]byte(strings.ToLower(pk))); err != ErrInvalidEncoding {
+		t.Fatalf("Expected %v, got %v", ErrInvalidEncoding, err)
+	}
+	if _, err := ValidateEncoded([]byte(pk + "====")); err != ErrInvalidEncoding {
+		t.Fatalf("Expected %v, got %v", ErrInvalidEncoding, err)
+	}
+	short, _ := Encode(PrefixByteUser, []byte("short"))
+	if _, err := ValidateEncoded(short); err != ErrInvalidKey {
+		t.Fatalf("Expected %v, got %v", ErrInvalidKey, err)
+	}
+}
+
+func TestSanitizeForFilename(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                 "_",
+		".":                "_",
+		"..":               "_.",
+		"../etc/passwd":    "_._etc_passwd",
+		".hidden":          "_hidden",
+		"acme prod/ü":      "acme_prod__",
+		"tenant-1.pub":     "tenant-1.pub",
+		"a?b*c:d|e\"f<g>h": "a_b_c_d_e_f_g_h",
+	} {
+		if got := SanitizeForFilename(in); got != want {
+			t.Fatalf("Expected %q, got %q", want, got)
+		}
+	}
+	if got := SanitizeForFilename(strings.Repeat("x", 300)); len(got) != 255 {
+		t.Fatalf("Expected 255 bytes, got %d", len(got))
+	}
+}
+
+func TestDeprecations(t *testing.T) {
+	cluster, _ := CreateCluster()
+	seed, _ := cluster.Seed()
+	pk, _ := cluster.PublicKey()
+
+	var notices []DeprecationNotice
+	handler := func(n DeprecationNotice) { notices = append(notices, n) }
+	err := SetDeprecations(handler, Deprecation{PrefixByteCluster, DeprecationWarn, "migrate by 2027"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer SetDeprecations(nil)
+
+	if _, err := CreateCluster(); err != nil {
+		t.Fatalf("Expected a warning only, got %v", err)
+	}
+	if _, err := FromSeed(seed); err != nil {
+		t.Fatalf("Expected a warning only, got %v", err)
+	}
+	if _, err := FromPublicKey(pk); err != nil {
+		t.Fatalf("Expected a warning only, got %v", err)
+	}
+	if _, err := CreateUser(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(notices) != 3 || notices[0].Operation != "create" || notices[2].Operation != "parse" {
+		t.Fatalf("Expected 3 notices, got %v", notices)
+	}
+	if notices[1].Message != "migrate by 2027" {
+		t.Fatalf("Expected the message, got %q", notices[1].Message)
+	}
+
+	SetDeprecations(nil, Deprecation{Prefix: PrefixByteCluster, Action: DeprecationRefuse})
+	if _, err := CreateCluster(); err != ErrKeyTypeDeprecated {
+		t.Fatalf("Expected %v, got %v", ErrKeyTypeDeprecated, err)
+	}
+	if _, err := FromSeed(seed); err != ErrKeyTypeDeprecated {
+		t.Fatalf("Expected %v, got %v", ErrKeyTypeDeprecated, err)
+	}
+	// Keys already loaded keep working.
+	if _, err := cluster.Sign([]byte("hello")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if deps := Deprecations(); len(deps) != 1 || deps[0].Prefix != PrefixByteCluster {
+		t.Fatalf("Expected the cluster deprecation, got %v", deps)
+	}
+
+	if err := SetDeprecations(nil, Deprecation{Prefix: PrefixByteSeed, Action: DeprecationWarn}); err != ErrInvalidPrefixByte {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPrefixByte, err)
+	}
+	SetDeprecations(nil)
+	if _, err := CreateCluster(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestDeprecationsAllPaths(t *testing.T) {
+	curve, _ := CreateCurveKeys()
+	cseed, _ := curve.Seed()
+	cpriv, _ := curve.PrivateKey()
+	cluster, _ := CreateCluster()
+	seed, _ := cluster.Seed()
+	priv, _ := cluster.PrivateKey()
+
+	var notices []DeprecationNotice
+	handler := func(n DeprecationNotice) { notices = append(notices, n) }
+	SetDeprecations(handler,
+		Deprecation{Prefix: PrefixByteCurve, Action: DeprecationWarn},
+		Deprecation{Prefix: PrefixByteCluster, Action: DeprecationWarn})
+	defer SetDeprecations(nil)
+
+	for name, f := range map[string]func() (KeyPair, error){
+		"CreateCurveKeys":         CreateCurveKeys,
+		"CreateCurveKeysWithRand": func() (KeyPair, error) { return CreateCurveKeysWithRand(rand.Reader) },
+		"CreatePair":              func() (KeyPair, error) { return CreatePair(PrefixByteCurve) },
+		"FromSeed":                func() (KeyPair, error) { return FromSeed(cseed) },
+		"FromCurveSeed":           func() (KeyPair, error) { return FromCurveSeed(cseed) },
+		"FromPrivateKey curve":    func() (KeyPair, error) { return FromPrivateKey(PrefixByteCurve, cpriv) },
+		"FromPrivateKey":          func() (KeyPair, error) { return FromPrivateKey(PrefixByteCluster, priv) },
+		"FromSeedWarm":            func() (KeyPair, error) { return FromSeedWarm(seed, "") },
+	} {
+		notices = nil
+		if _, err := f(); err != nil {
+			t.Fatalf("%s: expected a warning only, got %v", name, err)
+		}
+		if len(notices) != 1 {
+			t.Fatalf("%s: expected 1 notice, got %v", name, notices)
+		}
+		SetDeprecations(nil,
+			Deprecation{Prefix: PrefixByteCurve, Action: DeprecationRefuse},
+			Deprecation{Prefix: PrefixByteCluster, Action: DeprecationRefuse})
+		if _, err := f(); err != ErrKeyTypeDeprecated {
+			t.Fatalf("%s: expected %v, got %v", name, ErrKeyTypeDeprecated, err)
+		}
+		SetDeprecations(handler,
+			Deprecation{Prefix: PrefixByteCurve, Action: DeprecationWarn},
+			Deprecation{Prefix: PrefixByteCluster, Action: DeprecationWarn})
+	}
+}
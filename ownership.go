@@ -0,0 +1,139 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"io"
+	"time"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// Ownership proofs let a service ask for proof that a client controls a key
+// without the client signing bytes the service chose. The client only ever
+// signs a fixed 64 byte message: ownershipDomain followed by the SHA-256 of
+// the canonical challenge. The service can influence the digest but not
+// choose the signed bytes, and the NUL in the domain keeps the message from
+// parsing as a NATS nonce, a JWT or any other signed format, so the
+// signature is useless outside of VerifyOwnership.
+
+// ownershipDomain is exactly 32 bytes.
+const ownershipDomain = "nkeys ownership proof v1\x00\x00\x00\x00\x00\x00\x00\x00"
+
+// OwnershipNonceLen is the length of challenge nonces.
+const OwnershipNonceLen = 32
+
+// maxOwnershipService bounds the service name of a challenge.
+const maxOwnershipService = 256
+
+// OwnershipChallenge is issued by a service that wants proof of ownership.
+type OwnershipChallenge struct {
+	Service string    `json:"service"`
+	Nonce   []byte    `json:"nonce"`
+	Expires time.Time `json:"exp"`
+}
+
+// OwnershipProof answers an OwnershipChallenge. It holds no other data; the
+// verifier must supply the challenge it issued.
+type OwnershipProof struct {
+	PublicKey string `json:"pub"`
+	Signature []byte `json:"sig"`
+}
+
+// NewOwnershipChallenge returns a challenge from service with a random
+// nonce, valid for ttl from the time of clock, which may be nil for the
+// system clock. A ttl that is not positive returns ErrInvalidChallenge.
+func NewOwnershipChallenge(service string, ttl time.Duration, clock Clock) (*OwnershipChallenge, error) {
+	if ttl <= 0 {
+		return nil, ErrInvalidChallenge
+	}
+	c := &OwnershipChallenge{
+		Service: service,
+		Nonce:   make([]byte, OwnershipNonceLen),
+		Expires: ClockOrSystem(clock).Now().Add(ttl).UTC().Truncate(time.Second),
+	}
+	if _, err := io.ReadFull(rand.Reader, c.Nonce); err != nil {
+		return nil, err
+	}
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// check rejects challenges that do not have the fixed structure.
+func (c *OwnershipChallenge) check() error {
+	if len(c.Nonce) != OwnershipNonceLen || c.Expires.IsZero() ||
+		c.Service == "" || len(c.Service) > maxOwnershipService {
+		return ErrInvalidChallenge
+	}
+	for _, r := range c.Service {
+		if r < 0x20 || r == 0x7f {
+			return ErrInvalidChallenge
+		}
+	}
+	return nil
+}
+
+func (c *OwnershipChallenge) signedBytes(public string) []byte {
+	e := canonical.NewEncoder("nkeys.OwnershipChallenge")
+	e.Text(public)
+	e.Text(c.Service)
+	e.Blob(c.Nonce)
+	e.Time(c.Expires)
+	sum := sha256.Sum256(e.Bytes())
+	return append([]byte(ownershipDomain), sum[:]...)
+}
+
+// ProveOwnership answers c with kp. Malformed challenges and challenges
+// expired at the time of clock, which may be nil for the system clock, are
+// refused before anything is signed.
+func ProveOwnership(kp KeyPair, c *OwnershipChallenge, clock Clock) (*OwnershipProof, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	if err := (&VerifyPolicy{Clock: clock}).CheckValidity(time.Time{}, c.Expires); err != nil {
+		return nil, err
+	}
+	public, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := kp.Sign(c.signedBytes(public))
+	if err != nil {
+		return nil, err
+	}
+	return &OwnershipProof{PublicKey: public, Signature: sig}, nil
+}
+
+// VerifyOwnership checks that p answers the challenge c issued by the
+// caller, that c has not expired and applies the policy to the key. If
+// expected is not empty the proof must be for that key, otherwise
+// ErrInvalidOwnership is returned. The caller is responsible for only
+// accepting each challenge once.
+func VerifyOwnership(p *OwnershipProof, c *OwnershipChallenge, expected string, vp *VerifyPolicy) error {
+	if err := c.check(); err != nil {
+		return err
+	}
+	if expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(p.PublicKey)) != 1 {
+		return ErrInvalidOwnership
+	}
+	if err := VerifyWithPolicy(vp, p.PublicKey, c.signedBytes(p.PublicKey), p.Signature); err != nil {
+		return err
+	}
+	return vp.CheckValidity(time.Time{}, c.Expires)
+}
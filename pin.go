@@ -0,0 +1,190 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// PinMismatchError is returned when a server presents a different public
+// key than the one pinned for it.
+type PinMismatchError struct {
+	Name   string
+	Pinned string
+	Got    string
+}
+
+func (e *PinMismatchError) Error() string {
+	return fmt.Sprintf("nkeys: public key for %q changed from %s to %s", e.Name, e.Pinned, e.Got)
+}
+
+// Pin records the server and cluster public keys a client has seen, trusting
+// each on first use like ssh known_hosts, and rejects a later change of key.
+// Pins are persisted as a JSON object in a file.
+type Pin struct {
+	path string
+
+	// OnChange, when set, is called before Check returns a mismatch.
+	OnChange func(*PinMismatchError)
+
+	mu   sync.Mutex
+	pins map[string]string
+}
+
+// NewPin loads the pins in the file at path. A missing file starts empty.
+func NewPin(path string) (*Pin, error) {
+	p := &Pin{path: path, pins: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &p.pins); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func checkPinnable(public string) error {
+	if !IsValidPublicServerKey(public) && !IsValidPublicClusterKey(public) {
+		return ErrInvalidPublicKey
+	}
+	return nil
+}
+
+// Check pins public for name if nothing is pinned yet and returns a
+// *PinMismatchError if a different key is pinned.
+func (p *Pin) Check(name, public string) error {
+	if err := checkPinnable(public); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	pinned, ok := p.pins[name]
+	if !ok {
+		defer p.mu.Unlock()
+		return p.set(name, public)
+	}
+	p.mu.Unlock()
+	if pinned == public {
+		return nil
+	}
+	err := &PinMismatchError{Name: name, Pinned: pinned, Got: public}
+	if p.OnChange != nil {
+		p.OnChange(err)
+	}
+	return err
+}
+
+// Set pins public for name, replacing any previous pin, for example after
+// a planned key rotation.
+func (p *Pin) Set(name, public string) error {
+	if err := checkPinnable(public); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.set(name, public)
+}
+
+// Remove forgets the pin for name.
+func (p *Pin) Remove(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.pins[name]; !ok {
+		return ErrKeyNotFound
+	}
+	delete(p.pins, name)
+	return p.save()
+}
+
+// Get returns the key pinned for name.
+func (p *Pin) Get(name string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	public, ok := p.pins[name]
+	return public, ok
+}
+
+// Names returns the pinned names in lexical order.
+func (p *Pin) Names() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	names := make([]string, 0, len(p.pins))
+	for n := range p.pins {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// set must be called with p.mu held. The pin is only kept if it could be
+// persisted.
+func (p *Pin) set(name, public string) error {
+	prev, had := p.pins[name]
+	p.pins[name] = public
+	if err := p.save(); err != nil {
+		if had {
+			p.pins[name] = prev
+		} else {
+			delete(p.pins, name)
+		}
+		return err
+	}
+	return nil
+}
+
+// save atomically writes the pins. p.mu must be held.
+func (p *Pin) save() error {
+	data, err := json.MarshalIndent(p.pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p.path, data, 0600)
+}
+
+// writeFileAtomic replaces the file at path with data and the given
+// permissions, so that readers and crashes never see a partial write.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
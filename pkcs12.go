@@ -0,0 +1,42 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"crypto/ed25519"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// ImportPKCS12 extracts the ed25519 private key from a PKCS#12 (.p12/.pfx)
+// bundle and returns it as a KeyPair of the given type. The bundle must hold
+// exactly one private key and one certificate, and if the certificate holds
+// an ed25519 key it must match the private key.
+func ImportPKCS12(prefix PrefixByte, data []byte, password string) (KeyPair, error) {
+	key, cert, err := pkcs12.Decode(data, password)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, ErrIncompatibleKey
+	}
+	if pub, ok := cert.PublicKey.(ed25519.PublicKey); ok {
+		if !bytes.Equal(pub, priv.Public().(ed25519.PublicKey)) {
+			return nil, ErrIncompatibleKey
+		}
+	}
+	return FromExpandedPrivateKey(prefix, priv)
+}
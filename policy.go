@@ -0,0 +1,186 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "io"
+
+// Operation is a set of KeyPair operations that a Policy allows.
+type Operation uint8
+
+const (
+	// OpSign allows Sign.
+	OpSign Operation = 1 << iota
+	// OpVerify allows Verify.
+	OpVerify
+	// OpSeal allows Seal and SealWithRand.
+	OpSeal
+	// OpOpen allows Open.
+	OpOpen
+	// OpExport allows Seed and PrivateKey.
+	OpExport
+)
+
+const (
+	// SignOnly allows signing and nothing else.
+	SignOnly = OpSign
+	// VerifyOnly allows verification and nothing else.
+	VerifyOnly = OpVerify
+)
+
+// Policy describes how a KeyPair may be used. The zero value allows no
+// operations other than PublicKey and Wipe.
+type Policy struct {
+	// Allowed is the set of permitted operations.
+	Allowed Operation
+	// MaxPayload is the largest input accepted by Sign, Verify, Seal and Open.
+	// Zero means no limit.
+	MaxPayload int
+	// Contexts restricts the contexts accepted by the *Context methods.
+	// An empty list allows any context.
+	Contexts []string
+}
+
+// allows returns an error if op is not permitted for an input of size n.
+func (p *Policy) allows(op Operation, n int) error {
+	if p.Allowed&op == 0 {
+		return ErrOperationNotAllowed
+	}
+	if p.MaxPayload > 0 && n > p.MaxPayload {
+		return ErrPayloadTooLarge
+	}
+	return nil
+}
+
+// allowsContext returns an error if op is not permitted for an input of
+// size n in context. It is only used by the *Context methods.
+func (p *Policy) allowsContext(op Operation, n int, context string) error {
+	if err := p.allows(op, n); err != nil {
+		return err
+	}
+	if len(p.Contexts) == 0 {
+		return nil
+	}
+	for _, c := range p.Contexts {
+		if c == context {
+			return nil
+		}
+	}
+	return ErrContextNotAllowed
+}
+
+// PolicyKeyPair is a KeyPair whose operations are restricted by a Policy.
+type PolicyKeyPair struct {
+	kp     KeyPair
+	policy Policy
+}
+
+// WithPolicy returns a KeyPair that enforces the policy on every operation
+// before delegating to kp.
+func WithPolicy(kp KeyPair, policy Policy) *PolicyKeyPair {
+	policy.Contexts = append([]string{}, policy.Contexts...)
+	return &PolicyKeyPair{kp, policy}
+}
+
+// Policy returns the policy being enforced.
+func (p *PolicyKeyPair) Policy() Policy {
+	policy := p.policy
+	policy.Contexts = append([]string{}, p.policy.Contexts...)
+	return policy
+}
+
+// Seed will return the encoded seed if export is allowed.
+func (p *PolicyKeyPair) Seed() ([]byte, error) {
+	if err := p.policy.allows(OpExport, 0); err != nil {
+		return nil, err
+	}
+	return p.kp.Seed()
+}
+
+// PublicKey will return the encoded public key. It is always allowed.
+func (p *PolicyKeyPair) PublicKey() (string, error) {
+	return p.kp.PublicKey()
+}
+
+// PrivateKey will return the encoded private key if export is allowed.
+func (p *PolicyKeyPair) PrivateKey() ([]byte, error) {
+	if err := p.policy.allows(OpExport, 0); err != nil {
+		return nil, err
+	}
+	return p.kp.PrivateKey()
+}
+
+// Sign will sign the input if allowed by the policy.
+func (p *PolicyKeyPair) Sign(input []byte) ([]byte, error) {
+	return p.SignContext("", input)
+}
+
+// SignContext will sign the input on behalf of context if allowed by the policy.
+func (p *PolicyKeyPair) SignContext(context string, input []byte) ([]byte, error) {
+	if err := p.policy.allowsContext(OpSign, len(input), context); err != nil {
+		return nil, err
+	}
+	return p.kp.Sign(input)
+}
+
+// Verify will verify the input against a signature if allowed by the policy.
+func (p *PolicyKeyPair) Verify(input []byte, sig []byte) error {
+	return p.VerifyContext("", input, sig)
+}
+
+// VerifyContext will verify the input on behalf of context if allowed by the policy.
+func (p *PolicyKeyPair) VerifyContext(context string, input []byte, sig []byte) error {
+	if err := p.policy.allowsContext(OpVerify, len(input), context); err != nil {
+		return err
+	}
+	return p.kp.Verify(input, sig)
+}
+
+// PublicOnly returns a public only copy of the KeyPair restricted by the
+// same policy.
+func (p *PolicyKeyPair) PublicOnly() (KeyPair, error) {
+	public, err := p.kp.PublicOnly()
+	if err != nil {
+		return nil, err
+	}
+	return WithPolicy(public, p.policy), nil
+}
+
+// Wipe will wipe the underlying KeyPair.
+func (p *PolicyKeyPair) Wipe() {
+	p.kp.Wipe()
+}
+
+// Seal will seal the input if allowed by the policy.
+func (p *PolicyKeyPair) Seal(input []byte, recipient string) ([]byte, error) {
+	if err := p.policy.allows(OpSeal, len(input)); err != nil {
+		return nil, err
+	}
+	return p.kp.Seal(input, recipient)
+}
+
+// SealWithRand will seal the input if allowed by the policy.
+func (p *PolicyKeyPair) SealWithRand(input []byte, recipient string, rr io.Reader) ([]byte, error) {
+	if err := p.policy.allows(OpSeal, len(input)); err != nil {
+		return nil, err
+	}
+	return p.kp.SealWithRand(input, recipient, rr)
+}
+
+// Open will open the input if allowed by the policy.
+func (p *PolicyKeyPair) Open(input []byte, sender string) ([]byte, error) {
+	if err := p.policy.allows(OpOpen, len(input)); err != nil {
+		return nil, err
+	}
+	return p.kp.Open(input, sender)
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "testing"
+
+func TestPolicySignOnly(t *testing.T) {
+	user, _ := CreateUser()
+	kp := WithPolicy(user, Policy{Allowed: SignOnly, MaxPayload: 8})
+
+	sig, err := kp.Sign([]byte("payload"))
+	if err != nil {
+		t.Fatalf("Unexpected error signing: %v", err)
+	}
+	if _, err := kp.Sign([]byte("too large payload")); err != ErrPayloadTooLarge {
+		t.Fatalf("Expected ErrPayloadTooLarge, got %v", err)
+	}
+	if err := kp.Verify([]byte("payload"), sig); err != ErrOperationNotAllowed {
+		t.Fatalf("Expected ErrOperationNotAllowed, got %v", err)
+	}
+	if _, err := kp.Seed(); err != ErrOperationNotAllowed {
+		t.Fatalf("Expected ErrOperationNotAllowed for Seed, got %v", err)
+	}
+	if _, err := kp.PrivateKey(); err != ErrOperationNotAllowed {
+		t.Fatalf("Expected ErrOperationNotAllowed for PrivateKey, got %v", err)
+	}
+	pk, err := kp.PublicKey()
+	if err != nil {
+		t.Fatalf("Unexpected error getting public key: %v", err)
+	}
+	if upk, _ := user.PublicKey(); upk != pk {
+		t.Fatalf("Expected public keys to match")
+	}
+	if err := user.Verify([]byte("payload"), sig); err != nil {
+		t.Fatalf("Unexpected error verifying: %v", err)
+	}
+}
+
+func TestPolicyContexts(t *testing.T) {
+	user, _ := CreateUser()
+	kp := WithPolicy(user, Policy{Allowed: OpSign | OpVerify, Contexts: []string{"jwt"}})
+
+	if _, err := kp.Sign([]byte("x")); err != ErrContextNotAllowed {
+		t.Fatalf("Expected ErrContextNotAllowed, got %v", err)
+	}
+	sig, err := kp.SignContext("jwt", []byte("x"))
+	if err != nil {
+		t.Fatalf("Unexpected error signing: %v", err)
+	}
+	if err := kp.VerifyContext("jwt", []byte("x"), sig); err != nil {
+		t.Fatalf("Unexpected error verifying: %v", err)
+	}
+	if err := kp.VerifyContext("other", []byte("x"), sig); err != ErrContextNotAllowed {
+		t.Fatalf("Expected ErrContextNotAllowed, got %v", err)
+	}
+
+	p := kp.Policy()
+	p.Contexts[0] = "changed"
+	if _, err := kp.SignContext("jwt", []byte("x")); err != nil {
+		t.Fatalf("Expected policy to be unaffected by changes to a copy, got %v", err)
+	}
+
+	// Contexts only restrict the *Context methods.
+	curve, _ := CreateCurveKeys()
+	rpk, _ := curve.PublicKey()
+	ck := WithPolicy(curve, Policy{Allowed: OpSeal | OpOpen | OpExport, Contexts: []string{"jwt"}})
+	sealed, err := ck.Seal([]byte("x"), rpk)
+	if err != nil {
+		t.Fatalf("Unexpected error sealing: %v", err)
+	}
+	if _, err := ck.Open(sealed, rpk); err != nil {
+		t.Fatalf("Unexpected error opening: %v", err)
+	}
+	if _, err := ck.Seed(); err != nil {
+		t.Fatalf("Unexpected error exporting: %v", err)
+	}
+}
+
+func TestPolicyZeroValue(t *testing.T) {
+	curve, _ := CreateCurveKeys()
+	kp := WithPolicy(curve, Policy{})
+	rpk, _ := curve.PublicKey()
+	if _, err := kp.Seal([]byte("x"), rpk); err != ErrOperationNotAllowed {
+		t.Fatalf("Expected ErrOperationNotAllowed, got %v", err)
+	}
+	if _, err := kp.Open([]byte("x"), rpk); err != ErrOperationNotAllowed {
+		t.Fatalf("Expected ErrOperationNotAllowed, got %v", err)
+	}
+
+	kp = WithPolicy(curve, Policy{Allowed: OpSeal | OpOpen})
+	sealed, err := kp.Seal([]byte("x"), rpk)
+	if err != nil {
+		t.Fatalf("Unexpected error sealing: %v", err)
+	}
+	if _, err := kp.Open(sealed, rpk); err != nil {
+		t.Fatalf("Unexpected error opening: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "strings"
+
+// Every key, seed and private key this package encodes is unpadded base32
+// using only the characters A-Z and 2-7, and has a fixed length per form.
+// Such artifacts are plain ASCII, identical in every locale, and can be used
+// verbatim in URLs, file names, environment variables and shell words.
+const (
+	EncodedPublicKeyLen       = 56
+	EncodedSeedLen            = 58
+	EncodedPrivateKeyLen      = 108
+	EncodedCurvePrivateKeyLen = 56
+)
+
+const (
+	maxPortableFileNameLen     = 255
+	portableFileNameSubstitute = '_'
+)
+
+// ValidateEncoded checks that key is an artifact as emitted by this package:
+// only base32 characters, the fixed length of its form, and a valid
+// checksum. It returns the form of the key.
+func ValidateEncoded(key []byte) (KeyForm, error) {
+	for _, c := range key {
+		if !isBase32Char(c) {
+			return KeyFormUnknown, ErrInvalidEncoding
+		}
+	}
+	raw, err := decode(key)
+	if err != nil {
+		return KeyFormUnknown, err
+	}
+	defer wipeBytes(raw)
+	prefix := PrefixByte(raw[0])
+	switch {
+	case prefix == PrefixBytePrivate:
+		if len(key) == EncodedPrivateKeyLen || len(key) == EncodedCurvePrivateKeyLen {
+			return KeyFormPrivate, nil
+		}
+	case checkValidPublicPrefixByte(prefix) == nil:
+		if len(key) == EncodedPublicKeyLen {
+			return KeyFormPublic, nil
+		}
+	case raw[0]&248 == byte(PrefixByteSeed):
+		if len(key) == EncodedSeedLen {
+			return KeyFormSeed, nil
+		}
+	}
+	return KeyFormUnknown, ErrInvalidKey
+}
+
+func isBase32Char(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= '2' && c <= '7'
+}
+
+// SanitizeForFilename returns name as a single portable path element.
+// Letters, digits, '.', '-' and '_' are kept, everything else, including
+// path separators and non ASCII characters, is replaced by '_'. Leading dots
+// are replaced so the result is never hidden, "." or "..". The result is at
+// most 255 bytes and never empty. Encoded keys are returned unchanged.
+func SanitizeForFilename(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if b.Len() == maxPortableFileNameLen {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '-', r == '_':
+			b.WriteRune(r)
+		case r == '.' && b.Len() > 0:
+			b.WriteRune(r)
+		default:
+			b.WriteByte(portableFileNameSubstitute)
+		}
+	}
+	if b.Len() == 0 {
+		return string(portableFileNameSubstitute)
+	}
+	return b.String()
+}
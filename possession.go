@@ -0,0 +1,89 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"io"
+	"time"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// possessionNonceLen is the length of nonces generated by MintPossession.
+const possessionNonceLen = 16
+
+// Possession is a proof that the holder of PublicKey controls its private
+// key, bound to an audience and a nonce so it can not be replayed to other
+// services. APIs that accept a public key can demand one instead of
+// designing their own challenge format.
+type Possession struct {
+	PublicKey string    `json:"pub"`
+	Audience  string    `json:"aud"`
+	Nonce     []byte    `json:"nonce"`
+	Timestamp time.Time `json:"ts"`
+	Signature []byte    `json:"sig"`
+}
+
+func (p *Possession) signedBytes() []byte {
+	e := canonical.NewEncoder("nkeys.Possession")
+	e.Text(p.PublicKey)
+	e.Text(p.Audience)
+	e.Blob(p.Nonce)
+	e.Time(p.Timestamp)
+	return e.Bytes()
+}
+
+// MintPossession proves possession of kp to audience at the time of clock,
+// which may be nil for the system clock. The nonce is usually issued by the
+// audience; a random one is used if it is nil.
+func MintPossession(kp KeyPair, audience string, nonce []byte, clock Clock) (*Possession, error) {
+	pk, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if nonce == nil {
+		nonce = make([]byte, possessionNonceLen)
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return nil, err
+		}
+	}
+	p := &Possession{
+		PublicKey: pk,
+		Audience:  audience,
+		Nonce:     append([]byte{}, nonce...),
+		Timestamp: ClockOrSystem(clock).Now().UTC().Truncate(time.Second),
+	}
+	if p.Signature, err = kp.Sign(p.signedBytes()); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// VerifyPossession checks that p was minted for audience no more than maxAge
+// ago and applies the policy to its key. If nonce is not nil it must match
+// the one in p; the caller is responsible for only accepting each nonce once.
+func VerifyPossession(p *Possession, audience string, nonce []byte, maxAge time.Duration, vp *VerifyPolicy) error {
+	if p.Audience != audience {
+		return ErrInvalidPossession
+	}
+	if nonce != nil && subtle.ConstantTimeCompare(nonce, p.Nonce) != 1 {
+		return ErrInvalidPossession
+	}
+	if err := VerifyWithPolicy(vp, p.PublicKey, p.signedBytes(), p.Signature); err != nil {
+		return err
+	}
+	return vp.CheckValidity(p.Timestamp, p.Timestamp.Add(maxAge))
+}
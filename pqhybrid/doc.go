@@ -0,0 +1,21 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pqhybrid is an EXPERIMENTAL post-quantum hybrid key class. A
+// hybrid key pairs an ed25519 nkey with an ML-DSA-65 (Dilithium) key
+// derived from the same seed, and a hybrid signature is only valid if both
+// component signatures verify. The encodings may change without notice.
+//
+// The package relies on crypto/mldsa and is only available when built with
+// Go 1.27 or later.
+package pqhybrid
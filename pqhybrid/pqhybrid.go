@@ -0,0 +1,225 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.27
+
+package pqhybrid
+
+import (
+	"crypto/ed25519"
+	"crypto/mldsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"strings"
+
+	"github.com/nats-io/nkeys"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Errors
+const (
+	ErrInvalidPublicKey = hybridError("pqhybrid: invalid hybrid public key")
+	ErrInvalidSeed      = hybridError("pqhybrid: hybrid keys require an ed25519 seed")
+)
+
+type hybridError string
+
+func (e hybridError) Error() string {
+	return string(e)
+}
+
+const (
+	// context domain separates hybrid signatures from plain nkey signatures
+	// so that the ed25519 half can not be stripped off and used alone.
+	context = "nkeys-pqhybrid-v1"
+	// separator joins the ed25519 nkey and the ML-DSA public key.
+	separator = "."
+)
+
+// SignatureSize is the size of a hybrid signature: the ed25519 signature
+// followed by the ML-DSA-65 signature.
+const SignatureSize = ed25519.SignatureSize + mldsa.MLDSA65SignatureSize
+
+// KeyPair is a hybrid key pair.
+type KeyPair struct {
+	kp     nkeys.KeyPair
+	pq     *mldsa.PrivateKey
+	public string
+}
+
+// CreatePair creates a hybrid key pair of the given type.
+func CreatePair(prefix nkeys.PrefixByte) (*KeyPair, error) {
+	if nkeys.AlgorithmOf(prefix) != nkeys.AlgorithmEd25519 {
+		return nil, ErrInvalidSeed
+	}
+	kp, err := nkeys.CreatePair(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return fromKeyPair(kp)
+}
+
+// FromSeed creates a hybrid key pair from an ed25519 nkey seed. The ML-DSA
+// key is derived from the seed, so the seed alone restores both halves.
+func FromSeed(seed []byte) (*KeyPair, error) {
+	kp, err := nkeys.FromSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	return fromKeyPair(kp)
+}
+
+func fromKeyPair(kp nkeys.KeyPair) (*KeyPair, error) {
+	seed, err := kp.Seed()
+	if err != nil {
+		return nil, err
+	}
+	prefix, raw, err := nkeys.DecodeSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	if nkeys.AlgorithmOf(prefix) != nkeys.AlgorithmEd25519 {
+		return nil, ErrInvalidSeed
+	}
+	pqSeed := make([]byte, mldsa.PrivateKeySize)
+	r := hkdf.New(sha256.New, raw, []byte(context), []byte("ML-DSA-65"))
+	if _, err := io.ReadFull(r, pqSeed); err != nil {
+		return nil, err
+	}
+	pq, err := mldsa.NewPrivateKey(mldsa.MLDSA65(), pqSeed)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	public := pk + separator + base64.RawURLEncoding.EncodeToString(pq.PublicKey().Bytes())
+	return &KeyPair{kp: kp, pq: pq, public: public}, nil
+}
+
+// Seed returns the ed25519 nkey seed both halves are derived from.
+func (h *KeyPair) Seed() ([]byte, error) {
+	return h.kp.Seed()
+}
+
+// PublicKey returns the hybrid public key: the ed25519 nkey public key and
+// the base64url encoded ML-DSA-65 public key joined by a period.
+func (h *KeyPair) PublicKey() (string, error) {
+	if h.pq == nil {
+		return "", nkeys.ErrInvalidSeed
+	}
+	return h.public, nil
+}
+
+// Sign returns a hybrid signature over input.
+func (h *KeyPair) Sign(input []byte) ([]byte, error) {
+	if h.pq == nil {
+		return nil, nkeys.ErrInvalidSeed
+	}
+	sig, err := h.kp.Sign(contextMessage(input))
+	if err != nil {
+		return nil, err
+	}
+	pqSig, err := h.pq.SignDeterministic(input, &mldsa.Options{Context: context})
+	if err != nil {
+		return nil, err
+	}
+	return append(sig, pqSig...), nil
+}
+
+// Verify checks a hybrid signature over input.
+func (h *KeyPair) Verify(input []byte, sig []byte) error {
+	pk, err := h.PublicKey()
+	if err != nil {
+		return err
+	}
+	return Verify(pk, input, sig)
+}
+
+// Wipe wipes the ed25519 seed and drops the ML-DSA key. The ML-DSA key
+// material is owned by crypto/mldsa and can not be overwritten.
+func (h *KeyPair) Wipe() {
+	h.kp.Wipe()
+	h.pq = nil
+	h.public = ""
+}
+
+// PublicKey is a parsed hybrid public key.
+type PublicKey struct {
+	ed nkeys.KeyPair
+	pq *mldsa.PublicKey
+}
+
+// ParsePublicKey parses a hybrid public key as returned by KeyPair.PublicKey.
+func ParsePublicKey(public string) (*PublicKey, error) {
+	edPart, pqPart, ok := strings.Cut(public, separator)
+	if !ok || nkeys.AlgorithmOf(nkeys.Prefix(edPart)) != nkeys.AlgorithmEd25519 {
+		return nil, ErrInvalidPublicKey
+	}
+	ed, err := nkeys.FromPublicKey(edPart)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(pqPart)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	pq, err := mldsa.NewPublicKey(mldsa.MLDSA65(), raw)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	return &PublicKey{ed: ed, pq: pq}, nil
+}
+
+// Algorithm returns nkeys.AlgorithmHybridMLDSA65.
+func (p *PublicKey) Algorithm() nkeys.AlgorithmID {
+	return nkeys.AlgorithmHybridMLDSA65
+}
+
+// Ed25519 returns the classic nkey public key of the hybrid key.
+func (p *PublicKey) Ed25519() string {
+	pk, _ := p.ed.PublicKey()
+	return pk
+}
+
+// Verify checks a hybrid signature over input. Both component signatures
+// must be valid.
+func (p *PublicKey) Verify(input []byte, sig []byte) error {
+	if len(sig) != SignatureSize {
+		return nkeys.ErrInvalidSignature
+	}
+	edErr := p.ed.Verify(contextMessage(input), sig[:ed25519.SignatureSize])
+	pqErr := mldsa.Verify(p.pq, input, sig[ed25519.SignatureSize:], &mldsa.Options{Context: context})
+	if edErr != nil || pqErr != nil {
+		return nkeys.ErrInvalidSignature
+	}
+	return nil
+}
+
+// Verify checks a hybrid signature over input by the hybrid public key.
+func Verify(public string, input []byte, sig []byte) error {
+	p, err := ParsePublicKey(public)
+	if err != nil {
+		return err
+	}
+	return p.Verify(input, sig)
+}
+
+func contextMessage(input []byte) []byte {
+	m := make([]byte, 0, len(context)+1+len(input))
+	m = append(m, context...)
+	m = append(m, 0)
+	return append(m, input...)
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.27
+
+package pqhybrid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nats-io/nkeys"
+)
+
+func TestHybridSignVerify(t *testing.T) {
+	kp, err := CreatePair(nkeys.PrefixByteUser)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	pk, _ := kp.PublicKey()
+	data := []byte("hello")
+	sig, err := kp.Sign(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(sig) != SignatureSize {
+		t.Fatalf("Expected %d byte signature, got %d", SignatureSize, len(sig))
+	}
+	if err := Verify(pk, data, sig); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := Verify(pk, []byte("other"), sig); err != nkeys.ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", nkeys.ErrInvalidSignature, err)
+	}
+
+	// Either half being invalid fails verification.
+	for _, i := range []int{0, SignatureSize - 1} {
+		bad := append([]byte{}, sig...)
+		bad[i] ^= 1
+		if err := Verify(pk, data, bad); err != nkeys.ErrInvalidSignature {
+			t.Fatalf("Expected %v, got %v", nkeys.ErrInvalidSignature, err)
+		}
+	}
+
+	// The ed25519 half is not a valid plain nkey signature.
+	p, _ := ParsePublicKey(pk)
+	ed, _ := nkeys.FromPublicKey(p.Ed25519())
+	if err := ed.Verify(data, sig[:64]); err == nil {
+		t.Fatalf("Expected the ed25519 half to be domain separated")
+	}
+}
+
+func TestHybridFromSeed(t *testing.T) {
+	kp, _ := CreatePair(nkeys.PrefixByteAccount)
+	seed, _ := kp.Seed()
+	restored, err := FromSeed(seed)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	pk, _ := kp.PublicKey()
+	rpk, _ := restored.PublicKey()
+	if pk != rpk {
+		t.Fatalf("Expected the seed to restore the same hybrid key")
+	}
+	if !strings.HasPrefix(pk, "A") {
+		t.Fatalf("Expected an account key, got %q", pk)
+	}
+	curve, _ := nkeys.CreateCurveKeys()
+	cseed, _ := curve.Seed()
+	if _, err := FromSeed(cseed); err != ErrInvalidSeed {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSeed, err)
+	}
+	if _, err := ParsePublicKey(strings.SplitN(pk, ".", 2)[0]); err != ErrInvalidPublicKey {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPublicKey, err)
+	}
+}
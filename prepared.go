@@ -0,0 +1,80 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "crypto/ed25519"
+
+// VerifyPreparer is an extension point for Backends that can precompute
+// per key state, such as the decompressed public key point and its
+// multiples, to speed up repeated verifications with the same key. The
+// standard library does not expose such tables, so the default Backend does
+// not implement it and no precomputation happens unless a Backend that does
+// is installed with SetBackend.
+type VerifyPreparer interface {
+	// PrepareVerify returns a function that behaves like Verify for the
+	// 32 byte public key.
+	PrepareVerify(public []byte) (func(message []byte, sig []byte) bool, error)
+}
+
+// PreparedKey verifies signatures of one public key with the key decoded
+// and checked once up front. With the default Backend that is all it
+// saves; each Verify still does the full ed25519 verification. Backends
+// implementing VerifyPreparer can additionally precompute tables. It is
+// safe for concurrent use.
+type PreparedKey struct {
+	public string
+	verify func(message []byte, sig []byte) bool
+}
+
+// PrepareVerify decodes the public key of a signing key type and prepares
+// it for repeated verification.
+func PrepareVerify(public string) (*PreparedKey, error) {
+	raw, err := decode([]byte(public))
+	if err != nil {
+		return nil, err
+	}
+	pre := PrefixByte(raw[0])
+	if checkValidPublicPrefixByte(pre) != nil || AlgorithmOf(pre) != AlgorithmEd25519 ||
+		len(raw) != 1+ed25519.PublicKeySize {
+		return nil, ErrInvalidPublicKey
+	}
+	verify, err := prepareVerify(currentBackend(), raw[1:])
+	if err != nil {
+		return nil, err
+	}
+	return &PreparedKey{public: public, verify: verify}, nil
+}
+
+func prepareVerify(b Backend, raw []byte) (func(message []byte, sig []byte) bool, error) {
+	if p, ok := b.(VerifyPreparer); ok {
+		return p.PrepareVerify(raw)
+	}
+	key := append(ed25519.PublicKey{}, raw...)
+	return func(message []byte, sig []byte) bool {
+		return b.Verify(key, message, sig)
+	}, nil
+}
+
+// PublicKey returns the encoded public key.
+func (p *PreparedKey) PublicKey() string {
+	return p.public
+}
+
+// Verify will verify the input against a signature utilizing the public key.
+func (p *PreparedKey) Verify(input []byte, sig []byte) error {
+	if !p.verify(input, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
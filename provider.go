@@ -0,0 +1,125 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SeedProvider fetches seeds by name from wherever an application keeps
+// them, such as the environment, files or an external secret manager.
+// Fetch returns ErrKeyNotFound for unknown names. The caller wipes the
+// returned bytes.
+type SeedProvider interface {
+	Fetch(ctx context.Context, name string) ([]byte, error)
+}
+
+// FromProvider fetches the seed name from p and creates its KeyPair. The
+// seed must be of type expected, unless expected is PrefixByteUnknown.
+// Surrounding whitespace, as left by files and commands, is ignored.
+func FromProvider(ctx context.Context, p SeedProvider, name string, expected PrefixByte) (KeyPair, error) {
+	data, err := p.Fetch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(data)
+	seed := bytes.TrimSpace(data)
+	if err := checkSeedArg(seed); err != nil {
+		return nil, err
+	}
+	prefix, raw, err := DecodeSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	wipeBytes(raw)
+	if expected != PrefixByteUnknown && prefix != expected {
+		return nil, ErrIncompatibleKey
+	}
+	return FromSeed(seed)
+}
+
+// EnvProvider fetches seeds from environment variables named Prefix
+// followed by the name, e.g. NKEYS_ for NKEYS_ACCOUNT.
+type EnvProvider struct {
+	Prefix string
+	// Unset removes the variable once read, so that child processes do
+	// not inherit the seed.
+	Unset bool
+}
+
+// Fetch returns the value of the variable.
+func (e EnvProvider) Fetch(ctx context.Context, name string) ([]byte, error) {
+	key := e.Prefix + name
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil, ErrKeyNotFound
+	}
+	if e.Unset {
+		os.Unsetenv(key)
+	}
+	return []byte(v), nil
+}
+
+// FileProvider fetches seeds from the files named after them in Dir. Files
+// accessible by group or others are refused as by CheckSeedFile.
+type FileProvider struct {
+	Dir string
+}
+
+// Fetch returns the contents of the file.
+func (f FileProvider) Fetch(ctx context.Context, name string) ([]byte, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return nil, ErrKeyNotFound
+	}
+	path := filepath.Join(f.Dir, name)
+	if err := CheckSeedFile(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrKeyNotFound
+		}
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// ExecProvider fetches seeds from the standard output of a command, run
+// with Args followed by the name, for secret managers that ship a command
+// line client. The command is killed when the context is done.
+type ExecProvider struct {
+	Command string
+	Args    []string
+}
+
+// Fetch runs the command and returns its output.
+func (e ExecProvider) Fetch(ctx context.Context, name string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.Command, append(append([]string{}, e.Args...), name)...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		wipeBytes(stdout.Bytes())
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("nkeys: %s: %w: %s", e.Command, err, msg)
+		}
+		return nil, fmt.Errorf("nkeys: %s: %w", e.Command, err)
+	}
+	if stdout.Len() == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return stdout.Bytes(), nil
+}
@@ -14,26 +14,36 @@
 package nkeys
 
 import (
+	"crypto/ed25519"
 	"crypto/rand"
 	"io"
-
-	"golang.org/x/crypto/ed25519"
+	"sync"
 )
 
 // A KeyPair from a public key capable of verifying only.
 type pub struct {
 	pre PrefixByte
 	pub ed25519.PublicKey
+
+	// mu guards the lazily computed encoding.
+	mu      sync.Mutex
+	encoded string
 }
 
 // PublicKey will return the encoded public key associated with the KeyPair.
-// All KeyPairs have a public key.
+// All KeyPairs have a public key. The result is computed once and cached.
 func (p *pub) PublicKey() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.encoded != "" {
+		return p.encoded, nil
+	}
 	pk, err := Encode(p.pre, p.pub)
 	if err != nil {
 		return "", err
 	}
-	return string(pk), nil
+	p.encoded = string(pk)
+	return p.encoded, nil
 }
 
 // Seed will return an error since this is not available for public key only KeyPairs.
@@ -53,16 +63,24 @@ func (p *pub) Sign(input []byte) ([]byte, error) {
 
 // Verify will verify the input against a signature utilizing the public key.
 func (p *pub) Verify(input []byte, sig []byte) error {
-	if !ed25519.Verify(p.pub, input, sig) {
-		return ErrInvalidSignature
+	if !currentBackend().Verify(p.pub, input, sig) {
+		return countVerify(ErrInvalidSignature)
 	}
-	return nil
+	return countVerify(nil)
+}
+
+// PublicOnly returns a copy of the KeyPair.
+func (p *pub) PublicOnly() (KeyPair, error) {
+	return &pub{pre: p.pre, pub: append(ed25519.PublicKey{}, p.pub...)}, nil
 }
 
 // Wipe will randomize the public key and erase the pre byte.
 func (p *pub) Wipe() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.pre = '0'
 	io.ReadFull(rand.Reader, p.pub)
+	p.encoded = ""
 }
 
 func (p *pub) Seal(input []byte, recipient string) ([]byte, error) {
@@ -0,0 +1,396 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quorum lets a group of participants, typically in different
+// regions, jointly sign with an operator seed that none of them holds.
+//
+// The seed is split with Shamir sharing, one share per member of a Group.
+// For a signature the Coordinator sends a Request to every member over a
+// pluggable Transport. Members that approve seal their share to an
+// ephemeral key of the session and sign their approval. Once Threshold
+// approvals arrived the coordinator rebuilds the seed in memory, signs,
+// wipes it and returns a Transcript that anyone knowing the Group can audit
+// with VerifyTranscript.
+//
+// Ed25519 has no practical threshold variant without additional
+// dependencies, so the coordinator briefly holds the seed during a session.
+// It should run in a hardened environment and the sessions should be rare.
+package quorum
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/nats-io/nkeys"
+	"github.com/nats-io/nkeys/internal/canonical"
+	"github.com/nats-io/nkeys/internal/shamir"
+)
+
+// Errors
+const (
+	ErrInvalidGroup      = quorumError("quorum: invalid group")
+	ErrNotMember         = quorumError("quorum: not a member of the group")
+	ErrInvalidRequest    = quorumError("quorum: invalid signing request")
+	ErrInvalidApproval   = quorumError("quorum: invalid approval")
+	ErrNotApproved       = quorumError("quorum: request not approved")
+	ErrNoQuorum          = quorumError("quorum: not enough approvals")
+	ErrSeedMismatch      = quorumError("quorum: shares do not rebuild the operator seed")
+	ErrInvalidTranscript = quorumError("quorum: invalid transcript")
+)
+
+type quorumError string
+
+func (e quorumError) Error() string {
+	return string(e)
+}
+
+// Group describes who holds the shares of an operator seed. Member i holds
+// share i+1. A Group holds no secrets and is distributed to every party.
+type Group struct {
+	ID          string   `json:"id"`
+	OperatorKey string   `json:"operator"`
+	Threshold   int      `json:"threshold"`
+	Members     []string `json:"members"`
+}
+
+// Split splits seed between members, the public keys of the participant
+// identities, so that threshold of them can sign. The returned shares are
+// in the order of members and must each be handed to their member only.
+func Split(seed []byte, members []string, threshold int) (*Group, [][]byte, error) {
+	kp, err := nkeys.FromSeed(seed)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer kp.Wipe()
+	operator, err := kp.PublicKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	seen := make(map[string]bool)
+	for _, m := range members {
+		if !nkeys.IsValidPublicKey(m) || nkeys.Prefix(m) == nkeys.PrefixByteCurve || seen[m] {
+			return nil, nil, ErrInvalidGroup
+		}
+		seen[m] = true
+	}
+	shares, err := shamir.Split(seed, len(members), threshold, rand.Reader)
+	if err != nil {
+		return nil, nil, ErrInvalidGroup
+	}
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return nil, nil, err
+	}
+	g := &Group{
+		ID:          base64.RawURLEncoding.EncodeToString(id),
+		OperatorKey: operator,
+		Threshold:   threshold,
+		Members:     append([]string{}, members...),
+	}
+	return g, shares, nil
+}
+
+func (g *Group) index(member string) int {
+	for i, m := range g.Members {
+		if m == member {
+			return i
+		}
+	}
+	return -1
+}
+
+// Request asks the members of a group to approve signing Payload.
+type Request struct {
+	Session  string    `json:"session"`
+	Group    string    `json:"group"`
+	Operator string    `json:"operator"`
+	Payload  []byte    `json:"payload"`
+	Reason   string    `json:"reason"`
+	Created  time.Time `json:"created"`
+	// Coordinator is the ephemeral curve key that shares are sealed to.
+	Coordinator string `json:"coordinator"`
+}
+
+func (r *Request) digest() []byte {
+	e := canonical.NewEncoder("nkeys.QuorumRequest")
+	e.Text(r.Session)
+	e.Text(r.Group)
+	e.Text(r.Operator)
+	e.Blob(r.Payload)
+	e.Text(r.Reason)
+	e.Time(r.Created)
+	e.Text(r.Coordinator)
+	sum := sha256.Sum256(e.Bytes())
+	return sum[:]
+}
+
+// Approval is the answer of a member to a Request.
+type Approval struct {
+	Member string `json:"member"`
+	// Sender is the ephemeral curve key the share was sealed with.
+	Sender string `json:"sender"`
+	Sealed []byte `json:"sealed"`
+	// Signature by Member over the request and the sealed share.
+	Signature []byte `json:"sig"`
+}
+
+func (a *Approval) signedBytes(r *Request) []byte {
+	e := canonical.NewEncoder("nkeys.QuorumApproval")
+	e.Blob(r.digest())
+	e.Text(a.Member)
+	e.Text(a.Sender)
+	e.Blob(a.Sealed)
+	return e.Bytes()
+}
+
+func (a *Approval) verify(g *Group, r *Request) error {
+	if g.index(a.Member) < 0 {
+		return ErrNotMember
+	}
+	if err := nkeys.VerifyWithPolicy(nil, a.Member, a.signedBytes(r), a.Signature); err != nil {
+		return ErrInvalidApproval
+	}
+	return nil
+}
+
+// Transport delivers a Request to a member and returns its answer, usually
+// by calling Participant.Handle on the other side. A member that refuses
+// returns an error.
+type Transport interface {
+	Send(ctx context.Context, member string, r *Request) (*Approval, error)
+}
+
+// LocalTransport delivers requests to in-process participants by member
+// public key.
+type LocalTransport map[string]*Participant
+
+// Send calls Handle on the participant of member.
+func (t LocalTransport) Send(ctx context.Context, member string, r *Request) (*Approval, error) {
+	p, ok := t[member]
+	if !ok {
+		return nil, ErrNotMember
+	}
+	return p.Handle(r)
+}
+
+// Participant is a member holding a share.
+type Participant struct {
+	Identity nkeys.KeyPair
+	Group    *Group
+	Share    []byte
+	// Approve decides whether to take part in a request, for example by
+	// asking a human. A nil Approve refuses every request.
+	Approve func(*Request) error
+}
+
+// Handle answers a Request by sealing the share to the coordinator and
+// signing the approval.
+func (p *Participant) Handle(r *Request) (*Approval, error) {
+	member, err := p.Identity.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if r.Group != p.Group.ID || r.Operator != p.Group.OperatorKey || !nkeys.IsValidPublicCurveKey(r.Coordinator) {
+		return nil, ErrInvalidRequest
+	}
+	if p.Group.index(member) < 0 {
+		return nil, ErrNotMember
+	}
+	if p.Approve == nil {
+		return nil, ErrNotApproved
+	}
+	if err := p.Approve(r); err != nil {
+		return nil, err
+	}
+	ephemeral, err := nkeys.CreateCurveKeys()
+	if err != nil {
+		return nil, err
+	}
+	defer ephemeral.Wipe()
+	sender, err := ephemeral.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	// Bind the share to the session so it can not be replayed into another.
+	sealed, err := ephemeral.Seal(append(r.digest(), p.Share...), r.Coordinator)
+	if err != nil {
+		return nil, err
+	}
+	a := &Approval{Member: member, Sender: sender, Sealed: sealed}
+	if a.Signature, err = p.Identity.Sign(a.signedBytes(r)); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Coordinator runs signing sessions for a Group.
+type Coordinator struct {
+	Group     *Group
+	Transport Transport
+	// Clock defaults to nkeys.SystemClock.
+	Clock nkeys.Clock
+}
+
+// Transcript records a signing session for audit.
+type Transcript struct {
+	Request   *Request    `json:"request"`
+	Approvals []*Approval `json:"approvals"`
+	// Refusals maps members whose answer arrived before the quorum was
+	// reached and was not accepted to the error.
+	Refusals  map[string]string `json:"refusals,omitempty"`
+	Signature []byte            `json:"sig,omitempty"`
+}
+
+type answer struct {
+	member   string
+	approval *Approval
+	err      error
+}
+
+// Sign asks every member to approve signing payload for reason and signs
+// with the operator seed once Threshold members approved. The transcript is
+// returned with the error when no quorum was reached.
+func (c *Coordinator) Sign(ctx context.Context, payload []byte, reason string) (*Transcript, error) {
+	g := c.Group
+	if g == nil || g.Threshold < 2 || g.Threshold > len(g.Members) {
+		return nil, ErrInvalidGroup
+	}
+	ephemeral, err := nkeys.CreateCurveKeys()
+	if err != nil {
+		return nil, err
+	}
+	defer ephemeral.Wipe()
+	coordinator, err := ephemeral.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	session := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, session); err != nil {
+		return nil, err
+	}
+	r := &Request{
+		Session:     base64.RawURLEncoding.EncodeToString(session),
+		Group:       g.ID,
+		Operator:    g.OperatorKey,
+		Payload:     append([]byte{}, payload...),
+		Reason:      reason,
+		Created:     nkeys.ClockOrSystem(c.Clock).Now().UTC().Truncate(time.Second),
+		Coordinator: coordinator,
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	answers := make(chan answer, len(g.Members))
+	for _, m := range g.Members {
+		go func(m string) {
+			a, err := c.Transport.Send(ctx, m, r)
+			answers <- answer{m, a, err}
+		}(m)
+	}
+
+	t := &Transcript{Request: r, Refusals: make(map[string]string)}
+	var xs []byte
+	var ys [][]byte
+	defer func() {
+		for _, y := range ys {
+			shamir.Wipe(y)
+		}
+	}()
+	for i := 0; i < len(g.Members) && len(xs) < g.Threshold; i++ {
+		a := <-answers
+		var share []byte
+		if a.err == nil {
+			share, a.err = c.accept(r, a.member, a.approval, ephemeral)
+		}
+		if a.err == nil && len(ys) > 0 && len(share) != len(ys[0]) {
+			a.err = ErrInvalidApproval
+		}
+		if a.err != nil {
+			t.Refusals[a.member] = a.err.Error()
+			continue
+		}
+		xs = append(xs, byte(g.index(a.member)+1))
+		ys = append(ys, share)
+		t.Approvals = append(t.Approvals, a.approval)
+	}
+	sort.Slice(t.Approvals, func(i, j int) bool { return t.Approvals[i].Member < t.Approvals[j].Member })
+	if len(xs) < g.Threshold {
+		return t, ErrNoQuorum
+	}
+
+	seed := shamir.Combine(xs, ys)
+	defer shamir.Wipe(seed)
+	kp, err := nkeys.FromSeed(seed)
+	if err != nil {
+		return t, ErrSeedMismatch
+	}
+	defer kp.Wipe()
+	if public, err := kp.PublicKey(); err != nil || public != g.OperatorKey {
+		return t, ErrSeedMismatch
+	}
+	if t.Signature, err = kp.Sign(payload); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// accept checks an approval and returns its share if it was sealed for this
+// session.
+func (c *Coordinator) accept(r *Request, member string, a *Approval, ephemeral nkeys.KeyPair) ([]byte, error) {
+	if a == nil || a.Member != member {
+		return nil, ErrInvalidApproval
+	}
+	if err := a.verify(c.Group, r); err != nil {
+		return nil, err
+	}
+	opened, err := ephemeral.Open(a.Sealed, a.Sender)
+	if err != nil {
+		return nil, ErrInvalidApproval
+	}
+	digest := r.digest()
+	if len(opened) <= len(digest) || !bytes.Equal(opened[:len(digest)], digest) {
+		shamir.Wipe(opened)
+		return nil, ErrInvalidApproval
+	}
+	return opened[len(digest):], nil
+}
+
+// VerifyTranscript checks that t holds Threshold valid approvals by
+// distinct members of g and an operator signature over the payload. vp is
+// applied to the operator key and may be nil.
+func VerifyTranscript(t *Transcript, g *Group, vp *nkeys.VerifyPolicy) error {
+	r := t.Request
+	if r == nil || r.Group != g.ID || r.Operator != g.OperatorKey {
+		return ErrInvalidTranscript
+	}
+	seen := make(map[string]bool)
+	for _, a := range t.Approvals {
+		if seen[a.Member] {
+			return ErrInvalidTranscript
+		}
+		if err := a.verify(g, r); err != nil {
+			return err
+		}
+		seen[a.Member] = true
+	}
+	if len(seen) < g.Threshold {
+		return ErrNoQuorum
+	}
+	return nkeys.VerifyWithPolicy(vp, g.OperatorKey, r.Payload, t.Signature)
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quorum
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nkeys"
+)
+
+func newGroup(t *testing.T, n, threshold int) (nkeys.KeyPair, *Group, LocalTransport) {
+	t.Helper()
+	operator, _ := nkeys.CreateOperator()
+	seed, _ := operator.Seed()
+	var ids []nkeys.KeyPair
+	var members []string
+	for i := 0; i < n; i++ {
+		id, _ := nkeys.CreateUser()
+		pk, _ := id.PublicKey()
+		ids = append(ids, id)
+		members = append(members, pk)
+	}
+	g, shares, err := Split(seed, members, threshold)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lt := make(LocalTransport)
+	for i, id := range ids {
+		lt[members[i]] = &Participant{
+			Identity: id,
+			Group:    g,
+			Share:    shares[i],
+			Approve:  func(*Request) error { return nil },
+		}
+	}
+	return operator, g, lt
+}
+
+func TestQuorumSign(t *testing.T) {
+	operator, g, lt := newGroup(t, 5, 3)
+	// Two regions are unavailable or refuse.
+	lt[g.Members[0]].Approve = nil
+	lt[g.Members[3]].Approve = func(*Request) error { return errors.New("not today") }
+
+	c := &Coordinator{Group: g, Transport: lt}
+	tr, err := c.Sign(context.Background(), []byte("operator jwt"), "rotate signing key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := operator.Verify([]byte("operator jwt"), tr.Signature); err != nil {
+		t.Fatalf("Expected an operator signature, got %v", err)
+	}
+	if len(tr.Approvals) != 3 {
+		t.Fatalf("Expected 3 approvals, got %d", len(tr.Approvals))
+	}
+	if err := VerifyTranscript(tr, g, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tr.Approvals = tr.Approvals[1:]
+	if err := VerifyTranscript(tr, g, nil); err != ErrNoQuorum {
+		t.Fatalf("Expected %v, got %v", ErrNoQuorum, err)
+	}
+}
+
+func TestQuorumNotReached(t *testing.T) {
+	_, g, lt := newGroup(t, 3, 2)
+	lt[g.Members[0]].Approve = nil
+	lt[g.Members[1]].Approve = nil
+	c := &Coordinator{Group: g, Transport: lt}
+	tr, err := c.Sign(context.Background(), []byte("payload"), "")
+	if err != ErrNoQuorum {
+		t.Fatalf("Expected %v, got %v", ErrNoQuorum, err)
+	}
+	if tr.Signature != nil || len(tr.Refusals) != 2 {
+		t.Fatalf("Expected no signature and 2 refusals, got %+v", tr)
+	}
+}
+
+func TestQuorumReplayedApproval(t *testing.T) {
+	_, g, lt := newGroup(t, 3, 2)
+	p := lt[g.Members[0]]
+	old := &Request{Group: g.ID, Operator: g.OperatorKey, Coordinator: newCurveKey()}
+	replayed, err := p.Handle(old)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// A transport replaying an approval from an earlier session is refused.
+	c := &Coordinator{Group: g, Transport: replayTransport{lt, g.Members[0], replayed}}
+	tr, err := c.Sign(context.Background(), []byte("payload"), "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := tr.Refusals[g.Members[0]]; !ok {
+		t.Fatalf("Expected the replayed approval to be refused, got %v", tr.Refusals)
+	}
+
+	outsider, _ := nkeys.CreateUser()
+	p.Identity = outsider
+	if _, err := p.Handle(old); err != ErrNotMember {
+		t.Fatalf("Expected %v, got %v", ErrNotMember, err)
+	}
+}
+
+type replayTransport struct {
+	LocalTransport
+	member   string
+	approval *Approval
+}
+
+func (r replayTransport) Send(ctx context.Context, member string, req *Request) (*Approval, error) {
+	if member == r.member {
+		return r.approval, nil
+	}
+	return r.LocalTransport.Send(ctx, member, req)
+}
+
+func newCurveKey() string {
+	kp, _ := nkeys.CreateCurveKeys()
+	pk, _ := kp.PublicKey()
+	return pk
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// FromHexSeed creates a KeyPair of the given type from a hex encoded raw
+// seed, as stored by hand-rolled ed25519 systems. The 64 byte expanded
+// ed25519 private key, seed followed by public key, is accepted as well.
+func FromHexSeed(prefix PrefixByte, s string) (KeyPair, error) {
+	raw, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, ErrInvalidEncoding
+	}
+	defer wipeBytes(raw)
+	return fromRawSeedOrPrivateKey(prefix, raw)
+}
+
+// FromBase64Seed is like FromHexSeed for base64. Standard and URL
+// alphabets, with or without padding, are accepted.
+func FromBase64Seed(prefix PrefixByte, s string) (KeyPair, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	enc := base64.RawStdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.RawURLEncoding
+	}
+	raw, err := enc.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidEncoding
+	}
+	defer wipeBytes(raw)
+	return fromRawSeedOrPrivateKey(prefix, raw)
+}
+
+// fromRawSeedOrPrivateKey accepts what EncodeSeed accepts: a raw seed or,
+// for ed25519 types, a 64 byte private key.
+func fromRawSeedOrPrivateKey(prefix PrefixByte, raw []byte) (KeyPair, error) {
+	seed, err := EncodeSeed(prefix, raw)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(seed)
+	return FromSeed(seed)
+}
+
+// ExportHexSeed returns the raw seed of kp hex encoded, for systems that do
+// not understand nkeys. The result must be handled like the seed itself.
+func ExportHexSeed(kp KeyPair) (string, error) {
+	raw, err := exportRawSeed(kp)
+	if err != nil {
+		return "", err
+	}
+	defer wipeBytes(raw)
+	return hex.EncodeToString(raw), nil
+}
+
+// ExportBase64Seed is like ExportHexSeed with padded standard base64.
+func ExportBase64Seed(kp KeyPair) (string, error) {
+	raw, err := exportRawSeed(kp)
+	if err != nil {
+		return "", err
+	}
+	defer wipeBytes(raw)
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+func exportRawSeed(kp KeyPair) ([]byte, error) {
+	seed, err := kp.Seed()
+	if err != nil {
+		return nil, err
+	}
+	_, raw, err := DecodeSeed(seed)
+	return raw, err
+}
@@ -0,0 +1,327 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package reconcile manages an inventory of keys declaratively. Callers
+// describe the keys they want in a Spec; Plan compares it with the keys in
+// a seedstore.Store and lists the keys to create, rotate and retire, and
+// Apply carries the plan out. It is meant as the core of controllers, such
+// as a Kubernetes operator, that own all keys of their store.
+package reconcile
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/nats-io/nkeys"
+	"github.com/nats-io/nkeys/seedstore"
+)
+
+// Errors
+const (
+	ErrInvalidSpec = reconcileError("reconcile: invalid spec")
+	ErrStalePlan   = reconcileError("reconcile: store changed since the plan was made")
+)
+
+type reconcileError string
+
+func (e reconcileError) Error() string {
+	return string(e)
+}
+
+// RollbackError is returned when an action failed and undoing its change
+// to the store failed as well, leaving the store and the rotation state
+// out of step for the key Name. Err is the error of the action.
+type RollbackError struct {
+	Name        string
+	Err         error
+	RollbackErr error
+}
+
+func (e *RollbackError) Error() string {
+	return fmt.Sprintf("reconcile: %s: %v, and rolling back failed: %v", e.Name, e.Err, e.RollbackErr)
+}
+
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// KeySpec describes a group of keys of one type. Its keys are stored as
+// "<Name>-0" to "<Name>-<Count-1>".
+type KeySpec struct {
+	Name  string
+	Type  nkeys.PrefixByte
+	Count int
+	// Labels are carried on the actions for the caller, e.g. to label the
+	// secrets holding the keys. They are not stored.
+	Labels map[string]string
+	// Rotation limits the age and uses of the keys. Zero values disable
+	// rotation; Grace is that of the RotationManager. Curve keys can not
+	// sign rotation statements and can not be rotated.
+	Rotation nkeys.RotationPolicy
+}
+
+// Spec is the desired inventory. Keys in the store that it does not
+// describe are retired.
+type Spec struct {
+	Keys []KeySpec
+}
+
+// ActionType is what an Action does.
+type ActionType string
+
+const (
+	ActionCreate ActionType = "create"
+	ActionRotate ActionType = "rotate"
+	ActionRetire ActionType = "retire"
+)
+
+// Action is a single step of a Plan.
+type Action struct {
+	Type ActionType
+	// Name is the name of the key in the store.
+	Name    string
+	KeyType nkeys.PrefixByte
+	// Public is the current key for rotations and retirements.
+	Public string
+	Labels map[string]string
+}
+
+// Plan is the list of actions that make the store match a spec.
+// Retirements come first, so that a key of the wrong type can be replaced
+// under the same name, then rotations and creations, each ordered by name.
+type Plan struct {
+	Actions []Action
+}
+
+// Empty reports whether the store already matches the spec.
+func (p *Plan) Empty() bool {
+	return len(p.Actions) == 0
+}
+
+// Result is what Apply did.
+type Result struct {
+	// Created maps the names of new keys to their public keys.
+	Created map[string]string
+	// Rotations announce the rotated keys.
+	Rotations []*nkeys.RotationStatement
+	// Retired are the names of the removed keys.
+	Retired []string
+}
+
+// Reconciler reconciles the keys of Store with a spec. Rotation tracks the
+// age of the keys and must be dedicated to the reconciler as well. Keys put
+// in the store by other means are only rotated once tracked by Rotation.
+type Reconciler struct {
+	Store    seedstore.Store
+	Rotation *nkeys.RotationManager
+}
+
+// Plan returns the actions needed to make the store match spec, without
+// changing anything.
+func (r *Reconciler) Plan(spec Spec) (*Plan, error) {
+	want, err := desired(spec)
+	if err != nil {
+		return nil, err
+	}
+	names, err := r.Store.List()
+	if err != nil {
+		return nil, err
+	}
+	var retire, rotate, create []Action
+	have := make(map[string]bool, len(names))
+	for _, name := range names {
+		public, err := r.publicKey(name)
+		if err != nil {
+			return nil, err
+		}
+		ks, ok := want[name]
+		if !ok || nkeys.Prefix(public) != ks.Type {
+			retire = append(retire, Action{Type: ActionRetire, Name: name, KeyType: nkeys.Prefix(public), Public: public})
+			continue
+		}
+		have[name] = true
+		if r.Rotation.IsDue(name, ks.Rotation) {
+			rotate = append(rotate, Action{ActionRotate, name, ks.Type, public, ks.Labels})
+		}
+	}
+	for name, ks := range want {
+		if !have[name] {
+			create = append(create, Action{Type: ActionCreate, Name: name, KeyType: ks.Type, Labels: ks.Labels})
+		}
+	}
+	sort.Slice(create, func(i, j int) bool { return create[i].Name < create[j].Name })
+	p := &Plan{}
+	p.Actions = append(append(append(p.Actions, retire...), rotate...), create...)
+	return p, nil
+}
+
+// desired returns the key specs by store name.
+func desired(spec Spec) (map[string]KeySpec, error) {
+	want := make(map[string]KeySpec)
+	groups := make(map[string]bool)
+	for _, ks := range spec.Keys {
+		if ks.Name == "" || ks.Count < 0 || groups[ks.Name] {
+			return nil, ErrInvalidSpec
+		}
+		groups[ks.Name] = true
+		if ks.Type == nkeys.PrefixBytePrivate || nkeys.AlgorithmOf(ks.Type) == nkeys.AlgorithmUnknown {
+			return nil, ErrInvalidSpec
+		}
+		rotates := ks.Rotation.MaxAge > 0 || ks.Rotation.MaxUses > 0
+		if rotates && nkeys.AlgorithmOf(ks.Type) != nkeys.AlgorithmEd25519 {
+			return nil, ErrInvalidSpec
+		}
+		for i := 0; i < ks.Count; i++ {
+			want[ks.Name+"-"+strconv.Itoa(i)] = ks
+		}
+	}
+	return want, nil
+}
+
+// publicKey returns the public key of the seed stored as name.
+func (r *Reconciler) publicKey(name string) (string, error) {
+	kp, err := seedstore.GetKeyPair(r.Store, name)
+	if err != nil {
+		return "", err
+	}
+	defer kp.Wipe()
+	return kp.PublicKey()
+}
+
+// Apply carries out plan. Each action first checks that the key it acts on
+// is still the one planned for, and fails with ErrStalePlan otherwise.
+// Apply stops at the first error; the result lists what was done before.
+// An action that fails is undone, and a *RollbackError is returned if that
+// fails too.
+func (r *Reconciler) Apply(plan *Plan) (*Result, error) {
+	res := &Result{Created: make(map[string]string)}
+	for _, a := range plan.Actions {
+		var err error
+		switch a.Type {
+		case ActionRetire:
+			err = r.retire(a)
+			if err == nil {
+				res.Retired = append(res.Retired, a.Name)
+			}
+		case ActionRotate:
+			var rs *nkeys.RotationStatement
+			if rs, err = r.rotate(a); err == nil {
+				res.Rotations = append(res.Rotations, rs)
+			}
+		case ActionCreate:
+			var public string
+			if public, err = r.create(a); err == nil {
+				res.Created[a.Name] = public
+			}
+		default:
+			err = ErrInvalidSpec
+		}
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// Reconcile plans the changes for spec and applies them if approve, which
+// may be nil, returns true for the plan.
+func (r *Reconciler) Reconcile(spec Spec, approve func(*Plan) bool) (*Plan, *Result, error) {
+	plan, err := r.Plan(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	if plan.Empty() || approve != nil && !approve(plan) {
+		return plan, &Result{}, nil
+	}
+	res, err := r.Apply(plan)
+	return plan, res, err
+}
+
+// checkCurrent returns ErrStalePlan unless the key stored as name is public.
+func (r *Reconciler) checkCurrent(name, public string) error {
+	current, err := r.publicKey(name)
+	if err == seedstore.ErrNotFound || err == nil && current != public {
+		return ErrStalePlan
+	}
+	return err
+}
+
+func (r *Reconciler) retire(a Action) error {
+	if err := r.checkCurrent(a.Name, a.Public); err != nil {
+		return err
+	}
+	if err := r.Store.Delete(a.Name); err != nil {
+		return err
+	}
+	if err := r.Rotation.Untrack(a.Name); err != nil && err != nkeys.ErrKeyNotFound {
+		return err
+	}
+	return nil
+}
+
+func (r *Reconciler) rotate(a Action) (*nkeys.RotationStatement, error) {
+	old, err := seedstore.GetKeyPair(r.Store, a.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer old.Wipe()
+	if public, err := old.PublicKey(); err != nil || public != a.Public {
+		return nil, ErrStalePlan
+	}
+	next, err := nkeys.CreatePair(a.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	defer next.Wipe()
+	if err := seedstore.PutKeyPair(r.Store, a.Name, next); err != nil {
+		return nil, err
+	}
+	rs, err := r.Rotation.Rotate(a.Name, old, next)
+	if err != nil {
+		// Keep the store and the rotation state in step.
+		if rerr := seedstore.PutKeyPair(r.Store, a.Name, old); rerr != nil {
+			return nil, &RollbackError{Name: a.Name, Err: err, RollbackErr: rerr}
+		}
+		return nil, err
+	}
+	return rs, nil
+}
+
+func (r *Reconciler) create(a Action) (string, error) {
+	if _, err := r.Store.Get(a.Name); err != seedstore.ErrNotFound {
+		if err == nil {
+			err = ErrStalePlan
+		}
+		return "", err
+	}
+	kp, err := nkeys.CreatePair(a.KeyType)
+	if err != nil {
+		return "", err
+	}
+	defer kp.Wipe()
+	public, err := kp.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	if err := seedstore.PutKeyPair(r.Store, a.Name, kp); err != nil {
+		return "", err
+	}
+	if err := r.Rotation.Track(a.Name, public); err != nil {
+		if rerr := r.Store.Delete(a.Name); rerr != nil {
+			return "", &RollbackError{Name: a.Name, Err: err, RollbackErr: rerr}
+		}
+		return "", err
+	}
+	return public, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nkeys"
+	"github.com/nats-io/nkeys/seedstore"
+)
+
+func actionsOf(p *Plan) []string {
+	var out []string
+	for _, a := range p.Actions {
+		out = append(out, string(a.Type)+" "+a.Name)
+	}
+	return out
+}
+
+func TestReconcile(t *testing.T) {
+	dir := t.TempDir()
+	store, err := seedstore.NewDiskStore(filepath.Join(dir, "seeds"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	clock := nkeys.ClockFunc(func() time.Time { return now })
+	rm, err := nkeys.NewRotationManager(filepath.Join(dir, "rotation.json"), nkeys.RotationPolicy{Grace: time.Hour}, clock)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	r := &Reconciler{Store: store, Rotation: rm}
+
+	spec := Spec{Keys: []KeySpec{
+		{Name: "server", Type: nkeys.PrefixByteServer, Count: 2, Labels: map[string]string{"tier": "edge"},
+			Rotation: nkeys.RotationPolicy{MaxAge: 24 * time.Hour}},
+		{Name: "xkey", Type: nkeys.PrefixByteCurve, Count: 1},
+	}}
+	var planned *Plan
+	plan, res, err := r.Reconcile(spec, func(p *Plan) bool { planned = p; return true })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := []string{"create server-0", "create server-1", "create xkey-0"}
+	if got := actionsOf(plan); !reflect.DeepEqual(got, want) || planned != plan {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	if plan.Actions[0].Labels["tier"] != "edge" || len(res.Created) != 3 {
+		t.Fatalf("Expected labels and 3 keys, got %+v %+v", plan.Actions[0], res)
+	}
+	if !nkeys.IsValidPublicServerKey(res.Created["server-1"]) {
+		t.Fatalf("Expected a server key, got %q", res.Created["server-1"])
+	}
+
+	// Nothing to do until the server keys are due.
+	if plan, _ := r.Plan(spec); !plan.Empty() {
+		t.Fatalf("Expected an empty plan, got %v", actionsOf(plan))
+	}
+	now = now.Add(25 * time.Hour)
+	spec.Keys[0].Count = 1
+	spec.Keys[1].Type = nkeys.PrefixByteUser
+	plan, res, err = r.Reconcile(spec, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want = []string{"retire server-1", "retire xkey-0", "rotate server-0", "create xkey-0"}
+	if got := actionsOf(plan); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	if len(res.Rotations) != 1 || res.Rotations[0].Old != plan.Actions[2].Public {
+		t.Fatalf("Expected a rotation statement, got %+v", res.Rotations)
+	}
+	if err := nkeys.VerifyRotationStatement(res.Rotations[0], nil); err != nil {
+		t.Fatalf("Expected a valid statement, got %v", err)
+	}
+	names, _ := store.List()
+	if !reflect.DeepEqual(names, []string{"server-0", "xkey-0"}) {
+		t.Fatalf("Expected the spec'd keys, got %v", names)
+	}
+	if current, _ := rm.Current("server-0"); current != res.Rotations[0].New {
+		t.Fatalf("Expected the new key to be tracked, got %q", current)
+	}
+	if _, ok := rm.Current("server-1"); ok {
+		t.Fatalf("Expected retired keys to be untracked")
+	}
+
+	// A declined plan changes nothing, and applying it late is refused.
+	spec.Keys = spec.Keys[1:]
+	plan, _, _ = r.Reconcile(spec, func(*Plan) bool { return false })
+	if names, _ := store.List(); len(names) != 2 {
+		t.Fatalf("Expected no changes, got %v", names)
+	}
+	store.Delete("server-0")
+	if _, err := r.Apply(plan); err != ErrStalePlan {
+		t.Fatalf("Expected %v, got %v", ErrStalePlan, err)
+	}
+
+	for _, bad := range []Spec{
+		{Keys: []KeySpec{{Name: "a", Type: nkeys.PrefixByteUser, Count: 1}, {Name: "a", Type: nkeys.PrefixByteUser}}},
+		{Keys: []KeySpec{{Name: "a", Type: nkeys.PrefixByteSeed, Count: 1}}},
+		{Keys: []KeySpec{{Name: "a", Type: nkeys.PrefixByteCurve, Rotation: nkeys.RotationPolicy{MaxUses: 1}}}},
+	} {
+		if _, err := r.Plan(bad); err != ErrInvalidSpec {
+			t.Fatalf("Expected %v, got %v", ErrInvalidSpec, err)
+		}
+	}
+}
+
+// failingStore fails deletes, and puts after the first okPuts.
+type failingStore struct {
+	seedstore.Store
+	okPuts     int
+	failDelete bool
+}
+
+var errStoreFailed = errors.New("store failed")
+
+func (s *failingStore) Put(name string, data []byte) error {
+	if s.okPuts == 0 {
+		return errStoreFailed
+	}
+	s.okPuts--
+	return s.Store.Put(name, data)
+}
+
+func (s *failingStore) Delete(name string) error {
+	if s.failDelete {
+		return errStoreFailed
+	}
+	return s.Store.Delete(name)
+}
+
+func TestRollbackErrors(t *testing.T) {
+	dir := t.TempDir()
+	disk, err := seedstore.NewDiskStore(filepath.Join(dir, "seeds"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Saving the rotation state fails since its directory does not exist.
+	rm, err := nkeys.NewRotationManager(filepath.Join(dir, "missing", "rotation.json"), nkeys.RotationPolicy{}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	store := &failingStore{Store: disk, okPuts: 1}
+	r := &Reconciler{Store: store, Rotation: rm}
+
+	// Tracking the created key fails and the seed is removed again.
+	plan := &Plan{Actions: []Action{{Type: ActionCreate, Name: "a-0", KeyType: nkeys.PrefixByteUser}}}
+	var rerr *RollbackError
+	if _, err := r.Apply(plan); err == nil || errors.As(err, &rerr) {
+		t.Fatalf("Expected a plain error after a successful rollback, got %v", err)
+	}
+	if names, _ := disk.List(); len(names) != 0 {
+		t.Fatalf("Expected the seed to be removed, got %v", names)
+	}
+	store.okPuts, store.failDelete = 1, true
+	if _, err := r.Apply(plan); !errors.As(err, &rerr) || rerr.Name != "a-0" || rerr.RollbackErr != errStoreFailed {
+		t.Fatalf("Expected a *RollbackError, got %v", err)
+	}
+
+	// Rotating the untracked key fails, and so does restoring it.
+	old, _ := nkeys.CreateUser()
+	public, _ := old.PublicKey()
+	if err := seedstore.PutKeyPair(disk, "b-0", old); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	store.okPuts = 1
+	plan = &Plan{Actions: []Action{{Type: ActionRotate, Name: "b-0", KeyType: nkeys.PrefixByteUser, Public: public}}}
+	_, err = r.Apply(plan)
+	if !errors.As(err, &rerr) || rerr.RollbackErr != errStoreFailed || !errors.Is(err, nkeys.ErrKeyNotFound) {
+		t.Fatalf("Expected a *RollbackError, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+)
+
+// EntropySource can be implemented by readers passed to CreatePairWithReport
+// to identify themselves in the report, e.g. "/dev/hwrng".
+type EntropySource interface {
+	EntropySource() string
+}
+
+// GenerationReport records how a key pair was generated, for use as
+// compliance evidence. It holds no secret material.
+type GenerationReport struct {
+	PublicKey      string        `json:"public_key"`
+	Type           string        `json:"type"`
+	Algorithm      string        `json:"algorithm"`
+	LibraryVersion string        `json:"library_version"`
+	GoVersion      string        `json:"go_version"`
+	Backend        string        `json:"backend"`
+	EntropySource  string        `json:"entropy_source"`
+	Started        time.Time     `json:"started"`
+	Duration       time.Duration `json:"duration_ns"`
+}
+
+// CreatePairWithReport creates a KeyPair like CreatePairWithRand and returns
+// a report describing the generation. rr can be nil.
+func CreatePairWithReport(prefix PrefixByte, rr io.Reader) (KeyPair, *GenerationReport, error) {
+	if rr == nil {
+		rr = rand.Reader
+	}
+	started := time.Now()
+	kp, err := CreatePairWithRand(prefix, rr)
+	if err != nil {
+		return nil, nil, err
+	}
+	duration := time.Since(started)
+
+	pk, err := kp.PublicKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	r := &GenerationReport{
+		PublicKey:      pk,
+		Type:           prefix.String(),
+		Algorithm:      AlgorithmOf(prefix).String(),
+		LibraryVersion: Version,
+		GoVersion:      runtime.Version(),
+		Backend:        fmt.Sprintf("%T", currentBackend()),
+		EntropySource:  entropySourceName(rr),
+		Started:        started.UTC(),
+		Duration:       duration,
+	}
+	if prefix == PrefixByteCurve {
+		r.Backend = "golang.org/x/crypto/curve25519"
+	}
+	return kp, r, nil
+}
+
+func entropySourceName(rr io.Reader) string {
+	if rr == rand.Reader {
+		return "crypto/rand"
+	}
+	if es, ok := rr.(EntropySource); ok {
+		return es.EntropySource()
+	}
+	return fmt.Sprintf("%T", rr)
+}
@@ -0,0 +1,193 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+)
+
+// KeyInfo is metadata describing a public key.
+type KeyInfo struct {
+	PublicKey string `json:"public_key"`
+	Name      string `json:"name"`
+	// Parent is the public key of the issuing key, if any.
+	Parent string `json:"parent,omitempty"`
+}
+
+// Type returns the type of the public key.
+func (ki KeyInfo) Type() PrefixByte {
+	return Prefix(ki.PublicKey)
+}
+
+// Algorithm returns the algorithm of the public key.
+func (ki KeyInfo) Algorithm() AlgorithmID {
+	return AlgorithmOf(ki.Type())
+}
+
+// Resolver maps public keys to metadata.
+type Resolver interface {
+	// Resolve returns the metadata for the public key or ErrKeyNotFound.
+	Resolve(public string) (KeyInfo, error)
+}
+
+// MemoryResolver is an in-memory Resolver.
+type MemoryResolver struct {
+	mu   sync.RWMutex
+	keys map[string]KeyInfo
+}
+
+// NewMemoryResolver creates a Resolver holding the given entries.
+func NewMemoryResolver(infos ...KeyInfo) (*MemoryResolver, error) {
+	r := &MemoryResolver{keys: make(map[string]KeyInfo)}
+	for _, ki := range infos {
+		if err := r.Add(ki); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Add adds or replaces an entry.
+func (r *MemoryResolver) Add(ki KeyInfo) error {
+	if !IsValidPublicKey(ki.PublicKey) {
+		return ErrInvalidPublicKey
+	}
+	if ki.Parent != "" && !IsValidPublicKey(ki.Parent) {
+		return ErrInvalidPublicKey
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[ki.PublicKey] = ki
+	return nil
+}
+
+// Resolve returns the metadata for the public key.
+func (r *MemoryResolver) Resolve(public string) (KeyInfo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ki, ok := r.keys[public]
+	if !ok {
+		return KeyInfo{}, ErrKeyNotFound
+	}
+	return ki, nil
+}
+
+// FileResolver is a Resolver backed by a JSON file holding an array of KeyInfo.
+type FileResolver struct {
+	path string
+	mr   *MemoryResolver
+	mu   sync.RWMutex
+}
+
+// NewFileResolver loads the entries in the file at path.
+func NewFileResolver(path string) (*FileResolver, error) {
+	r := &FileResolver{path: path}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads the file. On error the previous entries are kept.
+func (r *FileResolver) Reload() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	var infos []KeyInfo
+	if err := json.Unmarshal(data, &infos); err != nil {
+		return err
+	}
+	mr, err := NewMemoryResolver(infos...)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.mr = mr
+	r.mu.Unlock()
+	return nil
+}
+
+// Resolve returns the metadata for the public key.
+func (r *FileResolver) Resolve(public string) (KeyInfo, error) {
+	r.mu.RLock()
+	mr := r.mr
+	r.mu.RUnlock()
+	return mr.Resolve(public)
+}
+
+// maxResolveDepth bounds the parent chain walk in case of cycles.
+const maxResolveDepth = 8
+
+// Signer describes the key that produced a signature.
+type Signer struct {
+	KeyInfo
+	// Known is false if the key could not be resolved.
+	Known bool
+	// Path holds the names from the root of the parent chain to this key.
+	Path []string
+}
+
+// String renders the signer as e.g. "account ACME/Billing". Unknown keys
+// are rendered with their public key.
+func (s Signer) String() string {
+	if !s.Known {
+		return s.Type().String() + " " + s.PublicKey
+	}
+	return s.Type().String() + " " + strings.Join(s.Path, "/")
+}
+
+// ResolveSigner resolves public and its parents. Keys that are not known to
+// the resolver are returned with Known set to false.
+func ResolveSigner(r Resolver, public string) (Signer, error) {
+	if !IsValidPublicKey(public) {
+		return Signer{}, ErrInvalidPublicKey
+	}
+	s := Signer{KeyInfo: KeyInfo{PublicKey: public}}
+	ki, err := r.Resolve(public)
+	if err == ErrKeyNotFound {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	s.KeyInfo, s.Known = ki, true
+	path := []string{ki.Name}
+	for i := 0; ki.Parent != "" && i < maxResolveDepth; i++ {
+		if ki, err = r.Resolve(ki.Parent); err != nil {
+			break
+		}
+		path = append([]string{ki.Name}, path...)
+	}
+	s.Path = path
+	return s, nil
+}
+
+// VerifyAndResolve verifies the signature with the public key and returns
+// the resolved signer.
+func VerifyAndResolve(r Resolver, public string, input []byte, sig []byte) (Signer, error) {
+	return VerifyAndResolveWithPolicy(r, nil, public, input, sig)
+}
+
+// VerifyAndResolveWithPolicy is like VerifyAndResolve but also applies the
+// verification policy to the signer.
+func VerifyAndResolveWithPolicy(r Resolver, vp *VerifyPolicy, public string, input []byte, sig []byte) (Signer, error) {
+	if err := VerifyWithPolicy(vp, public, input, sig); err != nil {
+		return Signer{}, err
+	}
+	return ResolveSigner(r, public)
+}
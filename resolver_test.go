@@ -0,0 +1,394 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResolveSigner(t *testing.T) {
+	op, _ := CreateOperator()
+	acc, _ := CreateAccount()
+	user, _ := CreateUser()
+	opk, _ := op.PublicKey()
+	apk, _ := acc.PublicKey()
+	upk, _ := user.PublicKey()
+
+	r, err := NewMemoryResolver(
+		KeyInfo{PublicKey: opk, Name: "ACME"},
+		KeyInfo{PublicKey: apk, Name: "Billing", Parent: opk},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error creating resolver: %v", err)
+	}
+
+	sig, _ := acc.Sign([]byte("hello"))
+	s, err := VerifyAndResolve(r, apk, []byte("hello"), sig)
+	if err != nil {
+		t.Fatalf("Unexpected error verifying: %v", err)
+	}
+	if got := s.String(); got != "account ACME/Billing" {
+		t.Fatalf("Expected %q, got %q", "account ACME/Billing", got)
+	}
+	if _, err := VerifyAndResolve(r, apk, []byte("bye"), sig); err != ErrInvalidSignature {
+		t.Fatalf("Expected ErrInvalidSignature, got %v", err)
+	}
+
+	s, err = ResolveSigner(r, upk)
+	if err != nil {
+		t.Fatalf("Unexpected error resolving: %v", err)
+	}
+	if s.Known || s.String() != "user "+upk {
+		t.Fatalf("Expected unknown user signer, got %q", s.String())
+	}
+
+	if _, err := NewMemoryResolver(KeyInfo{PublicKey: "bad"}); err != ErrInvalidPublicKey {
+		t.Fatalf("Expected ErrInvalidPublicKey, got %v", err)
+	}
+}
+
+func TestResolveSignerCycle(t *testing.T) {
+	a1, _ := CreateAccount()
+	a2, _ := CreateAccount()
+	pk1, _ := a1.PublicKey()
+	pk2, _ := a2.PublicKey()
+	r, _ := NewMemoryResolver(
+		KeyInfo{PublicKey: pk1, Name: "a", Parent: pk2},
+		KeyInfo{PublicKey: pk2, Name: "b", Parent: pk1},
+	)
+	s, err := ResolveSigner(r, pk1)
+	if err != nil {
+		t.Fatalf("Unexpected error resolving: %v", err)
+	}
+	if len(s.Path) != maxResolveDepth+1 {
+		t.Fatalf("Expected parent walk to stop at %d, got %d", maxResolveDepth+1, len(s.Path))
+	}
+}
+
+func TestFileResolver(t *testing.T) {
+	acc, _ := CreateAccount()
+	apk, _ := acc.PublicKey()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	data, _ := json.Marshal([]KeyInfo{{PublicKey: apk, Name: "Billing"}})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Unexpected error writing file: %v", err)
+	}
+	r, err := NewFileResolver(path)
+	if err != nil {
+		t.Fatalf("Unexpected error loading resolver: %v", err)
+	}
+	ki, err := r.Resolve(apk)
+	if err != nil || ki.Name != "Billing" || ki.Type() != PrefixByteAccount {
+		t.Fatalf("Unexpected resolve result %+v, %v", ki, err)
+	}
+
+	if err := os.WriteFile(path, []byte("[]"), 0600); err != nil {
+		t.Fatalf("Unexpected error writing file: %v", err)
+	}
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Unexpected error reloading: %v", err)
+	}
+	if _, err := r.Resolve(apk); err != ErrKeyNotFound {
+		t.Fatalf("Expected ErrKeyNotFound after reload, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatalf("Unexpected error writing file: %v", err)
+	}
+	if err := r.Reload(); err == nil {
+		t.Fatal("Expected an error reloading invalid file")
+	}
+}
+
+func TestTrustStore(t *testing.T) {
+	op, _ := CreateOperator()
+	acc, _ := CreateAccount()
+	user, _ := CreateUser()
+	opk, _ := op.PublicKey()
+	apk, _ := acc.PublicKey()
+	upk, _ := user.PublicKey()
+
+	path := filepath.Join(t.TempDir(), "trusted")
+	if err := os.WriteFile(path, []byte("# operators\n"+opk+"\n\n"), 0600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large" {
+			w.Write(bytes.Repeat([]byte("#"), maxTrustSourceSize+1))
+			return
+		}
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(apk + "\n"))
+	}))
+	defer srv.Close()
+
+	if _, err := NewTrustStore(path, "http://"+srv.Listener.Addr().String()); !errors.Is(err, ErrInsecureTrustSource) {
+		t.Fatalf("Expected %v, got %v", ErrInsecureTrustSource, err)
+	}
+	large := &TrustStore{sources: []string{srv.URL + "/large"}, Client: srv.Client()}
+	if err := large.Reload(); !errors.Is(err, ErrTrustSourceTooLarge) {
+		t.Fatalf("Expected %v, got %v", ErrTrustSourceTooLarge, err)
+	}
+	missing := &TrustStore{sources: []string{srv.URL + "/missing"}, Client: srv.Client()}
+	if err := missing.Reload(); !errors.Is(err, ErrTrustSourceUnavailable) || ErrorCode(err) != "NKEYS-0517" {
+		t.Fatalf("Expected %v, got %v", ErrTrustSourceUnavailable, err)
+	}
+
+	ts := &TrustStore{sources: []string{path, srv.URL}, Client: srv.Client()}
+	if err := ts.Reload(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !ts.IsTrusted(opk) || !ts.IsTrusted(apk) || ts.IsTrusted(upk) || len(ts.Keys()) != 2 {
+		t.Fatalf("Unexpected trusted keys %v", ts.Keys())
+	}
+
+	vp := &VerifyPolicy{Trusted: ts}
+	sig, _ := op.Sign([]byte("hello"))
+	if err := VerifyWithPolicy(vp, opk, []byte("hello"), sig); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	reloaded := make(chan struct{}, 1)
+	stop := ts.Watch(10*time.Millisecond, func(err error) {
+		var serr *TrustSourceError
+		if errors.As(err, &serr) && errors.Is(err, ErrInvalidPublicKey) {
+			select {
+			case reloaded <- struct{}{}:
+			default:
+			}
+		}
+	})
+	defer stop()
+
+	// Invalid sources are reported and the previous keys stay active.
+	if err := os.WriteFile(path, []byte(upk+"\n"), 0600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the invalid source to be reported")
+	}
+	if !ts.IsTrusted(opk) {
+		t.Fatalf("Expected previous keys to be kept")
+	}
+
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for ts.IsTrusted(opk) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := VerifyWithPolicy(vp, opk, []byte("hello"), sig); err != ErrKeyNotTrusted {
+		t.Fatalf("Expected %v, got %v", ErrKeyNotTrusted, err)
+	}
+}
+
+func TestPin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pins.json")
+	p, err := NewPin(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s1, _ := CreateServer()
+	s2, _ := CreateServer()
+	pk1, _ := s1.PublicKey()
+	pk2, _ := s2.PublicKey()
+
+	if err := p.Check("nats.example.com", pk1); err != nil {
+		t.Fatalf("Expected the first key to be trusted, got %v", err)
+	}
+	if err := p.Check("nats.example.com", pk1); err != nil {
+		t.Fatalf("Expected the pinned key to be accepted, got %v", err)
+	}
+
+	var alerted *PinMismatchError
+	p.OnChange = func(e *PinMismatchError) { alerted = e }
+	err = p.Check("nats.example.com", pk2)
+	var merr *PinMismatchError
+	if !errors.As(err, &merr) || merr.Pinned != pk1 || merr.Got != pk2 || alerted != merr {
+		t.Fatalf("Expected a mismatch, got %v", err)
+	}
+
+	// Pins survive a restart.
+	p, err = NewPin(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pinned, ok := p.Get("nats.example.com"); !ok || pinned != pk1 {
+		t.Fatalf("Expected %q to be pinned, got %q", pk1, pinned)
+	}
+	if err := p.Set("nats.example.com", pk2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := p.Check("nats.example.com", pk2); err != nil {
+		t.Fatalf("Expected the new pin to be accepted, got %v", err)
+	}
+	if err := p.Remove("nats.example.com"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(p.Names()) != 0 {
+		t.Fatalf("Expected no pins, got %v", p.Names())
+	}
+
+	user, _ := CreateUser()
+	upk, _ := user.PublicKey()
+	if err := p.Check("nats.example.com", upk); err != ErrInvalidPublicKey {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPublicKey, err)
+	}
+}
+
+func TestVerificationBundle(t *testing.T) {
+	publisher, _ := CreateOperator()
+	ppk, _ := publisher.PublicKey()
+	root, _ := CreateAccount()
+	revoked, _ := CreateAccount()
+	rpk, _ := root.PublicKey()
+	xpk, _ := revoked.PublicKey()
+
+	now := time.Now()
+	data, err := ExportVerificationBundle(publisher, []string{rpk, xpk, rpk}, []string{xpk}, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	b, err := LoadVerificationBundle(ppk, data, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(b.Roots) != 2 {
+		t.Fatalf("Expected duplicate roots to be dropped, got %v", b.Roots)
+	}
+	vp := b.Policy()
+	sig, _ := root.Sign([]byte("hello"))
+	if err := VerifyWithPolicy(vp, rpk, []byte("hello"), sig); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sig, _ = revoked.Sign([]byte("hello"))
+	if err := VerifyWithPolicy(vp, xpk, []byte("hello"), sig); err != ErrKeyRevoked {
+		t.Fatalf("Expected %v, got %v", ErrKeyRevoked, err)
+	}
+	sig, _ = publisher.Sign([]byte("hello"))
+	if err := VerifyWithPolicy(vp, ppk, []byte("hello"), sig); err != ErrKeyNotTrusted {
+		t.Fatalf("Expected %v, got %v", ErrKeyNotTrusted, err)
+	}
+
+	// A decoded bundle was never verified: it trusts nothing but still
+	// honours its revocations.
+	jdata, _ := json.Marshal(b)
+	var decoded VerificationBundle
+	if err := json.Unmarshal(jdata, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decoded.IsTrusted(rpk) {
+		t.Fatalf("Expected an unverified bundle to trust nothing")
+	}
+	if revoked, err := decoded.IsRevoked(xpk); !revoked || err != nil {
+		t.Fatalf("Expected %q to be revoked, got %v", xpk, err)
+	}
+
+	later := ClockFunc(func() time.Time { return now.Add(2 * time.Hour) })
+	if _, err := LoadVerificationBundle(ppk, data, later); err != ErrExpired {
+		t.Fatalf("Expected %v, got %v", ErrExpired, err)
+	}
+	if _, err := LoadVerificationBundle(rpk, data, nil); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	data[len(data)-1] ^= 1
+	if _, err := LoadVerificationBundle(ppk, data, nil); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	if _, err := LoadVerificationBundle(ppk, data[:10], nil); err != ErrInvalidBundle {
+		t.Fatalf("Expected %v, got %v", ErrInvalidBundle, err)
+	}
+}
+
+func TestRevocationDelta(t *testing.T) {
+	op, _ := CreateOperator()
+	opk, _ := op.PublicKey()
+	var keys []string
+	for i := 0; i < 4; i++ {
+		kp, _ := CreateUser()
+		pk, _ := kp.PublicKey()
+		keys = append(keys, pk)
+	}
+
+	base, err := SignRevocationList(op, 1, keys[:3])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, _ := base.MarshalBinary()
+	base, err = ParseRevocationList(opk, data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	next, _ := SignRevocationList(op, 2, []string{keys[1], keys[2], keys[3]})
+
+	delta, err := NewRevocationDelta(op, base, next)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(delta.Added) != 1 || delta.Added[0] != keys[3] || len(delta.Removed) != 1 || delta.Removed[0] != keys[0] {
+		t.Fatalf("Unexpected delta %+v", delta)
+	}
+	ddata, _ := delta.MarshalBinary()
+	var decoded RevocationDelta
+	if err := decoded.UnmarshalBinary(ddata); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	merged, err := base.Apply(&decoded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if merged.Hash() != next.Hash() {
+		t.Fatalf("Expected the merged list to equal the next list")
+	}
+	if revoked, _ := merged.IsRevoked(keys[0]); revoked {
+		t.Fatalf("Expected %q to no longer be revoked", keys[0])
+	}
+	if revoked, _ := merged.IsRevoked(keys[3]); !revoked {
+		t.Fatalf("Expected %q to be revoked", keys[3])
+	}
+
+	// A list that was only unmarshalled still answers.
+	jdata, _ := json.Marshal(next)
+	var unmarshalled RevocationList
+	if err := json.Unmarshal(jdata, &unmarshalled); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if revoked, _ := unmarshalled.IsRevoked(keys[3]); !revoked {
+		t.Fatalf("Expected %q to be revoked", keys[3])
+	}
+	if revoked, _ := unmarshalled.IsRevoked(keys[0]); revoked {
+		t.Fatalf("Expected %q not to be revoked", keys[0])
+	}
+
+	if _, err := merged.Apply(&decoded); err != ErrDeltaBaseMismatch {
+		t.Fatalf("Expected %v, got %v", ErrDeltaBaseMismatch, err)
+	}
+	decoded.Added = append(decoded.Added, keys[0])
+	if _, err := base.Apply(&decoded); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+}
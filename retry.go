@@ -0,0 +1,207 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RetryOptions configure WithRetry. Zero fields use the defaults noted.
+type RetryOptions struct {
+	// MaxAttempts per Sign, 3 by default.
+	MaxAttempts int
+	// BaseDelay before the second attempt, 50ms by default. It doubles
+	// after every attempt up to MaxDelay, 2s by default.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// NoJitter disables the randomization of delays. By default each delay
+	// is chosen uniformly up to its nominal value, so that many clients do
+	// not retry in lockstep after an outage.
+	NoJitter bool
+	// FailureThreshold consecutive transient failures open the circuit, 5
+	// by default; errors that Retryable rejects do not count. While open,
+	// Sign fails with ErrCircuitOpen without contacting the signer until
+	// Cooldown, 30s by default, has passed. The first attempt after that
+	// closes it again if it succeeds.
+	FailureThreshold int
+	Cooldown         time.Duration
+	// Retryable reports whether an error may be transient. By default all
+	// errors are retried except the errors of this package, which do not
+	// change on a retry.
+	Retryable func(error) bool
+	// Clock defaults to SystemClock.
+	Clock Clock
+}
+
+// RetryKeyPair is a KeyPair that retries failed signatures of a remote
+// signer, such as an agent or an ExecSigner.
+type RetryKeyPair struct {
+	kp   KeyPair
+	pub  KeyPair
+	opts RetryOptions
+
+	// sleep is replaced by tests.
+	sleep func(time.Duration)
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// WithRetry returns a KeyPair that retries Sign on kp with exponential
+// backoff and jitter, stops calling kp while it keeps failing, and verifies
+// every signature against the public key before returning it.
+func WithRetry(kp KeyPair, opts RetryOptions) (*RetryKeyPair, error) {
+	public, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := FromPublicKey(public)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = 50 * time.Millisecond
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 2 * time.Second
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 30 * time.Second
+	}
+	if opts.Retryable == nil {
+		opts.Retryable = isTransient
+	}
+	opts.Clock = ClockOrSystem(opts.Clock)
+	return &RetryKeyPair{kp: kp, pub: pub, opts: opts, sleep: time.Sleep}, nil
+}
+
+func isTransient(err error) bool {
+	var ne nkeysError
+	var se *PayloadSizeError
+	return !errors.As(err, &ne) && !errors.As(err, &se)
+}
+
+// CircuitOpen reports whether Sign is currently failing fast.
+func (r *RetryKeyPair) CircuitOpen() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.opts.Clock.Now().Before(r.openUntil)
+}
+
+// Sign will sign the input with the wrapped KeyPair, retrying transient
+// failures. Signatures that do not verify count as failures.
+func (r *RetryKeyPair) Sign(input []byte) ([]byte, error) {
+	delay := r.opts.BaseDelay
+	var err error
+	for attempt := 0; attempt < r.opts.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			d := delay
+			if !r.opts.NoJitter {
+				d = time.Duration(rand.Int63n(int64(delay) + 1))
+			}
+			r.sleep(d)
+			if delay *= 2; delay > r.opts.MaxDelay {
+				delay = r.opts.MaxDelay
+			}
+		}
+		if r.CircuitOpen() {
+			return nil, ErrCircuitOpen
+		}
+		var sig []byte
+		sig, err = r.kp.Sign(input)
+		if err == nil && r.pub.Verify(input, sig) != nil {
+			err = ErrInvalidSignature
+		}
+		if err != nil && err != ErrInvalidSignature && !r.opts.Retryable(err) {
+			return nil, err
+		}
+		r.record(err)
+		if err == nil {
+			return sig, nil
+		}
+	}
+	return nil, err
+}
+
+// record updates the circuit with the outcome of an attempt.
+func (r *RetryKeyPair) record(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		r.failures = 0
+		return
+	}
+	r.failures++
+	if r.failures >= r.opts.FailureThreshold {
+		r.openUntil = r.opts.Clock.Now().Add(r.opts.Cooldown)
+		// Allow a single probe once the cooldown is over.
+		r.failures = r.opts.FailureThreshold - 1
+	}
+}
+
+// Seed will return the seed of the wrapped KeyPair.
+func (r *RetryKeyPair) Seed() ([]byte, error) {
+	return r.kp.Seed()
+}
+
+// PublicKey will return the encoded public key.
+func (r *RetryKeyPair) PublicKey() (string, error) {
+	return r.pub.PublicKey()
+}
+
+// PrivateKey will return the private key of the wrapped KeyPair.
+func (r *RetryKeyPair) PrivateKey() ([]byte, error) {
+	return r.kp.PrivateKey()
+}
+
+// Verify will verify the input against a signature locally.
+func (r *RetryKeyPair) Verify(input []byte, sig []byte) error {
+	return r.pub.Verify(input, sig)
+}
+
+// PublicOnly returns a public only copy of the KeyPair.
+func (r *RetryKeyPair) PublicOnly() (KeyPair, error) {
+	return r.pub.PublicOnly()
+}
+
+// Wipe will wipe the wrapped KeyPair.
+func (r *RetryKeyPair) Wipe() {
+	r.kp.Wipe()
+}
+
+// Seal will seal the input.
+func (r *RetryKeyPair) Seal(input []byte, recipient string) ([]byte, error) {
+	return r.kp.Seal(input, recipient)
+}
+
+// SealWithRand will seal the input.
+func (r *RetryKeyPair) SealWithRand(input []byte, recipient string, rr io.Reader) ([]byte, error) {
+	return r.kp.SealWithRand(input, recipient, rr)
+}
+
+// Open will open the input.
+func (r *RetryKeyPair) Open(input []byte, sender string) ([]byte, error) {
+	return r.kp.Open(input, sender)
+}
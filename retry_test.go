@@ -0,0 +1,108 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type flakyKeyPair struct {
+	KeyPair
+	failures int
+	corrupt  bool
+	calls    int
+}
+
+func (f *flakyKeyPair) Sign(input []byte) ([]byte, error) {
+	f.calls++
+	if f.failures > 0 {
+		f.failures--
+		return nil, errors.New("signer unavailable")
+	}
+	sig, err := f.KeyPair.Sign(input)
+	if err == nil && f.corrupt {
+		sig[0] ^= 0xff
+	}
+	return sig, err
+}
+
+func TestWithRetry(t *testing.T) {
+	kp, _ := CreateUser()
+	now := time.Unix(1700000000, 0)
+	clock := ClockFunc(func() time.Time { return now })
+	flaky := &flakyKeyPair{KeyPair: kp, failures: 2}
+	r, err := WithRetry(flaky, RetryOptions{FailureThreshold: 3, Cooldown: time.Minute, Clock: clock})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var slept []time.Duration
+	r.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	sig, err := r.Sign([]byte("hello"))
+	if err != nil {
+		t.Fatalf("Expected retries to succeed, got %v", err)
+	}
+	if err := kp.Verify([]byte("hello"), sig); err != nil {
+		t.Fatalf("Expected a valid signature, got %v", err)
+	}
+	if flaky.calls != 3 || len(slept) != 2 {
+		t.Fatalf("Expected 3 calls and 2 delays, got %d and %d", flaky.calls, len(slept))
+	}
+	for i, d := range slept {
+		if limit := 50 * time.Millisecond << i; d < 0 || d > limit {
+			t.Fatalf("Expected delay %d up to %v, got %v", i, limit, d)
+		}
+	}
+
+	// Bad signatures are failures and open the circuit.
+	flaky.corrupt = true
+	flaky.calls = 0
+	if _, err := r.Sign([]byte("hello")); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	if !r.CircuitOpen() {
+		t.Fatalf("Expected the circuit to be open")
+	}
+	if _, err := r.Sign([]byte("hello")); err != ErrCircuitOpen {
+		t.Fatalf("Expected %v, got %v", ErrCircuitOpen, err)
+	}
+	if flaky.calls != 3 {
+		t.Fatalf("Expected no calls while open, got %d", flaky.calls)
+	}
+
+	// After the cooldown a single probe closes it again.
+	flaky.corrupt = false
+	now = now.Add(time.Minute)
+	if _, err := r.Sign([]byte("hello")); err != nil {
+		t.Fatalf("Expected the probe to succeed, got %v", err)
+	}
+	if r.CircuitOpen() {
+		t.Fatalf("Expected the circuit to be closed")
+	}
+
+	// Errors of this package are not retried and do not open the circuit.
+	ckp, _ := CreateCurveKeys()
+	cr, _ := WithRetry(ckp, RetryOptions{FailureThreshold: 1})
+	cr.sleep = func(time.Duration) { t.Fatalf("Expected no retry") }
+	for i := 0; i < 2; i++ {
+		if _, err := cr.Sign([]byte("hello")); err != ErrInvalidCurveKeyOperation {
+			t.Fatalf("Expected %v, got %v", ErrInvalidCurveKeyOperation, err)
+		}
+	}
+	if cr.CircuitOpen() {
+		t.Fatalf("Expected the circuit to stay closed")
+	}
+}
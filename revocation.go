@@ -0,0 +1,253 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/sha256"
+	"sort"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// RevocationList is a signed, sequenced list of revoked public keys. It
+// implements RevocationChecker.
+type RevocationList struct {
+	Issuer    string
+	Sequence  uint64
+	Revoked   []string
+	Signature []byte
+
+	revoked map[string]struct{}
+}
+
+// SignRevocationList creates a revocation list signed by issuer.
+func SignRevocationList(issuer KeyPair, sequence uint64, revoked []string) (*RevocationList, error) {
+	pk, err := issuer.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	rl := &RevocationList{Issuer: pk, Sequence: sequence}
+	if rl.Revoked, err = sortedKeys(revoked); err != nil {
+		return nil, err
+	}
+	if rl.Signature, err = issuer.Sign(rl.signedBytes()); err != nil {
+		return nil, err
+	}
+	rl.revoked = keySet(rl.Revoked)
+	return rl, nil
+}
+
+func (rl *RevocationList) signedBytes() []byte {
+	e := canonical.NewEncoder("nkeys.RevocationList")
+	e.Text(rl.Issuer)
+	e.Uint64(rl.Sequence)
+	e.Strings(rl.Revoked)
+	return e.Bytes()
+}
+
+// Hash identifies the list, including its signature.
+func (rl *RevocationList) Hash() [sha256.Size]byte {
+	data, _ := rl.MarshalBinary()
+	return sha256.Sum256(data)
+}
+
+// IsRevoked reports whether public is on the list. Lists that were signed
+// or verified by this package are indexed; others, such as lists built by
+// hand or decoded from JSON, are searched.
+func (rl *RevocationList) IsRevoked(public string) (bool, error) {
+	if rl.revoked == nil {
+		for _, k := range rl.Revoked {
+			if k == public {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	_, ok := rl.revoked[public]
+	return ok, nil
+}
+
+// MarshalBinary returns the canonical encoding of the list.
+func (rl *RevocationList) MarshalBinary() ([]byte, error) {
+	e := canonical.NewEncoder("nkeys.SignedRevocationList")
+	e.Text(rl.Issuer)
+	e.Uint64(rl.Sequence)
+	e.Strings(rl.Revoked)
+	e.Blob(rl.Signature)
+	return e.Bytes(), nil
+}
+
+// ParseRevocationList decodes a list and checks it was signed by issuer.
+func ParseRevocationList(issuer string, data []byte) (*RevocationList, error) {
+	d := canonical.NewDecoder("nkeys.SignedRevocationList", data)
+	rl := &RevocationList{
+		Issuer:    d.Text(),
+		Sequence:  d.Uint64(),
+		Revoked:   d.Strings(),
+		Signature: d.Blob(),
+	}
+	if err := d.Finish(); err != nil {
+		return nil, ErrInvalidRevocationList
+	}
+	if err := rl.verify(issuer); err != nil {
+		return nil, err
+	}
+	return rl, nil
+}
+
+func (rl *RevocationList) verify(issuer string) error {
+	if rl.Issuer != issuer {
+		return ErrInvalidSignature
+	}
+	if !sort.StringsAreSorted(rl.Revoked) {
+		return ErrInvalidRevocationList
+	}
+	if err := VerifyWithPolicy(nil, issuer, rl.signedBytes(), rl.Signature); err != nil {
+		return err
+	}
+	rl.revoked = keySet(rl.Revoked)
+	return nil
+}
+
+// RevocationDelta updates a base RevocationList to the next one by listing
+// the keys added and removed, so fleets with large lists only download the
+// changes. It carries the signature of the resulting list, so applying it
+// reproduces a list that verifies on its own.
+type RevocationDelta struct {
+	Issuer   string
+	BaseHash [sha256.Size]byte
+	Sequence uint64
+	Added    []string
+	Removed  []string
+	// ListSignature is the signature of the resulting list.
+	ListSignature []byte
+	Signature     []byte
+}
+
+// NewRevocationDelta returns the signed delta from base to next, which must
+// both be issued by issuer.
+func NewRevocationDelta(issuer KeyPair, base, next *RevocationList) (*RevocationDelta, error) {
+	pk, err := issuer.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if base.Issuer != pk || next.Issuer != pk || next.Sequence <= base.Sequence {
+		return nil, ErrInvalidRevocationList
+	}
+	d := &RevocationDelta{
+		Issuer:        pk,
+		BaseHash:      base.Hash(),
+		Sequence:      next.Sequence,
+		ListSignature: next.Signature,
+	}
+	baseSet, nextSet := keySet(base.Revoked), keySet(next.Revoked)
+	for _, k := range next.Revoked {
+		if _, ok := baseSet[k]; !ok {
+			d.Added = append(d.Added, k)
+		}
+	}
+	for _, k := range base.Revoked {
+		if _, ok := nextSet[k]; !ok {
+			d.Removed = append(d.Removed, k)
+		}
+	}
+	if d.Signature, err = issuer.Sign(d.signedBytes()); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *RevocationDelta) encode(e *canonical.Encoder) {
+	e.Text(d.Issuer)
+	e.Blob(d.BaseHash[:])
+	e.Uint64(d.Sequence)
+	e.Strings(d.Added)
+	e.Strings(d.Removed)
+	e.Blob(d.ListSignature)
+}
+
+func (d *RevocationDelta) signedBytes() []byte {
+	e := canonical.NewEncoder("nkeys.RevocationDelta")
+	d.encode(e)
+	return e.Bytes()
+}
+
+// MarshalBinary returns the canonical encoding of the delta.
+func (d *RevocationDelta) MarshalBinary() ([]byte, error) {
+	e := canonical.NewEncoder("nkeys.SignedRevocationDelta")
+	d.encode(e)
+	e.Blob(d.Signature)
+	return e.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the canonical encoding of a delta. The delta is
+// verified when applied.
+func (d *RevocationDelta) UnmarshalBinary(data []byte) error {
+	dec := canonical.NewDecoder("nkeys.SignedRevocationDelta", data)
+	v := RevocationDelta{Issuer: dec.Text()}
+	hash := dec.Blob()
+	v.Sequence = dec.Uint64()
+	v.Added = dec.Strings()
+	v.Removed = dec.Strings()
+	v.ListSignature = dec.Blob()
+	v.Signature = dec.Blob()
+	if err := dec.Finish(); err != nil {
+		return err
+	}
+	if len(hash) != sha256.Size {
+		return canonical.ErrInvalid
+	}
+	copy(v.BaseHash[:], hash)
+	*d = v
+	return nil
+}
+
+// Apply merges the delta into rl and returns the resulting list, which is
+// verified against the list signature carried by the delta. rl is not
+// modified.
+func (rl *RevocationList) Apply(d *RevocationDelta) (*RevocationList, error) {
+	if d.Issuer != rl.Issuer {
+		return nil, ErrInvalidSignature
+	}
+	if err := VerifyWithPolicy(nil, d.Issuer, d.signedBytes(), d.Signature); err != nil {
+		return nil, err
+	}
+	if d.BaseHash != rl.Hash() || d.Sequence <= rl.Sequence {
+		return nil, ErrDeltaBaseMismatch
+	}
+	set := keySet(rl.Revoked)
+	for _, k := range d.Removed {
+		if _, ok := set[k]; !ok {
+			return nil, ErrDeltaBaseMismatch
+		}
+		delete(set, k)
+	}
+	for _, k := range d.Added {
+		set[k] = struct{}{}
+	}
+	next := &RevocationList{
+		Issuer:    rl.Issuer,
+		Sequence:  d.Sequence,
+		Revoked:   make([]string, 0, len(set)),
+		Signature: d.ListSignature,
+	}
+	for k := range set {
+		next.Revoked = append(next.Revoked, k)
+	}
+	sort.Strings(next.Revoked)
+	if err := next.verify(rl.Issuer); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
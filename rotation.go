@@ -0,0 +1,346 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// RotationPolicy decides when a key is due for rotation. Zero values
+// disable the corresponding limit.
+type RotationPolicy struct {
+	// MaxAge is the age at which a key is due.
+	MaxAge time.Duration
+	// MaxUses is the number of recorded uses at which a key is due.
+	MaxUses uint64
+	// Grace is how long a rotated out key keeps KeyStatusPrevious before it
+	// becomes KeyStatusRetired.
+	Grace time.Duration
+}
+
+// RotationStatement announces that the key Old of Name has been replaced by
+// New. It is signed by both keys, so relying parties can follow the chain
+// of rotations starting from a key they already trust.
+type RotationStatement struct {
+	Name         string    `json:"name"`
+	Old          string    `json:"old"`
+	New          string    `json:"new"`
+	Sequence     uint64    `json:"seq"`
+	IssuedAt     time.Time `json:"iat"`
+	OldSignature []byte    `json:"old_sig"`
+	NewSignature []byte    `json:"new_sig"`
+}
+
+func (rs *RotationStatement) signedBytes() []byte {
+	e := canonical.NewEncoder("nkeys.RotationStatement")
+	e.Text(rs.Name)
+	e.Text(rs.Old)
+	e.Text(rs.New)
+	e.Uint64(rs.Sequence)
+	e.Time(rs.IssuedAt)
+	return e.Bytes()
+}
+
+// VerifyRotationStatement checks both signatures of rs and applies the
+// policy to the old key, which is the one the relying party already knows.
+func VerifyRotationStatement(rs *RotationStatement, vp *VerifyPolicy) error {
+	if rs.Old == rs.New || Prefix(rs.Old) != Prefix(rs.New) {
+		return ErrInvalidRotationStatement
+	}
+	input := rs.signedBytes()
+	if err := VerifyWithPolicy(vp, rs.Old, input, rs.OldSignature); err != nil {
+		return err
+	}
+	return VerifyWithPolicy(nil, rs.New, input, rs.NewSignature)
+}
+
+type retiredKey struct {
+	Public    string    `json:"public"`
+	RetiredAt time.Time `json:"retired_at"`
+}
+
+type rotationState struct {
+	Public   string       `json:"public"`
+	Created  time.Time    `json:"created"`
+	Uses     uint64       `json:"uses"`
+	Sequence uint64       `json:"seq"`
+	Previous []retiredKey `json:"previous,omitempty"`
+}
+
+// RotationManager tracks the age and use of named keys, reports which are
+// due for rotation under its policy, and prepares the statements announcing
+// each rotation. Its state, which holds public keys only, is persisted as a
+// JSON file. It implements RotationChecker, so it can be used directly in a
+// VerifyPolicy.
+type RotationManager struct {
+	path   string
+	policy RotationPolicy
+	clock  Clock
+
+	mu   sync.Mutex
+	keys map[string]*rotationState
+}
+
+// NewRotationManager loads the state in the file at path. A missing file
+// starts empty. The clock defaults to SystemClock.
+func NewRotationManager(path string, policy RotationPolicy, clock Clock) (*RotationManager, error) {
+	m := &RotationManager{
+		path:   path,
+		policy: policy,
+		clock:  ClockOrSystem(clock),
+		keys:   make(map[string]*rotationState),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &m.keys); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Track starts tracking public under name. Tracking the current key again
+// does nothing; a different key replaces the current one without a
+// statement, as for keys rotated outside of the manager.
+func (m *RotationManager) Track(name, public string) error {
+	if !IsValidPublicKey(public) {
+		return ErrInvalidPublicKey
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.keys[name]
+	if ok && st.Public == public {
+		return nil
+	}
+	now := m.clock.Now().UTC()
+	next := &rotationState{Public: public, Created: now}
+	if ok {
+		next.Sequence = st.Sequence + 1
+		next.Previous = append(st.Previous, retiredKey{st.Public, now})
+	}
+	return m.replace(name, next)
+}
+
+// RecordUse adds n uses of the key of name. Uses are kept in memory until
+// the next Save, Track or Rotate.
+func (m *RotationManager) RecordUse(name string, n uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.keys[name]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	st.Uses += n
+	return nil
+}
+
+// Due returns the sorted names of the keys that are due for rotation.
+func (m *RotationManager) Due() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	var due []string
+	for name, st := range m.keys {
+		if st.due(m.policy, now) {
+			due = append(due, name)
+		}
+	}
+	sort.Strings(due)
+	return due
+}
+
+// IsDue reports whether the key of name is due for rotation under policy
+// instead of the policy of the manager, for keys with their own limits.
+func (m *RotationManager) IsDue(name string, policy RotationPolicy) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.keys[name]
+	return ok && st.due(policy, m.clock.Now())
+}
+
+func (st *rotationState) due(policy RotationPolicy, now time.Time) bool {
+	return policy.MaxAge > 0 && now.Sub(st.Created) >= policy.MaxAge ||
+		policy.MaxUses > 0 && st.Uses >= policy.MaxUses
+}
+
+// Untrack stops tracking name. Its current and previous keys are no longer
+// known to KeyStatus.
+func (m *RotationManager) Untrack(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.keys[name]; !ok {
+		return ErrKeyNotFound
+	}
+	return m.replace(name, nil)
+}
+
+// Rotate replaces the key of name, which must be old, by new and returns the
+// statement announcing it, signed by both keys.
+func (m *RotationManager) Rotate(name string, old, new KeyPair) (*RotationStatement, error) {
+	oldPub, err := old.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	newPub, err := new.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if Prefix(oldPub) != Prefix(newPub) || oldPub == newPub {
+		return nil, ErrIncompatibleKey
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.keys[name]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	if st.Public != oldPub {
+		return nil, ErrIncompatibleKey
+	}
+	now := m.clock.Now().UTC().Truncate(time.Second)
+	rs := &RotationStatement{
+		Name:     name,
+		Old:      oldPub,
+		New:      newPub,
+		Sequence: st.Sequence + 1,
+		IssuedAt: now,
+	}
+	input := rs.signedBytes()
+	if rs.OldSignature, err = old.Sign(input); err != nil {
+		return nil, err
+	}
+	if rs.NewSignature, err = new.Sign(input); err != nil {
+		return nil, err
+	}
+	next := &rotationState{
+		Public:   newPub,
+		Created:  now,
+		Sequence: rs.Sequence,
+		Previous: append(st.Previous, retiredKey{oldPub, now}),
+	}
+	if err := m.replace(name, next); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// KeyStatus reports the status of public, or ErrKeyNotFound if it is not
+// tracked.
+func (m *RotationManager) KeyStatus(public string) (KeyStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	for _, st := range m.keys {
+		if st.Public == public {
+			return KeyStatusCurrent, nil
+		}
+		for _, rk := range st.Previous {
+			if rk.Public != public {
+				continue
+			}
+			if now.Before(rk.RetiredAt.Add(m.policy.Grace)) {
+				return KeyStatusPrevious, nil
+			}
+			return KeyStatusRetired, nil
+		}
+	}
+	return KeyStatusRetired, ErrKeyNotFound
+}
+
+// Current returns the current public key of name.
+func (m *RotationManager) Current(name string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.keys[name]
+	if !ok {
+		return "", false
+	}
+	return st.Public, true
+}
+
+// Save persists the state, including the uses recorded so far.
+func (m *RotationManager) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.save()
+}
+
+// Watch saves the state and calls onDue with the keys that are due every
+// interval, until stop is called. onDue is expected to create the new keys,
+// call Rotate and publish the statements. Errors from saving are passed to
+// onError.
+func (m *RotationManager) Watch(interval time.Duration, onDue func(names []string), onError func(error)) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := m.Save(); err != nil && onError != nil {
+					onError(err)
+				}
+				if due := m.Due(); len(due) > 0 {
+					onDue(due)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// replace installs the state of name, or removes it if st is nil, keeping
+// the previous one if it could not be persisted. m.mu must be held.
+func (m *RotationManager) replace(name string, st *rotationState) error {
+	prev, had := m.keys[name]
+	if st != nil {
+		m.keys[name] = st
+	} else {
+		delete(m.keys, name)
+	}
+	if err := m.save(); err != nil {
+		if had {
+			m.keys[name] = prev
+		} else {
+			delete(m.keys, name)
+		}
+		return err
+	}
+	return nil
+}
+
+// save atomically writes the state. m.mu must be held.
+func (m *RotationManager) save() error {
+	data, err := json.MarshalIndent(m.keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(m.path, data, 0600)
+}
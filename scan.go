@@ -0,0 +1,162 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// maxScanFileSize bounds the files ScanDir reads.
+const maxScanFileSize = 1 << 20
+
+// Material is the kind of nkey material found by ScanDir.
+type Material string
+
+const (
+	MaterialSeed          Material = "seed"
+	MaterialEncryptedSeed Material = "encrypted_seed"
+	MaterialPublicKey     Material = "public_key"
+	MaterialCreds         Material = "creds"
+)
+
+// Finding is one piece of nkey material found in a file.
+type Finding struct {
+	Path     string     `json:"path"`
+	Material Material   `json:"material"`
+	Type     PrefixByte `json:"type,omitempty"`
+	// PublicKey is empty for encrypted seeds.
+	PublicKey   string      `json:"public_key,omitempty"`
+	Fingerprint string      `json:"fingerprint,omitempty"`
+	Mode        fs.FileMode `json:"mode"`
+	// Insecure is set for secret material readable by group or others.
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// Inventory is the result of ScanDir.
+type Inventory struct {
+	Findings []Finding `json:"findings"`
+}
+
+// Insecure returns the findings of secret material with insecure permissions.
+func (inv *Inventory) Insecure() []Finding {
+	var out []Finding
+	for _, f := range inv.Findings {
+		if f.Insecure {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Fingerprint returns a short identifier of a public key in the style of
+// ssh: "SHA256:" followed by the unpadded base64 SHA-256 of the raw key.
+func Fingerprint(public string) (string, error) {
+	raw, err := decode([]byte(public))
+	if err != nil {
+		return "", err
+	}
+	if checkValidPublicPrefixByte(PrefixByte(raw[0])) != nil {
+		return "", ErrInvalidPublicKey
+	}
+	sum := sha256.Sum256(raw[1:])
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:]), nil
+}
+
+// ScanDir walks the directory tree at root and identifies nkey material by
+// content: seeds, encrypted seeds, public keys and creds files. Files that
+// can not be read are skipped.
+func ScanDir(root string) (*Inventory, error) {
+	inv := &Inventory{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil || fi.Size() > maxScanFileSize {
+			return nil
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		defer wipeBytes(contents)
+		inv.Findings = append(inv.Findings, scanContents(path, fi.Mode(), contents)...)
+		return nil
+	})
+	return inv, err
+}
+
+func scanContents(path string, mode fs.FileMode, contents []byte) []Finding {
+	insecure := runtime.GOOS != "windows" && mode.Perm()&0077 != 0
+	if IsEncryptedSeed(contents) {
+		return []Finding{{Path: path, Material: MaterialEncryptedSeed, Mode: mode, Insecure: insecure}}
+	}
+	isCreds := bytes.Contains(contents, []byte("BEGIN NATS USER JWT"))
+
+	var findings []Finding
+	seen := make(map[string]bool)
+	for _, tok := range bytes.FieldsFunc(contents, isNotBase32) {
+		if len(tok) < 56 || !IsValidEncoding(tok) {
+			continue
+		}
+		f := Finding{Path: path, Mode: mode}
+		if prefix, raw, err := DecodeSeed(tok); err == nil {
+			kp, err := FromSeed(tok)
+			wipeBytes(raw)
+			if err != nil {
+				continue
+			}
+			f.PublicKey, _ = kp.PublicKey()
+			kp.Wipe()
+			f.Material, f.Type, f.Insecure = MaterialSeed, prefix, insecure
+			if isCreds {
+				f.Material = MaterialCreds
+			}
+		} else if IsValidPublicKey(string(tok)) {
+			f.Material, f.Type, f.PublicKey = MaterialPublicKey, Prefix(string(tok)), string(tok)
+		} else {
+			continue
+		}
+		key := string(f.Material) + f.PublicKey
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		f.Fingerprint, _ = Fingerprint(f.PublicKey)
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+func isNotBase32(c rune) bool {
+	return !(c >= 'A' && c <= 'Z' || c >= '2' && c <= '7')
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"runtime"
+)
+
+// SeedFileMode is the permission seed files are expected to have.
+const SeedFileMode fs.FileMode = 0600
+
+// InsecureSeedFileError is returned when a seed file is accessible by group
+// or others, similar to how ssh refuses to use such private keys.
+type InsecureSeedFileError struct {
+	Path string
+	Mode fs.FileMode
+}
+
+func (e *InsecureSeedFileError) Error() string {
+	return fmt.Sprintf("nkeys: permissions %04o for %q are too open", e.Mode.Perm(), e.Path)
+}
+
+// Fix restricts the permissions of the file to SeedFileMode.
+func (e *InsecureSeedFileError) Fix() error {
+	return FixSeedFile(e.Path)
+}
+
+// InsecureSeedFileHook, when set, is called instead of failing when a seed
+// file has insecure permissions. Loading continues if it returns nil, which
+// allows applications to only log a warning.
+var InsecureSeedFileHook func(err *InsecureSeedFileError) error
+
+// CheckSeedFile returns an *InsecureSeedFileError if the file at path is
+// group or world accessible. The check is skipped on Windows.
+func CheckSeedFile(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" || fi.Mode().Perm()&0077 == 0 {
+		return nil
+	}
+	ierr := &InsecureSeedFileError{Path: path, Mode: fi.Mode()}
+	if InsecureSeedFileHook != nil {
+		return InsecureSeedFileHook(ierr)
+	}
+	return ierr
+}
+
+// FixSeedFile restricts the permissions of the file at path to SeedFileMode.
+func FixSeedFile(path string) error {
+	return os.Chmod(path, SeedFileMode)
+}
+
+// FromSeedFile checks the permissions of the file at path and creates a
+// KeyPair from the first seed found in it.
+func FromSeedFile(path string) (KeyPair, error) {
+	if err := CheckSeedFile(path); err != nil {
+		return nil, err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(contents)
+
+	for _, line := range bytes.Split(contents, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if _, _, err := DecodeSeed(line); err == nil {
+			return FromSeed(line)
+		}
+	}
+	return nil, ErrNoSeedFound
+}
+
+// wipeBytes overwrites buf so that secrets do not linger in memory.
+func wipeBytes(buf []byte) {
+	for i := range buf {
+		buf[i] = 'x'
+	}
+}
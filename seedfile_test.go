@@ -0,0 +1,188 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeSeedFile(t *testing.T, mode os.FileMode) (KeyPair, string) {
+	t.Helper()
+	user, _ := CreateUser()
+	seed, _ := user.Seed()
+	path := filepath.Join(t.TempDir(), "user.nk")
+	if err := os.WriteFile(path, append(append([]byte("# comment\n"), seed...), '\n'), mode); err != nil {
+		t.Fatalf("Unexpected error writing seed file: %v", err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatalf("Unexpected error setting permissions: %v", err)
+	}
+	return user, path
+}
+
+func TestFromSeedFile(t *testing.T) {
+	user, path := writeSeedFile(t, 0600)
+	kp, err := FromSeedFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error loading seed file: %v", err)
+	}
+	pk, _ := kp.PublicKey()
+	if upk, _ := user.PublicKey(); pk != upk {
+		t.Fatalf("Expected public key %q, got %q", upk, pk)
+	}
+
+	if err := os.WriteFile(path, []byte("nothing here"), 0600); err != nil {
+		t.Fatalf("Unexpected error writing file: %v", err)
+	}
+	if _, err := FromSeedFile(path); err != ErrNoSeedFound {
+		t.Fatalf("Expected ErrNoSeedFound, got %v", err)
+	}
+}
+
+func TestInsecureSeedFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+	_, path := writeSeedFile(t, 0644)
+	_, err := FromSeedFile(path)
+	var ierr *InsecureSeedFileError
+	if !errors.As(err, &ierr) {
+		t.Fatalf("Expected InsecureSeedFileError, got %v", err)
+	}
+	if ierr.Mode.Perm() != 0644 {
+		t.Fatalf("Expected mode 0644, got %04o", ierr.Mode.Perm())
+	}
+
+	// A hook can downgrade the failure to a warning.
+	var warned bool
+	InsecureSeedFileHook = func(err *InsecureSeedFileError) error {
+		warned = true
+		return nil
+	}
+	_, err = FromSeedFile(path)
+	InsecureSeedFileHook = nil
+	if err != nil || !warned {
+		t.Fatalf("Expected hook to allow loading, got %v", err)
+	}
+
+	if err := ierr.Fix(); err != nil {
+		t.Fatalf("Unexpected error fixing permissions: %v", err)
+	}
+	if _, err := FromSeedFile(path); err != nil {
+		t.Fatalf("Unexpected error after fixing permissions: %v", err)
+	}
+}
+
+func TestScanDir(t *testing.T) {
+	user, path := writeSeedFile(t, 0644)
+	dir := filepath.Dir(path)
+	upk, _ := user.PublicKey()
+	acc, _ := CreateAccount()
+	apk, _ := acc.PublicKey()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0700); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	conf := []byte("accounts: { A: { nkey: \"" + apk + "\" } }\n")
+	if err := os.WriteFile(filepath.Join(dir, "sub", "server.conf"), conf, 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("nothing here"), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	inv, err := ScanDir(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(inv.Findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %+v", inv.Findings)
+	}
+	seed, pub := inv.Findings[1], inv.Findings[0]
+	if seed.Path != path || seed.Material != MaterialSeed || seed.Type != PrefixByteUser || seed.PublicKey != upk {
+		t.Fatalf("Unexpected seed finding %+v", seed)
+	}
+	if fp, _ := Fingerprint(upk); seed.Fingerprint != fp || fp == "" {
+		t.Fatalf("Expected fingerprint %q, got %q", fp, seed.Fingerprint)
+	}
+	if pub.Material != MaterialPublicKey || pub.Type != PrefixByteAccount || pub.PublicKey != apk || pub.Insecure {
+		t.Fatalf("Unexpected public key finding %+v", pub)
+	}
+	if runtime.GOOS != "windows" {
+		if insecure := inv.Insecure(); len(insecure) != 1 || insecure[0].Path != path {
+			t.Fatalf("Expected the seed file to be insecure, got %+v", insecure)
+		}
+	}
+}
+
+func TestSeedProviders(t *testing.T) {
+	ctx := context.Background()
+	user, _ := CreateUser()
+	seed, _ := user.Seed()
+	pub, _ := user.PublicKey()
+
+	t.Setenv("TEST_NKEYS_USER", string(seed)+"\n")
+	env := EnvProvider{Prefix: "TEST_NKEYS_", Unset: true}
+	kp, err := FromProvider(ctx, env, "USER", PrefixByteUser)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, _ := kp.PublicKey(); got != pub {
+		t.Fatalf("Expected %q, got %q", pub, got)
+	}
+	if _, ok := os.LookupEnv("TEST_NKEYS_USER"); ok {
+		t.Fatal("Expected the variable to be unset")
+	}
+	if _, err := FromProvider(ctx, env, "USER", PrefixByteUser); err != ErrKeyNotFound {
+		t.Fatalf("Expected %v, got %v", ErrKeyNotFound, err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "user"), seed, 0600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	files := FileProvider{Dir: dir}
+	if _, err := FromProvider(ctx, files, "user", PrefixByteUnknown); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := FromProvider(ctx, files, "user", PrefixByteAccount); err != ErrIncompatibleKey {
+		t.Fatalf("Expected %v, got %v", ErrIncompatibleKey, err)
+	}
+	for _, name := range []string{"missing", "../user", ".."} {
+		if _, err := FromProvider(ctx, files, name, PrefixByteUser); err != ErrKeyNotFound {
+			t.Fatalf("Expected %v for %q, got %v", ErrKeyNotFound, name, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "public"), []byte(pub), 0600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := FromProvider(ctx, files, "public", PrefixByteUser); err != ErrExpectedSeedGotPublicKey {
+		t.Fatalf("Expected %v, got %v", ErrExpectedSeedGotPublicKey, err)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	cmd := ExecProvider{Command: "cat", Args: []string{"--"}}
+	if _, err := FromProvider(ctx, cmd, filepath.Join(dir, "user"), PrefixByteUser); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := FromProvider(ctx, cmd, filepath.Join(dir, "missing"), PrefixByteUser); err == nil {
+		t.Fatal("Expected the command to fail")
+	}
+}
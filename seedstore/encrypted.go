@@ -0,0 +1,54 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seedstore
+
+// Encrypter is a hook for encrypting seeds before they reach a Store, for
+// example by calling out to a KMS. The name is passed so it can be bound to
+// the ciphertext as associated data.
+type Encrypter interface {
+	Encrypt(name string, plaintext []byte) ([]byte, error)
+	Decrypt(name string, ciphertext []byte) ([]byte, error)
+}
+
+// Encrypted is a Store that encrypts data before passing it to another Store.
+type Encrypted struct {
+	Store
+	enc Encrypter
+}
+
+// NewEncrypted wraps s so that all data is encrypted with enc.
+func NewEncrypted(s Store, enc Encrypter) *Encrypted {
+	return &Encrypted{s, enc}
+}
+
+// Put encrypts and stores the data.
+func (e *Encrypted) Put(name string, data []byte) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	ct, err := e.enc.Encrypt(name, data)
+	if err != nil {
+		return err
+	}
+	return e.Store.Put(name, ct)
+}
+
+// Get loads and decrypts the data.
+func (e *Encrypted) Get(name string) ([]byte, error) {
+	ct, err := e.Store.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return e.enc.Decrypt(name, ct)
+}
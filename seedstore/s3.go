@@ -0,0 +1,328 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seedstore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nkeys"
+)
+
+// maxObjectSize bounds the size of objects read back from the bucket.
+const maxObjectSize = 64 * 1024
+
+// S3Store stores seeds as objects in an S3 compatible bucket, using path
+// style requests signed with AWS Signature Version 4.
+type S3Store struct {
+	// Endpoint is the service URL, e.g. https://s3.us-east-1.amazonaws.com.
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Prefix is prepended to every object key, e.g. "nkeys/".
+	Prefix string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// ServerSideEncryption is sent as x-amz-server-side-encryption,
+	// e.g. "AES256" or "aws:kms".
+	ServerSideEncryption string
+	// KMSKeyID is sent as x-amz-server-side-encryption-aws-kms-key-id.
+	KMSKeyID string
+
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+	// Clock is used to date request signatures. Defaults to nkeys.SystemClock.
+	Clock nkeys.Clock
+}
+
+// S3Error is returned for unexpected responses from the object store.
+type S3Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *S3Error) Error() string {
+	return fmt.Sprintf("seedstore: s3 request failed with status %d: %s %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (s *S3Store) client() *http.Client {
+	if s.Client == nil {
+		return http.DefaultClient
+	}
+	return s.Client
+}
+
+func (s *S3Store) do(method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u, err := url.Parse(strings.TrimSuffix(s.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/" + s.Bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = awsEscapePath(u.Path)
+	u.RawQuery = awsCanonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if method == http.MethodPut {
+		if s.ServerSideEncryption != "" {
+			req.Header.Set("X-Amz-Server-Side-Encryption", s.ServerSideEncryption)
+		}
+		if s.KMSKeyID != "" {
+			req.Header.Set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", s.KMSKeyID)
+		}
+	}
+	s.sign(req, body, nkeys.ClockOrSystem(s.Clock).Now().UTC())
+	return s.client().Do(req)
+}
+
+func readS3Error(resp *http.Response) error {
+	e := &S3Error{StatusCode: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxObjectSize))
+	xml.Unmarshal(data, e)
+	return e
+}
+
+// Put uploads the data.
+func (s *S3Store) Put(name string, data []byte) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodPut, s.Prefix+name, nil, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return readS3Error(resp)
+	}
+	return nil
+}
+
+// Get downloads the data.
+func (s *S3Store) Get(name string) ([]byte, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(http.MethodGet, s.Prefix+name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(io.LimitReader(resp.Body, maxObjectSize))
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	}
+	return nil, readS3Error(resp)
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns the names of all objects under Prefix.
+func (s *S3Store) List() ([]string, error) {
+	var names []string
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {s.Prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		resp, err := s.do(http.MethodGet, "", q, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err = readS3Error(resp)
+			resp.Body.Close()
+			return nil, err
+		}
+		var lbr listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&lbr)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range lbr.Contents {
+			if name := strings.TrimPrefix(c.Key, s.Prefix); checkName(name) == nil {
+				names = append(names, name)
+			}
+		}
+		if !lbr.IsTruncated || lbr.NextContinuationToken == "" {
+			break
+		}
+		token = lbr.NextContinuationToken
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete removes the object. S3 does not report missing keys on delete,
+// so the object is looked up first to honor the Store contract.
+func (s *S3Store) Delete(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodHead, s.Prefix+name, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		return &S3Error{StatusCode: resp.StatusCode}
+	}
+	resp, err = s.do(http.MethodDelete, s.Prefix+name, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return readS3Error(resp)
+	}
+	return nil
+}
+
+// sign adds AWS Signature Version 4 headers to req.
+func (s *S3Store) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsEscape escapes everything except the unreserved characters of RFC 3986.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func awsEscapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		segments[i] = awsEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+func awsCanonicalQuery(q url.Values) string {
+	if len(q) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string{}, q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
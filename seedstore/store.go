@@ -0,0 +1,81 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package seedstore defines a storage interface for nkey seeds together
+// with local disk and S3 compatible object store implementations.
+package seedstore
+
+import (
+	"regexp"
+
+	"github.com/nats-io/nkeys"
+)
+
+// Errors
+const (
+	ErrNotFound    = seedStoreError("seedstore: seed not found")
+	ErrInvalidName = seedStoreError("seedstore: invalid seed name")
+)
+
+type seedStoreError string
+
+func (e seedStoreError) Error() string {
+	return string(e)
+}
+
+// Store persists seeds by name. Implementations treat the data as opaque
+// bytes, so they can be layered with Encrypted.
+type Store interface {
+	Put(name string, data []byte) error
+	// Get returns the stored data or ErrNotFound.
+	Get(name string) ([]byte, error)
+	// List returns the names of all stored seeds in lexical order.
+	List() ([]string, error)
+	// Delete removes the seed. Deleting a missing seed returns ErrNotFound.
+	Delete(name string) error
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_\-][A-Za-z0-9_.\-]*$`)
+
+// checkName rejects names that could escape the store, such as paths.
+func checkName(name string) error {
+	if len(name) > 255 || !validName.MatchString(name) {
+		return ErrInvalidName
+	}
+	return nil
+}
+
+// PutKeyPair stores the seed of kp under name.
+func PutKeyPair(s Store, name string, kp nkeys.KeyPair) error {
+	seed, err := kp.Seed()
+	if err != nil {
+		return err
+	}
+	return s.Put(name, seed)
+}
+
+// GetKeyPair loads the seed stored under name and returns its KeyPair.
+func GetKeyPair(s Store, name string) (nkeys.KeyPair, error) {
+	seed, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(seed)
+	return nkeys.FromSeed(seed)
+}
+
+func wipe(buf []byte) {
+	for i := range buf {
+		buf[i] = 'x'
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+)
+
+// SelfTestEnv is the environment variable that, when set to "1", makes the
+// package run SelfTest during initialization and panic on failure.
+const SelfTestEnv = "NKEYS_SELFTEST"
+
+// Known answer vectors. The raw seed, public key and signature are taken
+// from RFC 8032, section 7.1, TEST 1 (empty message).
+const (
+	katRawSeed   = "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"
+	katRawPublic = "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a"
+	katSignature = "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e06522490155" +
+		"5fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b"
+	katSeed   = "SUAJ2YNRTXX72WTAXKCEV5ES5QWMIRCJYVUXWMTJDFYDXLADDSXH6YALCA"
+	katPublic = "UDLVVGABQKYQVN6VJP7NHSLEA45A5YLS6PNKMIZFV4BBU2HXA5IRUVAL"
+)
+
+// selfTestOnSet is set by SelfTestEnv and makes SetBackend test backends
+// before selecting them.
+var selfTestOnSet bool
+
+func init() {
+	if os.Getenv(SelfTestEnv) == "1" {
+		selfTestOnSet = true
+		if err := SelfTestBackend(stdBackend{}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// SelfTest runs known answer tests for seed and key encoding, decoding,
+// signing and verification with the selected backend. It returns
+// ErrSelfTestFailed if any of the results differ from the expected values.
+// Unlike using a key, it does not prevent SetBackend from being called.
+func SelfTest() error {
+	return SelfTestBackend(loadBackend())
+}
+
+// SelfTestBackend runs the tests of SelfTest with b, e.g. before passing it
+// to SetBackend.
+func SelfTestBackend(b Backend) error {
+	rawSeed, _ := hex.DecodeString(katRawSeed)
+	rawPublic, _ := hex.DecodeString(katRawPublic)
+	sig, _ := hex.DecodeString(katSignature)
+
+	// Encoding
+	seed, err := EncodeSeed(PrefixByteUser, rawSeed)
+	if err != nil || string(seed) != katSeed {
+		return ErrSelfTestFailed
+	}
+	public, err := Encode(PrefixByteUser, rawPublic)
+	if err != nil || string(public) != katPublic {
+		return ErrSelfTestFailed
+	}
+
+	// Decoding
+	pre, raw, err := DecodeSeed([]byte(katSeed))
+	if err != nil || pre != PrefixByteUser || !bytes.Equal(raw, rawSeed) {
+		return ErrSelfTestFailed
+	}
+	raw, err = Decode(PrefixByteUser, []byte(katPublic))
+	if err != nil || !bytes.Equal(raw, rawPublic) {
+		return ErrSelfTestFailed
+	}
+
+	// Key derivation
+	pub, priv, err := b.NewKeyFromSeed(rawSeed)
+	if err != nil || !bytes.Equal(pub, rawPublic) {
+		return ErrSelfTestFailed
+	}
+	defer wipeBytes(priv)
+
+	// Signing
+	if s, err := b.Sign(priv, nil); err != nil || !bytes.Equal(s, sig) {
+		return ErrSelfTestFailed
+	}
+
+	// Verification, including negative tests.
+	if !b.Verify(rawPublic, nil, sig) {
+		return ErrSelfTestFailed
+	}
+	bad := append([]byte{}, sig...)
+	bad[0] ^= 0x01
+	if b.Verify(rawPublic, nil, bad) || b.Verify(rawPublic, []byte("x"), sig) {
+		return ErrSelfTestFailed
+	}
+	return nil
+}
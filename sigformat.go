@@ -0,0 +1,103 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// SignatureFormat is an encoding of a signature detected by DecodeSignature.
+type SignatureFormat uint8
+
+const (
+	SignatureRaw SignatureFormat = iota
+	SignatureBase64
+	SignatureBase64URL
+	SignatureBase32
+	SignatureHex
+)
+
+func (f SignatureFormat) String() string {
+	switch f {
+	case SignatureRaw:
+		return "raw"
+	case SignatureBase64:
+		return "base64"
+	case SignatureBase64URL:
+		return "base64url"
+	case SignatureBase32:
+		return "base32"
+	case SignatureHex:
+		return "hex"
+	}
+	return "unknown"
+}
+
+// DecodeSignature returns the raw ed25519 signature from sig, which may be
+// raw or encoded with base64, base64url, the unpadded base32 used for keys,
+// or hex. The encodings of a 64 byte signature all have different lengths,
+// so detection is unambiguous. Surrounding whitespace is ignored for the
+// encoded forms.
+func DecodeSignature(sig []byte) ([]byte, SignatureFormat, error) {
+	if len(sig) == ed25519.SignatureSize {
+		return sig, SignatureRaw, nil
+	}
+	text := string(bytes.TrimSpace(sig))
+	var raw []byte
+	var format SignatureFormat
+	var err error
+	switch len(text) {
+	case base64.StdEncoding.EncodedLen(ed25519.SignatureSize), base64.RawStdEncoding.EncodedLen(ed25519.SignatureSize):
+		format = SignatureBase64
+		enc := base64.RawStdEncoding
+		if bytes.ContainsAny([]byte(text), "-_") {
+			format, enc = SignatureBase64URL, base64.RawURLEncoding
+		}
+		raw, err = enc.DecodeString(trimPadding(text))
+	case b32Enc.EncodedLen(ed25519.SignatureSize):
+		format = SignatureBase32
+		raw, err = b32Enc.DecodeString(text)
+	case hex.EncodedLen(ed25519.SignatureSize):
+		format = SignatureHex
+		raw, err = hex.DecodeString(text)
+	default:
+		return nil, SignatureRaw, ErrInvalidEncoding
+	}
+	if err != nil || len(raw) != ed25519.SignatureSize {
+		return nil, SignatureRaw, ErrInvalidEncoding
+	}
+	return raw, format, nil
+}
+
+func trimPadding(s string) string {
+	for len(s) > 0 && s[len(s)-1] == '=' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// VerifyFlexible verifies sig over input by public like VerifyWithPolicy,
+// accepting any signature format understood by DecodeSignature. It is meant
+// for integrations where clients disagree on how to encode signatures; new
+// code should settle on raw signatures.
+func VerifyFlexible(vp *VerifyPolicy, public string, input []byte, sig []byte) error {
+	raw, _, err := DecodeSignature(sig)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	return VerifyWithPolicy(vp, public, input, raw)
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"io"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// DigestThreshold is the input size above which callers should sign a
+// digest with SignDigest rather than hold the whole input in memory.
+const DigestThreshold = 1 << 20
+
+// PayloadSizeError is returned when an input exceeds a configured size
+// limit. It matches ErrPayloadTooLarge with errors.Is.
+type PayloadSizeError struct {
+	Size  int
+	Limit int
+}
+
+func (e *PayloadSizeError) Error() string {
+	return fmt.Sprintf("nkeys: payload of %d bytes exceeds limit of %d bytes, use SignDigest for large inputs", e.Size, e.Limit)
+}
+
+// Is reports whether target is ErrPayloadTooLarge.
+func (e *PayloadSizeError) Is(target error) bool {
+	return target == ErrPayloadTooLarge
+}
+
+func checkPayloadSize(n, limit int) error {
+	if limit > 0 && n > limit {
+		return &PayloadSizeError{Size: n, Limit: limit}
+	}
+	return nil
+}
+
+// SignLimited signs input with kp unless it is longer than limit bytes, in
+// which case a *PayloadSizeError is returned. A limit of zero or less uses
+// DigestThreshold. Use it where input comes from untrusted callers; use
+// WithPolicy to apply a limit to every call on a KeyPair.
+func SignLimited(kp KeyPair, input []byte, limit int) ([]byte, error) {
+	if limit <= 0 {
+		limit = DigestThreshold
+	}
+	if err := checkPayloadSize(len(input), limit); err != nil {
+		return nil, err
+	}
+	return kp.Sign(input)
+}
+
+// SignDigest signs the SHA-512 digest of everything read from r, so that
+// inputs of any size can be signed without buffering them. The signature
+// only verifies with VerifyDigest.
+func SignDigest(kp KeyPair, r io.Reader) ([]byte, error) {
+	input, err := digestSignedBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	return kp.Sign(input)
+}
+
+// VerifyDigest verifies a signature made by SignDigest over the contents of
+// r and applies vp to the signer. vp may be nil. The MaxPayload of vp does
+// not apply since r is never buffered.
+func VerifyDigest(vp *VerifyPolicy, public string, r io.Reader, sig []byte) error {
+	input, err := digestSignedBytes(r)
+	if err != nil {
+		return err
+	}
+	if vp != nil && vp.MaxPayload > 0 {
+		unlimited := *vp
+		unlimited.MaxPayload = 0
+		vp = &unlimited
+	}
+	return VerifyWithPolicy(vp, public, input, sig)
+}
+
+func digestSignedBytes(r io.Reader) ([]byte, error) {
+	h := sha512.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return nil, err
+	}
+	e := canonical.NewEncoder("nkeys.Digest")
+	e.Text("sha512")
+	e.Int64(n)
+	e.Blob(h.Sum(nil))
+	return e.Bytes(), nil
+}
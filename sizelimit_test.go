@@ -0,0 +1,60 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPayloadLimits(t *testing.T) {
+	user, _ := CreateUser()
+	public, _ := user.PublicKey()
+	if _, err := SignLimited(user, make([]byte, 16), 16); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err := SignLimited(user, make([]byte, 17), 16)
+	var se *PayloadSizeError
+	if !errors.As(err, &se) || se.Size != 17 || se.Limit != 16 || !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("Expected a PayloadSizeError, got %v", err)
+	}
+	if code := ErrorCode(err); code != "NKEYS-0501" {
+		t.Fatalf("Expected NKEYS-0501, got %q", code)
+	}
+	if isTransient(err) {
+		t.Fatalf("Expected %v to be permanent", err)
+	}
+	if _, err := SignLimited(user, make([]byte, DigestThreshold+1), 0); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("Expected %v, got %v", ErrPayloadTooLarge, err)
+	}
+
+	sig, _ := user.Sign(make([]byte, 17))
+	vp := &VerifyPolicy{MaxPayload: 16}
+	if err := VerifyWithPolicy(vp, public, make([]byte, 17), sig); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("Expected %v, got %v", ErrPayloadTooLarge, err)
+	}
+
+	big := bytes.Repeat([]byte("x"), 3*DigestThreshold)
+	sig, err = SignDigest(user, bytes.NewReader(big))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := VerifyDigest(vp, public, bytes.NewReader(big), sig); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := VerifyDigest(nil, public, bytes.NewReader(big[1:]), sig); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+}
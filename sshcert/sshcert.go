@@ -0,0 +1,284 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sshcert mints and verifies OpenSSH certificates whose certificate
+// authority is an nkey, so that a fleet that already trusts an operator or
+// account key can grant SSH access from the same root of trust. Only
+// ed25519 nkeys can be authorities; their SSH form is an ssh-ed25519 key.
+package sshcert
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+
+	"github.com/nats-io/nkeys"
+	"golang.org/x/crypto/ssh"
+)
+
+// Errors
+const (
+	ErrUnsupportedKey    = sshError("sshcert: only ed25519 nkeys can be SSH keys")
+	ErrInvalidOptions    = sshError("sshcert: certificate needs a key ID, principals and a validity period")
+	ErrUnknownAuthority  = sshError("sshcert: certificate is not signed by a trusted nkey")
+	ErrWrongCertType     = sshError("sshcert: wrong certificate type")
+	ErrPrincipalMismatch = sshError("sshcert: principal is not listed in the certificate")
+	ErrCriticalOption    = sshError("sshcert: certificate has an unsupported critical option")
+)
+
+type sshError string
+
+func (e sshError) Error() string {
+	return string(e)
+}
+
+// PublicKey returns the SSH form of an encoded ed25519 nkey public key.
+func PublicKey(public string) (ssh.PublicKey, error) {
+	pre := nkeys.Prefix(public)
+	if nkeys.AlgorithmOf(pre) != nkeys.AlgorithmEd25519 {
+		return nil, ErrUnsupportedKey
+	}
+	raw, err := nkeys.Decode(pre, []byte(public))
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewPublicKey(ed25519.PublicKey(raw))
+}
+
+// AuthorizedKey returns public as a line for authorized_keys files and the
+// TrustedUserCAKeys option of sshd, ending in a newline.
+func AuthorizedKey(public string) (string, error) {
+	pk, err := PublicKey(public)
+	if err != nil {
+		return "", err
+	}
+	return string(ssh.MarshalAuthorizedKey(pk)), nil
+}
+
+// KnownHostsAuthority returns a known_hosts line that trusts public to
+// certify the keys of hosts matching pattern, e.g. "*.example.com".
+func KnownHostsAuthority(public string, pattern string) (string, error) {
+	line, err := AuthorizedKey(public)
+	if err != nil {
+		return "", err
+	}
+	return "@cert-authority " + pattern + " " + line, nil
+}
+
+type signer struct {
+	kp  nkeys.KeyPair
+	pub ssh.PublicKey
+}
+
+// NewSigner returns an ssh.Signer that signs with kp, for use with
+// ssh.Certificate.SignCert or as an SSH client or host key.
+func NewSigner(kp nkeys.KeyPair) (ssh.Signer, error) {
+	public, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := PublicKey(public)
+	if err != nil {
+		return nil, err
+	}
+	return &signer{kp, pub}, nil
+}
+
+func (s *signer) PublicKey() ssh.PublicKey {
+	return s.pub
+}
+
+func (s *signer) Sign(_ io.Reader, data []byte) (*ssh.Signature, error) {
+	sig, err := s.kp.Sign(data)
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.Signature{Format: ssh.KeyAlgoED25519, Blob: sig}, nil
+}
+
+// Options describe a certificate.
+type Options struct {
+	// CertType is ssh.UserCert or ssh.HostCert.
+	CertType uint32
+	// KeyID identifies the certificate in server logs.
+	KeyID string
+	// Principals are the user names, or the host names for host
+	// certificates, the certificate is valid for.
+	Principals  []string
+	ValidAfter  time.Time
+	ValidBefore time.Time
+	// Serial defaults to a random number.
+	Serial uint64
+	// Permissions holds critical options and extensions. User certificates
+	// without extensions get the defaults of ssh-keygen.
+	Permissions ssh.Permissions
+}
+
+// defaultUserExtensions are the extensions ssh-keygen grants by default.
+var defaultUserExtensions = []string{
+	"permit-X11-forwarding",
+	"permit-agent-forwarding",
+	"permit-port-forwarding",
+	"permit-pty",
+	"permit-user-rc",
+}
+
+// Mint returns a certificate for key signed by the nkey ca.
+func Mint(ca nkeys.KeyPair, key ssh.PublicKey, opts Options) (*ssh.Certificate, error) {
+	if opts.CertType != ssh.UserCert && opts.CertType != ssh.HostCert {
+		return nil, ErrWrongCertType
+	}
+	if opts.KeyID == "" || len(opts.Principals) == 0 ||
+		opts.ValidAfter.IsZero() || !opts.ValidAfter.Before(opts.ValidBefore) {
+		return nil, ErrInvalidOptions
+	}
+	s, err := NewSigner(ca)
+	if err != nil {
+		return nil, err
+	}
+	serial := opts.Serial
+	if serial == 0 {
+		var b [8]byte
+		if _, err := io.ReadFull(rand.Reader, b[:]); err != nil {
+			return nil, err
+		}
+		serial = binary.BigEndian.Uint64(b[:])
+	}
+	perms := opts.Permissions
+	if opts.CertType == ssh.UserCert && perms.Extensions == nil {
+		perms.Extensions = make(map[string]string)
+		for _, ext := range defaultUserExtensions {
+			perms.Extensions[ext] = ""
+		}
+	}
+	cert := &ssh.Certificate{
+		Key:             key,
+		Serial:          serial,
+		CertType:        opts.CertType,
+		KeyId:           opts.KeyID,
+		ValidPrincipals: append([]string(nil), opts.Principals...),
+		ValidAfter:      uint64(opts.ValidAfter.Unix()),
+		ValidBefore:     uint64(opts.ValidBefore.Unix()),
+		Permissions:     perms,
+	}
+	if err := cert.SignCert(rand.Reader, s); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// Verify checks that cert is of certType, was signed by one of the
+// authorities, passes vp, is currently valid and lists principal. The
+// authority must also pass the key rules of vp, such as revocation, and
+// vp.Clock and vp.MaxClockSkew apply to the validity period. Verify cannot
+// enforce critical options such as force-command or source-address, so
+// certificates carrying any are rejected. It returns the authority that
+// signed cert.
+func Verify(cert *ssh.Certificate, certType uint32, principal string, vp *nkeys.VerifyPolicy, authorities ...string) (string, error) {
+	if cert.CertType != certType {
+		return "", ErrWrongCertType
+	}
+	if cert.SignatureKey == nil || cert.Signature == nil {
+		return "", ErrUnknownAuthority
+	}
+	signer := string(cert.SignatureKey.Marshal())
+	authority := ""
+	for _, a := range authorities {
+		if pk, err := PublicKey(a); err == nil && string(pk.Marshal()) == signer {
+			authority = a
+			break
+		}
+	}
+	if authority == "" {
+		return "", ErrUnknownAuthority
+	}
+	if err := vp.CheckKey(authority); err != nil {
+		return "", err
+	}
+	if err := cert.SignatureKey.Verify(signedBytes(cert), cert.Signature); err != nil {
+		return "", nkeys.ErrInvalidSignature
+	}
+	var before time.Time
+	if cert.ValidBefore != ssh.CertTimeInfinity {
+		before = time.Unix(int64(cert.ValidBefore), 0)
+	}
+	if err := vp.CheckValidity(time.Unix(int64(cert.ValidAfter), 0), before); err != nil {
+		return "", err
+	}
+	if len(cert.CriticalOptions) > 0 {
+		return "", ErrCriticalOption
+	}
+	for _, p := range cert.ValidPrincipals {
+		if p == principal {
+			return authority, nil
+		}
+	}
+	return "", ErrPrincipalMismatch
+}
+
+// signedBytes returns the part of cert covered by its signature, the wire
+// format without the trailing signature.
+func signedBytes(cert *ssh.Certificate) []byte {
+	c := *cert
+	c.Signature = nil
+	out := c.Marshal()
+	// Drop the length of the empty signature.
+	return out[:len(out)-4]
+}
+
+// UserCertChecker returns an ssh.CertChecker for SSH servers that accepts
+// user certificates signed by the given nkeys, suitable as the basis of
+// ssh.ServerConfig.PublicKeyCallback via its Authenticate method.
+func UserCertChecker(authorities ...string) (*ssh.CertChecker, error) {
+	trusted, err := authoritySet(authorities)
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return trusted[string(auth.Marshal())]
+		},
+	}, nil
+}
+
+// HostKeyCallback returns an ssh.HostKeyCallback for SSH clients that
+// accepts hosts presenting certificates signed by the given nkeys.
+func HostKeyCallback(authorities ...string) (ssh.HostKeyCallback, error) {
+	trusted, err := authoritySet(authorities)
+	if err != nil {
+		return nil, err
+	}
+	c := &ssh.CertChecker{
+		IsHostAuthority: func(auth ssh.PublicKey, _ string) bool {
+			return trusted[string(auth.Marshal())]
+		},
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return c.CheckHostKey(hostname, remote, key)
+	}, nil
+}
+
+func authoritySet(authorities []string) (map[string]bool, error) {
+	trusted := make(map[string]bool, len(authorities))
+	for _, a := range authorities {
+		pk, err := PublicKey(a)
+		if err != nil {
+			return nil, err
+		}
+		trusted[string(pk.Marshal())] = true
+	}
+	return trusted, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshcert
+
+import (
+	"crypto/ed25519"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nkeys"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestMintAndVerify(t *testing.T) {
+	ca, _ := nkeys.CreateOperator()
+	capk, _ := ca.PublicKey()
+	other, _ := nkeys.CreateAccount()
+	otherpk, _ := other.PublicKey()
+	edpub, _, _ := ed25519.GenerateKey(nil)
+	key, _ := ssh.NewPublicKey(edpub)
+
+	now := time.Unix(1700000000, 0)
+	vp := &nkeys.VerifyPolicy{Clock: nkeys.ClockFunc(func() time.Time { return now })}
+	opts := Options{
+		CertType:    ssh.UserCert,
+		KeyID:       "alice@laptop",
+		Principals:  []string{"alice"},
+		ValidAfter:  now.Add(-time.Minute),
+		ValidBefore: now.Add(time.Hour),
+	}
+	cert, err := Mint(ca, key, opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := cert.Permissions.Extensions["permit-pty"]; !ok {
+		t.Fatalf("Expected default extensions, got %v", cert.Permissions.Extensions)
+	}
+	got, err := Verify(cert, ssh.UserCert, "alice", vp, otherpk, capk)
+	if err != nil || got != capk {
+		t.Fatalf("Expected %v, got %v %v", capk, got, err)
+	}
+
+	// The certificate survives the authorized key encoding.
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey(ssh.MarshalAuthorizedKey(cert))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := Verify(parsed.(*ssh.Certificate), ssh.UserCert, "alice", vp, capk); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		cert      func() *ssh.Certificate
+		certType  uint32
+		principal string
+		vp        *nkeys.VerifyPolicy
+		want      error
+	}{
+		{"principal", func() *ssh.Certificate { return cert }, ssh.UserCert, "bob", vp, ErrPrincipalMismatch},
+		{"type", func() *ssh.Certificate { return cert }, ssh.HostCert, "alice", vp, ErrWrongCertType},
+		{"expired", func() *ssh.Certificate { return cert }, ssh.UserCert, "alice",
+			&nkeys.VerifyPolicy{Clock: nkeys.ClockFunc(func() time.Time { return now.Add(2 * time.Hour) })}, nkeys.ErrExpired},
+		{"tampered", func() *ssh.Certificate {
+			c := *cert
+			c.ValidPrincipals = []string{"alice", "root"}
+			return &c
+		}, ssh.UserCert, "root", vp, nkeys.ErrInvalidSignature},
+		{"untrusted", func() *ssh.Certificate { return cert }, ssh.UserCert, "alice",
+			&nkeys.VerifyPolicy{AllowedTypes: []nkeys.PrefixByte{nkeys.PrefixByteAccount}}, nkeys.ErrKeyTypeNotAllowed},
+	} {
+		if _, err := Verify(tc.cert(), tc.certType, tc.principal, tc.vp, capk); err != tc.want {
+			t.Fatalf("%s: Expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+	opts.Permissions.CriticalOptions = map[string]string{"force-command": "/bin/true"}
+	forced, _ := Mint(ca, key, opts)
+	if _, err := Verify(forced, ssh.UserCert, "alice", vp, capk); err != ErrCriticalOption {
+		t.Fatalf("Expected %v, got %v", ErrCriticalOption, err)
+	}
+	if _, err := Verify(cert, ssh.UserCert, "alice", vp, otherpk); err != ErrUnknownAuthority {
+		t.Fatalf("Expected %v, got %v", ErrUnknownAuthority, err)
+	}
+
+	opts.Principals = nil
+	if _, err := Mint(ca, key, opts); err != ErrInvalidOptions {
+		t.Fatalf("Expected %v, got %v", ErrInvalidOptions, err)
+	}
+	curve, _ := nkeys.CreateCurveKeys()
+	if _, err := NewSigner(curve); err != ErrUnsupportedKey {
+		t.Fatalf("Expected %v, got %v", ErrUnsupportedKey, err)
+	}
+}
+
+func TestCheckers(t *testing.T) {
+	ca, _ := nkeys.CreateOperator()
+	capk, _ := ca.PublicKey()
+	edpub, _, _ := ed25519.GenerateKey(nil)
+	key, _ := ssh.NewPublicKey(edpub)
+	opts := Options{
+		CertType:    ssh.HostCert,
+		KeyID:       "host",
+		Principals:  []string{"host.example.com"},
+		ValidAfter:  time.Now().Add(-time.Minute),
+		ValidBefore: time.Now().Add(time.Hour),
+	}
+	cert, err := Mint(ca, key, opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	cb, err := HostKeyCallback(capk)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := cb("host.example.com:22", nil, cert); err != nil {
+		t.Fatalf("Expected host to be accepted, got %v", err)
+	}
+	if err := cb("other.example.com:22", nil, cert); err == nil {
+		t.Fatalf("Expected other host to be refused")
+	}
+
+	checker, err := UserCertChecker(capk)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !checker.IsUserAuthority(cert.SignatureKey) {
+		t.Fatalf("Expected the nkey to be a user authority")
+	}
+
+	line, err := KnownHostsAuthority(capk, "*.example.com")
+	if err != nil || !strings.HasPrefix(line, "@cert-authority *.example.com ssh-ed25519 ") {
+		t.Fatalf("Expected a known_hosts line, got %q %v", line, err)
+	}
+	_, _, pk, _, _, err := ssh.ParseKnownHosts([]byte(line))
+	if err != nil || string(pk.Marshal()) != string(cert.SignatureKey.Marshal()) {
+		t.Fatalf("Expected the authority key, got %v", err)
+	}
+}
@@ -15,8 +15,11 @@ package nkeys
 
 import (
 	"bytes"
+	"crypto/ed25519"
 	"encoding/base32"
 	"encoding/binary"
+	"io"
+	"strings"
 )
 
 // PrefixByte is a lead byte representing the type.
@@ -84,13 +87,89 @@ func Encode(prefix PrefixByte, src []byte) ([]byte, error) {
 	return buf[:], nil
 }
 
+// EncodeTo will encode a raw key or seed like Encode and write it to w.
+func EncodeTo(w io.Writer, prefix PrefixByte, src []byte) error {
+	if err := checkValidPrefixByte(prefix); err != nil {
+		return err
+	}
+	var head [1]byte
+	head[0] = byte(prefix)
+	crc := crc16Update(crc16(head[:]), src)
+	var tail [2]byte
+	binary.LittleEndian.PutUint16(tail[:], crc)
+
+	enc := base32.NewEncoder(b32Enc, w)
+	if _, err := enc.Write(head[:]); err != nil {
+		return err
+	}
+	if _, err := enc.Write(src); err != nil {
+		return err
+	}
+	if _, err := enc.Write(tail[:]); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// maxStreamKeyLen is the longest encoded key DecodeFrom accepts. It fits
+// encoded private keys, the longest form.
+const maxStreamKeyLen = 128
+
+// DecodeFrom reads one encoded key from r and decodes it like Decode.
+// Leading whitespace is skipped and the key ends at the next whitespace
+// or at EOF. At most one byte past the key is consumed.
+func DecodeFrom(r io.Reader, expectedPrefix PrefixByte) ([]byte, error) {
+	var (
+		buf [maxStreamKeyLen]byte
+		one [1]byte
+		n   int
+	)
+	for {
+		_, err := io.ReadFull(r, one[:])
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if isSpace(one[0]) {
+			if n == 0 {
+				continue
+			}
+			break
+		}
+		if n == len(buf) {
+			return nil, ErrInvalidEncoding
+		}
+		buf[n] = one[0]
+		n++
+	}
+	if n == 0 {
+		return nil, io.EOF
+	}
+	return Decode(expectedPrefix, buf[:n])
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
 // EncodeSeed will encode a raw key with the prefix and then seed prefix and crc16 and then base32 encoded.
-// `src` must be 32 bytes long (ed25519.SeedSize).
+// `src` must be 32 bytes long (ed25519.SeedSize). For ed25519 key types a
+// 64 byte private key (ed25519.PrivateKeySize), the seed followed by its
+// public key, is also accepted; the public half must match the seed or
+// ErrInvalidPrivateKey is returned. Other lengths return ErrInvalidSeedLen.
 func EncodeSeed(public PrefixByte, src []byte) ([]byte, error) {
 	if err := checkValidPublicPrefixByte(public); err != nil {
 		return nil, err
 	}
 
+	if len(src) == ed25519.PrivateKeySize && AlgorithmOf(public) == AlgorithmEd25519 {
+		var err error
+		if src, err = expandedSeed(src); err != nil {
+			return nil, err
+		}
+	}
 	if len(src) != seedLen {
 		return nil, ErrInvalidSeedLen
 	}
@@ -128,8 +207,41 @@ func IsValidEncoding(src []byte) bool {
 	return err == nil
 }
 
+// NormalizeKey will return the canonical form of an encoded key or seed that
+// may have been mangled when copied from chat or email. Surrounding whitespace
+// and quotes are trimmed, the key is uppercased and base32 padding is removed.
+// An error is returned if the result is not a valid encoding.
+func NormalizeKey(s string) (string, error) {
+	s = normalizeKeyText(s)
+	if _, err := decode([]byte(s)); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+// normalizeKeyText trims whitespace, quotes and padding and uppercases s.
+func normalizeKeyText(s string) string {
+	s = strings.TrimSpace(s)
+	for len(s) >= 2 && isQuote(s[0]) && s[len(s)-1] == s[0] {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	return strings.ToUpper(strings.TrimRight(s, "="))
+}
+
+func isQuote(c byte) bool {
+	return c == '"' || c == '\'' || c == '`'
+}
+
 // decode will decode the base32 and check crc16 and the prefix for validity.
 func decode(src []byte) ([]byte, error) {
+	raw, err := decodeRaw(src)
+	if err != nil {
+		countDecodeFailure()
+	}
+	return raw, err
+}
+
+func decodeRaw(src []byte) ([]byte, error) {
 	raw := make([]byte, b32Enc.DecodedLen(len(src)))
 	n, err := b32Enc.Decode(raw, src)
 	if err != nil {
@@ -0,0 +1,142 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"time"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+	"github.com/nats-io/nkeys/internal/sigtoken"
+)
+
+// MaxSubkeyTTL bounds the lifetime of subkeys.
+const MaxSubkeyTTL = sigtoken.MaxTTL
+
+// SubkeyIssuance records that an account key issued a short-lived user
+// subkey for a scope, such as "ci:release/myapp". CI systems receive the
+// subkey seed and the issuance record, so the account seed never leaves
+// the issuer. Every issuance has a unique ID for audit logs.
+type SubkeyIssuance struct {
+	ID       string    `json:"jti"`
+	Issuer   string    `json:"iss"`
+	Subkey   string    `json:"sub"`
+	Scope    string    `json:"scope"`
+	IssuedAt time.Time `json:"iat"`
+	Expires  time.Time `json:"exp"`
+}
+
+func (si *SubkeyIssuance) signedBytes() []byte {
+	e := canonical.NewEncoder("nkeys.SubkeyIssuance")
+	e.Text(si.ID)
+	e.Text(si.Issuer)
+	e.Text(si.Subkey)
+	e.Text(si.Scope)
+	e.Time(si.IssuedAt)
+	e.Time(si.Expires)
+	return e.Bytes()
+}
+
+// IssueSubkey creates a user subkey valid for ttl from the time of clock,
+// which may be nil for the system clock, within scope and returns it with
+// its issuance record, signed by the account key. The record is the
+// base64url encoded JSON SubkeyIssuance and signature separated by a '.',
+// and is published alongside everything the subkey signs.
+func IssueSubkey(account KeyPair, scope string, ttl time.Duration, clock Clock) (KeyPair, string, error) {
+	issuer, err := account.PublicKey()
+	if err != nil {
+		return nil, "", err
+	}
+	if Prefix(issuer) != PrefixByteAccount {
+		return nil, "", ErrIncompatibleKey
+	}
+	if scope == "" {
+		return nil, "", ErrScopeNotAllowed
+	}
+	iat, exp, ok := sigtoken.Window(ClockOrSystem(clock).Now(), ttl, MaxSubkeyTTL)
+	if !ok {
+		return nil, "", ErrInvalidLifetime
+	}
+	id, err := sigtoken.NewID(nil)
+	if err != nil {
+		return nil, "", err
+	}
+	sub, err := CreateUser()
+	if err != nil {
+		return nil, "", err
+	}
+	subPub, err := sub.PublicKey()
+	if err != nil {
+		return nil, "", err
+	}
+	si := SubkeyIssuance{
+		ID:       id,
+		Issuer:   issuer,
+		Subkey:   subPub,
+		Scope:    scope,
+		IssuedAt: iat,
+		Expires:  exp,
+	}
+	sig, err := account.Sign(si.signedBytes())
+	if err != nil {
+		sub.Wipe()
+		return nil, "", err
+	}
+	payload, err := sigtoken.Payload(si)
+	if err != nil {
+		sub.Wipe()
+		return nil, "", err
+	}
+	return sub, sigtoken.Join(payload, sig), nil
+}
+
+// ParseSubkeyIssuance verifies an issuance record made by IssueSubkey and
+// applies the policy to the issuing account key. It does not check the
+// validity window, see VerifySubkeySignature.
+func ParseSubkeyIssuance(issuance string, vp *VerifyPolicy) (*SubkeyIssuance, error) {
+	var si SubkeyIssuance
+	_, sig, ok := sigtoken.Split(issuance, &si)
+	if !ok {
+		return nil, ErrInvalidSubkeyIssuance
+	}
+	if Prefix(si.Issuer) != PrefixByteAccount || !IsValidPublicUserKey(si.Subkey) ||
+		si.Expires.Sub(si.IssuedAt) > MaxSubkeyTTL {
+		return nil, ErrInvalidSubkeyIssuance
+	}
+	if err := VerifyWithPolicy(vp, si.Issuer, si.signedBytes(), sig); err != nil {
+		return nil, err
+	}
+	return &si, nil
+}
+
+// VerifySubkeySignature checks that sig is a signature of input by the
+// subkey of the issuance record, that the record was signed by an account
+// the policy accepts, that it was issued for scope, and that it is valid
+// at the time of the policy's clock. To verify artifacts after the subkey
+// expired, set the clock to a trusted time of signing.
+func VerifySubkeySignature(issuance string, scope string, input, sig []byte, vp *VerifyPolicy) (*SubkeyIssuance, error) {
+	si, err := ParseSubkeyIssuance(issuance, vp)
+	if err != nil {
+		return nil, err
+	}
+	if si.Scope != scope {
+		return nil, ErrScopeNotAllowed
+	}
+	if err := vp.CheckValidity(si.IssuedAt, si.Expires); err != nil {
+		return nil, err
+	}
+	if err := VerifyWithPolicy(nil, si.Subkey, input, sig); err != nil {
+		return nil, err
+	}
+	return si, nil
+}
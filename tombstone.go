@@ -0,0 +1,179 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// tombstoneParents lists the key types that may issue tombstones for keys
+// of another type. Curve keys can not sign, so their tombstones are issued
+// by the ed25519 key that owned them.
+var tombstoneParents = map[PrefixByte][]PrefixByte{
+	PrefixByteUser:    {PrefixByteAccount},
+	PrefixByteAccount: {PrefixByteOperator},
+	PrefixByteServer:  {PrefixByteOperator},
+	PrefixByteCluster: {PrefixByteOperator},
+	PrefixByteCurve:   {PrefixByteOperator, PrefixByteAccount, PrefixByteUser, PrefixByteServer, PrefixByteCluster},
+}
+
+// Tombstone asserts that Key was destroyed at DestroyedAt, as positive
+// evidence for inventory systems. It is issued by the key itself, proving
+// it was still held when the tombstone was made, or by a key of its parent
+// type, such as the account of a user.
+type Tombstone struct {
+	Key         string    `json:"key"`
+	Issuer      string    `json:"iss"`
+	Reason      string    `json:"reason,omitempty"`
+	DestroyedAt time.Time `json:"destroyed_at"`
+	Signature   []byte    `json:"sig"`
+}
+
+func (ts *Tombstone) signedBytes() []byte {
+	e := canonical.NewEncoder("nkeys.Tombstone")
+	e.Text(ts.Key)
+	e.Text(ts.Issuer)
+	e.Text(ts.Reason)
+	e.Time(ts.DestroyedAt)
+	return e.Bytes()
+}
+
+// SelfIssued reports whether the tombstone was signed by the destroyed key.
+func (ts *Tombstone) SelfIssued() bool {
+	return ts.Key == ts.Issuer
+}
+
+// IssueTombstone makes a tombstone for key signed by issuer, which is the
+// key itself or a key of its parent type. A zero time means the time of
+// clock, which may be nil for the system clock. When issuing for the key
+// itself, do so before wiping it.
+func IssueTombstone(issuer KeyPair, key string, reason string, destroyedAt time.Time, clock Clock) (*Tombstone, error) {
+	public, err := issuer.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if !IsValidPublicKey(key) {
+		return nil, ErrInvalidPublicKey
+	}
+	if public != key && !canIssueTombstone(Prefix(public), Prefix(key)) {
+		return nil, ErrIncompatibleKey
+	}
+	if destroyedAt.IsZero() {
+		destroyedAt = ClockOrSystem(clock).Now()
+	}
+	ts := &Tombstone{
+		Key:         key,
+		Issuer:      public,
+		Reason:      reason,
+		DestroyedAt: destroyedAt.UTC().Truncate(time.Second),
+	}
+	if ts.Signature, err = issuer.Sign(ts.signedBytes()); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+func canIssueTombstone(issuer, key PrefixByte) bool {
+	for _, p := range tombstoneParents[key] {
+		if p == issuer {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyTombstone checks the signature of ts and that its issuer may issue
+// tombstones for the key, and applies the policy to the issuer. The key
+// type alone does not prove the issuer is the actual parent of the key, so
+// parent issued tombstones are only accepted when the policy restricts the
+// issuers with Trusted, e.g. a TrustStore, and fail with ErrKeyNotTrusted
+// otherwise. Revocation and rotation checks are not applied to
+// self-issued tombstones, since the destroyed key is expected to be
+// retired.
+func VerifyTombstone(ts *Tombstone, vp *VerifyPolicy) error {
+	return verifyTombstone(ts, vp, "")
+}
+
+// verifyTombstone is VerifyTombstone with parent, when not empty, as the
+// known parent of ts.Key, which then stands in for a trusted policy.
+func verifyTombstone(ts *Tombstone, vp *VerifyPolicy, parent string) error {
+	if !IsValidPublicKey(ts.Key) {
+		return ErrInvalidTombstone
+	}
+	if ts.SelfIssued() {
+		if vp != nil {
+			vp = &VerifyPolicy{AllowedTypes: vp.AllowedTypes, Trusted: vp.Trusted}
+		}
+	} else if !canIssueTombstone(Prefix(ts.Issuer), Prefix(ts.Key)) {
+		return ErrInvalidTombstone
+	} else if parent != "" && ts.Issuer != parent {
+		return ErrKeyNotTrusted
+	} else if parent == "" && (vp == nil || vp.Trusted == nil) {
+		return ErrKeyNotTrusted
+	}
+	return VerifyWithPolicy(vp, ts.Issuer, ts.signedBytes(), ts.Signature)
+}
+
+// Graveyard collects verified tombstones. It implements RevocationChecker,
+// so destroyed keys can be rejected by a VerifyPolicy.
+type Graveyard struct {
+	// Parents maps keys to the public key of their parent. A tombstone
+	// for a listed key is only accepted from the key itself or its
+	// parent, even when the policy has no Trusted checker. Set it before
+	// adding tombstones.
+	Parents map[string]string
+
+	vp *VerifyPolicy
+
+	mu         sync.RWMutex
+	tombstones map[string]*Tombstone
+}
+
+// NewGraveyard returns an empty graveyard verifying tombstones with vp,
+// which may be nil. Without a Trusted policy or Parents only self-issued
+// tombstones are accepted.
+func NewGraveyard(vp *VerifyPolicy) *Graveyard {
+	return &Graveyard{vp: vp, tombstones: make(map[string]*Tombstone)}
+}
+
+// Add verifies ts and records it. A key keeps its earliest tombstone.
+func (g *Graveyard) Add(ts *Tombstone) error {
+	if err := verifyTombstone(ts, g.vp, g.Parents[ts.Key]); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if prev, ok := g.tombstones[ts.Key]; ok && !ts.DestroyedAt.Before(prev.DestroyedAt) {
+		return nil
+	}
+	g.tombstones[ts.Key] = ts
+	return nil
+}
+
+// Tombstone returns the tombstone of public, if any.
+func (g *Graveyard) Tombstone(public string) (*Tombstone, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	ts, ok := g.tombstones[public]
+	return ts, ok
+}
+
+// IsRevoked reports whether public has a tombstone.
+func (g *Graveyard) IsRevoked(public string) (bool, error) {
+	_, ok := g.Tombstone(public)
+	return ok, nil
+}
@@ -0,0 +1,343 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tpm
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+)
+
+// Constants from the TPM 2.0 Library specification, part 2.
+const (
+	stNoSessions = 0x8001
+	stSessions   = 0x8002
+
+	ccCreatePrimary    = 0x00000131
+	ccCreate           = 0x00000153
+	ccLoad             = 0x00000157
+	ccUnseal           = 0x0000015E
+	ccFlushContext     = 0x00000165
+	ccStartAuthSession = 0x00000176
+	ccPolicyPCR        = 0x0000017F
+	ccPolicyGetDigest  = 0x00000189
+
+	rhOwner = 0x40000001
+	rhNull  = 0x40000007
+	rsPW    = 0x40000009
+
+	algAES       = 0x0006
+	algKeyedHash = 0x0008
+	algSHA256    = 0x000B
+	algNull      = 0x0010
+	algECC       = 0x0023
+	algCFB       = 0x0043
+	eccNistP256  = 0x0003
+
+	sessionPolicy = 0x01
+	sessionTrial  = 0x03
+
+	attrContinueSession = 0x01
+
+	// Object attributes.
+	attrFixedTPM            = 1 << 1
+	attrFixedParent         = 1 << 4
+	attrSensitiveDataOrigin = 1 << 5
+	attrUserWithAuth        = 1 << 6
+	attrNoDA                = 1 << 10
+	attrRestricted          = 1 << 16
+	attrDecrypt             = 1 << 17
+
+	headerSize  = 10
+	maxResponse = 4096
+	nonceSize   = 16
+)
+
+// buffer marshals TPM structures, which are big endian.
+type buffer []byte
+
+func (b *buffer) u8(v uint8)     { *b = append(*b, v) }
+func (b *buffer) u16(v uint16)   { *b = binary.BigEndian.AppendUint16(*b, v) }
+func (b *buffer) u32(v uint32)   { *b = binary.BigEndian.AppendUint32(*b, v) }
+func (b *buffer) bytes(v []byte) { *b = append(*b, v...) }
+func (b *buffer) tpm2b(v []byte) { b.u16(uint16(len(v))); b.bytes(v) }
+func (b *buffer) sized(f func(*buffer)) {
+	var inner buffer
+	f(&inner)
+	b.tpm2b(inner)
+}
+
+// reader unmarshals TPM structures.
+type reader struct {
+	b   []byte
+	err bool
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err || len(r.b) < n {
+		r.err = true
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *reader) u32() uint32 {
+	if v := r.next(4); v != nil {
+		return binary.BigEndian.Uint32(v)
+	}
+	return 0
+}
+
+func (r *reader) tpm2b() []byte {
+	v := r.next(2)
+	if v == nil {
+		return nil
+	}
+	return r.next(int(binary.BigEndian.Uint16(v)))
+}
+
+// session is an authorization in a command's authorization area.
+type session struct {
+	handle uint32
+	nonce  []byte
+	attrs  uint8
+}
+
+// passwordSession authorizes with the empty password.
+var passwordSession = session{handle: rsPW, attrs: attrContinueSession}
+
+// run sends a command and returns the response parameters. Response
+// handles, if any, are returned ahead of the parameters.
+func (t *TPM) run(cc uint32, handles []uint32, sessions []session, params buffer) ([]byte, error) {
+	var cmd buffer
+	if len(sessions) > 0 {
+		cmd.u16(stSessions)
+	} else {
+		cmd.u16(stNoSessions)
+	}
+	cmd.u32(0) // size, set below
+	cmd.u32(cc)
+	for _, h := range handles {
+		cmd.u32(h)
+	}
+	if len(sessions) > 0 {
+		var auth buffer
+		for _, s := range sessions {
+			auth.u32(s.handle)
+			auth.tpm2b(s.nonce)
+			auth.u8(s.attrs)
+			auth.tpm2b(nil)
+		}
+		cmd.u32(uint32(len(auth)))
+		cmd.bytes(auth)
+	}
+	cmd.bytes(params)
+	binary.BigEndian.PutUint32(cmd[2:], uint32(len(cmd)))
+
+	_, err := t.rw.Write(cmd)
+	// Commands may carry the seed.
+	wipe(cmd)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]byte, maxResponse)
+	n, err := t.rw.Read(resp)
+	if err != nil {
+		return nil, err
+	}
+	resp = resp[:n]
+	if len(resp) < headerSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if rc := binary.BigEndian.Uint32(resp[6:]); rc != 0 {
+		return nil, &ResponseError{Command: cc, Code: rc}
+	}
+	return resp[headerSize:], nil
+}
+
+// withSessions strips the parameter size of responses to commands with
+// sessions, keeping numHandles response handles in front.
+func withSessions(resp []byte, numHandles int) ([]byte, error) {
+	r := reader{b: resp}
+	handles := r.next(4 * numHandles)
+	size := r.u32()
+	params := r.next(int(size))
+	if r.err {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return append(append([]byte{}, handles...), params...), nil
+}
+
+func (t *TPM) flush(handle uint32) {
+	var p buffer
+	p.u32(handle)
+	t.run(ccFlushContext, nil, nil, p)
+}
+
+// createPrimary creates the standard ECC P-256 storage root key in the
+// owner hierarchy. It is derived from the hierarchy seed, so the same key is
+// recreated every time.
+func (t *TPM) createPrimary() (uint32, error) {
+	var p buffer
+	p.sized(func(b *buffer) { b.tpm2b(nil); b.tpm2b(nil) })
+	p.sized(func(b *buffer) {
+		b.u16(algECC)
+		b.u16(algSHA256)
+		b.u32(attrFixedTPM | attrFixedParent | attrSensitiveDataOrigin | attrUserWithAuth | attrNoDA | attrRestricted | attrDecrypt)
+		b.tpm2b(nil)
+		b.u16(algAES)
+		b.u16(128)
+		b.u16(algCFB)
+		b.u16(algNull)
+		b.u16(eccNistP256)
+		b.u16(algNull)
+		b.tpm2b(nil)
+		b.tpm2b(nil)
+	})
+	p.tpm2b(nil) // outsideInfo
+	p.u32(0)     // creationPCR
+	resp, err := t.run(ccCreatePrimary, []uint32{rhOwner}, []session{passwordSession}, p)
+	if err != nil {
+		return 0, err
+	}
+	r := reader{b: resp}
+	handle := r.u32()
+	if r.err {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return handle, nil
+}
+
+func (t *TPM) startSession(kind uint8) (uint32, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return 0, err
+	}
+	var p buffer
+	p.tpm2b(nonce)
+	p.tpm2b(nil) // encryptedSalt
+	p.u8(kind)
+	p.u16(algNull) // symmetric
+	p.u16(algSHA256)
+	resp, err := t.run(ccStartAuthSession, []uint32{rhNull, rhNull}, nil, p)
+	if err != nil {
+		return 0, err
+	}
+	r := reader{b: resp}
+	handle := r.u32()
+	if r.err {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return handle, nil
+}
+
+func (t *TPM) policyPCR(session uint32, sel []byte) error {
+	var p buffer
+	p.tpm2b(nil) // use the current PCR values
+	p.bytes(sel)
+	_, err := t.run(ccPolicyPCR, []uint32{session}, nil, p)
+	return err
+}
+
+// pcrPolicyDigest computes the policy digest for the current values of the
+// selected PCRs with a trial session.
+func (t *TPM) pcrPolicyDigest(sel []byte) ([]byte, error) {
+	session, err := t.startSession(sessionTrial)
+	if err != nil {
+		return nil, err
+	}
+	defer t.flush(session)
+	if err := t.policyPCR(session, sel); err != nil {
+		return nil, err
+	}
+	resp, err := t.run(ccPolicyGetDigest, []uint32{session}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	r := reader{b: resp}
+	digest := r.tpm2b()
+	if r.err {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return digest, nil
+}
+
+// create makes a sealed data object holding data that can only be unsealed
+// by satisfying policy.
+func (t *TPM) create(parent uint32, data, policy []byte) ([]byte, []byte, error) {
+	var p buffer
+	p.sized(func(b *buffer) { b.tpm2b(nil); b.tpm2b(data) })
+	p.sized(func(b *buffer) {
+		b.u16(algKeyedHash)
+		b.u16(algSHA256)
+		b.u32(attrFixedTPM | attrFixedParent)
+		b.tpm2b(policy)
+		b.u16(algNull)
+		b.tpm2b(nil)
+	})
+	p.tpm2b(nil) // outsideInfo
+	p.u32(0)     // creationPCR
+	resp, err := t.run(ccCreate, []uint32{parent}, []session{passwordSession}, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp, err = withSessions(resp, 0); err != nil {
+		return nil, nil, err
+	}
+	r := reader{b: resp}
+	private, public := r.tpm2b(), r.tpm2b()
+	if r.err {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	return append([]byte{}, private...), append([]byte{}, public...), nil
+}
+
+func (t *TPM) load(parent uint32, private, public []byte) (uint32, error) {
+	var p buffer
+	p.tpm2b(private)
+	p.tpm2b(public)
+	resp, err := t.run(ccLoad, []uint32{parent}, []session{passwordSession}, p)
+	if err != nil {
+		return 0, err
+	}
+	r := reader{b: resp}
+	handle := r.u32()
+	if r.err {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return handle, nil
+}
+
+// unseal returns the data of a sealed object, authorized by a policy
+// session that is closed by the TPM afterwards.
+func (t *TPM) unseal(item, policySession uint32) ([]byte, error) {
+	raw, err := t.run(ccUnseal, []uint32{item}, []session{{handle: policySession}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(raw)
+	resp, err := withSessions(raw, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(resp)
+	r := reader{b: resp}
+	data := r.tpm2b()
+	if r.err {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return append([]byte{}, data...), nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tpm seals nkey seeds to a TPM 2.0 PCR policy, so that they can
+// only be unsealed by the same TPM while the selected PCRs hold the values
+// they had when sealing, i.e. in the same boot state. A stolen disk image
+// therefore does not yield usable server or cluster keys.
+//
+// The package talks to the TPM with raw commands over the kernel's
+// resource manager device and has no dependencies beyond the standard
+// library and nkeys.
+package tpm
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/nats-io/nkeys"
+	"github.com/nats-io/nkeys/internal/canonical"
+)
+
+// Errors
+const (
+	ErrInvalidBlob = tpmError("tpm: invalid sealed seed")
+	ErrInvalidPCR  = tpmError("tpm: invalid PCR selection")
+)
+
+type tpmError string
+
+func (e tpmError) Error() string {
+	return string(e)
+}
+
+// ResponseError is returned when the TPM fails a command, for example
+// because the PCR values no longer match the sealing policy.
+type ResponseError struct {
+	Command uint32
+	Code    uint32
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("tpm: command %#x failed with response code %#x", e.Command, e.Code)
+}
+
+// DefaultDevice is the Linux TPM 2.0 resource manager device.
+const DefaultDevice = "/dev/tpmrm0"
+
+// DefaultPCRs cover the firmware, boot loader and its configuration.
+var DefaultPCRs = []int{0, 2, 4, 7}
+
+// maxPCR is the highest PCR index in the SHA-256 bank.
+const maxPCR = 23
+
+// TPM is a connection to a TPM 2.0.
+type TPM struct {
+	mu sync.Mutex
+	rw io.ReadWriter
+}
+
+// New uses the TPM reachable over rw.
+func New(rw io.ReadWriter) *TPM {
+	return &TPM{rw: rw}
+}
+
+// Open opens a TPM device such as DefaultDevice.
+func Open(path string) (*TPM, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return New(f), nil
+}
+
+// Close closes the device if it is an io.Closer.
+func (t *TPM) Close() error {
+	if c, ok := t.rw.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// SealSeed seals an encoded seed to the current values of the given SHA-256
+// bank PCRs. The result can be stored on disk and unsealed with UnsealSeed.
+func (t *TPM) SealSeed(seed []byte, pcrs []int) ([]byte, error) {
+	if _, _, err := nkeys.DecodeSeed(seed); err != nil {
+		return nil, err
+	}
+	sel, err := pcrSelection(pcrs)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	primary, err := t.createPrimary()
+	if err != nil {
+		return nil, err
+	}
+	defer t.flush(primary)
+	policy, err := t.pcrPolicyDigest(sel)
+	if err != nil {
+		return nil, err
+	}
+	private, public, err := t.create(primary, seed, policy)
+	if err != nil {
+		return nil, err
+	}
+	e := canonical.NewEncoder("tpm.SealedSeed")
+	e.Blob(sel)
+	e.Blob(private)
+	e.Blob(public)
+	return e.Bytes(), nil
+}
+
+// UnsealSeed returns the encoded seed sealed by SealSeed. It fails if the
+// PCR values differ from when the seed was sealed.
+func (t *TPM) UnsealSeed(blob []byte) ([]byte, error) {
+	d := canonical.NewDecoder("tpm.SealedSeed", blob)
+	sel, private, public := d.Blob(), d.Blob(), d.Blob()
+	if err := d.Finish(); err != nil {
+		return nil, ErrInvalidBlob
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	primary, err := t.createPrimary()
+	if err != nil {
+		return nil, err
+	}
+	defer t.flush(primary)
+	item, err := t.load(primary, private, public)
+	if err != nil {
+		return nil, err
+	}
+	defer t.flush(item)
+	session, err := t.startSession(sessionPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.policyPCR(session, sel); err != nil {
+		t.flush(session)
+		return nil, err
+	}
+	// The session is closed by the TPM after Unseal.
+	seed, err := t.unseal(item, session)
+	if err != nil {
+		t.flush(session)
+		return nil, err
+	}
+	if _, _, err := nkeys.DecodeSeed(seed); err != nil {
+		return nil, ErrInvalidBlob
+	}
+	return seed, nil
+}
+
+// UnsealKeyPair unseals the seed and returns its KeyPair.
+func (t *TPM) UnsealKeyPair(blob []byte) (nkeys.KeyPair, error) {
+	seed, err := t.UnsealSeed(blob)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(seed)
+	return nkeys.FromSeed(seed)
+}
+
+func wipe(b []byte) {
+	io.ReadFull(rand.Reader, b)
+}
+
+// pcrSelection returns the TPML_PCR_SELECTION for the SHA-256 bank.
+func pcrSelection(pcrs []int) ([]byte, error) {
+	if len(pcrs) == 0 {
+		return nil, ErrInvalidPCR
+	}
+	var bitmap [3]byte
+	for _, p := range pcrs {
+		if p < 0 || p > maxPCR {
+			return nil, ErrInvalidPCR
+		}
+		bitmap[p/8] |= 1 << (p % 8)
+	}
+	var b buffer
+	b.u32(1)
+	b.u16(algSHA256)
+	b.u8(uint8(len(bitmap)))
+	b.bytes(bitmap[:])
+	return b, nil
+}
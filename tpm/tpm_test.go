@@ -0,0 +1,193 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tpm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nkeys"
+)
+
+const rcPolicyFail = 0x99D
+
+type fakeObject struct {
+	data, policy []byte
+}
+
+// fakeTPM simulates the commands used by the package, including PCR policy
+// evaluation, by parsing them as a real TPM would.
+type fakeTPM struct {
+	pcrs     [maxPCR + 1][32]byte
+	next     uint32
+	sessions map[uint32][]byte
+	objects  map[uint32]fakeObject
+	resp     []byte
+}
+
+func newFakeTPM() *fakeTPM {
+	return &fakeTPM{next: 0x80000000, sessions: map[uint32][]byte{}, objects: map[uint32]fakeObject{}}
+}
+
+func (f *fakeTPM) handle() uint32 {
+	f.next++
+	return f.next
+}
+
+func (f *fakeTPM) Read(p []byte) (int, error) {
+	return copy(p, f.resp), nil
+}
+
+func (f *fakeTPM) Write(cmd []byte) (int, error) {
+	r := reader{b: cmd}
+	tag := binary.BigEndian.Uint16(r.next(2))
+	r.u32()
+	cc := r.u32()
+	numHandles := map[uint32]int{ccStartAuthSession: 2, ccFlushContext: 0}
+	n, ok := numHandles[cc]
+	if !ok {
+		n = 1
+	}
+	var handles []uint32
+	for i := 0; i < n; i++ {
+		handles = append(handles, r.u32())
+	}
+	var authSession uint32
+	if tag == stSessions {
+		auth := reader{b: r.next(int(r.u32()))}
+		authSession = auth.u32()
+	}
+	rc, out := f.exec(cc, handles, authSession, &r)
+	if r.err {
+		rc = 0x1D5 // TPM_RC_INSUFFICIENT
+	}
+	var resp buffer
+	resp.u16(tag)
+	resp.u32(0)
+	resp.u32(rc)
+	if rc == 0 {
+		resp.bytes(out)
+	}
+	binary.BigEndian.PutUint32(resp[2:], uint32(len(resp)))
+	f.resp = resp
+	return len(cmd), nil
+}
+
+func (f *fakeTPM) exec(cc uint32, handles []uint32, auth uint32, r *reader) (uint32, buffer) {
+	var out buffer
+	switch cc {
+	case ccCreatePrimary:
+		out.u32(f.handle())
+	case ccStartAuthSession:
+		h := f.handle()
+		f.sessions[h] = make([]byte, 32)
+		out.u32(h)
+		out.tpm2b(make([]byte, nonceSize))
+	case ccPolicyPCR:
+		r.tpm2b()
+		sel := r.next(10)
+		if r.err {
+			return 0, nil
+		}
+		h := sha256.New()
+		bitmap := sel[7:]
+		for i := 0; i <= maxPCR; i++ {
+			if bitmap[i/8]&(1<<(i%8)) != 0 {
+				h.Write(f.pcrs[i][:])
+			}
+		}
+		var in buffer
+		in.bytes(f.sessions[handles[0]])
+		in.u32(ccPolicyPCR)
+		in.bytes(sel)
+		in.bytes(h.Sum(nil))
+		sum := sha256.Sum256(in)
+		f.sessions[handles[0]] = sum[:]
+	case ccPolicyGetDigest:
+		out.tpm2b(f.sessions[handles[0]])
+	case ccFlushContext:
+		delete(f.sessions, r.u32())
+	case ccCreate:
+		sensitive := reader{b: r.tpm2b()}
+		sensitive.tpm2b()
+		data := sensitive.tpm2b()
+		public := r.tpm2b()
+		var params buffer
+		params.tpm2b(data)
+		params.tpm2b(public)
+		out.u32(uint32(len(params)))
+		out.bytes(params)
+	case ccLoad:
+		data := r.tpm2b()
+		public := reader{b: r.tpm2b()}
+		public.next(8)
+		policy := public.tpm2b()
+		h := f.handle()
+		// The command buffer is wiped after it is sent.
+		f.objects[h] = fakeObject{append([]byte{}, data...), append([]byte{}, policy...)}
+		out.u32(h)
+	case ccUnseal:
+		obj := f.objects[handles[0]]
+		if !bytes.Equal(f.sessions[auth], obj.policy) {
+			return rcPolicyFail, nil
+		}
+		delete(f.sessions, auth)
+		var params buffer
+		params.tpm2b(obj.data)
+		out.u32(uint32(len(params)))
+		out.bytes(params)
+	}
+	return 0, out
+}
+
+func TestSealSeed(t *testing.T) {
+	fake := newFakeTPM()
+	tpm := New(fake)
+	server, _ := nkeys.CreateServer()
+	seed, _ := server.Seed()
+
+	blob, err := tpm.SealSeed(seed, DefaultPCRs)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	kp, err := tpm.UnsealKeyPair(blob)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	spk, _ := server.PublicKey()
+	if pk, _ := kp.PublicKey(); pk != spk {
+		t.Fatalf("Expected %q, got %q", spk, pk)
+	}
+	if len(fake.sessions) != 0 {
+		t.Fatalf("Expected all sessions to be closed, got %d", len(fake.sessions))
+	}
+
+	// A different boot state changes the PCRs.
+	fake.pcrs[7][0] = 1
+	_, err = tpm.UnsealSeed(blob)
+	var rerr *ResponseError
+	if !errors.As(err, &rerr) || rerr.Code != rcPolicyFail {
+		t.Fatalf("Expected a policy failure, got %v", err)
+	}
+
+	if _, err := tpm.SealSeed(seed, []int{24}); err != ErrInvalidPCR {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPCR, err)
+	}
+	if _, err := tpm.UnsealSeed([]byte("garbage")); err != ErrInvalidBlob {
+		t.Fatalf("Expected %v, got %v", ErrInvalidBlob, err)
+	}
+}
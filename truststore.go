@@ -0,0 +1,199 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTrustSourceSize bounds the size of a trust store source.
+const maxTrustSourceSize = 1 << 20
+
+// TrustChecker reports whether a public key is trusted.
+type TrustChecker interface {
+	IsTrusted(public string) bool
+}
+
+// TrustSourceError is returned when a trust store source can not be loaded.
+type TrustSourceError struct {
+	Source string
+	Err    error
+}
+
+func (e *TrustSourceError) Error() string {
+	return fmt.Sprintf("nkeys: trust source %q: %v", e.Source, e.Err)
+}
+
+func (e *TrustSourceError) Unwrap() error {
+	return e.Err
+}
+
+// TrustStore holds the trusted operator and account public keys loaded from
+// files, directories of key records or https URLs. File and URL sources
+// list one key per line; blank lines and lines starting with '#' are
+// ignored. Directories are read with ImportKeyRecords. Reload swaps the
+// whole set at once, so verifications never see a partially loaded store.
+type TrustStore struct {
+	sources []string
+
+	// Client fetches URL sources. Defaults to a client with a 10s timeout.
+	Client *http.Client
+
+	mu   sync.RWMutex
+	keys map[string]struct{}
+}
+
+// NewTrustStore loads the keys in sources.
+func NewTrustStore(sources ...string) (*TrustStore, error) {
+	ts := &TrustStore{sources: sources}
+	if err := ts.Reload(); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// Reload re-reads every source. On error the previous keys are kept and a
+// *TrustSourceError is returned.
+func (ts *TrustStore) Reload() error {
+	keys := make(map[string]struct{})
+	for _, src := range ts.sources {
+		if err := ts.load(src, keys); err != nil {
+			return &TrustSourceError{Source: src, Err: err}
+		}
+	}
+	ts.mu.Lock()
+	ts.keys = keys
+	ts.mu.Unlock()
+	return nil
+}
+
+func (ts *TrustStore) load(src string, keys map[string]struct{}) error {
+	if fi, err := os.Stat(src); err == nil && fi.IsDir() {
+		return loadKeyRecords(src, keys)
+	}
+	data, err := ts.read(src)
+	if err != nil {
+		return err
+	}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if !IsValidPublicOperatorKey(line) && !IsValidPublicAccountKey(line) {
+			return ErrInvalidPublicKey
+		}
+		keys[line] = struct{}{}
+	}
+	return s.Err()
+}
+
+// loadKeyRecords adds the operator and account keys of the records in dir.
+func loadKeyRecords(dir string, keys map[string]struct{}) error {
+	records, err := ImportKeyRecords(dir)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		if !IsValidPublicOperatorKey(r.PublicKey) && !IsValidPublicAccountKey(r.PublicKey) {
+			return ErrInvalidPublicKey
+		}
+		keys[r.PublicKey] = struct{}{}
+	}
+	return nil
+}
+
+// read returns the contents of a file or https source. Plain http sources
+// are rejected since anyone on the path could add keys to the store.
+func (ts *TrustStore) read(src string) ([]byte, error) {
+	if strings.HasPrefix(src, "http://") {
+		return nil, ErrInsecureTrustSource
+	}
+	if !strings.HasPrefix(src, "https://") {
+		return os.ReadFile(src)
+	}
+	client := ts.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status %s", ErrTrustSourceUnavailable, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTrustSourceSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxTrustSourceSize {
+		return nil, ErrTrustSourceTooLarge
+	}
+	return data, nil
+}
+
+// IsTrusted reports whether public is in the store.
+func (ts *TrustStore) IsTrusted(public string) bool {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	_, ok := ts.keys[public]
+	return ok
+}
+
+// Keys returns the trusted keys in lexical order.
+func (ts *TrustStore) Keys() []string {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	keys := make([]string, 0, len(ts.keys))
+	for k := range ts.keys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Watch reloads the store every interval until the returned function is
+// called. Reload errors are passed to onError, which may be nil, and the
+// previous keys stay active.
+func (ts *TrustStore) Watch(interval time.Duration, onError func(error)) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := ts.Reload(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/ed25519"
+	"sync"
+	"time"
+)
+
+// uniformDummyKey is verified against when the public key can not be
+// decoded, so that malformed keys take as long as bad signatures.
+var uniformDummyKey = make(ed25519.PublicKey, ed25519.PublicKeySize)
+
+// UniformVerifier verifies signatures for services exposed to untrusted
+// callers. Every failure, whether caused by a bad prefix, a bad checksum or
+// a bad signature, is reported as ErrVerificationFailed after the same
+// amount of work, so that error responses can not be used as an oracle.
+// The zero value is ready to use.
+type UniformVerifier struct {
+	// MinDuration, when set, pads every call to at least this long to hide
+	// remaining timing differences.
+	MinDuration time.Duration
+	// MaxFailures, when set, limits the number of failures per Window.
+	// Once exceeded all calls fail with ErrTooManyFailures until the
+	// window has passed.
+	MaxFailures int
+	Window      time.Duration
+	// Clock defaults to SystemClock.
+	Clock Clock
+
+	mu          sync.Mutex
+	windowStart time.Time
+	failures    int
+}
+
+// Verify checks that sig is a signature of input by public.
+func (u *UniformVerifier) Verify(public string, input []byte, sig []byte) error {
+	clock := ClockOrSystem(u.Clock)
+	start := clock.Now()
+	if u.MinDuration > 0 {
+		defer func() {
+			if d := u.MinDuration - clock.Now().Sub(start); d > 0 {
+				time.Sleep(d)
+			}
+		}()
+	}
+	if u.limited(start) {
+		return ErrTooManyFailures
+	}
+
+	key := uniformDummyKey
+	valid := false
+	if raw, err := decode([]byte(public)); err == nil && len(raw) == 1+ed25519.PublicKeySize &&
+		AlgorithmOf(PrefixByte(raw[0])) == AlgorithmEd25519 && PrefixByte(raw[0]) != PrefixBytePrivate {
+		key, valid = raw[1:], true
+	}
+	// Verify runs even for malformed keys, and with a well formed signature
+	// length, so every failure path does the same work.
+	var padded [ed25519.SignatureSize]byte
+	copy(padded[:], sig)
+	ok := currentBackend().Verify(key, input, padded[:])
+	if valid && ok && len(sig) == ed25519.SignatureSize {
+		return nil
+	}
+	u.fail()
+	return ErrVerificationFailed
+}
+
+func (u *UniformVerifier) limited(now time.Time) bool {
+	if u.MaxFailures <= 0 {
+		return false
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if now.Sub(u.windowStart) >= u.Window {
+		u.windowStart, u.failures = now, 0
+	}
+	return u.failures >= u.MaxFailures
+}
+
+func (u *UniformVerifier) fail() {
+	if u.MaxFailures <= 0 {
+		return
+	}
+	u.mu.Lock()
+	u.failures++
+	u.mu.Unlock()
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package verify decodes nkey public keys and verifies signatures. It only
+// depends on the standard library and is meant for components that never
+// sign, where binary size and attack surface matter.
+package verify
+
+import (
+	"crypto/ed25519"
+	"encoding/base32"
+)
+
+// Errors
+const (
+	ErrInvalidEncoding  = verifyError("nkeys: invalid encoded key")
+	ErrInvalidChecksum  = verifyError("nkeys: invalid checksum")
+	ErrInvalidPublicKey = verifyError("nkeys: invalid public key")
+	ErrInvalidSignature = verifyError("nkeys: signature verification failed")
+)
+
+type verifyError string
+
+func (e verifyError) Error() string {
+	return string(e)
+}
+
+// Prefix bytes of the public key types that can verify signatures.
+const (
+	PrefixByteOperator byte = 14 << 3 // Base32-encodes to 'O...'
+	PrefixByteServer   byte = 13 << 3 // Base32-encodes to 'N...'
+	PrefixByteCluster  byte = 2 << 3  // Base32-encodes to 'C...'
+	PrefixByteAccount  byte = 0       // Base32-encodes to 'A...'
+	PrefixByteUser     byte = 20 << 3 // Base32-encodes to 'U...'
+)
+
+// encodedLen is the length of an encoded public key: base32 of the prefix
+// byte, the 32 byte key and the 2 byte checksum.
+const encodedLen = 56
+
+var b32Enc = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// PublicKey is a decoded nkey public key.
+type PublicKey struct {
+	Prefix byte
+	Key    ed25519.PublicKey
+}
+
+// Parse decodes an encoded public key, checking its checksum and type.
+func Parse(public string) (*PublicKey, error) {
+	if len(public) != encodedLen {
+		return nil, ErrInvalidEncoding
+	}
+	raw, err := b32Enc.DecodeString(public)
+	if err != nil || len(raw) != 1+ed25519.PublicKeySize+2 {
+		return nil, ErrInvalidEncoding
+	}
+	end := len(raw) - 2
+	if crc16(raw[:end]) != uint16(raw[end])|uint16(raw[end+1])<<8 {
+		return nil, ErrInvalidChecksum
+	}
+	switch raw[0] {
+	case PrefixByteOperator, PrefixByteServer, PrefixByteCluster, PrefixByteAccount, PrefixByteUser:
+	default:
+		return nil, ErrInvalidPublicKey
+	}
+	return &PublicKey{raw[0], ed25519.PublicKey(raw[1:end])}, nil
+}
+
+// Verify verifies the signature of input.
+func (p *PublicKey) Verify(input []byte, sig []byte) error {
+	if !ed25519.Verify(p.Key, input, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Verify decodes the public key and verifies the signature of input.
+func Verify(public string, input []byte, sig []byte) error {
+	pk, err := Parse(public)
+	if err != nil {
+		return err
+	}
+	return pk.Verify(input, sig)
+}
+
+// crc16 is the CCITT XMODEM checksum used by nkeys, computed without the
+// lookup table to keep this package small.
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
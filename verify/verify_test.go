@@ -0,0 +1,83 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package verify
+
+import (
+	"go/build"
+	"strings"
+	"testing"
+
+	"github.com/nats-io/nkeys"
+)
+
+func TestVerify(t *testing.T) {
+	for _, create := range []func() (nkeys.KeyPair, error){
+		nkeys.CreateUser, nkeys.CreateAccount, nkeys.CreateOperator,
+		nkeys.CreateServer, nkeys.CreateCluster,
+	} {
+		kp, _ := create()
+		pk, _ := kp.PublicKey()
+		sig, _ := kp.Sign([]byte("hello"))
+
+		p, err := Parse(pk)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %v", pk, err)
+		}
+		if p.Prefix != byte(nkeys.Prefix(pk)) {
+			t.Fatalf("Expected prefix %v, got %v", nkeys.Prefix(pk), p.Prefix)
+		}
+		if err := Verify(pk, []byte("hello"), sig); err != nil {
+			t.Fatalf("Unexpected error verifying: %v", err)
+		}
+		if err := Verify(pk, []byte("bye"), sig); err != ErrInvalidSignature {
+			t.Fatalf("Expected ErrInvalidSignature, got %v", err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	user, _ := nkeys.CreateUser()
+	pk, _ := user.PublicKey()
+	seed, _ := user.Seed()
+	curve, _ := nkeys.CreateCurveKeys()
+	cpk, _ := curve.PublicKey()
+
+	bad := []byte(pk)
+	bad[10] = 'A' + (bad[10]-'A'+1)%26
+	for in, expected := range map[string]error{
+		"":                  ErrInvalidEncoding,
+		pk[1:]:              ErrInvalidEncoding,
+		string(seed):        ErrInvalidEncoding,
+		string(bad):         ErrInvalidChecksum,
+		cpk:                 ErrInvalidPublicKey,
+		"1" + pk[1:]:        ErrInvalidEncoding,
+		strings.ToLower(pk): ErrInvalidEncoding,
+	} {
+		if _, err := Parse(in); err != expected {
+			t.Fatalf("Expected %v for %q, got %v", expected, in, err)
+		}
+	}
+}
+
+func TestStdlibOnly(t *testing.T) {
+	pkg, err := build.ImportDir(".", 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, imp := range pkg.Imports {
+		if strings.Contains(strings.SplitN(imp, "/", 2)[0], ".") {
+			t.Fatalf("Expected only standard library imports, found %q", imp)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"crypto/ed25519"
+	"time"
+)
+
+// FailureReason categorizes why a verification failed.
+type FailureReason uint8
+
+const (
+	// ReasonNone means the signature is valid.
+	ReasonNone FailureReason = iota
+	// ReasonMalformedKey means the public key could not be decoded.
+	ReasonMalformedKey
+	// ReasonUnsupportedKey means the key can not sign, e.g. a curve key.
+	ReasonUnsupportedKey
+	// ReasonMalformedSignature means the signature has the wrong length.
+	ReasonMalformedSignature
+	// ReasonBadSignature means the signature does not match the input.
+	ReasonBadSignature
+)
+
+func (r FailureReason) String() string {
+	switch r {
+	case ReasonNone:
+		return "none"
+	case ReasonMalformedKey:
+		return "malformed_key"
+	case ReasonUnsupportedKey:
+		return "unsupported_key"
+	case ReasonMalformedSignature:
+		return "malformed_signature"
+	case ReasonBadSignature:
+		return "bad_signature"
+	}
+	return "unknown"
+}
+
+// VerifyResult describes the outcome of VerifyDetailed.
+type VerifyResult struct {
+	// PublicKey is the canonical form of the key that was verified against.
+	PublicKey string
+	// Normalized is set if the given key differed from its canonical form,
+	// for example because it was quoted or lowercased.
+	Normalized bool
+	Type       PrefixByte
+	Algorithm  AlgorithmID
+	// Duration is the time spent verifying.
+	Duration time.Duration
+	Reason   FailureReason
+	// Err is nil if the signature is valid.
+	Err error
+}
+
+// OK reports whether the signature is valid.
+func (r VerifyResult) OK() bool {
+	return r.Err == nil
+}
+
+// VerifyDetailed verifies the signature of input by public like Verify
+// but returns a result describing the key and why verification failed, so
+// that services can report precise metrics and messages. The key is
+// normalized with NormalizeKey first.
+func VerifyDetailed(public string, input []byte, sig []byte) VerifyResult {
+	start := time.Now()
+	r := verifyDetailed(public, input, sig)
+	r.Duration = time.Since(start)
+	return r
+}
+
+func verifyDetailed(public string, input []byte, sig []byte) VerifyResult {
+	var r VerifyResult
+	key, err := NormalizeKey(public)
+	if err != nil {
+		r.Reason, r.Err = ReasonMalformedKey, ErrInvalidPublicKey
+		return r
+	}
+	r.PublicKey, r.Normalized = key, key != public
+	r.Type = Prefix(key)
+	if checkValidPublicPrefixByte(r.Type) != nil {
+		r.Reason, r.Err = ReasonMalformedKey, ErrInvalidPublicKey
+		return r
+	}
+	r.Algorithm = AlgorithmOf(r.Type)
+	if r.Algorithm != AlgorithmEd25519 {
+		r.Reason, r.Err = ReasonUnsupportedKey, ErrUnsupportedAlgorithm
+		return r
+	}
+	if len(sig) != ed25519.SignatureSize {
+		r.Reason, r.Err = ReasonMalformedSignature, ErrInvalidSignature
+		return r
+	}
+	kp, err := FromPublicKey(key)
+	if err != nil {
+		r.Reason, r.Err = ReasonMalformedKey, err
+		return r
+	}
+	if err := kp.Verify(input, sig); err != nil {
+		r.Reason, r.Err = ReasonBadSignature, err
+	}
+	return r
+}
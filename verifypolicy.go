@@ -0,0 +1,146 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "time"
+
+// KeyStatus is the rotation status of a key.
+type KeyStatus int
+
+const (
+	// KeyStatusCurrent is a key in active use.
+	KeyStatusCurrent KeyStatus = iota
+	// KeyStatusPrevious is a key that was rotated out but is still honored
+	// during a grace period.
+	KeyStatusPrevious
+	// KeyStatusRetired is a key that must no longer be honored.
+	KeyStatusRetired
+)
+
+// RevocationChecker reports whether a public key has been revoked.
+type RevocationChecker interface {
+	IsRevoked(public string) (bool, error)
+}
+
+// RotationChecker reports the rotation status of a public key.
+type RotationChecker interface {
+	KeyStatus(public string) (KeyStatus, error)
+}
+
+// VerifyPolicy centrally expresses which signatures are acceptable. It is
+// accepted by the *WithPolicy verification helpers. A nil *VerifyPolicy
+// accepts any valid signature.
+type VerifyPolicy struct {
+	// AllowedTypes restricts the signer key types. Empty allows all.
+	AllowedTypes []PrefixByte
+	// Trusted, when set, rejects signers it does not trust, e.g. a TrustStore.
+	Trusted TrustChecker
+	// MaxClockSkew is tolerated when checking validity windows.
+	MaxClockSkew time.Duration
+	// Clock defaults to SystemClock.
+	Clock Clock
+	// Revocation, when set, rejects revoked signers.
+	Revocation RevocationChecker
+	// Rotation, when set, rejects signers whose status is worse than MaxStatus.
+	Rotation  RotationChecker
+	MaxStatus KeyStatus
+	// MaxPayload, when positive, rejects inputs longer than it with a
+	// *PayloadSizeError before verifying.
+	MaxPayload int
+}
+
+// CheckKey applies the key type, trust, revocation and rotation rules to
+// public.
+func (vp *VerifyPolicy) CheckKey(public string) error {
+	if vp == nil {
+		return nil
+	}
+	if len(vp.AllowedTypes) > 0 {
+		pre := Prefix(public)
+		allowed := false
+		for _, t := range vp.AllowedTypes {
+			if t == pre {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return ErrKeyTypeNotAllowed
+		}
+	}
+	if vp.Trusted != nil && !vp.Trusted.IsTrusted(public) {
+		return ErrKeyNotTrusted
+	}
+	if vp.Revocation != nil {
+		revoked, err := vp.Revocation.IsRevoked(public)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrKeyRevoked
+		}
+	}
+	if vp.Rotation != nil {
+		status, err := vp.Rotation.KeyStatus(public)
+		if err != nil {
+			return err
+		}
+		if status > vp.MaxStatus {
+			return ErrKeyRetired
+		}
+	}
+	return nil
+}
+
+// CheckValidity checks that the current time, give or take MaxClockSkew,
+// is within [notBefore, expires). Zero times are not checked.
+func (vp *VerifyPolicy) CheckValidity(notBefore, expires time.Time) error {
+	var clock Clock
+	var skew time.Duration
+	if vp != nil {
+		clock, skew = vp.Clock, vp.MaxClockSkew
+	}
+	now := ClockOrSystem(clock).Now()
+	if !notBefore.IsZero() && now.Add(skew).Before(notBefore) {
+		return ErrNotYetValid
+	}
+	if !expires.IsZero() && !now.Add(-skew).Before(expires) {
+		return ErrExpired
+	}
+	return nil
+}
+
+// VerifyWithPolicy verifies the signature of input by public and applies
+// the policy to the signer.
+func VerifyWithPolicy(vp *VerifyPolicy, public string, input []byte, sig []byte) error {
+	if err := checkPublicKeyArg(public); err != nil {
+		return err
+	}
+	if !IsValidPublicKey(public) {
+		return ErrInvalidPublicKey
+	}
+	if err := vp.CheckKey(public); err != nil {
+		return err
+	}
+	if vp != nil {
+		if err := checkPayloadSize(len(input), vp.MaxPayload); err != nil {
+			return err
+		}
+	}
+	kp, err := FromPublicKey(public)
+	if err != nil {
+		return err
+	}
+	return kp.Verify(input, sig)
+}
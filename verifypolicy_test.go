@@ -0,0 +1,757 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type staticChecker struct {
+	revoked map[string]bool
+	status  map[string]KeyStatus
+}
+
+func (c staticChecker) IsRevoked(public string) (bool, error) {
+	return c.revoked[public], nil
+}
+
+func (c staticChecker) KeyStatus(public string) (KeyStatus, error) {
+	return c.status[public], nil
+}
+
+type staticTrust map[string]bool
+
+func (t staticTrust) IsTrusted(public string) bool {
+	return t[public]
+}
+
+func TestVerifyWithPolicy(t *testing.T) {
+	acc, _ := CreateAccount()
+	user, _ := CreateUser()
+	apk, _ := acc.PublicKey()
+	upk, _ := user.PublicKey()
+	data := []byte("hello")
+	asig, _ := acc.Sign(data)
+	usig, _ := user.Sign(data)
+
+	if err := VerifyWithPolicy(nil, upk, data, usig); err != nil {
+		t.Fatalf("Expected nil policy to accept, got %v", err)
+	}
+	vp := &VerifyPolicy{AllowedTypes: []PrefixByte{PrefixByteAccount}}
+	if err := VerifyWithPolicy(vp, apk, data, asig); err != nil {
+		t.Fatalf("Expected account to be accepted, got %v", err)
+	}
+	if err := VerifyWithPolicy(vp, upk, data, usig); err != ErrKeyTypeNotAllowed {
+		t.Fatalf("Expected %v, got %v", ErrKeyTypeNotAllowed, err)
+	}
+	if err := VerifyWithPolicy(vp, apk, []byte("other"), asig); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+
+	checker := staticChecker{
+		revoked: map[string]bool{upk: true},
+		status:  map[string]KeyStatus{apk: KeyStatusPrevious},
+	}
+	vp = &VerifyPolicy{Revocation: checker}
+	if err := VerifyWithPolicy(vp, upk, data, usig); err != ErrKeyRevoked {
+		t.Fatalf("Expected %v, got %v", ErrKeyRevoked, err)
+	}
+	vp = &VerifyPolicy{Rotation: checker}
+	if err := VerifyWithPolicy(vp, apk, data, asig); err != ErrKeyRetired {
+		t.Fatalf("Expected %v, got %v", ErrKeyRetired, err)
+	}
+	vp.MaxStatus = KeyStatusPrevious
+	if err := VerifyWithPolicy(vp, apk, data, asig); err != nil {
+		t.Fatalf("Expected key in grace period to be accepted, got %v", err)
+	}
+}
+
+func TestVerifyPolicyValidity(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	vp := &VerifyPolicy{
+		MaxClockSkew: time.Minute,
+		Clock:        ClockFunc(func() time.Time { return now }),
+	}
+	if err := vp.CheckValidity(now.Add(30*time.Second), now.Add(time.Hour)); err != nil {
+		t.Fatalf("Expected skew to be tolerated, got %v", err)
+	}
+	if err := vp.CheckValidity(now.Add(2*time.Minute), time.Time{}); err != ErrNotYetValid {
+		t.Fatalf("Expected %v, got %v", ErrNotYetValid, err)
+	}
+	if err := vp.CheckValidity(time.Time{}, now.Add(-30*time.Second)); err != nil {
+		t.Fatalf("Expected skew to be tolerated, got %v", err)
+	}
+	if err := vp.CheckValidity(time.Time{}, now.Add(-2*time.Minute)); err != ErrExpired {
+		t.Fatalf("Expected %v, got %v", ErrExpired, err)
+	}
+}
+
+func TestUniformVerifier(t *testing.T) {
+	user, _ := CreateUser()
+	upk, _ := user.PublicKey()
+	data := []byte("hello")
+	sig, _ := user.Sign(data)
+
+	var u UniformVerifier
+	if err := u.Verify(upk, data, sig); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	seed, _ := user.Seed()
+	badCRC := upk[:len(upk)-1] + "A"
+	if badCRC == upk {
+		badCRC = upk[:len(upk)-1] + "B"
+	}
+	for _, tc := range []struct {
+		public string
+		sig    []byte
+	}{
+		{"X" + upk[1:], sig},
+		{badCRC, sig},
+		{string(seed), sig},
+		{upk, sig[:10]},
+		{upk, append([]byte{sig[0] ^ 1}, sig[1:]...)},
+	} {
+		if err := u.Verify(tc.public, data, tc.sig); err != ErrVerificationFailed {
+			t.Fatalf("Expected %v for %q, got %v", ErrVerificationFailed, tc.public, err)
+		}
+	}
+}
+
+func TestUniformVerifierLimit(t *testing.T) {
+	user, _ := CreateUser()
+	upk, _ := user.PublicKey()
+	data := []byte("hello")
+	sig, _ := user.Sign(data)
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	u := &UniformVerifier{
+		MaxFailures: 2,
+		Window:      time.Minute,
+		Clock:       ClockFunc(func() time.Time { return now }),
+	}
+	u.Verify(upk, []byte("bad"), sig)
+	u.Verify(upk, []byte("bad"), sig)
+	if err := u.Verify(upk, data, sig); err != ErrTooManyFailures {
+		t.Fatalf("Expected %v, got %v", ErrTooManyFailures, err)
+	}
+	now = now.Add(time.Minute)
+	if err := u.Verify(upk, data, sig); err != nil {
+		t.Fatalf("Expected no error after the window, got %v", err)
+	}
+}
+
+func TestVerifyAny(t *testing.T) {
+	var publics []string
+	var kps []KeyPair
+	for i := 0; i < 4; i++ {
+		kp, _ := CreateAccount()
+		pk, _ := kp.PublicKey()
+		kps = append(kps, kp)
+		publics = append(publics, pk)
+	}
+	data := []byte("hello")
+	ks, err := NewKeySet(publics...)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, i := range []int{2, 2, 0, 3} {
+		sig, _ := kps[i].Sign(data)
+		got, err := ks.VerifyAny(data, sig)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got != publics[i] {
+			t.Fatalf("Expected %q, got %q", publics[i], got)
+		}
+	}
+	other, _ := CreateAccount()
+	sig, _ := other.Sign(data)
+	if _, err := VerifyAny(publics, data, sig); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	curve, _ := CreateCurveKeys()
+	cpk, _ := curve.PublicKey()
+	if _, err := NewKeySet(cpk); err != ErrInvalidPublicKey {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPublicKey, err)
+	}
+}
+
+func TestVerifyDetailed(t *testing.T) {
+	user, _ := CreateUser()
+	pk, _ := user.PublicKey()
+	sig, _ := user.Sign([]byte("hello"))
+
+	r := VerifyDetailed(`"`+strings.ToLower(pk)+`"`, []byte("hello"), sig)
+	if !r.OK() || r.PublicKey != pk || !r.Normalized || r.Type != PrefixByteUser || r.Algorithm != AlgorithmEd25519 {
+		t.Fatalf("Unexpected result %+v", r)
+	}
+	if r.Duration <= 0 {
+		t.Fatalf("Expected a duration, got %v", r.Duration)
+	}
+
+	curve, _ := CreateCurveKeys()
+	cpk, _ := curve.PublicKey()
+	seed, _ := user.Seed()
+	for _, tc := range []struct {
+		public string
+		input  string
+		sig    []byte
+		reason FailureReason
+	}{
+		{pk, "hello", sig, ReasonNone},
+		{"bad", "hello", sig, ReasonMalformedKey},
+		{string(seed), "hello", sig, ReasonMalformedKey},
+		{cpk, "hello", sig, ReasonUnsupportedKey},
+		{pk, "hello", sig[:10], ReasonMalformedSignature},
+		{pk, "goodbye", sig, ReasonBadSignature},
+	} {
+		r := VerifyDetailed(tc.public, []byte(tc.input), tc.sig)
+		if r.Reason != tc.reason || r.OK() != (tc.reason == ReasonNone) {
+			t.Fatalf("Expected %v, got %v (%v)", tc.reason, r.Reason, r.Err)
+		}
+	}
+}
+
+func TestImpersonationToken(t *testing.T) {
+	admin, _ := CreateAccount()
+	apk, _ := admin.PublicKey()
+	user, _ := CreateUser()
+	upk, _ := user.PublicKey()
+
+	token, err := MintImpersonationToken(admin, upk, time.Hour, []string{"read"}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	im, err := VerifyImpersonationToken(token, &VerifyPolicy{AllowedTypes: []PrefixByte{PrefixByteAccount}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if im.Admin != apk || im.Target != upk || im.ID == "" || !im.Allows("read") || im.Allows("write") {
+		t.Fatalf("Unexpected impersonation %+v", im)
+	}
+
+	later := &VerifyPolicy{Clock: ClockFunc(func() time.Time { return time.Now().Add(2 * time.Hour) })}
+	if _, err := VerifyImpersonationToken(token, later); err != ErrExpired {
+		t.Fatalf("Expected %v, got %v", ErrExpired, err)
+	}
+	operators := &VerifyPolicy{AllowedTypes: []PrefixByte{PrefixByteOperator}}
+	if _, err := VerifyImpersonationToken(token, operators); err != ErrKeyTypeNotAllowed {
+		t.Fatalf("Expected %v, got %v", ErrKeyTypeNotAllowed, err)
+	}
+
+	// Widening the scope invalidates the signature.
+	im.Scope = append(im.Scope, "write")
+	claims, _ := json.Marshal(im)
+	forged := base64.RawURLEncoding.EncodeToString(claims) + token[strings.IndexByte(token, '.'):]
+	if _, err := VerifyImpersonationToken(forged, nil); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+
+	if _, err := MintImpersonationToken(admin, apk, time.Hour, nil, nil); err != ErrInvalidPublicKey {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPublicKey, err)
+	}
+	if _, err := MintImpersonationToken(admin, upk, 0, nil, nil); err != ErrInvalidLifetime {
+		t.Fatalf("Expected %v, got %v", ErrInvalidLifetime, err)
+	}
+	past := ClockFunc(func() time.Time { return time.Unix(1700000000, 0) })
+	old, _ := MintImpersonationToken(admin, upk, time.Hour, nil, past)
+	if _, err := VerifyImpersonationToken(old, nil); err != ErrExpired {
+		t.Fatalf("Expected %v, got %v", ErrExpired, err)
+	}
+	if _, err := VerifyImpersonationToken(old, &VerifyPolicy{Clock: past}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := VerifyImpersonationToken("garbage", nil); err != ErrInvalidImpersonation {
+		t.Fatalf("Expected %v, got %v", ErrInvalidImpersonation, err)
+	}
+}
+
+func TestPossession(t *testing.T) {
+	user, _ := CreateUser()
+	nonce := []byte("server-nonce")
+	p, err := MintPossession(user, "api.example.com", nonce, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := VerifyPossession(p, "api.example.com", nonce, time.Minute, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := VerifyPossession(p, "other.example.com", nonce, time.Minute, nil); err != ErrInvalidPossession {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPossession, err)
+	}
+	if err := VerifyPossession(p, "api.example.com", []byte("other"), time.Minute, nil); err != ErrInvalidPossession {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPossession, err)
+	}
+	later := &VerifyPolicy{Clock: ClockFunc(func() time.Time { return time.Now().Add(time.Hour) })}
+	if err := VerifyPossession(p, "api.example.com", nonce, time.Minute, later); err != ErrExpired {
+		t.Fatalf("Expected %v, got %v", ErrExpired, err)
+	}
+
+	// The proof can not be moved to another key.
+	other, _ := CreateUser()
+	p.PublicKey, _ = other.PublicKey()
+	if err := VerifyPossession(p, "api.example.com", nonce, time.Minute, nil); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+
+	random, _ := MintPossession(user, "api.example.com", nil, nil)
+	if len(random.Nonce) == 0 {
+		t.Fatalf("Expected a random nonce")
+	}
+	if err := VerifyPossession(random, "api.example.com", nil, time.Minute, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	past := ClockFunc(func() time.Time { return time.Unix(1700000000, 0) })
+	old, _ := MintPossession(user, "api.example.com", nil, past)
+	if !old.Timestamp.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("Expected the timestamp from the clock, got %v", old.Timestamp)
+	}
+	if err := VerifyPossession(old, "api.example.com", nil, time.Minute, nil); err != ErrExpired {
+		t.Fatalf("Expected %v, got %v", ErrExpired, err)
+	}
+}
+
+func TestRotationManager(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	clock := ClockFunc(func() time.Time { return now })
+	path := filepath.Join(t.TempDir(), "rotation.json")
+	policy := RotationPolicy{MaxAge: 24 * time.Hour, MaxUses: 100, Grace: time.Hour}
+	m, err := NewRotationManager(path, policy, clock)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	old, _ := CreateServer()
+	oldPub, _ := old.PublicKey()
+	if err := m.Track("srv", oldPub); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if due := m.Due(); len(due) != 0 {
+		t.Fatalf("Expected nothing due, got %v", due)
+	}
+	m.RecordUse("srv", 100)
+	if due := m.Due(); len(due) != 1 || due[0] != "srv" {
+		t.Fatalf("Expected srv to be due, got %v", due)
+	}
+
+	next, _ := CreateServer()
+	nextPub, _ := next.PublicKey()
+	other, _ := CreateServer()
+	if _, err := m.Rotate("srv", other, next); err != ErrIncompatibleKey {
+		t.Fatalf("Expected %v, got %v", ErrIncompatibleKey, err)
+	}
+	rs, err := m.Rotate("srv", old, next)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := VerifyRotationStatement(rs, &VerifyPolicy{AllowedTypes: []PrefixByte{PrefixByteServer}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	rs.Sequence++
+	if err := VerifyRotationStatement(rs, nil); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	if due := m.Due(); len(due) != 0 {
+		t.Fatalf("Expected nothing due, got %v", due)
+	}
+
+	// The state survives a restart, and the old key ages out of its grace.
+	m, err = NewRotationManager(path, policy, clock)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	vp := &VerifyPolicy{Rotation: m, MaxStatus: KeyStatusPrevious}
+	if err := vp.CheckKey(oldPub); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+	if err := vp.CheckKey(oldPub); err != ErrKeyRetired {
+		t.Fatalf("Expected %v, got %v", ErrKeyRetired, err)
+	}
+	if err := vp.CheckKey(nextPub); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	now = now.Add(24 * time.Hour)
+	if due := m.Due(); len(due) != 1 {
+		t.Fatalf("Expected srv to be due, got %v", due)
+	}
+	if m.IsDue("srv", RotationPolicy{MaxAge: 48 * time.Hour}) {
+		t.Fatalf("Expected srv not to be due under a longer policy")
+	}
+	if err := m.Untrack("srv"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := m.KeyStatus(nextPub); err != ErrKeyNotFound {
+		t.Fatalf("Expected %v, got %v", ErrKeyNotFound, err)
+	}
+	if err := m.Untrack("srv"); err != ErrKeyNotFound {
+		t.Fatalf("Expected %v, got %v", ErrKeyNotFound, err)
+	}
+}
+
+type preparingBackend struct {
+	stdBackend
+	prepared int
+}
+
+func (b *preparingBackend) PrepareVerify(public []byte) (func([]byte, []byte) bool, error) {
+	b.prepared++
+	return func(message, sig []byte) bool {
+		return b.Verify(public, message, sig)
+	}, nil
+}
+
+func TestPrepareVerify(t *testing.T) {
+	user, _ := CreateUser()
+	pk, _ := user.PublicKey()
+	sig, _ := user.Sign([]byte("hello"))
+
+	p, err := PrepareVerify(pk)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p.PublicKey() != pk {
+		t.Fatalf("Expected %q, got %q", pk, p.PublicKey())
+	}
+	if err := p.Verify([]byte("hello"), sig); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := p.Verify([]byte("hellO"), sig); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	curve, _ := CreateCurveKeys()
+	cpk, _ := curve.PublicKey()
+	if _, err := PrepareVerify(cpk); err != ErrInvalidPublicKey {
+		t.Fatalf("Expected %v, got %v", ErrInvalidPublicKey, err)
+	}
+
+	// Backends that can precompute are asked to.
+	b := &preparingBackend{}
+	raw, _ := Decode(PrefixByteUser, []byte(pk))
+	verify, err := prepareVerify(b, raw)
+	if err != nil || b.prepared != 1 {
+		t.Fatalf("Expected the backend to prepare the key, got %d, %v", b.prepared, err)
+	}
+	if !verify([]byte("hello"), sig) {
+		t.Fatalf("Expected the signature to verify")
+	}
+}
+
+func TestSubkeyIssuance(t *testing.T) {
+	account, _ := CreateAccount()
+	apk, _ := account.PublicKey()
+	sub, issuance, err := IssueSubkey(account, "ci:release/app", time.Hour, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := sub.Seed(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	artifact := []byte("artifact")
+	sig, _ := sub.Sign(artifact)
+
+	vp := &VerifyPolicy{AllowedTypes: []PrefixByte{PrefixByteAccount}}
+	si, err := VerifySubkeySignature(issuance, "ci:release/app", artifact, sig, vp)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if spk, _ := sub.PublicKey(); si.Issuer != apk || si.Subkey != spk || si.ID == "" {
+		t.Fatalf("Unexpected issuance %+v", si)
+	}
+
+	if _, err := VerifySubkeySignature(issuance, "ci:release/other", artifact, sig, vp); err != ErrScopeNotAllowed {
+		t.Fatalf("Expected %v, got %v", ErrScopeNotAllowed, err)
+	}
+	if _, err := VerifySubkeySignature(issuance, "ci:release/app", []byte("tampered"), sig, vp); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	later := &VerifyPolicy{Clock: ClockFunc(func() time.Time { return time.Now().Add(2 * time.Hour) })}
+	if _, err := VerifySubkeySignature(issuance, "ci:release/app", artifact, sig, later); err != ErrExpired {
+		t.Fatalf("Expected %v, got %v", ErrExpired, err)
+	}
+	operators := &VerifyPolicy{AllowedTypes: []PrefixByte{PrefixByteOperator}}
+	if _, err := VerifySubkeySignature(issuance, "ci:release/app", artifact, sig, operators); err != ErrKeyTypeNotAllowed {
+		t.Fatalf("Expected %v, got %v", ErrKeyTypeNotAllowed, err)
+	}
+
+	// A signature by the account itself is not a subkey signature.
+	asig, _ := account.Sign(artifact)
+	if _, err := VerifySubkeySignature(issuance, "ci:release/app", artifact, asig, vp); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	user, _ := CreateUser()
+	if _, _, err := IssueSubkey(user, "ci", time.Hour, nil); err != ErrIncompatibleKey {
+		t.Fatalf("Expected %v, got %v", ErrIncompatibleKey, err)
+	}
+	if _, _, err := IssueSubkey(account, "ci", 48*time.Hour, nil); err != ErrInvalidLifetime {
+		t.Fatalf("Expected %v, got %v", ErrInvalidLifetime, err)
+	}
+	past := ClockFunc(func() time.Time { return time.Unix(1700000000, 0) })
+	_, old, _ := IssueSubkey(account, "ci", time.Hour, past)
+	if si, err := ParseSubkeyIssuance(old, nil); err != nil || !si.IssuedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("Expected the issuance time from the clock, got %+v (%v)", si, err)
+	}
+	if _, err := ParseSubkeyIssuance("garbage", nil); err != ErrInvalidSubkeyIssuance {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSubkeyIssuance, err)
+	}
+}
+
+func TestEnvelopeThreshold(t *testing.T) {
+	var kps []KeyPair
+	var signers []string
+	for i := 0; i < 3; i++ {
+		kp, _ := CreateAccount()
+		pub, _ := kp.PublicKey()
+		kps, signers = append(kps, kp), append(signers, pub)
+	}
+	tp := ThresholdPolicy{Signers: signers, Threshold: 2}
+
+	env := NewEnvelope([]byte("release v1.2.3"))
+	for _, kp := range []KeyPair{kps[2], kps[0], kps[2]} {
+		if err := env.Sign(kp); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	outsider, _ := CreateUser()
+	env.Sign(outsider)
+	if len(env.Signatures) != 3 {
+		t.Fatalf("Expected %d, got %d", 3, len(env.Signatures))
+	}
+	data, err := env.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded Envelope
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	counted, err := decoded.VerifyThreshold(tp, nil)
+	if err != nil || len(counted) != 2 {
+		t.Fatalf("Expected 2 signers, got %v (%v)", counted, err)
+	}
+
+	// Revoking one of the approvers drops the envelope below threshold.
+	vp := &VerifyPolicy{Revocation: staticChecker{revoked: map[string]bool{signers[0]: true}}}
+	if _, err := decoded.VerifyThreshold(tp, vp); err != ErrThresholdNotMet {
+		t.Fatalf("Expected %v, got %v", ErrThresholdNotMet, err)
+	}
+	if _, err := decoded.VerifyThreshold(ThresholdPolicy{Signers: signers, Threshold: 3}, nil); err != ErrThresholdNotMet {
+		t.Fatalf("Expected %v, got %v", ErrThresholdNotMet, err)
+	}
+	if _, err := decoded.VerifyThreshold(ThresholdPolicy{Signers: signers[:1], Threshold: 2}, nil); err != ErrInvalidThreshold {
+		t.Fatalf("Expected %v, got %v", ErrInvalidThreshold, err)
+	}
+
+	decoded.Payload = []byte("release v6.6.6")
+	if _, err := decoded.VerifyThreshold(tp, nil); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	env.Signatures[0], env.Signatures[1] = env.Signatures[1], env.Signatures[0]
+	if _, err := env.MarshalBinary(); err != ErrInvalidEnvelope {
+		t.Fatalf("Expected %v, got %v", ErrInvalidEnvelope, err)
+	}
+}
+
+func TestTombstone(t *testing.T) {
+	account, _ := CreateAccount()
+	apk, _ := account.PublicKey()
+	user, _ := CreateUser()
+	upk, _ := user.PublicKey()
+
+	self, err := IssueTombstone(user, upk, "decommissioned", time.Time{}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !self.SelfIssued() || self.DestroyedAt.IsZero() {
+		t.Fatalf("Unexpected tombstone %+v", self)
+	}
+	byParent, err := IssueTombstone(account, upk, "lost device", time.Unix(1700000000, 0), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Without trusted issuers or a parent mapping any account could bury
+	// the user.
+	if err := VerifyTombstone(byParent, nil); err != ErrKeyNotTrusted {
+		t.Fatalf("Expected %v, got %v", ErrKeyNotTrusted, err)
+	}
+	if err := NewGraveyard(nil).Add(byParent); err != ErrKeyNotTrusted {
+		t.Fatalf("Expected %v, got %v", ErrKeyNotTrusted, err)
+	}
+	trusted := &VerifyPolicy{Trusted: staticTrust{apk: true}}
+	if err := VerifyTombstone(byParent, trusted); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	otherAccount, _ := CreateAccount()
+	byOther, _ := IssueTombstone(otherAccount, upk, "", time.Time{}, ClockFunc(func() time.Time { return time.Unix(1600000000, 0) }))
+	if !byOther.DestroyedAt.Equal(time.Unix(1600000000, 0)) {
+		t.Fatalf("Expected the time from the clock, got %v", byOther.DestroyedAt)
+	}
+	if err := VerifyTombstone(byOther, trusted); err != ErrKeyNotTrusted {
+		t.Fatalf("Expected %v, got %v", ErrKeyNotTrusted, err)
+	}
+
+	// The graveyard revokes the key, which must not prevent accepting
+	// its own tombstone.
+	g := NewGraveyard(&VerifyPolicy{Revocation: staticChecker{revoked: map[string]bool{upk: true}}})
+	g.Parents = map[string]string{upk: apk}
+	if err := g.Add(byOther); err != ErrKeyNotTrusted {
+		t.Fatalf("Expected %v, got %v", ErrKeyNotTrusted, err)
+	}
+	for _, ts := range []*Tombstone{self, byParent} {
+		if err := g.Add(ts); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if ts, ok := g.Tombstone(upk); !ok || ts != byParent {
+		t.Fatalf("Expected the earliest tombstone, got %+v", ts)
+	}
+	vp := &VerifyPolicy{Revocation: g}
+	sig, _ := user.Sign([]byte("late"))
+	if err := VerifyWithPolicy(vp, upk, []byte("late"), sig); err != ErrKeyRevoked {
+		t.Fatalf("Expected %v, got %v", ErrKeyRevoked, err)
+	}
+
+	forged := *byParent
+	forged.DestroyedAt = forged.DestroyedAt.Add(time.Hour)
+	if err := VerifyTombstone(&forged, trusted); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	other, _ := CreateUser()
+	if _, err := IssueTombstone(other, upk, "", time.Time{}, nil); err != ErrIncompatibleKey {
+		t.Fatalf("Expected %v, got %v", ErrIncompatibleKey, err)
+	}
+	// A user can not bury its account.
+	forged = *self
+	forged.Key = apk
+	if err := VerifyTombstone(&forged, nil); err != ErrInvalidTombstone {
+		t.Fatalf("Expected %v, got %v", ErrInvalidTombstone, err)
+	}
+}
+
+func TestVerifyFlexible(t *testing.T) {
+	user, _ := CreateUser()
+	upk, _ := user.PublicKey()
+	input := []byte("hello")
+	sig, _ := user.Sign(input)
+
+	for format, encoded := range map[SignatureFormat][]byte{
+		SignatureRaw:       sig,
+		SignatureBase64:    []byte(base64.StdEncoding.EncodeToString(sig) + "\n"),
+		SignatureBase64URL: []byte(base64.RawURLEncoding.EncodeToString(sig)),
+		SignatureBase32:    []byte(b32Enc.EncodeToString(sig)),
+		SignatureHex:       []byte(hex.EncodeToString(sig)),
+	} {
+		raw, got, err := DecodeSignature(encoded)
+		// Without '-' or '_' base64url can not be told apart from base64,
+		// which decodes to the same bytes.
+		if format == SignatureBase64URL && !bytes.ContainsAny(encoded, "-_") {
+			format = SignatureBase64
+		}
+		if err != nil || got != format || !bytes.Equal(raw, sig) {
+			t.Fatalf("Expected %v, got %v (%v)", format, got, err)
+		}
+		if err := VerifyFlexible(nil, upk, input, encoded); err != nil {
+			t.Fatalf("%v: unexpected error: %v", format, err)
+		}
+	}
+	if err := VerifyFlexible(nil, upk, []byte("other"), sig); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	if err := VerifyFlexible(nil, upk, input, []byte("garbage")); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	if _, _, err := DecodeSignature([]byte(strings.Repeat("z", 128))); err != ErrInvalidEncoding {
+		t.Fatalf("Expected %v, got %v", ErrInvalidEncoding, err)
+	}
+}
+
+func TestOwnershipProof(t *testing.T) {
+	user, _ := CreateUser()
+	public, _ := user.PublicKey()
+	c, err := NewOwnershipChallenge("api.example.com", time.Minute, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	p, err := ProveOwnership(user, c, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := VerifyOwnership(p, c, public, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The signed message is fixed length and starts with the domain.
+	msg := c.signedBytes(public)
+	if len(msg) != 64 || !bytes.HasPrefix(msg, []byte(ownershipDomain)) {
+		t.Fatalf("Expected a 64 byte domain separated message, got %q", msg)
+	}
+
+	other, _ := NewOwnershipChallenge("other.example.com", time.Minute, nil)
+	if err := VerifyOwnership(p, other, "", nil); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	account, _ := CreateAccount()
+	apk, _ := account.PublicKey()
+	if err := VerifyOwnership(p, c, apk, nil); err != ErrInvalidOwnership {
+		t.Fatalf("Expected %v, got %v", ErrInvalidOwnership, err)
+	}
+	later := &VerifyPolicy{Clock: ClockFunc(func() time.Time { return time.Now().Add(time.Hour) })}
+	if err := VerifyOwnership(p, c, public, later); err != ErrExpired {
+		t.Fatalf("Expected %v, got %v", ErrExpired, err)
+	}
+
+	// Challenges that do not have the fixed structure are not signed.
+	bad := []*OwnershipChallenge{
+		{Service: "api", Nonce: []byte("attacker chosen bytes"), Expires: c.Expires},
+		{Service: "api\nHost: evil", Nonce: c.Nonce, Expires: c.Expires},
+		{Service: "api", Nonce: c.Nonce},
+	}
+	for _, b := range bad {
+		if _, err := ProveOwnership(user, b, nil); err != ErrInvalidChallenge {
+			t.Fatalf("Expected %v, got %v", ErrInvalidChallenge, err)
+		}
+	}
+	expired := *c
+	expired.Expires = time.Now().Add(-time.Minute)
+	if _, err := ProveOwnership(user, &expired, nil); err != ErrExpired {
+		t.Fatalf("Expected %v, got %v", ErrExpired, err)
+	}
+
+	for _, ttl := range []time.Duration{0, -time.Minute} {
+		if _, err := NewOwnershipChallenge("api.example.com", ttl, nil); err != ErrInvalidChallenge {
+			t.Fatalf("Expected %v, got %v", ErrInvalidChallenge, err)
+		}
+	}
+
+	// Both sides use their clocks.
+	past := ClockFunc(func() time.Time { return time.Unix(1700000000, 0) })
+	old, _ := NewOwnershipChallenge("api.example.com", time.Minute, past)
+	if !old.Expires.Equal(time.Unix(1700000060, 0)) {
+		t.Fatalf("Expected the expiry from the clock, got %v", old.Expires)
+	}
+	if _, err := ProveOwnership(user, old, nil); err != ErrExpired {
+		t.Fatalf("Expected %v, got %v", ErrExpired, err)
+	}
+	if _, err := ProveOwnership(user, old, past); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
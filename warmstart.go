@@ -0,0 +1,67 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import "crypto/subtle"
+
+// FromSeedWarm loads an ed25519 seed for processes that authenticate once
+// per start, such as serverless functions. Unlike FromSeed followed by
+// PublicKey and Sign, which each decode the seed and derive the key pair,
+// the seed is decoded and the key pair derived exactly once and retained
+// as with PrecomputeKeys.
+//
+// public is the encoded public key of the seed, typically stored next to it
+// at deploy time. When given it is cached as is after checking it against
+// the derived key, saving its encoding; a key that does not belong to the
+// seed returns ErrIncompatibleKey. Pass "" to have it computed. Curve seeds
+// return ErrInvalidNKeyOperation.
+func FromSeedWarm(seed []byte, public string) (KeyPair, error) {
+	prefix, raw, err := DecodeSeed(seed)
+	if err != nil {
+		if cerr := checkSeedArg(seed); cerr != nil {
+			return nil, cerr
+		}
+		return nil, err
+	}
+	if AlgorithmOf(prefix) != AlgorithmEd25519 {
+		return nil, ErrInvalidNKeyOperation
+	}
+	if err := checkDeprecated(prefix, "load"); err != nil {
+		wipeBytes(raw)
+		return nil, err
+	}
+	pub, priv, err := currentBackend().NewKeyFromSeed(raw)
+	wipeBytes(raw)
+	if err != nil {
+		return nil, err
+	}
+	if public == "" {
+		pk, err := Encode(prefix, pub)
+		if err != nil {
+			return nil, err
+		}
+		public = string(pk)
+	} else {
+		want, err := Decode(prefix, []byte(public))
+		if err != nil || subtle.ConstantTimeCompare(want, pub) != 1 {
+			return nil, ErrIncompatibleKey
+		}
+	}
+	return &kp{
+		seed:    append([]byte{}, seed...),
+		public:  public,
+		rawPub:  pub,
+		rawPriv: priv,
+	}, nil
+}
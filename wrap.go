@@ -0,0 +1,90 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nkeys
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+)
+
+// WrapVersionV1 prefixes seeds wrapped by WrapSeed.
+const WrapVersionV1 = "nkw1"
+
+// curvePublicLen is the length of an encoded curve public key.
+const curvePublicLen = 56
+
+// WrapSeed encrypts an encoded seed to the holder of the target curve key,
+// so that it can be delivered over an untrusted channel. A fresh ephemeral
+// curve key is used as the sender, so the result does not identify who
+// wrapped it; sign it if the recipient needs to know.
+func WrapSeed(target string, seed []byte) ([]byte, error) {
+	return WrapSeedWithRand(target, seed, rand.Reader)
+}
+
+// WrapSeedWithRand is like WrapSeed but reads randomness from rr.
+func WrapSeedWithRand(target string, seed []byte, rr io.Reader) ([]byte, error) {
+	if _, _, err := DecodeSeed(seed); err != nil {
+		return nil, err
+	}
+	eph, err := createCurveKeys(rr)
+	if err != nil {
+		return nil, err
+	}
+	defer eph.Wipe()
+	epk, err := eph.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := eph.SealWithRand(seed, target, rr)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(WrapVersionV1)+len(epk)+len(sealed))
+	out = append(out, WrapVersionV1...)
+	out = append(out, epk...)
+	return append(out, sealed...), nil
+}
+
+// UnwrapSeed decrypts a seed wrapped by WrapSeed with the recipient's curve
+// key pair and returns the encoded seed.
+func UnwrapSeed(recipient KeyPair, wrapped []byte) ([]byte, error) {
+	if len(wrapped) <= len(WrapVersionV1)+curvePublicLen {
+		return nil, ErrInvalidWrappedSeed
+	}
+	if !bytes.Equal(wrapped[:len(WrapVersionV1)], []byte(WrapVersionV1)) {
+		return nil, ErrInvalidEncVersion
+	}
+	rest := wrapped[len(WrapVersionV1):]
+	sender := string(rest[:curvePublicLen])
+	seed, err := recipient.Open(rest[curvePublicLen:], sender)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := DecodeSeed(seed); err != nil {
+		wipeBytes(seed)
+		return nil, ErrInvalidWrappedSeed
+	}
+	return seed, nil
+}
+
+// UnwrapKeyPair decrypts a wrapped seed and creates a KeyPair from it.
+func UnwrapKeyPair(recipient KeyPair, wrapped []byte) (KeyPair, error) {
+	seed, err := UnwrapSeed(recipient, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(seed)
+	return FromSeed(seed)
+}
@@ -45,18 +45,40 @@ func CreateCurveKeys() (KeyPair, error) {
 
 // CreateUser will create a User typed KeyPair with specified rand source.
 func CreateCurveKeysWithRand(rr io.Reader) (KeyPair, error) {
+	if err := checkDeprecated(PrefixByteCurve, "create"); err != nil {
+		return nil, err
+	}
+	return createCurveKeys(rr)
+}
+
+// createCurveKeys is CreateCurveKeysWithRand without the deprecation check,
+// for callers that already made it.
+func createCurveKeys(rr io.Reader) (KeyPair, error) {
 	var kp ckp
 	_, err := io.ReadFull(rr, kp.seed[:])
 	if err != nil {
 		return nil, err
 	}
+	countCreated(PrefixByteCurve)
 	return &kp, nil
 }
 
 // Will create a curve key pair from seed.
 func FromCurveSeed(seed []byte) (KeyPair, error) {
+	if err := checkDeprecated(PrefixByteCurve, "load"); err != nil {
+		return nil, err
+	}
+	return fromCurveSeed(seed)
+}
+
+// fromCurveSeed is FromCurveSeed without the deprecation check, for callers
+// that already made it.
+func fromCurveSeed(seed []byte) (KeyPair, error) {
 	pb, raw, err := DecodeSeed(seed)
 	if err != nil {
+		if cerr := checkSeedArg(seed); cerr != nil {
+			return nil, cerr
+		}
 		return nil, err
 	}
 	if pb != PrefixByteCurve || len(raw) != curveKeyLen {
@@ -80,13 +102,24 @@ func (pair *ckp) PublicKey() (string, error) {
 	return string(key), err
 }
 
+// PublicOnly returns a KeyPair holding only the public curve key.
+func (pair *ckp) PublicOnly() (KeyPair, error) {
+	var raw [curveKeyLen]byte
+	curve25519.ScalarBaseMult(&raw, &pair.seed)
+	return &pub{pre: PrefixByteCurve, pub: raw[:]}, nil
+}
+
 // PrivateKey will return the encoded private key.
 func (pair *ckp) PrivateKey() ([]byte, error) {
 	return Encode(PrefixBytePrivate, pair.seed[:])
 }
 
-func decodePubCurveKey(src string, dest [curveKeyLen]byte) error {
+func decodePubCurveKey(src string, dest *[curveKeyLen]byte) error {
 	var raw [curveDecodeLen]byte // should always be 35
+	// Longer input, such as a seed, would overflow raw.
+	if b32Enc.DecodedLen(len(src)) != curveDecodeLen {
+		return ErrInvalidCurveKey
+	}
 	n, err := b32Enc.Decode(raw[:], []byte(src))
 	if err != nil {
 		return err
@@ -134,7 +167,10 @@ func (pair *ckp) SealWithRand(input []byte, recipient string, rr io.Reader) ([]b
 		err   error
 	)
 
-	if err = decodePubCurveKey(recipient, rpub); err != nil {
+	if err = decodePubCurveKey(recipient, &rpub); err != nil {
+		if cerr := checkPublicKeyArg(recipient); cerr != nil {
+			return nil, cerr
+		}
 		return nil, ErrInvalidRecipient
 	}
 	if _, err := io.ReadFull(rr, nonce[:]); err != nil {
@@ -159,7 +195,10 @@ func (pair *ckp) Open(input []byte, sender string) ([]byte, error) {
 	}
 	copy(nonce[:], input[vlen:vlen+curveNonceLen])
 
-	if err = decodePubCurveKey(sender, spub); err != nil {
+	if err = decodePubCurveKey(sender, &spub); err != nil {
+		if cerr := checkPublicKeyArg(sender); cerr != nil {
+			return nil, cerr
+		}
 		return nil, ErrInvalidSender
 	}
 
@@ -15,6 +15,9 @@ package nkeys
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
 	"reflect"
 	"testing"
 )
@@ -154,3 +157,215 @@ func TestCurvePublic(t *testing.T) {
 		t.Fatalf("Expected %v but got %v", ErrCannotSeal, err)
 	}
 }
+
+func TestWrapSeed(t *testing.T) {
+	tenant, _ := CreateCurveKeys()
+	tpk, _ := tenant.PublicKey()
+	acc, _ := CreateAccount()
+	seed, _ := acc.Seed()
+
+	wrapped, err := WrapSeed(tpk, seed)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if bytes.Contains(wrapped, seed) {
+		t.Fatalf("Expected the seed to be encrypted")
+	}
+	kp, err := UnwrapKeyPair(tenant, wrapped)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	got, _ := kp.Seed()
+	if !bytes.Equal(got, seed) {
+		t.Fatalf("Expected seeds to match")
+	}
+
+	other, _ := CreateCurveKeys()
+	if _, err := UnwrapSeed(other, wrapped); err != ErrCouldNotDecrypt {
+		t.Fatalf("Expected %v, got %v", ErrCouldNotDecrypt, err)
+	}
+	if _, err := UnwrapSeed(tenant, wrapped[:10]); err != ErrInvalidWrappedSeed {
+		t.Fatalf("Expected %v, got %v", ErrInvalidWrappedSeed, err)
+	}
+	if _, err := WrapSeed(tpk, []byte("bad")); err == nil {
+		t.Fatalf("Expected invalid seeds to be rejected")
+	}
+	apk, _ := acc.PublicKey()
+	if _, err := WrapSeed(apk, seed); err != ErrInvalidRecipient {
+		t.Fatalf("Expected %v, got %v", ErrInvalidRecipient, err)
+	}
+}
+
+func TestSealOnlyOpensForRecipient(t *testing.T) {
+	sender, _ := CreateCurveKeys()
+	recipient, _ := CreateCurveKeys()
+	eve, _ := CreateCurveKeys()
+	spk, _ := sender.PublicKey()
+	rpk, _ := recipient.PublicKey()
+
+	sealed, err := sender.Seal([]byte("secret"), rpk)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := recipient.Open(sealed, spk); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := eve.Open(sealed, spk); err != ErrCouldNotDecrypt {
+		t.Fatalf("Expected %v, got %v", ErrCouldNotDecrypt, err)
+	}
+}
+
+func TestDeriveEncryptionKey(t *testing.T) {
+	user, _ := CreateUser()
+	upk, _ := user.PublicKey()
+	ekp, err := DeriveEncryptionKey(user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	epk, _ := ekp.PublicKey()
+	if !IsValidPublicCurveKey(epk) {
+		t.Fatalf("Expected a curve key, got %q", epk)
+	}
+	again, _ := DeriveEncryptionKey(user)
+	if apk, _ := again.PublicKey(); apk != epk {
+		t.Fatalf("Expected derivation to be deterministic")
+	}
+	other, _ := CreateUser()
+	oekp, _ := DeriveEncryptionKey(other)
+	if opk, _ := oekp.PublicKey(); opk == epk {
+		t.Fatalf("Expected distinct keys for distinct identities")
+	}
+
+	proof, err := LinkEncryptionKey(user, epk)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := VerifyEncryptionKeyLink(upk, epk, proof); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	xkp, _ := CreateCurveKeys()
+	xpk, _ := xkp.PublicKey()
+	if err := VerifyEncryptionKeyLink(upk, xpk, proof); err != ErrInvalidSignature {
+		t.Fatalf("Expected %v, got %v", ErrInvalidSignature, err)
+	}
+	if _, err := DeriveEncryptionKey(xkp); err != ErrUnsupportedAlgorithm {
+		t.Fatalf("Expected %v, got %v", ErrUnsupportedAlgorithm, err)
+	}
+}
+
+// Curve keys share their encoding with the other nkeys ports: 'X' public
+// keys, 'SX' seeds holding the raw X25519 private key and 'P' private keys,
+// and sealed messages are "xkv1" || nonce || nacl box. The vectors use the
+// RFC 7748 section 6.1 key pairs so any port can reproduce them.
+var curveVectors = struct {
+	alicePriv, alicePub, bobPriv, bobPub string
+	aliceSeed, alicePublic, alicePrivate string
+	bobSeed, bobPublic                   string
+	nonce, message, sealed               string
+}{
+	alicePriv:    "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a",
+	alicePub:     "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a",
+	bobPriv:      "5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb",
+	bobPub:       "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f",
+	aliceSeed:    "SXAHOB3NBJZRRJL5HQLMC4SRWJTELX2MF6D6XQEZFKYXP65FDW4SYKQZVQ",
+	alicePublic:  "XCCSB4AJREYKOVDURN65ZNB665NA3PZ2BUTDQGXU5OSKTDVKTNHGULRF",
+	alicePrivate: "PB3QO3IKOMMKK7J4C3AXEUNSMZC56TBPQ7V4BGJKWF37XJI5XEWCUJ4F",
+	bobSeed:      "SXAF3KYIPZREVCSLPHQX7C4DQAHOM3Z3WEUSMGFW7UOC7CZH76EOB22AYQ",
+	bobPublic:    "XDPJ5W35PN64DNGTLNQ4F3HEGU3T7A2DZBNXQZ2NVX6H4FDPRAVU74VP",
+	nonce:        "424242424242424242424242424242424242424242424242",
+	message:      "Hello xkeys!",
+	sealed:       "786b763142424242424242424242424242424242424242424242424248536d3fe3841f4384e48042d1f558413b805af149ef4580e2e26c2e",
+}
+
+func TestCurveInteropVectors(t *testing.T) {
+	v := curveVectors
+	alicePriv, _ := hex.DecodeString(v.alicePriv)
+	bobPriv, _ := hex.DecodeString(v.bobPriv)
+
+	for _, tc := range []struct {
+		raw                  []byte
+		seed, public, rawPub string
+	}{
+		{alicePriv, v.aliceSeed, v.alicePublic, v.alicePub},
+		{bobPriv, v.bobSeed, v.bobPublic, v.bobPub},
+	} {
+		seed, err := EncodeSeed(PrefixByteCurve, tc.raw)
+		if err != nil || string(seed) != tc.seed {
+			t.Fatalf("Expected seed %q, got %q (%v)", tc.seed, seed, err)
+		}
+		kp, err := FromSeed(seed)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if pk, _ := kp.PublicKey(); pk != tc.public {
+			t.Fatalf("Expected public key %q, got %q", tc.public, pk)
+		}
+		raw, err := Decode(PrefixByteCurve, []byte(tc.public))
+		if err != nil || hex.EncodeToString(raw) != tc.rawPub {
+			t.Fatalf("Expected raw public key %s, got %x (%v)", tc.rawPub, raw, err)
+		}
+	}
+
+	alice, _ := FromSeed([]byte(v.aliceSeed))
+	bob, _ := FromSeed([]byte(v.bobSeed))
+	if priv, _ := alice.PrivateKey(); string(priv) != v.alicePrivate {
+		t.Fatalf("Expected private key %q, got %q", v.alicePrivate, priv)
+	}
+	nonce, _ := hex.DecodeString(v.nonce)
+	sealed, err := alice.SealWithRand([]byte(v.message), v.bobPublic, bytes.NewReader(nonce))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if hex.EncodeToString(sealed) != v.sealed {
+		t.Fatalf("Expected sealed %s, got %x", v.sealed, sealed)
+	}
+	expected, _ := hex.DecodeString(v.sealed)
+	opened, err := bob.Open(expected, v.alicePublic)
+	if err != nil || string(opened) != v.message {
+		t.Fatalf("Expected %q, got %q (%v)", v.message, opened, err)
+	}
+}
+
+func TestEncryptStream(t *testing.T) {
+	sender, _ := CreateCurveKeys()
+	spub, _ := sender.PublicKey()
+	recipient, _ := CreateCurveKeys()
+	rpub, _ := recipient.PublicKey()
+
+	for _, size := range []int{0, 10, fileChunkSize, 3*fileChunkSize + 7} {
+		content := make([]byte, size)
+		rand.Read(content)
+		var enc bytes.Buffer
+		if err := EncryptStream(&enc, bytes.NewReader(content), sender, rpub); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		data := enc.Bytes()
+
+		var dec bytes.Buffer
+		from, err := DecryptStream(&dec, bytes.NewReader(data), recipient)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if from != spub || !bytes.Equal(dec.Bytes(), content) {
+			t.Fatalf("Expected the content from %q, got %d bytes from %q", spub, dec.Len(), from)
+		}
+
+		// Dropping the last chunk is detected even on chunk boundaries.
+		lastLen := size % fileChunkSize
+		if size > 0 && lastLen == 0 {
+			lastLen = fileChunkSize
+		}
+		last := len(data) - (lastLen + 16 + 4)
+		if _, err := DecryptStream(io.Discard, bytes.NewReader(data[:last]), recipient); err != ErrInvalidEncryptedFile {
+			t.Fatalf("Expected %v, got %v", ErrInvalidEncryptedFile, err)
+		}
+		tampered := append([]byte{}, data...)
+		tampered[len(tampered)-1] ^= 1
+		if _, err := DecryptStream(io.Discard, bytes.NewReader(tampered), recipient); err != ErrInvalidEncryptedFile {
+			t.Fatalf("Expected %v, got %v", ErrInvalidEncryptedFile, err)
+		}
+		if _, err := DecryptStream(io.Discard, bytes.NewReader(append(data, 0)), recipient); err != ErrInvalidEncryptedFile {
+			t.Fatalf("Expected %v, got %v", ErrInvalidEncryptedFile, err)
+		}
+	}
+}